@@ -0,0 +1,406 @@
+// Package chess implements chess rules, FEN handling and PGN parsing for the backend.
+// It replays games with full legality checking so that handlers can work with real
+// position data instead of treating PGNs as opaque strings.
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The FEN of the standard starting position.
+const StartingFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+type Color int8
+
+const (
+	White Color = iota
+	Black
+)
+
+// Other returns the opposite color.
+func (c Color) Other() Color {
+	return c ^ 1
+}
+
+func (c Color) String() string {
+	if c == White {
+		return "w"
+	}
+	return "b"
+}
+
+type PieceType int8
+
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+// Returns the uppercase SAN letter of the piece type. Pawns return an empty string.
+func (pt PieceType) String() string {
+	switch pt {
+	case Knight:
+		return "N"
+	case Bishop:
+		return "B"
+	case Rook:
+		return "R"
+	case Queen:
+		return "Q"
+	case King:
+		return "K"
+	}
+	return ""
+}
+
+// pieceTypeFromLetter returns the piece type for the given uppercase letter.
+func pieceTypeFromLetter(b byte) PieceType {
+	switch b {
+	case 'P':
+		return Pawn
+	case 'N':
+		return Knight
+	case 'B':
+		return Bishop
+	case 'R':
+		return Rook
+	case 'Q':
+		return Queen
+	case 'K':
+		return King
+	}
+	return NoPieceType
+}
+
+// Piece is a colored piece. The zero value is an empty square.
+type Piece struct {
+	Type  PieceType
+	Color Color
+}
+
+// NoPiece represents an empty square.
+var NoPiece = Piece{}
+
+// fenLetter returns the letter used for the piece in a FEN string.
+func (p Piece) fenLetter() byte {
+	letter := "?PNBRQK"[p.Type]
+	if p.Color == Black {
+		return letter + ('a' - 'A')
+	}
+	return letter
+}
+
+// Square is an index into the board, where a1 is 0, b1 is 1 and h8 is 63.
+type Square int8
+
+// NoSquare is used when a square is not applicable, such as an absent en passant target.
+const NoSquare Square = -1
+
+// NewSquare returns the square with the given 0-based file and rank.
+func NewSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+// ParseSquare parses a square in algebraic notation, such as e4.
+func ParseSquare(s string) (Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return NoSquare, fmt.Errorf("invalid square %q", s)
+	}
+	return NewSquare(int(s[0]-'a'), int(s[1]-'1')), nil
+}
+
+// File returns the 0-based file of the square.
+func (s Square) File() int {
+	return int(s) % 8
+}
+
+// Rank returns the 0-based rank of the square.
+func (s Square) Rank() int {
+	return int(s) / 8
+}
+
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+// CastlingRights is a bitmask of the castling moves still available.
+type CastlingRights uint8
+
+const (
+	WhiteKingside CastlingRights = 1 << iota
+	WhiteQueenside
+	BlackKingside
+	BlackQueenside
+)
+
+func (cr CastlingRights) String() string {
+	var sb strings.Builder
+	if cr&WhiteKingside != 0 {
+		sb.WriteByte('K')
+	}
+	if cr&WhiteQueenside != 0 {
+		sb.WriteByte('Q')
+	}
+	if cr&BlackKingside != 0 {
+		sb.WriteByte('k')
+	}
+	if cr&BlackQueenside != 0 {
+		sb.WriteByte('q')
+	}
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
+
+// Position is a complete chess position, equivalent to a FEN.
+type Position struct {
+	board [64]Piece
+
+	// The side to move.
+	Turn Color
+
+	// The castling moves still available.
+	Castling CastlingRights
+
+	// The en passant target square, or NoSquare.
+	EnPassant Square
+
+	// The number of halfmoves since the last capture or pawn move.
+	HalfmoveClock int
+
+	// The number of the full move, starting at 1 and incremented after Black moves.
+	FullmoveNumber int
+}
+
+// NewPosition returns the standard starting position.
+func NewPosition() *Position {
+	p, _ := ParseFen(StartingFen)
+	return p
+}
+
+// ParseFen parses the given FEN into a Position. The halfmove clock and fullmove number
+// fields are optional and default to 0 and 1 respectively. The position must contain
+// exactly one king per side and the side not to move must not be in check.
+func ParseFen(fen string) (*Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid FEN %q: expected at least 4 fields", fen)
+	}
+
+	p := &Position{EnPassant: NoSquare, FullmoveNumber: 1}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("invalid FEN %q: expected 8 ranks", fen)
+	}
+	for i, row := range ranks {
+		rank := 7 - i
+		file := 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+
+			color := White
+			if c >= 'a' && c <= 'z' {
+				color = Black
+				c -= 'a' - 'A'
+			}
+			pt := pieceTypeFromLetter(c)
+			if pt == NoPieceType || file > 7 {
+				return nil, fmt.Errorf("invalid FEN %q: bad piece placement on rank %d", fen, rank+1)
+			}
+			p.board[NewSquare(file, rank)] = Piece{Type: pt, Color: color}
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("invalid FEN %q: rank %d does not have 8 files", fen, rank+1)
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		p.Turn = White
+	case "b":
+		p.Turn = Black
+	default:
+		return nil, fmt.Errorf("invalid FEN %q: bad side to move %q", fen, fields[1])
+	}
+
+	if fields[2] != "-" {
+		for _, c := range fields[2] {
+			switch c {
+			case 'K':
+				p.Castling |= WhiteKingside
+			case 'Q':
+				p.Castling |= WhiteQueenside
+			case 'k':
+				p.Castling |= BlackKingside
+			case 'q':
+				p.Castling |= BlackQueenside
+			default:
+				return nil, fmt.Errorf("invalid FEN %q: bad castling rights %q", fen, fields[2])
+			}
+		}
+	}
+
+	if fields[3] != "-" {
+		sq, err := ParseSquare(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN %q: bad en passant square: %w", fen, err)
+		}
+		p.EnPassant = sq
+	}
+
+	if len(fields) > 4 {
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid FEN %q: bad halfmove clock %q", fen, fields[4])
+		}
+		p.HalfmoveClock = n
+	}
+	if len(fields) > 5 {
+		n, err := strconv.Atoi(fields[5])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid FEN %q: bad fullmove number %q", fen, fields[5])
+		}
+		p.FullmoveNumber = n
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+	p.sanitizeCastling()
+	return p, nil
+}
+
+// validate checks the piece placement for basic legality.
+func (p *Position) validate() error {
+	kings := [2]int{}
+	for sq, piece := range p.board {
+		if piece.Type == King {
+			kings[piece.Color]++
+		}
+		if piece.Type == Pawn && (Square(sq).Rank() == 0 || Square(sq).Rank() == 7) {
+			return fmt.Errorf("pawn on back rank %s", Square(sq))
+		}
+	}
+	if kings[White] != 1 || kings[Black] != 1 {
+		return fmt.Errorf("each side must have exactly one king")
+	}
+	if p.isAttacked(p.kingSquare(p.Turn.Other()), p.Turn) {
+		return fmt.Errorf("side not to move is in check")
+	}
+	return nil
+}
+
+// sanitizeCastling removes castling rights which are impossible given the piece placement.
+func (p *Position) sanitizeCastling() {
+	required := []struct {
+		right CastlingRights
+		king  Square
+		rook  Square
+		color Color
+	}{
+		{WhiteKingside, NewSquare(4, 0), NewSquare(7, 0), White},
+		{WhiteQueenside, NewSquare(4, 0), NewSquare(0, 0), White},
+		{BlackKingside, NewSquare(4, 7), NewSquare(7, 7), Black},
+		{BlackQueenside, NewSquare(4, 7), NewSquare(0, 7), Black},
+	}
+	for _, r := range required {
+		if p.board[r.king] != (Piece{King, r.color}) || p.board[r.rook] != (Piece{Rook, r.color}) {
+			p.Castling &^= r.right
+		}
+	}
+}
+
+// PieceAt returns the piece on the given square.
+func (p *Position) PieceAt(sq Square) Piece {
+	return p.board[sq]
+}
+
+// Copy returns a deep copy of the position.
+func (p *Position) Copy() *Position {
+	c := *p
+	return &c
+}
+
+// Fen returns the full FEN of the position.
+func (p *Position) Fen() string {
+	return fmt.Sprintf("%s %s %s %s %d %d", p.placement(), p.Turn, p.Castling, p.EnPassant, p.HalfmoveClock, p.FullmoveNumber)
+}
+
+// NormalizedFen returns the FEN of the position, normalized in the same way as the
+// explorer: the en passant square is only included if en passant is a legal move, the
+// halfmove clock is set to 0 and the fullmove number is set to 1.
+func (p *Position) NormalizedFen() string {
+	ep := NoSquare
+	if p.EnPassant != NoSquare {
+		for _, m := range p.LegalMoves() {
+			if m.IsEnPassant() {
+				ep = p.EnPassant
+				break
+			}
+		}
+	}
+	return fmt.Sprintf("%s %s %s %s 0 1", p.placement(), p.Turn, p.Castling, ep)
+}
+
+// NormalizeFen parses the given FEN and returns its normalized form. See Position.NormalizedFen.
+func NormalizeFen(fen string) (string, error) {
+	p, err := ParseFen(fen)
+	if err != nil {
+		return "", err
+	}
+	return p.NormalizedFen(), nil
+}
+
+// placement returns the piece placement field of the FEN.
+func (p *Position) placement() string {
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := p.board[NewSquare(file, rank)]
+			if piece == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(piece.fenLetter())
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	return sb.String()
+}
+
+// kingSquare returns the square of the king of the given color.
+func (p *Position) kingSquare(c Color) Square {
+	for sq, piece := range p.board {
+		if piece.Type == King && piece.Color == c {
+			return Square(sq)
+		}
+	}
+	return NoSquare
+}
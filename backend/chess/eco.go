@@ -0,0 +1,102 @@
+package chess
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//go:generate ../scripts/eco.sh eco.tsv
+
+// The opening table, with one opening per line in the form eco<TAB>name<TAB>pgn. The first
+// line is a header. It is generated from the lichess chess-openings dataset.
+//
+//go:embed eco.tsv
+var ecoTable string
+
+// The openings of ecoTable, keyed by the normalized FEN of the position after their moves,
+// and the set of ECO codes in ecoTable.
+var (
+	openingsOnce  sync.Once
+	openingsByFen map[string]Opening
+	ecoCodes      map[string]bool
+)
+
+// Opening is a named opening from the ECO classification.
+type Opening struct {
+	// The ECO code of the opening, such as C60.
+	Eco string
+
+	// The name of the opening, such as Ruy Lopez.
+	Name string
+}
+
+// parseOpenings returns the openings in the given table, keyed by the normalized FEN of
+// the position reached by their moves.
+func parseOpenings(table string) (map[string]Opening, error) {
+	openings := make(map[string]Opening)
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	for i, line := range lines[1:] {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected 3 fields, got %d", i+2, len(fields))
+		}
+		game, err := Parse(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		fen := game.FinalPosition().NormalizedFen()
+		if _, ok := openings[fen]; ok {
+			return nil, fmt.Errorf("line %d: duplicate position %q", i+2, fen)
+		}
+		openings[fen] = Opening{Eco: fields[0], Name: fields[1]}
+	}
+	return openings, nil
+}
+
+// loadOpenings parses ecoTable on first use.
+func loadOpenings() map[string]Opening {
+	openingsOnce.Do(func() {
+		var err error
+		if openingsByFen, err = parseOpenings(ecoTable); err != nil {
+			panic(fmt.Sprintf("chess: invalid opening table: %v", err))
+		}
+		ecoCodes = make(map[string]bool)
+		for _, opening := range openingsByFen {
+			ecoCodes[opening.Eco] = true
+		}
+	})
+	return openingsByFen
+}
+
+// LookupOpening returns the opening whose moves reach the given position.
+func LookupOpening(p *Position) (Opening, bool) {
+	opening, ok := loadOpenings()[p.NormalizedFen()]
+	return opening, ok
+}
+
+// The number of ECO codes, A00 through E99.
+const ecoCodeCount = 500
+
+// HasAllEcos returns true if the opening table contains an opening for every ECO code. Only
+// a table which does can be trusted to classify a game more accurately than its own ECO
+// header.
+func HasAllEcos() bool {
+	loadOpenings()
+	return len(ecoCodes) == ecoCodeCount
+}
+
+// ClassifyOpening returns the opening of the given game, which is the opening of the last
+// mainline position found in the opening table. Since openings are looked up by position,
+// games which reach an opening by a different move order are classified the same way.
+func ClassifyOpening(g *Game) (Opening, bool) {
+	var opening Opening
+	var found bool
+	for _, p := range g.Positions() {
+		if o, ok := LookupOpening(p); ok {
+			opening, found = o, true
+		}
+	}
+	return opening, found
+}
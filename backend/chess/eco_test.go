@@ -0,0 +1,75 @@
+package chess
+
+import "testing"
+
+func TestParseOpenings(t *testing.T) {
+	openings, err := parseOpenings(ecoTable)
+	if err != nil {
+		t.Fatalf("parseOpenings got error: %v", err)
+	}
+	if len(openings) == 0 {
+		t.Errorf("parseOpenings got no openings")
+	}
+
+	_, err = parseOpenings("eco\tname\tpgn\nC20\tKing's Pawn Game\t1. e4 e5\nC20\tDuplicate\t1. e4 e5")
+	if err == nil {
+		t.Errorf("parseOpenings with duplicate position got nil error")
+	}
+}
+
+func TestClassifyOpening(t *testing.T) {
+	table := []struct {
+		name   string
+		pgn    string
+		want   Opening
+		wantOk bool
+	}{
+		{
+			name:   "NoMoves",
+			pgn:    "*",
+			wantOk: false,
+		},
+		{
+			name:   "ExactMatch",
+			pgn:    "1. e4 e5 2. Nf3 Nc6 3. Bb5 *",
+			want:   Opening{Eco: "C60", Name: "Ruy Lopez"},
+			wantOk: true,
+		},
+		{
+			name:   "DeepestMatch",
+			pgn:    "1. e4 c5 2. Nf3 d6 3. d4 cxd4 4. Nxd4 Nf6 5. Nc3 a6 6. h3 e5 7. Nde2 *",
+			want:   Opening{Eco: "B90", Name: "Sicilian Defense: Najdorf Variation"},
+			wantOk: true,
+		},
+		{
+			name:   "Transposition",
+			pgn:    "1. Nf3 d5 2. d4 Nf6 3. c4 e6 4. Nc3 *",
+			want:   Opening{Eco: "D37", Name: "Queen's Gambit Declined: Three Knights Variation"},
+			wantOk: true,
+		},
+		{
+			name:   "LeavesBook",
+			pgn:    "1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0",
+			want:   Opening{Eco: "C20", Name: "King's Pawn Game"},
+			wantOk: true,
+		},
+		{
+			name:   "CustomPosition",
+			pgn:    "[SetUp \"1\"]\n[FEN \"8/8/8/4k3/8/8/4P3/4K3 w - - 0 1\"]\n\n1. e4 *",
+			wantOk: false,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			game, err := Parse(tc.pgn)
+			if err != nil {
+				t.Fatalf("Parse got error: %v", err)
+			}
+			got, ok := ClassifyOpening(game)
+			if ok != tc.wantOk || got != tc.want {
+				t.Errorf("ClassifyOpening got: %+v, %v; want: %+v, %v", got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
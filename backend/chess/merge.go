@@ -0,0 +1,209 @@
+package chess
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Merge combines the changes made to base in g and in other, which were both edited from
+// base, and stores the result in g. A change made in only one of the games is kept, so a
+// move or annotation removed in other is also removed from g unless g changed it too.
+// Moves are matched by their path from the starting position. A removed move is kept if
+// the other game changed its annotations or continuations. When both games change the
+// same annotation, g's value is kept, except that two changed comments are combined unless
+// one contains the other. Headers and the result are merged in the same way. The games
+// must start from the same position.
+func (g *Game) Merge(base, other *Game) error {
+	if g.Root.Position.Fen() != other.Root.Position.Fen() || g.Root.Position.Fen() != base.Root.Position.Fen() {
+		return fmt.Errorf("games start from different positions")
+	}
+
+	names := slices.Concat(base.headerOrder, other.headerOrder)
+	for _, name := range names {
+		if g.Header(name) != base.Header(name) || other.Header(name) == base.Header(name) {
+			continue
+		}
+		if value := other.Header(name); value == "" {
+			g.DeleteHeader(name)
+		} else {
+			g.SetHeader(name, value)
+		}
+	}
+	if g.Result == base.Result {
+		g.Result = other.Result
+	}
+	return mergeNode(g.Root, base.Root, other.Root)
+}
+
+// mergeNode merges the changes made to base in src into dst, which was also edited from
+// base, and then recursively merges the children. base is nil if neither dst nor src
+// existed in the base game.
+func mergeNode(dst, base, src *Node) error {
+	if base == nil {
+		base = &Node{}
+	}
+
+	dst.CommentBefore = mergeComment(dst.CommentBefore, base.CommentBefore, src.CommentBefore)
+	dst.Comment = mergeComment(dst.Comment, base.Comment, src.Comment)
+	dst.Nags = mergeNags(dst.Nags, base.Nags, src.Nags)
+	dst.Commands = mergeNodeCommands(dst.Commands, base.Commands, src.Commands)
+
+	keepBaseOrder := hasBaseOrder(dst.Children, base)
+	children := make([]*Node, 0, len(dst.Children))
+	for _, child := range dst.Children {
+		baseChild := findChild(base, child.Move)
+		srcChild := findChild(src, child.Move)
+		switch {
+		case srcChild != nil:
+			if err := mergeNode(child, baseChild, srcChild); err != nil {
+				return err
+			}
+		case baseChild != nil && unchanged(child, baseChild):
+			// Removed in src and not changed in dst
+			continue
+		}
+		children = append(children, child)
+	}
+	dst.Children = children
+
+	for _, child := range src.Children {
+		if findChild(dst, child.Move) != nil {
+			continue
+		}
+		if baseChild := findChild(base, child.Move); baseChild != nil && unchanged(child, baseChild) {
+			// Removed in dst and not changed in src
+			continue
+		}
+		next, err := dst.AddMove(child.Move)
+		if err != nil {
+			return err
+		}
+		if err := mergeNode(next, nil, child); err != nil {
+			return err
+		}
+	}
+
+	if keepBaseOrder && !hasBaseOrder(src.Children, base) {
+		// Only src reordered the moves, such as by promoting a variation
+		slices.SortStableFunc(dst.Children, func(a, b *Node) int {
+			return cmp.Compare(childIndex(src, a.Move), childIndex(src, b.Move))
+		})
+	}
+	return nil
+}
+
+// findChild returns the child of n reached by the given move, or nil if there is none.
+func findChild(n *Node, m Move) *Node {
+	if i := childIndex(n, m); i < len(n.Children) {
+		return n.Children[i]
+	}
+	return nil
+}
+
+// childIndex returns the index of the child of n reached by the given move, or the number
+// of children if there is none.
+func childIndex(n *Node, m Move) int {
+	for i, c := range n.Children {
+		if c.Move == m {
+			return i
+		}
+	}
+	return len(n.Children)
+}
+
+// hasBaseOrder returns true if the given children which also exist in base are in the
+// same order as they are in base.
+func hasBaseOrder(children []*Node, base *Node) bool {
+	last := -1
+	for _, child := range children {
+		i := childIndex(base, child.Move)
+		if i == len(base.Children) {
+			continue
+		}
+		if i < last {
+			return false
+		}
+		last = i
+	}
+	return true
+}
+
+// unchanged returns true if n and its continuations have the same moves and annotations
+// as base.
+func unchanged(n, base *Node) bool {
+	if n.CommentBefore != base.CommentBefore || n.Comment != base.Comment ||
+		!slices.Equal(n.Nags, base.Nags) || !maps.Equal(n.Commands, base.Commands) ||
+		len(n.Children) != len(base.Children) {
+		return false
+	}
+	for i, child := range n.Children {
+		if child.Move != base.Children[i].Move || !unchanged(child, base.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeComment returns the comment resulting from changing base to ours in one game and to
+// theirs in the other. If both changed it, the changed comments are combined.
+func mergeComment(ours, base, theirs string) string {
+	if theirs == base || theirs == ours {
+		return ours
+	}
+	if ours == base {
+		return theirs
+	}
+	if strings.Contains(ours, theirs) {
+		return ours
+	}
+	if strings.Contains(theirs, ours) {
+		return theirs
+	}
+	return ours + " " + theirs
+}
+
+// mergeNags returns ours with the NAGs added to and removed from base in theirs applied.
+func mergeNags(ours, base, theirs []int) []int {
+	var result []int
+	for _, nag := range ours {
+		if slices.Contains(theirs, nag) || !slices.Contains(base, nag) {
+			result = append(result, nag)
+		}
+	}
+	for _, nag := range theirs {
+		if !slices.Contains(base, nag) && !slices.Contains(result, nag) {
+			result = append(result, nag)
+		}
+	}
+	return result
+}
+
+// mergeNodeCommands returns ours with the commands changed from base in theirs applied to
+// the commands ours did not change.
+func mergeNodeCommands(ours, base, theirs map[string]string) map[string]string {
+	result := maps.Clone(ours)
+	for _, commands := range []map[string]string{base, theirs} {
+		for name := range commands {
+			ourValue, ourOk := ours[name]
+			baseValue, baseOk := base[name]
+			if ourValue != baseValue || ourOk != baseOk {
+				continue
+			}
+			if value, ok := theirs[name]; ok {
+				if result == nil {
+					result = make(map[string]string)
+				}
+				result[name] = value
+			} else {
+				delete(result, name)
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
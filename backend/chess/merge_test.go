@@ -0,0 +1,148 @@
+package chess
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		ours  string
+		other string
+		want  string
+	}{
+		{
+			name:  "AddsVariation",
+			base:  "1. e4 e5 *",
+			ours:  "1. e4 e5 2. Nf3 *",
+			other: "1. e4 e5 (1... c5) *",
+			want:  "1. e4 e5 (1... c5) 2. Nf3 *",
+		},
+		{
+			name:  "ExtendsMainline",
+			base:  "1. e4 e5 *",
+			ours:  "1. e4 e5 *",
+			other: "1. e4 e5 2. Nf3 Nc6 *",
+			want:  "1. e4 e5 2. Nf3 Nc6 *",
+		},
+		{
+			name:  "RemovesMove",
+			base:  "1. e4 e5 (1... c5) 2. Nf3 *",
+			ours:  "1. e4 e5 (1... c5) 2. Nf3 {Develops} *",
+			other: "1. e4 e5 2. Nf3 *",
+			want:  "1. e4 e5 2. Nf3 {Develops} *",
+		},
+		{
+			name:  "KeepsRemovedMoveChangedByOther",
+			base:  "1. e4 e5 (1... c5) *",
+			ours:  "1. e4 e5 (1... c5 {Sicilian}) *",
+			other: "1. e4 e5 *",
+			want:  "1. e4 e5 (1... c5 {Sicilian}) *",
+		},
+		{
+			name:  "DoesNotRestoreRemovedMove",
+			base:  "1. e4 e5 (1... c5) *",
+			ours:  "1. e4 e5 *",
+			other: "1. e4 e5 (1... c5) 2. Nf3 *",
+			want:  "1. e4 e5 2. Nf3 *",
+		},
+		{
+			name:  "ReplacesComment",
+			base:  "1. e4 {Good} e5 *",
+			ours:  "1. e4 {Good} e5 {Symmetrical} *",
+			other: "1. e4 {Best by test} e5 *",
+			want:  "1. e4 {Best by test} 1... e5 {Symmetrical} *",
+		},
+		{
+			name:  "RemovesComment",
+			base:  "1. e4 {Good} *",
+			ours:  "1. e4 {Good} *",
+			other: "1. e4 *",
+			want:  "1. e4 *",
+		},
+		{
+			name:  "CombinesConflictingComments",
+			base:  "1. e4 {Good} *",
+			ours:  "1. e4 {Central} *",
+			other: "1. e4 {Best by test} *",
+			want:  "1. e4 {Central Best by test} *",
+		},
+		{
+			name:  "ExtendedComment",
+			base:  "1. e4 {Good} *",
+			ours:  "1. e4 {Good.} *",
+			other: "1. e4 {Good. Controls the center} *",
+			want:  "1. e4 {Good. Controls the center} *",
+		},
+		{
+			name:  "MergesNagsAndCommands",
+			base:  "1. e4 $1 {[%clk 1:30:00] [%eval 0.2]} *",
+			ours:  "1. e4 $1 {[%clk 1:30:00] [%eval 0.2] [%cal Ge2e4]} *",
+			other: "1. e4 $14 {[%clk 1:30:00] [%eval 0.3]} *",
+			want:  "1. e4 $14 {[%cal Ge2e4] [%clk 1:30:00] [%eval 0.3]} *",
+		},
+		{
+			name:  "PromotesVariation",
+			base:  "1. e4 e5 (1... c5) *",
+			ours:  "1. e4 e5 (1... c5) *",
+			other: "1. e4 c5 (1... e5) *",
+			want:  "1. e4 c5 (1... e5) *",
+		},
+		{
+			name:  "TakesResult",
+			base:  "1. e4 e5 *",
+			ours:  "1. e4 e5 *",
+			other: "1. e4 e5 1-0",
+			want:  "1. e4 e5 1-0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			base, err := Parse(tc.base)
+			if err != nil {
+				t.Fatalf("Parse(base) got error: %v", err)
+			}
+			ours, err := Parse(tc.ours)
+			if err != nil {
+				t.Fatalf("Parse(ours) got error: %v", err)
+			}
+			other, err := Parse(tc.other)
+			if err != nil {
+				t.Fatalf("Parse(other) got error: %v", err)
+			}
+
+			if err := ours.Merge(base, other); err != nil {
+				t.Fatalf("Merge got error: %v", err)
+			}
+			if got := ours.Movetext(); got != tc.want {
+				t.Errorf("Merge got: %q; want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeHeaders(t *testing.T) {
+	base, _ := Parse("[White \"Alice\"]\n[Black \"?\"]\n[Annotator \"Alice\"]\n\n1. e4 *")
+	ours, _ := Parse("[White \"Alice\"]\n[Black \"Bob\"]\n[Annotator \"Alice\"]\n\n1. e4 *")
+	other, _ := Parse("[White \"Carol\"]\n[Black \"?\"]\n[Event \"Club Match\"]\n\n1. e4 *")
+
+	if err := ours.Merge(base, other); err != nil {
+		t.Fatalf("Merge got error: %v", err)
+	}
+	want := map[string]string{"White": "Carol", "Black": "Bob", "Event": "Club Match", "Annotator": ""}
+	for name, value := range want {
+		if got := ours.Header(name); got != value {
+			t.Errorf("Header(%s) got: %q; want: %q", name, got, value)
+		}
+	}
+}
+
+func TestMergeDifferentStart(t *testing.T) {
+	base, _ := Parse("1. e4 *")
+	ours, _ := Parse("1. e4 *")
+	other, _ := Parse("[FEN \"4k3/8/8/8/8/8/8/4K2R w K - 0 1\"]\n[SetUp \"1\"]\n\n1. O-O *")
+
+	if err := ours.Merge(base, other); err == nil {
+		t.Errorf("Merge got nil error; want error for different starting positions")
+	}
+}
@@ -0,0 +1,378 @@
+package chess
+
+type moveFlag uint8
+
+const (
+	flagCastle moveFlag = 1 << iota
+	flagEnPassant
+	flagNull
+)
+
+// Move is a single move from one square to another. Moves are only meaningful in the
+// context of the position they are played in.
+type Move struct {
+	From Square
+	To   Square
+
+	// The piece type a pawn promotes to, or NoPieceType.
+	Promotion PieceType
+
+	flags moveFlag
+}
+
+// NullMove passes the turn to the opponent without moving a piece. It is used in
+// analysis variations.
+var NullMove = Move{From: NoSquare, To: NoSquare, flags: flagNull}
+
+// IsCastle returns true if the move is a castling move.
+func (m Move) IsCastle() bool {
+	return m.flags&flagCastle != 0
+}
+
+// IsEnPassant returns true if the move is an en passant capture.
+func (m Move) IsEnPassant() bool {
+	return m.flags&flagEnPassant != 0
+}
+
+// IsNull returns true if the move is a null move.
+func (m Move) IsNull() bool {
+	return m.flags&flagNull != 0
+}
+
+// UCI returns the move in UCI long algebraic notation, such as e2e4 or e7e8q.
+func (m Move) UCI() string {
+	if m.IsNull() {
+		return "0000"
+	}
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += string("?pnbrqk"[m.Promotion])
+	}
+	return s
+}
+
+var knightOffsets = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+var kingOffsets = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+var bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+var rookDirections = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+// offset returns the square reached by moving df files and dr ranks from sq, and
+// false if that square is off the board.
+func offset(sq Square, df, dr int) (Square, bool) {
+	f, r := sq.File()+df, sq.Rank()+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return NoSquare, false
+	}
+	return NewSquare(f, r), true
+}
+
+// isAttacked returns true if the given square is attacked by any piece of the given color.
+func (p *Position) isAttacked(sq Square, by Color) bool {
+	if sq == NoSquare {
+		return false
+	}
+
+	pawnDir := 1
+	if by == White {
+		pawnDir = -1
+	}
+	for _, df := range []int{-1, 1} {
+		if from, ok := offset(sq, df, pawnDir); ok && p.board[from] == (Piece{Pawn, by}) {
+			return true
+		}
+	}
+
+	for _, o := range knightOffsets {
+		if from, ok := offset(sq, o[0], o[1]); ok && p.board[from] == (Piece{Knight, by}) {
+			return true
+		}
+	}
+
+	for _, o := range kingOffsets {
+		if from, ok := offset(sq, o[0], o[1]); ok && p.board[from] == (Piece{King, by}) {
+			return true
+		}
+	}
+
+	if p.slidingAttack(sq, by, bishopDirections[:], Bishop) || p.slidingAttack(sq, by, rookDirections[:], Rook) {
+		return true
+	}
+	return false
+}
+
+// slidingAttack returns true if sq is attacked along one of the given directions by a piece
+// of the given slider type (or a queen) of the given color.
+func (p *Position) slidingAttack(sq Square, by Color, directions [][2]int, slider PieceType) bool {
+	for _, d := range directions {
+		cur := sq
+		for {
+			next, ok := offset(cur, d[0], d[1])
+			if !ok {
+				break
+			}
+			cur = next
+			piece := p.board[cur]
+			if piece == NoPiece {
+				continue
+			}
+			if piece.Color == by && (piece.Type == slider || piece.Type == Queen) {
+				return true
+			}
+			break
+		}
+	}
+	return false
+}
+
+// InCheck returns true if the side to move is in check.
+func (p *Position) InCheck() bool {
+	return p.isAttacked(p.kingSquare(p.Turn), p.Turn.Other())
+}
+
+// IsCheckmate returns true if the side to move is checkmated.
+func (p *Position) IsCheckmate() bool {
+	return p.InCheck() && len(p.LegalMoves()) == 0
+}
+
+// IsStalemate returns true if the side to move has no legal moves but is not in check.
+func (p *Position) IsStalemate() bool {
+	return !p.InCheck() && len(p.LegalMoves()) == 0
+}
+
+// LegalMoves returns all legal moves in the position.
+func (p *Position) LegalMoves() []Move {
+	pseudo := p.pseudoLegalMoves()
+	legal := pseudo[:0]
+	for _, m := range pseudo {
+		next := p.apply(m)
+		if !next.isAttacked(next.kingSquare(p.Turn), p.Turn.Other()) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// IsLegal returns true if the given move is legal in the position.
+func (p *Position) IsLegal(m Move) bool {
+	if m.IsNull() {
+		return !p.InCheck()
+	}
+	for _, lm := range p.LegalMoves() {
+		if lm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// pseudoLegalMoves returns all moves which follow the movement rules of the pieces,
+// without checking whether the moving side's king is left in check.
+func (p *Position) pseudoLegalMoves() []Move {
+	moves := make([]Move, 0, 64)
+	for i, piece := range p.board {
+		if piece == NoPiece || piece.Color != p.Turn {
+			continue
+		}
+		from := Square(i)
+
+		switch piece.Type {
+		case Pawn:
+			moves = p.appendPawnMoves(moves, from)
+		case Knight:
+			moves = p.appendStepMoves(moves, from, knightOffsets[:])
+		case Bishop:
+			moves = p.appendSlidingMoves(moves, from, bishopDirections[:])
+		case Rook:
+			moves = p.appendSlidingMoves(moves, from, rookDirections[:])
+		case Queen:
+			moves = p.appendSlidingMoves(moves, from, bishopDirections[:])
+			moves = p.appendSlidingMoves(moves, from, rookDirections[:])
+		case King:
+			moves = p.appendStepMoves(moves, from, kingOffsets[:])
+			moves = p.appendCastlingMoves(moves, from)
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendPawnMoves(moves []Move, from Square) []Move {
+	dir, startRank, lastRank := 1, 1, 7
+	if p.Turn == Black {
+		dir, startRank, lastRank = -1, 6, 0
+	}
+
+	appendPawn := func(to Square, flags moveFlag) {
+		if to.Rank() == lastRank {
+			for _, pt := range []PieceType{Queen, Rook, Bishop, Knight} {
+				moves = append(moves, Move{From: from, To: to, Promotion: pt, flags: flags})
+			}
+		} else {
+			moves = append(moves, Move{From: from, To: to, flags: flags})
+		}
+	}
+
+	if to, ok := offset(from, 0, dir); ok && p.board[to] == NoPiece {
+		appendPawn(to, 0)
+		if from.Rank() == startRank {
+			if to2, ok := offset(to, 0, dir); ok && p.board[to2] == NoPiece {
+				appendPawn(to2, 0)
+			}
+		}
+	}
+
+	for _, df := range []int{-1, 1} {
+		to, ok := offset(from, df, dir)
+		if !ok {
+			continue
+		}
+		if target := p.board[to]; target != NoPiece && target.Color != p.Turn {
+			appendPawn(to, 0)
+		} else if to == p.EnPassant && target == NoPiece {
+			appendPawn(to, flagEnPassant)
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendStepMoves(moves []Move, from Square, offsets [][2]int) []Move {
+	for _, o := range offsets {
+		to, ok := offset(from, o[0], o[1])
+		if !ok {
+			continue
+		}
+		if target := p.board[to]; target == NoPiece || target.Color != p.Turn {
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendSlidingMoves(moves []Move, from Square, directions [][2]int) []Move {
+	for _, d := range directions {
+		cur := from
+		for {
+			to, ok := offset(cur, d[0], d[1])
+			if !ok {
+				break
+			}
+			cur = to
+			target := p.board[to]
+			if target == NoPiece {
+				moves = append(moves, Move{From: from, To: to})
+				continue
+			}
+			if target.Color != p.Turn {
+				moves = append(moves, Move{From: from, To: to})
+			}
+			break
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendCastlingMoves(moves []Move, from Square) []Move {
+	rank := 0
+	kingside, queenside := WhiteKingside, WhiteQueenside
+	if p.Turn == Black {
+		rank = 7
+		kingside, queenside = BlackKingside, BlackQueenside
+	}
+	if from != NewSquare(4, rank) || p.isAttacked(from, p.Turn.Other()) {
+		return moves
+	}
+
+	if p.Castling&kingside != 0 &&
+		p.board[NewSquare(5, rank)] == NoPiece && p.board[NewSquare(6, rank)] == NoPiece &&
+		!p.isAttacked(NewSquare(5, rank), p.Turn.Other()) {
+		moves = append(moves, Move{From: from, To: NewSquare(6, rank), flags: flagCastle})
+	}
+
+	if p.Castling&queenside != 0 &&
+		p.board[NewSquare(3, rank)] == NoPiece && p.board[NewSquare(2, rank)] == NoPiece && p.board[NewSquare(1, rank)] == NoPiece &&
+		!p.isAttacked(NewSquare(3, rank), p.Turn.Other()) {
+		moves = append(moves, Move{From: from, To: NewSquare(2, rank), flags: flagCastle})
+	}
+	return moves
+}
+
+// Play returns the position after playing the given move, or false if the move is illegal.
+// The receiver is not modified.
+func (p *Position) Play(m Move) (*Position, bool) {
+	if !p.IsLegal(m) {
+		return nil, false
+	}
+	return p.apply(m), true
+}
+
+// apply returns the position after playing the given move, without checking legality.
+func (p *Position) apply(m Move) *Position {
+	next := p.Copy()
+	next.EnPassant = NoSquare
+	if p.Turn == Black {
+		next.FullmoveNumber++
+	}
+	next.Turn = p.Turn.Other()
+
+	if m.IsNull() {
+		next.HalfmoveClock++
+		return next
+	}
+
+	piece := p.board[m.From]
+	captured := p.board[m.To]
+
+	next.board[m.From] = NoPiece
+	next.board[m.To] = piece
+	if m.Promotion != NoPieceType {
+		next.board[m.To] = Piece{Type: m.Promotion, Color: piece.Color}
+	}
+
+	if m.IsEnPassant() {
+		capturedSq := NewSquare(m.To.File(), m.From.Rank())
+		captured = next.board[capturedSq]
+		next.board[capturedSq] = NoPiece
+	}
+
+	if m.IsCastle() {
+		rank := m.From.Rank()
+		if m.To.File() == 6 {
+			next.board[NewSquare(5, rank)] = next.board[NewSquare(7, rank)]
+			next.board[NewSquare(7, rank)] = NoPiece
+		} else {
+			next.board[NewSquare(3, rank)] = next.board[NewSquare(0, rank)]
+			next.board[NewSquare(0, rank)] = NoPiece
+		}
+	}
+
+	if piece.Type == Pawn || captured != NoPiece {
+		next.HalfmoveClock = 0
+	} else {
+		next.HalfmoveClock++
+	}
+
+	if piece.Type == Pawn && (m.To.Rank()-m.From.Rank() == 2 || m.From.Rank()-m.To.Rank() == 2) {
+		next.EnPassant = NewSquare(m.From.File(), (m.From.Rank()+m.To.Rank())/2)
+	}
+
+	next.updateCastlingRights(m.From)
+	next.updateCastlingRights(m.To)
+	return next
+}
+
+// updateCastlingRights removes the castling rights affected by a piece moving from or to sq.
+func (p *Position) updateCastlingRights(sq Square) {
+	switch sq {
+	case NewSquare(4, 0):
+		p.Castling &^= WhiteKingside | WhiteQueenside
+	case NewSquare(7, 0):
+		p.Castling &^= WhiteKingside
+	case NewSquare(0, 0):
+		p.Castling &^= WhiteQueenside
+	case NewSquare(4, 7):
+		p.Castling &^= BlackKingside | BlackQueenside
+	case NewSquare(7, 7):
+		p.Castling &^= BlackKingside
+	case NewSquare(0, 7):
+		p.Castling &^= BlackQueenside
+	}
+}
@@ -0,0 +1,222 @@
+package chess
+
+import (
+	"testing"
+)
+
+func perft(p *Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	count := 0
+	for _, m := range p.LegalMoves() {
+		count += perft(p.apply(m), depth-1)
+	}
+	return count
+}
+
+func TestPerft(t *testing.T) {
+	table := []struct {
+		name  string
+		fen   string
+		depth int
+		want  int
+	}{
+		{
+			name:  "StartingPosition",
+			fen:   StartingFen,
+			depth: 3,
+			want:  8902,
+		},
+		{
+			name:  "Kiwipete",
+			fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			depth: 3,
+			want:  97862,
+		},
+		{
+			name:  "EnPassantAndPromotion",
+			fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+			depth: 4,
+			want:  43238,
+		},
+		{
+			name:  "Promotions",
+			fen:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+			depth: 3,
+			want:  9467,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseFen(tc.fen)
+			if err != nil {
+				t.Fatalf("ParseFen(%s) got error: %v", tc.fen, err)
+			}
+			if got := perft(p, tc.depth); got != tc.want {
+				t.Errorf("perft(%s, %d) got: %d; want: %d", tc.fen, tc.depth, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFen(t *testing.T) {
+	table := []struct {
+		name    string
+		fen     string
+		wantErr bool
+	}{
+		{name: "StartingPosition", fen: StartingFen},
+		{name: "MissingClocks", fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3"},
+		{name: "TooFewFields", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w", wantErr: true},
+		{name: "TooFewRanks", fen: "rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: true},
+		{name: "BadPiece", fen: "rnbqkbnr/ppppxppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: true},
+		{name: "MissingKing", fen: "rnbq1bnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1", wantErr: true},
+		{name: "OpponentInCheck", fen: "4k3/8/8/8/8/8/8/4KR2 w - - 0 1", wantErr: false},
+		{name: "SideNotToMoveInCheck", fen: "4k3/4R3/8/8/8/8/8/4K3 w - - 0 1", wantErr: true},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseFen(tc.fen)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ParseFen(%s) got error: %v; want error: %t", tc.fen, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizedFen(t *testing.T) {
+	table := []struct {
+		name string
+		fen  string
+		want string
+	}{
+		{
+			name: "EnPassantNotPossible",
+			fen:  "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			want: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		},
+		{
+			name: "EnPassantPossible",
+			fen:  "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			want: "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 1",
+		},
+		{
+			name: "Clocks",
+			fen:  "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+			want: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 1",
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeFen(tc.fen)
+			if err != nil {
+				t.Fatalf("NormalizeFen(%s) got error: %v", tc.fen, err)
+			}
+			if got != tc.want {
+				t.Errorf("NormalizeFen(%s) got: %s; want: %s", tc.fen, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSan(t *testing.T) {
+	table := []struct {
+		name string
+		fen  string
+		san  string
+		want string
+	}{
+		{name: "PawnPush", fen: StartingFen, san: "e4", want: "e4"},
+		{name: "Knight", fen: StartingFen, san: "Nf3", want: "Nf3"},
+		{name: "LongAlgebraic", fen: StartingFen, san: "Ng1-f3", want: "Nf3"},
+		{name: "Annotated", fen: StartingFen, san: "e4!?", want: "e4"},
+		{
+			name: "FileDisambiguation",
+			fen:  "4k3/8/8/8/8/8/4K3/R6R w - - 0 1",
+			san:  "Rad1",
+			want: "Rad1",
+		},
+		{
+			name: "RankDisambiguation",
+			fen:  "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1",
+			san:  "R1a3",
+			want: "R1a3",
+		},
+		{
+			name: "Castling",
+			fen:  "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			san:  "0-0-0",
+			want: "O-O-O",
+		},
+		{
+			name: "PromotionWithoutEquals",
+			fen:  "8/4P3/8/8/8/8/k7/4K3 w - - 0 1",
+			san:  "e8Q",
+			want: "e8=Q",
+		},
+		{
+			name: "LowercasePromotion",
+			fen:  "8/4P3/8/8/8/8/k7/4K3 w - - 0 1",
+			san:  "e8n",
+			want: "e8=N",
+		},
+		{
+			name: "Checkmate",
+			fen:  "rnbqkbnr/ppppp2p/5p2/6p1/4P3/8/PPPP1PPP/RNBQKBNR w KQkq g6 0 3",
+			san:  "Qh5",
+			want: "Qh5#",
+		},
+		{
+			name: "EnPassant",
+			fen:  "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			san:  "exf6",
+			want: "exf6",
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseFen(tc.fen)
+			if err != nil {
+				t.Fatalf("ParseFen(%s) got error: %v", tc.fen, err)
+			}
+			m, err := p.ParseSan(tc.san)
+			if err != nil {
+				t.Fatalf("ParseSan(%s) got error: %v", tc.san, err)
+			}
+			if got := p.San(m); got != tc.want {
+				t.Errorf("San(ParseSan(%s)) got: %s; want: %s", tc.san, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSanErrors(t *testing.T) {
+	table := []struct {
+		name string
+		fen  string
+		san  string
+	}{
+		{name: "Illegal", fen: StartingFen, san: "e5"},
+		{name: "Garbage", fen: StartingFen, san: "hello"},
+		{name: "Ambiguous", fen: "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", san: "Rd1"},
+		{name: "CastleThroughCheck", fen: "4kr2/8/8/8/8/8/8/4K2R w K - 0 1", san: "O-O"},
+		{name: "PinnedPiece", fen: "4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1", san: "Nf4"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseFen(tc.fen)
+			if err != nil {
+				t.Fatalf("ParseFen(%s) got error: %v", tc.fen, err)
+			}
+			if m, err := p.ParseSan(tc.san); err == nil {
+				t.Errorf("ParseSan(%s) got: %s; want error", tc.san, m.UCI())
+			}
+		})
+	}
+}
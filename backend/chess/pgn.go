@@ -0,0 +1,759 @@
+package chess
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Seven Tag Roster, in the order required by the PGN specification.
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// The NAGs corresponding to the traditional move suffix annotations.
+var suffixNags = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+var commandRegexp = regexp.MustCompile(`\[%(\w+)\s*([^\]]*)\]`)
+
+// Game is a parsed PGN game. The moves form a tree rooted at Root, whose first child
+// at each level is the mainline and whose remaining children are variations.
+type Game struct {
+	// The PGN headers of the game.
+	Headers map[string]string
+
+	// The order the headers were set in, used when writing the game.
+	headerOrder []string
+
+	// The node containing the starting position of the game. It has no move.
+	Root *Node
+
+	// The game termination marker: 1-0, 0-1, 1/2-1/2 or *.
+	Result string
+}
+
+// Node is a single position in the move tree of a Game.
+type Node struct {
+	// The node containing the previous position. Nil for the root.
+	Parent *Node
+
+	// The moves played from this position. Children[0] is the mainline continuation.
+	Children []*Node
+
+	// The move which reached this position. The zero value for the root.
+	Move Move
+
+	// The SAN of Move, including any check or checkmate suffix.
+	San string
+
+	// The position after Move was played.
+	Position *Position
+
+	// The number of halfmoves played from the start of the game to reach this position.
+	Ply int
+
+	// The Numeric Annotation Glyphs attached to the move.
+	Nags []int
+
+	// A comment placed before the move, used at the start of variations.
+	CommentBefore string
+
+	// The text of the comment after the move, with any commands removed.
+	Comment string
+
+	// The commands embedded in the comment after the move, such as clk or eval,
+	// mapped from the command name to its raw value.
+	Commands map[string]string
+}
+
+// NewGame returns a game with no moves starting from the given position.
+func NewGame(start *Position) *Game {
+	return &Game{
+		Headers: make(map[string]string),
+		Root:    &Node{Position: start},
+		Result:  "*",
+	}
+}
+
+// Header returns the value of the given header, or an empty string if it is not set.
+func (g *Game) Header(name string) string {
+	return g.Headers[name]
+}
+
+// SetHeader sets the value of the given header.
+func (g *Game) SetHeader(name, value string) {
+	if _, ok := g.Headers[name]; !ok {
+		g.headerOrder = append(g.headerOrder, name)
+	}
+	g.Headers[name] = value
+}
+
+// DeleteHeader removes the given header.
+func (g *Game) DeleteHeader(name string) {
+	delete(g.Headers, name)
+	for i, h := range g.headerOrder {
+		if h == name {
+			g.headerOrder = append(g.headerOrder[:i], g.headerOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// Mainline returns the nodes of the mainline, excluding the root.
+func (g *Game) Mainline() []*Node {
+	var nodes []*Node
+	for n := g.Root.Next(); n != nil; n = n.Next() {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// Positions returns the positions of the mainline, including the starting position.
+func (g *Game) Positions() []*Position {
+	positions := []*Position{g.Root.Position}
+	for _, n := range g.Mainline() {
+		positions = append(positions, n.Position)
+	}
+	return positions
+}
+
+// Fens returns the FENs of the mainline positions, including the starting position.
+func (g *Game) Fens() []string {
+	positions := g.Positions()
+	fens := make([]string, len(positions))
+	for i, p := range positions {
+		fens[i] = p.Fen()
+	}
+	return fens
+}
+
+// FinalPosition returns the last position of the mainline.
+func (g *Game) FinalPosition() *Position {
+	n := g.Root
+	for next := n.Next(); next != nil; next = n.Next() {
+		n = next
+	}
+	return n.Position
+}
+
+// Walk calls fn on every node of the move tree, excluding the root, in depth-first order.
+// Mainline moves are visited before the variations which branch from them.
+func (g *Game) Walk(fn func(n *Node)) {
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for _, c := range n.Children {
+			fn(c)
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(g.Root)
+}
+
+// Next returns the mainline continuation of the node, or nil if there is none.
+func (n *Node) Next() *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
+
+// IsMainline returns true if the node is part of the game's mainline.
+func (n *Node) IsMainline() bool {
+	for cur := n; cur.Parent != nil; cur = cur.Parent {
+		if cur.Parent.Children[0] != cur {
+			return false
+		}
+	}
+	return true
+}
+
+// AddMove appends the given move as a new child of the node and returns the new node.
+// If the move already exists as a child, the existing node is returned instead. The move
+// must be legal in the node's position.
+func (n *Node) AddMove(m Move) (*Node, error) {
+	for _, c := range n.Children {
+		if c.Move == m {
+			return c, nil
+		}
+	}
+
+	next, ok := n.Position.Play(m)
+	if !ok {
+		return nil, fmt.Errorf("illegal move %s", m.UCI())
+	}
+	child := &Node{
+		Parent:   n,
+		Move:     m,
+		San:      n.Position.San(m),
+		Position: next,
+		Ply:      n.Ply + 1,
+	}
+	n.Children = append(n.Children, child)
+	return child, nil
+}
+
+// Clock returns the remaining clock time recorded by the %clk command, if present.
+func (n *Node) Clock() (time.Duration, bool) {
+	value, ok := n.Commands["clk"]
+	if !ok {
+		return 0, false
+	}
+	d, err := ParseClock(value)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// Eval returns the evaluation recorded by the %eval command, if present.
+func (n *Node) Eval() (Score, bool) {
+	value, ok := n.Commands["eval"]
+	if !ok {
+		return Score{}, false
+	}
+	s, err := ParseScore(value)
+	if err != nil {
+		return Score{}, false
+	}
+	return s, true
+}
+
+// ParseClock parses a clock value in the form h:mm:ss, mm:ss or ss, with optional
+// fractional seconds.
+func ParseClock(value string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid clock %q", value)
+	}
+
+	var total float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid clock %q", value)
+		}
+		total = total*60 + n
+	}
+	return time.Duration(total * float64(time.Second)), nil
+}
+
+// FormatClock formats the duration in the h:mm:ss form used by the %clk command.
+func FormatClock(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// Score is a position evaluation from White's perspective.
+type Score struct {
+	// The evaluation in hundredths of a pawn. Ignored if Mate is non-zero.
+	Centipawns int `dynamodbav:"cp,omitempty" json:"cp,omitempty"`
+
+	// The number of moves until mate, positive if White mates and negative if Black mates.
+	Mate int `dynamodbav:"mate,omitempty" json:"mate,omitempty"`
+}
+
+// ParseScore parses an evaluation in the format of the %eval command, such as 0.35,
+// -1.20 or #-3.
+func ParseScore(value string) (Score, error) {
+	value = strings.TrimSpace(value)
+	if value, ok := strings.CutPrefix(value, "#"); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n == 0 {
+			return Score{}, fmt.Errorf("invalid mate score %q", value)
+		}
+		return Score{Mate: n}, nil
+	}
+
+	// Some tools write the depth after a comma, as in 0.35,20
+	value, _, _ = strings.Cut(value, ",")
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Score{}, fmt.Errorf("invalid score %q", value)
+	}
+	return Score{Centipawns: int(math.Round(f * 100))}, nil
+}
+
+// String formats the score in the format of the %eval command.
+func (s Score) String() string {
+	if s.Mate != 0 {
+		return fmt.Sprintf("#%d", s.Mate)
+	}
+	return strconv.FormatFloat(float64(s.Centipawns)/100, 'f', 2, 64)
+}
+
+// ParseError is returned when a PGN cannot be parsed or contains an illegal move.
+type ParseError struct {
+	// The 1-based line of the PGN on which the error occurred.
+	Line int
+
+	// A description of the error.
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("pgn: line %d: %s", e.Line, e.Message)
+}
+
+// Parse parses a PGN containing a single game. If the PGN contains several games, only
+// the first is returned.
+func Parse(pgn string) (*Game, error) {
+	p := &parser{input: strings.TrimPrefix(pgn, "\ufeff"), line: 1}
+	game, err := p.parseGame()
+	if err != nil {
+		return nil, err
+	}
+	if game == nil {
+		return nil, &ParseError{Line: p.line, Message: "no game found"}
+	}
+	return game, nil
+}
+
+// ParseAll parses a PGN database containing any number of games.
+func ParseAll(pgn string) ([]*Game, error) {
+	p := &parser{input: strings.TrimPrefix(pgn, "\ufeff"), line: 1}
+	var games []*Game
+	for {
+		game, err := p.parseGame()
+		if err != nil {
+			return nil, err
+		}
+		if game == nil {
+			return games, nil
+		}
+		games = append(games, game)
+	}
+}
+
+// ParseVariation parses a variation, such as a suggested variation, played from the
+// given position. The variation may be preceded by headers, as when a client renders it
+// as a PGN. A FEN header must describe the given position, and its move counters are used
+// to number the moves; other headers are ignored. The returned game has the SetUp and FEN
+// headers of its starting position if it is not the standard starting position, and its
+// result is always *. An error is returned if the variation contains no moves or anything
+// other than a single line with its comments and variations.
+func ParseVariation(start *Position, pgn string) (*Game, error) {
+	p := &parser{input: strings.TrimPrefix(pgn, "\ufeff"), line: 1}
+	for p.skipSpace(); p.peek() == '['; p.skipSpace() {
+		name, value, err := p.parseHeader()
+		if err != nil {
+			return nil, err
+		}
+		if name != "FEN" {
+			continue
+		}
+		position, err := ParseFen(value)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if position.NormalizedFen() != start.NormalizedFen() {
+			return nil, p.errorf("FEN header %q does not match the starting position", value)
+		}
+		start = position
+	}
+
+	game := NewGame(start)
+	if start.Fen() != NewPosition().Fen() {
+		game.SetHeader("SetUp", "1")
+		game.SetHeader("FEN", start.Fen())
+	}
+	if err := p.parseMovetext(game); err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q after variation", p.peek())
+	}
+	if len(game.Root.Children) == 0 {
+		return nil, p.errorf("variation has no moves")
+	}
+	game.Result = "*"
+	return game, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+	line  int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &ParseError{Line: p.line, Message: fmt.Sprintf(format, args...)}
+}
+
+// peek returns the next byte of input, or 0 at the end of input.
+func (p *parser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) advance() byte {
+	c := p.input[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipSpace skips whitespace and escaped lines (lines beginning with %).
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) {
+		c := p.peek()
+		switch {
+		case c == '%' && (p.pos == 0 || p.input[p.pos-1] == '\n'):
+			for p.pos < len(p.input) && p.peek() != '\n' {
+				p.advance()
+			}
+		case isSpace(c):
+			p.advance()
+		default:
+			return
+		}
+	}
+}
+
+// parseGame parses the next game in the input. It returns nil if no game remains.
+func (p *parser) parseGame() (*Game, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, nil
+	}
+
+	game := &Game{Headers: make(map[string]string), Result: "*"}
+	for p.skipSpace(); p.peek() == '['; p.skipSpace() {
+		name, value, err := p.parseHeader()
+		if err != nil {
+			return nil, err
+		}
+		game.SetHeader(name, value)
+	}
+
+	start := NewPosition()
+	if fen := game.Headers["FEN"]; fen != "" {
+		var err error
+		if start, err = ParseFen(fen); err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	}
+	game.Root = &Node{Position: start}
+
+	if err := p.parseMovetext(game); err != nil {
+		return nil, err
+	}
+	if game.Result == "*" && game.Headers["Result"] != "" {
+		game.Result = game.Headers["Result"]
+	}
+	return game, nil
+}
+
+// parseHeader parses a single header in the form [Name "Value"].
+func (p *parser) parseHeader() (string, string, error) {
+	p.advance() // [
+	p.skipSpace()
+
+	start := p.pos
+	for p.pos < len(p.input) && !isSpace(p.peek()) && p.peek() != '"' && p.peek() != ']' {
+		p.advance()
+	}
+	name := p.input[start:p.pos]
+	if name == "" {
+		return "", "", p.errorf("header is missing a name")
+	}
+
+	p.skipSpace()
+	if p.peek() != '"' {
+		return "", "", p.errorf("header %q is missing a value", name)
+	}
+	p.advance()
+
+	var value strings.Builder
+	for {
+		if p.pos >= len(p.input) || p.peek() == '\n' {
+			return "", "", p.errorf("header %q has an unterminated value", name)
+		}
+		c := p.advance()
+		if c == '\\' && (p.peek() == '"' || p.peek() == '\\') {
+			value.WriteByte(p.advance())
+			continue
+		}
+		if c == '"' {
+			break
+		}
+		value.WriteByte(c)
+	}
+
+	p.skipSpace()
+	if p.peek() != ']' {
+		return "", "", p.errorf("header %q is missing a closing bracket", name)
+	}
+	p.advance()
+	return name, value.String(), nil
+}
+
+// parseMovetext parses the moves, comments and variations of a game until the game
+// termination marker, the headers of the next game or the end of input.
+func (p *parser) parseMovetext(game *Game) error {
+	cur := game.Root
+	var stack []*Node
+	var pendingComment string
+	var pendingCommands map[string]string
+
+	// Whether no move has been played yet in the game or the innermost variation
+	atStart := true
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			break
+		}
+
+		c := p.peek()
+		switch {
+		case c == '[' && len(stack) == 0:
+			// The headers of the next game, without a termination marker on this one
+			return nil
+
+		case c == '{' || c == ';':
+			var text string
+			if c == '{' {
+				var err error
+				if text, err = p.parseComment(); err != nil {
+					return err
+				}
+			} else {
+				text = p.parseLineComment()
+			}
+			comment, commands := splitCommands(text)
+			if atStart && len(stack) > 0 {
+				// A comment before the first move of a variation, which belongs to that move
+				// rather than to the move the variation branches from
+				pendingComment = joinComment(pendingComment, comment)
+				pendingCommands = joinCommands(pendingCommands, commands)
+				continue
+			}
+			cur.Comment = joinComment(cur.Comment, comment)
+			mergeCommands(cur, commands)
+
+		case c == '(':
+			p.advance()
+			if cur.Parent == nil {
+				return p.errorf("variation has no move to branch from")
+			}
+			stack = append(stack, cur)
+			cur = cur.Parent
+			atStart = true
+
+		case c == ')':
+			p.advance()
+			if len(stack) == 0 {
+				return p.errorf("unexpected closing parenthesis")
+			}
+			cur = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			pendingComment = ""
+			pendingCommands = nil
+			atStart = false
+
+		case c == '$':
+			p.advance()
+			start := p.pos
+			for p.pos < len(p.input) && isDigit(p.peek()) {
+				p.advance()
+			}
+			nag, err := strconv.Atoi(p.input[start:p.pos])
+			if err != nil {
+				return p.errorf("invalid NAG")
+			}
+			cur.Nags = append(cur.Nags, nag)
+
+		case c == '}':
+			return p.errorf("unexpected %q", c)
+
+		default:
+			token := p.parseSymbol()
+			if token == "" {
+				return p.errorf("unexpected %q", p.advance())
+			}
+
+			if isResult(token) {
+				if len(stack) > 0 {
+					return p.errorf("game termination marker %q inside a variation", token)
+				}
+				game.Result = token
+				return nil
+			}
+
+			san := stripMoveNumber(token)
+			if san == "" {
+				continue
+			}
+			if nag, ok := suffixNags[san]; ok {
+				cur.Nags = append(cur.Nags, nag)
+				continue
+			}
+
+			move := strings.TrimRight(san, "!?")
+			annotation := san[len(move):]
+			next, err := p.playSan(cur, move)
+			if err != nil {
+				return err
+			}
+			if nag, ok := suffixNags[annotation]; ok {
+				next.Nags = append(next.Nags, nag)
+			}
+			next.CommentBefore = joinComment(next.CommentBefore, pendingComment)
+			mergeCommands(next, pendingCommands)
+			pendingComment = ""
+			pendingCommands = nil
+			cur = next
+			atStart = false
+		}
+	}
+
+	if len(stack) > 0 {
+		return p.errorf("unterminated variation")
+	}
+	return nil
+}
+
+// playSan adds the given SAN as a child of cur, checking its legality.
+func (p *parser) playSan(cur *Node, san string) (*Node, error) {
+	m, err := cur.Position.ParseSan(san)
+	if err != nil {
+		return nil, p.errorf("ply %d: %v", cur.Ply+1, err)
+	}
+	next, err := cur.AddMove(m)
+	if err != nil {
+		return nil, p.errorf("ply %d: %v", cur.Ply+1, err)
+	}
+	return next, nil
+}
+
+// parseLineComment parses a comment starting with ; and continuing to the end of the line.
+func (p *parser) parseLineComment() string {
+	p.advance() // ;
+	start := p.pos
+	for p.pos < len(p.input) && p.peek() != '\n' {
+		p.advance()
+	}
+	return p.input[start:p.pos]
+}
+
+// parseComment parses a brace comment and returns its text.
+func (p *parser) parseComment() (string, error) {
+	line := p.line
+	p.advance() // {
+	start := p.pos
+	for p.pos < len(p.input) && p.peek() != '}' {
+		p.advance()
+	}
+	if p.pos >= len(p.input) {
+		return "", &ParseError{Line: line, Message: "unterminated comment"}
+	}
+	text := p.input[start:p.pos]
+	p.advance() // }
+	return text, nil
+}
+
+// parseSymbol reads a run of characters up to the next whitespace or delimiter.
+func (p *parser) parseSymbol() string {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.peek()
+		if isSpace(c) || strings.IndexByte("{}()[];$", c) >= 0 {
+			break
+		}
+		p.advance()
+	}
+	return p.input[start:p.pos]
+}
+
+// stripMoveNumber removes a leading move number indication, such as 12. or 12...,
+// from the token.
+func stripMoveNumber(token string) string {
+	i := 0
+	for i < len(token) && isDigit(token[i]) {
+		i++
+	}
+	if i < len(token) && token[i] != '.' {
+		// Not a move number, such as 0-0 castling
+		return token
+	}
+	for i < len(token) && token[i] == '.' {
+		i++
+	}
+	return token[i:]
+}
+
+// splitCommands separates the commands, such as [%clk 0:10:00], from the text of a comment.
+func splitCommands(text string) (string, map[string]string) {
+	var commands map[string]string
+	for _, match := range commandRegexp.FindAllStringSubmatch(text, -1) {
+		if commands == nil {
+			commands = make(map[string]string)
+		}
+		commands[match[1]] = strings.TrimSpace(match[2])
+	}
+	text = commandRegexp.ReplaceAllString(text, "")
+	return strings.TrimSpace(text), commands
+}
+
+// joinCommands returns existing with the given commands added, overwriting any existing
+// commands with the same name.
+func joinCommands(existing, commands map[string]string) map[string]string {
+	if len(commands) == 0 {
+		return existing
+	}
+	if existing == nil {
+		existing = make(map[string]string, len(commands))
+	}
+	for k, v := range commands {
+		existing[k] = v
+	}
+	return existing
+}
+
+func mergeCommands(n *Node, commands map[string]string) {
+	if len(commands) == 0 {
+		return
+	}
+	if n.Commands == nil {
+		n.Commands = make(map[string]string)
+	}
+	for k, v := range commands {
+		n.Commands[k] = v
+	}
+}
+
+func joinComment(existing, comment string) string {
+	if existing == "" {
+		return comment
+	}
+	if comment == "" {
+		return existing
+	}
+	return existing + " " + comment
+}
+
+func isResult(token string) bool {
+	return token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*"
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
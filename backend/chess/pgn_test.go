@@ -0,0 +1,316 @@
+package chess
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const annotatedPgn = `[Event "Dojo Open"]
+[Site "Lichess"]
+[Date "2024.01.02"]
+[Round "1"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+[TimeControl "5400+30"]
+
+{Opening comment} 1. e4 {[%clk 1:30:00] Best by test} e5 (1... c5 {Sicilian} 2. Nf3
+(2. c3 d5) 2... d6) 2. Nf3 $1 Nc6 3. Bb5!? {[%eval 0.3] [%clk 1:29:00]} a6 ; a line comment
+4. Ba4 (4. Bxc6 dxc6 5. O-O) 4... Nf6 5. O-O 1-0`
+
+func TestParse(t *testing.T) {
+	game, err := Parse(annotatedPgn)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+
+	if got := game.Header("White"); got != "Alice" {
+		t.Errorf("Header(White) got: %q; want: %q", got, "Alice")
+	}
+	if game.Result != "1-0" {
+		t.Errorf("Result got: %q; want: %q", game.Result, "1-0")
+	}
+	if game.Root.Comment != "Opening comment" {
+		t.Errorf("Root.Comment got: %q; want: %q", game.Root.Comment, "Opening comment")
+	}
+
+	var sans []string
+	for _, n := range game.Mainline() {
+		sans = append(sans, n.San)
+	}
+	if got, want := strings.Join(sans, " "), "e4 e5 Nf3 Nc6 Bb5 a6 Ba4 Nf6 O-O"; got != want {
+		t.Errorf("Mainline got: %s; want: %s", got, want)
+	}
+
+	e4 := game.Root.Next()
+	if e4.Comment != "Best by test" {
+		t.Errorf("e4.Comment got: %q; want: %q", e4.Comment, "Best by test")
+	}
+	if clk, ok := e4.Clock(); !ok || clk != 90*time.Minute {
+		t.Errorf("e4.Clock() got: %v, %t; want: %v, true", clk, ok, 90*time.Minute)
+	}
+	if len(e4.Children) != 2 || e4.Children[1].San != "c5" || e4.Children[1].Comment != "Sicilian" {
+		t.Fatalf("e4.Children got: %v; want e5 and c5 {Sicilian}", e4.Children)
+	}
+	c5 := e4.Children[1]
+	if c5.IsMainline() {
+		t.Errorf("c5.IsMainline() got: true; want: false")
+	}
+	nf3 := c5.Next()
+	if len(nf3.Children) != 1 || nf3.Next().San != "d6" || len(c5.Children) != 2 || c5.Children[1].Next().San != "d5" {
+		t.Errorf("Nested variation was not parsed correctly")
+	}
+
+	mainNf3 := game.Mainline()[2]
+	if len(mainNf3.Nags) != 1 || mainNf3.Nags[0] != 1 {
+		t.Errorf("Nf3.Nags got: %v; want: [1]", mainNf3.Nags)
+	}
+
+	bb5 := game.Mainline()[4]
+	if len(bb5.Nags) != 1 || bb5.Nags[0] != 5 {
+		t.Errorf("Bb5.Nags got: %v; want: [5]", bb5.Nags)
+	}
+	if eval, ok := bb5.Eval(); !ok || eval.Centipawns != 30 {
+		t.Errorf("Bb5.Eval() got: %v, %t; want: 30, true", eval, ok)
+	}
+	if a6 := bb5.Next(); a6.Comment != "a line comment" {
+		t.Errorf("a6.Comment got: %q; want: %q", a6.Comment, "a line comment")
+	}
+
+	want := "r1bqkb1r/1ppp1ppp/p1n2n2/4p3/B3P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 3 5"
+	if got := game.FinalPosition().Fen(); got != want {
+		t.Errorf("FinalPosition().Fen() got: %s; want: %s", got, want)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	game, err := Parse(annotatedPgn)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+
+	written := game.String()
+	reparsed, err := Parse(written)
+	if err != nil {
+		t.Fatalf("Parse(%s) got error: %v", written, err)
+	}
+	if got := reparsed.String(); got != written {
+		t.Errorf("Round trip got:\n%s\nwant:\n%s", got, written)
+	}
+	if !strings.Contains(strings.ReplaceAll(written, "\n", " "), "(1... c5 {Sicilian} 2. Nf3 (2. c3 d5) 2... d6) 2. Nf3") {
+		t.Errorf("String() got:\n%s\nwant variations written in standard form", written)
+	}
+}
+
+func TestSetPgnHeader(t *testing.T) {
+	table := []struct {
+		name  string
+		pgn   string
+		value string
+		want  string
+	}{
+		{
+			name:  "Replace",
+			pgn:   "[Event \"?\"]\r\n[ECO \"C20\"]\r\n\r\n1. e4 e5  {keep  spacing} *",
+			value: "C60",
+			want:  "[Event \"?\"]\r\n[ECO \"C60\"]\r\n\r\n1. e4 e5  {keep  spacing} *",
+		},
+		{
+			name:  "Add",
+			pgn:   "[Event \"?\"]\n[ECOExtra \"x\"]\n\n1. e4 *",
+			value: "B00",
+			want:  "[Event \"?\"]\n[ECOExtra \"x\"]\n[ECO \"B00\"]\n\n1. e4 *",
+		},
+		{
+			name:  "NoHeaders",
+			pgn:   "1. e4 *",
+			value: "B00",
+			want:  "[ECO \"B00\"]\n\n1. e4 *",
+		},
+		{
+			name:  "Escaped",
+			pgn:   "[ECO \"?\"]\n\n1. e4 *",
+			value: `B"00`,
+			want:  "[ECO \"B\\\"00\"]\n\n1. e4 *",
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SetPgnHeader(tc.pgn, "ECO", tc.value); got != tc.want {
+				t.Errorf("SetPgnHeader got: %q; want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	table := []struct {
+		name string
+		pgn  string
+		line int
+	}{
+		{name: "IllegalMove", pgn: "[Event \"?\"]\n\n1. e4 e5 2. Ke3 *", line: 3},
+		{name: "UnterminatedComment", pgn: "1. e4 {never closed", line: 1},
+		{name: "UnterminatedVariation", pgn: "1. e4 (1. d4 d5 *", line: 1},
+		{name: "UnterminatedHeader", pgn: "[Event \"Dojo\n1. e4", line: 1},
+		{name: "BadFen", pgn: "[SetUp \"1\"]\n[FEN \"not a fen\"]\n\n1. e4 *", line: 4},
+		{name: "Empty", pgn: "  \n", line: 2},
+		{name: "UnexpectedBrace", pgn: "1. e4 } e5 *", line: 1},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.pgn)
+			if err == nil {
+				t.Fatalf("Parse(%q) got no error; want error", tc.pgn)
+			}
+			perr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Parse(%q) got error type %T; want *ParseError", tc.pgn, err)
+			}
+			if perr.Line != tc.line {
+				t.Errorf("Parse(%q) got error on line %d; want line %d: %v", tc.pgn, perr.Line, tc.line, err)
+			}
+		})
+	}
+}
+
+func TestParseVariationStartComments(t *testing.T) {
+	table := []struct {
+		name string
+		pgn  string
+	}{
+		{name: "Brace", pgn: "1. e4 e5 ({Also good} {[%cal Gc7c5]} 1... c5) *"},
+		{name: "Semicolon", pgn: "1. e4 e5 (; Also good [%cal Gc7c5]\n1... c5) *"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			game, err := Parse(tc.pgn)
+			if err != nil {
+				t.Fatalf("Parse got error: %v", err)
+			}
+			e4 := game.Root.Children[0]
+			if e4.Comment != "" || e4.Children[0].Comment != "" || len(e4.Children[0].Commands) > 0 {
+				t.Errorf("Parse attached the variation comment to the moves before it")
+			}
+			c5 := e4.Children[1]
+			if c5.CommentBefore != "Also good" {
+				t.Errorf("c5.CommentBefore got: %q; want: %q", c5.CommentBefore, "Also good")
+			}
+			if c5.Commands["cal"] != "Gc7c5" {
+				t.Errorf("c5.Commands got: %v; want cal Gc7c5", c5.Commands)
+			}
+		})
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	pgn := "[Event \"One\"]\n\n1. e4 e5 1-0\n\n[Event \"Two\"]\n\n1. d4 d5 *\n[Event \"Three\"]\n[SetUp \"1\"]\n[FEN \"4k3/8/8/8/8/8/8/4K2R w K - 0 1\"]\n\n1. O-O Kd7"
+
+	games, err := ParseAll(pgn)
+	if err != nil {
+		t.Fatalf("ParseAll got error: %v", err)
+	}
+	if len(games) != 3 {
+		t.Fatalf("ParseAll got %d games; want 3", len(games))
+	}
+	for i, want := range []string{"One", "Two", "Three"} {
+		if got := games[i].Header("Event"); got != want {
+			t.Errorf("games[%d].Header(Event) got: %q; want: %q", i, got, want)
+		}
+	}
+	if len(games[2].Mainline()) != 2 || games[2].Mainline()[0].San != "O-O" {
+		t.Errorf("games[2] did not start from the FEN header")
+	}
+}
+
+func TestParseVariation(t *testing.T) {
+	start, err := ParseFen("rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 0 1")
+	if err != nil {
+		t.Fatalf("ParseFen got error: %v", err)
+	}
+
+	table := []struct {
+		name      string
+		movetext  string
+		want      string
+		wantFen   string
+		wantError bool
+	}{
+		{
+			name:     "Normalized",
+			movetext: "2... Nc6 3. Bb5 (3.Bc4 Bc5) a6!? 1-0",
+			want:     "1... Nc6 2. Bb5 (2. Bc4 Bc5) 2... a6 $5 *",
+			wantFen:  "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 0 1",
+		},
+		{
+			name:     "MissingMoveNumbers",
+			movetext: "Nc6 Bb5",
+			want:     "1... Nc6 2. Bb5 *",
+			wantFen:  "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 0 1",
+		},
+		{
+			name:     "FenHeader",
+			movetext: "[SetUp \"1\"]\n[FEN \"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2\"]\n\n2... Nc6 3. Bb5 *",
+			want:     "2... Nc6 3. Bb5 *",
+			wantFen:  "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+		},
+		{
+			name:     "OtherHeaders",
+			movetext: "[Event \"?\"]\n[Result \"*\"]\n\nNc6 *",
+			want:     "1... Nc6 *",
+			wantFen:  "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 0 1",
+		},
+		{name: "IllegalMove", movetext: "2... Nc6 3. Bb6", wantError: true},
+		{name: "WrongSide", movetext: "3. Bc4", wantError: true},
+		{name: "NoMoves", movetext: "{just a comment} *", wantError: true},
+		{name: "WrongFen", movetext: "[FEN \"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\"]\n\n1. e4 *", wantError: true},
+		{name: "TrailingGame", movetext: "Nc6 * 1. d4", wantError: true},
+		{name: "Garbage", movetext: "<script>", wantError: true},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			game, err := ParseVariation(start, tc.movetext)
+			if tc.wantError {
+				if err == nil {
+					t.Fatalf("ParseVariation(%q) got no error; want error", tc.movetext)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseVariation(%q) got error: %v", tc.movetext, err)
+			}
+			if got := game.Movetext(); got != tc.want {
+				t.Errorf("ParseVariation(%q).Movetext() got: %q; want: %q", tc.movetext, got, tc.want)
+			}
+			if got := game.Header("FEN"); got != tc.wantFen || game.Header("SetUp") != "1" {
+				t.Errorf("ParseVariation(%q) got FEN header: %q; want: %q", tc.movetext, got, tc.wantFen)
+			}
+		})
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	table := []struct {
+		value string
+		want  Score
+	}{
+		{value: "0.35", want: Score{Centipawns: 35}},
+		{value: "-1.29", want: Score{Centipawns: -129}},
+		{value: "#-3", want: Score{Mate: -3}},
+		{value: "0.17,24", want: Score{Centipawns: 17}},
+	}
+
+	for _, tc := range table {
+		got, err := ParseScore(tc.value)
+		if err != nil {
+			t.Errorf("ParseScore(%s) got error: %v", tc.value, err)
+		} else if got != tc.want {
+			t.Errorf("ParseScore(%s) got: %v; want: %v", tc.value, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// San returns the move in Standard Algebraic Notation, including a check or checkmate
+// suffix. The move must be legal in the position.
+func (p *Position) San(m Move) string {
+	if m.IsNull() {
+		return "--"
+	}
+
+	var sb strings.Builder
+	piece := p.board[m.From]
+
+	switch {
+	case m.IsCastle() && m.To.File() == 6:
+		sb.WriteString("O-O")
+	case m.IsCastle():
+		sb.WriteString("O-O-O")
+	case piece.Type == Pawn:
+		if m.From.File() != m.To.File() {
+			sb.WriteByte(byte('a' + m.From.File()))
+			sb.WriteByte('x')
+		}
+		sb.WriteString(m.To.String())
+		if m.Promotion != NoPieceType {
+			sb.WriteByte('=')
+			sb.WriteString(m.Promotion.String())
+		}
+	default:
+		sb.WriteString(piece.Type.String())
+		sb.WriteString(p.disambiguation(m))
+		if p.board[m.To] != NoPiece {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(m.To.String())
+	}
+
+	next := p.apply(m)
+	if next.InCheck() {
+		if len(next.LegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+	return sb.String()
+}
+
+// disambiguation returns the file, rank or square of the moving piece when another piece
+// of the same type could also legally move to the target square.
+func (p *Position) disambiguation(m Move) string {
+	piece := p.board[m.From]
+	sameFile, sameRank, ambiguous := false, false, false
+
+	for _, other := range p.LegalMoves() {
+		if other.To != m.To || other.From == m.From || p.board[other.From] != piece {
+			continue
+		}
+		ambiguous = true
+		if other.From.File() == m.From.File() {
+			sameFile = true
+		}
+		if other.From.Rank() == m.From.Rank() {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(byte('a' + m.From.File()))
+	case !sameRank:
+		return string(byte('1' + m.From.Rank()))
+	default:
+		return m.From.String()
+	}
+}
+
+// ParseSan returns the legal move described by the given SAN. Trailing check, checkmate
+// and annotation symbols are ignored, castling may be written with zeros and the
+// promotion `=` is optional. An error is returned if the SAN is malformed, illegal or
+// ambiguous.
+func (p *Position) ParseSan(san string) (Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	if s == "--" || s == "Z0" {
+		if p.InCheck() {
+			return Move{}, fmt.Errorf("illegal null move %q: side to move is in check", san)
+		}
+		return NullMove, nil
+	}
+
+	s = strings.ReplaceAll(s, "0", "O")
+	if s == "O-O" || s == "O-O-O" {
+		toFile := 6
+		if s == "O-O-O" {
+			toFile = 2
+		}
+		for _, m := range p.LegalMoves() {
+			if m.IsCastle() && m.To.File() == toFile {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("illegal move %q", san)
+	}
+
+	pieceType := Pawn
+	if len(s) > 0 && s[0] >= 'A' && s[0] <= 'Z' {
+		pieceType = pieceTypeFromLetter(s[0])
+		if pieceType == NoPieceType {
+			return Move{}, fmt.Errorf("invalid SAN %q", san)
+		}
+		s = s[1:]
+	}
+
+	promotion := NoPieceType
+	if n := len(s); n > 0 && s[n-1] >= 'A' && s[n-1] <= 'Z' {
+		promotion = pieceTypeFromLetter(s[n-1])
+		if promotion == NoPieceType || promotion == Pawn || promotion == King {
+			return Move{}, fmt.Errorf("invalid promotion in SAN %q", san)
+		}
+		s = strings.TrimSuffix(s[:n-1], "=")
+	} else if n := len(s); pieceType == Pawn && n >= 3 && strings.IndexByte("nbrq", s[n-1]) >= 0 && s[n-2] >= '1' && s[n-2] <= '8' {
+		promotion = pieceTypeFromLetter(s[n-1] - ('a' - 'A'))
+		s = strings.TrimSuffix(s[:n-1], "=")
+	}
+
+	if len(s) < 2 {
+		return Move{}, fmt.Errorf("invalid SAN %q", san)
+	}
+	to, err := ParseSquare(s[len(s)-2:])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid SAN %q: %w", san, err)
+	}
+
+	from := strings.ReplaceAll(strings.ReplaceAll(s[:len(s)-2], "x", ""), "-", "")
+	fromFile, fromRank := -1, -1
+	for _, c := range from {
+		switch {
+		case c >= 'a' && c <= 'h':
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = int(c - '1')
+		default:
+			return Move{}, fmt.Errorf("invalid SAN %q", san)
+		}
+	}
+
+	var match Move
+	matches := 0
+	for _, m := range p.LegalMoves() {
+		if m.To != to || m.Promotion != promotion || p.board[m.From].Type != pieceType {
+			continue
+		}
+		if (fromFile >= 0 && m.From.File() != fromFile) || (fromRank >= 0 && m.From.Rank() != fromRank) {
+			continue
+		}
+		match = m
+		matches++
+	}
+
+	switch matches {
+	case 0:
+		return Move{}, fmt.Errorf("illegal move %q", san)
+	case 1:
+		return match, nil
+	default:
+		return Move{}, fmt.Errorf("ambiguous move %q", san)
+	}
+}
+
+// ParseUCI returns the legal move described by the given UCI long algebraic notation,
+// such as e2e4 or e7e8q.
+func (p *Position) ParseUCI(uci string) (Move, error) {
+	if uci == "0000" {
+		return NullMove, nil
+	}
+	if len(uci) < 4 || len(uci) > 5 {
+		return Move{}, fmt.Errorf("invalid UCI move %q", uci)
+	}
+
+	from, err := ParseSquare(uci[:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move %q: %w", uci, err)
+	}
+	to, err := ParseSquare(uci[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move %q: %w", uci, err)
+	}
+	promotion := NoPieceType
+	if len(uci) == 5 {
+		promotion = pieceTypeFromLetter(strings.ToUpper(uci[4:])[0])
+	}
+
+	for _, m := range p.LegalMoves() {
+		if m.From == from && m.To == to && m.Promotion == promotion {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("illegal move %q", uci)
+}
@@ -0,0 +1,206 @@
+package chess
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The maximum length of a movetext line when writing a PGN.
+const maxLineLength = 80
+
+// String returns the game in PGN format.
+func (g *Game) String() string {
+	var sb strings.Builder
+	g.writeHeaders(&sb)
+	sb.WriteString("\n")
+	sb.WriteString(g.Movetext())
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// escapeHeader escapes the backslashes and quotes in the given header value.
+func escapeHeader(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// SetPgnHeader returns the given PGN text with the tag pair of the given header set to
+// value. The rest of the text, including the formatting of the movetext, is unchanged. An
+// existing tag pair for the header is replaced, and otherwise the tag pair is added after
+// the existing ones.
+func SetPgnHeader(pgn, name, value string) string {
+	tag := fmt.Sprintf("[%s \"%s\"]", name, escapeHeader(value))
+	lines := strings.SplitAfter(pgn, "\n")
+
+	end := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "[") {
+			break
+		}
+		if strings.HasPrefix(trimmed, "["+name+" ") || strings.HasPrefix(trimmed, "["+name+"\"") {
+			lines[i] = tag + line[len(strings.TrimRight(line, "\r\n")):]
+			return strings.Join(lines, "")
+		}
+		end = i + 1
+	}
+
+	if end == 0 {
+		return tag + "\n\n" + pgn
+	}
+	if !strings.HasSuffix(lines[end-1], "\n") {
+		lines[end-1] += "\n"
+	}
+	lines[end-1] += tag + "\n"
+	return strings.Join(lines, "")
+}
+
+// writeHeaders writes the Seven Tag Roster followed by the remaining headers in the
+// order they were set.
+func (g *Game) writeHeaders(sb *strings.Builder) {
+	written := make(map[string]bool, len(g.Headers))
+	write := func(name string) {
+		if written[name] {
+			return
+		}
+		value, ok := g.Headers[name]
+		if !ok {
+			if name != "Result" {
+				return
+			}
+			value = g.Result
+		}
+		written[name] = true
+		fmt.Fprintf(sb, "[%s \"%s\"]\n", name, escapeHeader(value))
+	}
+
+	for _, name := range sevenTagRoster {
+		write(name)
+	}
+	for _, name := range g.headerOrder {
+		write(name)
+	}
+
+	// Headers set directly on the map rather than through SetHeader
+	var rest []string
+	for name := range g.Headers {
+		if !written[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		write(name)
+	}
+}
+
+// Movetext returns the moves, comments and variations of the game followed by the game
+// termination marker, wrapped to lines of at most 80 characters where possible.
+func (g *Game) Movetext() string {
+	w := &movetextWriter{}
+	if comment := formatComment(g.Root.Comment, g.Root.Commands); comment != "" {
+		w.tokens = append(w.tokens, comment)
+	}
+	w.writeLine(g.Root)
+	w.tokens = append(w.tokens, g.Result)
+	return w.String()
+}
+
+type movetextWriter struct {
+	tokens []string
+}
+
+// writeLine writes the continuation of the given node, including its variations.
+func (w *movetextWriter) writeLine(n *Node) {
+	// A game starting with Black to move needs a move number before its first move
+	forceNumber := n.Parent == nil
+	for cur := n; len(cur.Children) > 0; {
+		main := cur.Children[0]
+		forceNumber = w.writeMove(main, forceNumber)
+
+		for _, variation := range cur.Children[1:] {
+			w.tokens = append(w.tokens, "(")
+			w.writeMove(variation, true)
+			w.writeLine(variation)
+			w.tokens = append(w.tokens, ")")
+			forceNumber = true
+		}
+		cur = main
+	}
+}
+
+// writeMove writes a single move with its move number, NAGs and comments. It returns
+// true if the move number must be repeated before the next move.
+func (w *movetextWriter) writeMove(n *Node, forceNumber bool) bool {
+	if comment := formatComment(n.CommentBefore, nil); comment != "" {
+		w.tokens = append(w.tokens, comment)
+		forceNumber = true
+	}
+
+	// The move number is kept in the same token as the SAN so that they are never split
+	// across lines
+	parent := n.Parent.Position
+	if parent.Turn == White {
+		w.tokens = append(w.tokens, fmt.Sprintf("%d. %s", parent.FullmoveNumber, n.San))
+	} else if forceNumber {
+		w.tokens = append(w.tokens, fmt.Sprintf("%d... %s", parent.FullmoveNumber, n.San))
+	} else {
+		w.tokens = append(w.tokens, n.San)
+	}
+	for _, nag := range n.Nags {
+		w.tokens = append(w.tokens, fmt.Sprintf("$%d", nag))
+	}
+
+	if comment := formatComment(n.Comment, n.Commands); comment != "" {
+		w.tokens = append(w.tokens, comment)
+		return true
+	}
+	return false
+}
+
+func (w *movetextWriter) String() string {
+	var sb strings.Builder
+	lineLength := 0
+	for i, token := range w.tokens {
+		if i > 0 {
+			previous := w.tokens[i-1]
+			switch {
+			case previous == "(" || token == ")":
+				// No space inside parentheses
+			case lineLength+1+len(token) > maxLineLength:
+				sb.WriteString("\n")
+				lineLength = 0
+			default:
+				sb.WriteString(" ")
+				lineLength++
+			}
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	return sb.String()
+}
+
+// formatComment returns the brace comment for the given text and commands, or an empty
+// string if both are empty.
+func formatComment(text string, commands map[string]string) string {
+	if text == "" && len(commands) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("[%%%s %s]", name, commands[name]))
+	}
+	if text != "" {
+		parts = append(parts, strings.ReplaceAll(text, "}", ")"))
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// The max length of a reaction type, which is usually a single emoji.
+const maxReactionTypeLength = 32
+
+// ToggleReactionType returns the types of the given reaction after adding reactionType if
+// it is missing or removing it if it is present. reaction may be nil if the user has not
+// reacted yet. The returned bool is true if the type was added. A 400 error is returned if
+// reactionType is invalid.
+func ToggleReactionType(reaction *Reaction, reactionType string) ([]string, bool, error) {
+	reactionType = strings.TrimSpace(reactionType)
+	if reactionType == "" {
+		return nil, false, errors.New(400, "Invalid request: type is required", "")
+	}
+	if utf8.RuneCountInString(reactionType) > maxReactionTypeLength {
+		return nil, false, errors.New(400, fmt.Sprintf("Invalid request: type must be at most %d characters", maxReactionTypeLength), "")
+	}
+
+	var types []string
+	if reaction != nil {
+		types = slices.Clone(reaction.Types)
+	}
+	if i := slices.Index(types, reactionType); i >= 0 {
+		return slices.Delete(types, i, i+1), false, nil
+	}
+	return append(types, reactionType), true, nil
+}
+
+// GetPositionComment returns the comment with the given id on the given FEN. parentIds is
+// the comma-separated list of the comment's parent ids. False is returned if the comment
+// does not exist.
+func (g *Game) GetPositionComment(fen, parentIds, id string) (*PositionComment, bool) {
+	comments := g.PositionComments[fen]
+	if parentIds != "" {
+		for _, parentId := range strings.Split(parentIds, ",") {
+			parent, ok := comments[parentId]
+			if !ok {
+				return nil, false
+			}
+			comments = parent.Replies
+		}
+	}
+
+	comment, ok := comments[id]
+	if !ok {
+		return nil, false
+	}
+	return &comment, true
+}
+
+// CountCommentReactions sets the ReactionCounts of every position comment of the game,
+// including replies.
+func (g *Game) CountCommentReactions() {
+	for _, comments := range g.PositionComments {
+		countCommentReactions(comments)
+	}
+}
+
+// countCommentReactions sets the ReactionCounts of the given comments and their replies.
+func countCommentReactions(comments map[string]PositionComment) {
+	for id, comment := range comments {
+		comment.ReactionCounts = nil
+		for _, reaction := range comment.Reactions {
+			for _, t := range reaction.Types {
+				if comment.ReactionCounts == nil {
+					comment.ReactionCounts = make(map[string]int)
+				}
+				comment.ReactionCounts[t]++
+			}
+		}
+		countCommentReactions(comment.Replies)
+		comments[id] = comment
+	}
+}
+
+// PositionCommentReaction identifies a position comment to react to.
+type PositionCommentReaction struct {
+	// The cohort of the game containing the comment.
+	Cohort DojoCohort `json:"cohort"`
+
+	// The id of the game containing the comment.
+	GameId string `json:"gameId"`
+
+	// The normalized FEN of the comment.
+	Fen string `json:"fen"`
+
+	// A comma-separated list of the parent comment ids. Empty for a top-level comment.
+	ParentIds string `json:"parentIds"`
+
+	// The id of the comment.
+	Id string `json:"id"`
+
+	// The type of reaction to toggle.
+	Type string `json:"type"`
+}
+
+type GameCommentReactor interface {
+	GameGetter
+	UserGetter
+
+	// SetCommentReaction sets the given reaction on the given position comment. If the
+	// reaction has no types, the user's reaction is removed instead. The updated game is
+	// returned.
+	SetCommentReaction(target *PositionCommentReaction, reaction *Reaction) (*Game, error)
+}
+
+// SetCommentReaction sets the given reaction on the given position comment. If the
+// reaction has no types, the user's reaction is removed instead. The updated game is
+// returned.
+func (repo *dynamoRepository) SetCommentReaction(target *PositionCommentReaction, reaction *Reaction) (*Game, error) {
+	names := map[string]*string{
+		"#p":         aws.String("positionComments"),
+		"#fen":       aws.String(target.Fen),
+		"#id":        aws.String(target.Id),
+		"#reactions": aws.String("reactions"),
+		"#username":  aws.String(reaction.Username),
+	}
+	commentPath := fmt.Sprintf("#p.#fen.%s#id", getCommentPath(target.ParentIds, names))
+	key := map[string]*dynamodb.AttributeValue{
+		"cohort": {S: aws.String(string(target.Cohort))},
+		"id":     {S: aws.String(target.GameId)},
+	}
+
+	if len(reaction.Types) == 0 {
+		input := &dynamodb.UpdateItemInput{
+			ConditionExpression:      aws.String(fmt.Sprintf("attribute_exists(%s.#reactions)", commentPath)),
+			UpdateExpression:         aws.String(fmt.Sprintf("REMOVE %s.#reactions.#username", commentPath)),
+			ExpressionAttributeNames: names,
+			Key:                      key,
+			ReturnValues:             aws.String("ALL_NEW"),
+			TableName:                aws.String(gameTable),
+		}
+		return repo.updateCommentReaction(input)
+	}
+
+	item, err := dynamodbattribute.MarshalMap(reaction)
+	if err != nil {
+		return nil, errors.Wrap(400, "Invalid request: reaction cannot be marshaled", "", err)
+	}
+
+	// Comments created before reactions were supported have no reactions map, so it is
+	// created with the first reaction if setting the reaction inside it fails.
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression:      aws.String(fmt.Sprintf("attribute_exists(%s.#reactions)", commentPath)),
+		UpdateExpression:         aws.String(fmt.Sprintf("SET %s.#reactions.#username = :r", commentPath)),
+		ExpressionAttributeNames: names,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":r": {M: item},
+		},
+		Key:          key,
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+	game, err := repo.updateCommentReaction(input)
+	if err == nil {
+		return game, nil
+	}
+	if aerr, ok := err.(*errors.Error); !ok || aerr.Code != 404 {
+		return nil, err
+	}
+
+	delete(names, "#username")
+	input = &dynamodb.UpdateItemInput{
+		ConditionExpression:      aws.String(fmt.Sprintf("attribute_exists(%s) AND attribute_not_exists(%s.#reactions)", commentPath, commentPath)),
+		UpdateExpression:         aws.String(fmt.Sprintf("SET %s.#reactions = :r", commentPath)),
+		ExpressionAttributeNames: names,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":r": {M: map[string]*dynamodb.AttributeValue{
+				reaction.Username: {M: item},
+			}},
+		},
+		Key:          key,
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+	return repo.updateCommentReaction(input)
+}
+
+// updateCommentReaction applies the given update to a comment's reactions. A 404 error is
+// returned if the condition fails.
+func (repo *dynamoRepository) updateCommentReaction(input *dynamodb.UpdateItemInput) (*Game, error) {
+	game := Game{}
+	if err := repo.updateItem(input, &game); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: comment not found", "DynamoDB conditional check failed", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return &game, nil
+}
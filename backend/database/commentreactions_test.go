@@ -0,0 +1,127 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToggleReactionType(t *testing.T) {
+	table := []struct {
+		name         string
+		reaction     *Reaction
+		reactionType string
+		want         []string
+		wantAdded    bool
+		wantErr      bool
+	}{
+		{
+			name:         "FirstReaction",
+			reaction:     nil,
+			reactionType: "👍",
+			want:         []string{"👍"},
+			wantAdded:    true,
+		},
+		{
+			name:         "AddsType",
+			reaction:     &Reaction{Types: []string{"👍"}},
+			reactionType: "🔥",
+			want:         []string{"👍", "🔥"},
+			wantAdded:    true,
+		},
+		{
+			name:         "RemovesType",
+			reaction:     &Reaction{Types: []string{"👍", "🔥"}},
+			reactionType: "👍",
+			want:         []string{"🔥"},
+			wantAdded:    false,
+		},
+		{
+			name:         "RemovesLastType",
+			reaction:     &Reaction{Types: []string{"👍"}},
+			reactionType: "👍",
+			want:         []string{},
+			wantAdded:    false,
+		},
+		{
+			name:         "EmptyType",
+			reactionType: " ",
+			wantErr:      true,
+		},
+		{
+			name:         "LongType",
+			reactionType: "abcdefghijklmnopqrstuvwxyzabcdefg",
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			var before []string
+			if tc.reaction != nil {
+				before = append(before, tc.reaction.Types...)
+			}
+
+			got, added, err := ToggleReactionType(tc.reaction, tc.reactionType)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("ToggleReactionType got: %v; want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToggleReactionType got error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) || added != tc.wantAdded {
+				t.Errorf("ToggleReactionType got: (%v, %t); want: (%v, %t)", got, added, tc.want, tc.wantAdded)
+			}
+			if tc.reaction != nil && !reflect.DeepEqual(tc.reaction.Types, before) {
+				t.Errorf("ToggleReactionType modified the existing reaction: %v", tc.reaction.Types)
+			}
+		})
+	}
+}
+
+func TestCountCommentReactions(t *testing.T) {
+	game := &Game{
+		PositionComments: map[string]map[string]PositionComment{
+			"fen": {
+				"parent": {
+					Id: "parent",
+					Reactions: map[string]Reaction{
+						"alice": {Types: []string{"👍", "🔥"}},
+						"bob":   {Types: []string{"👍"}},
+					},
+					Replies: map[string]PositionComment{
+						"reply": {
+							Id:        "reply",
+							ParentIds: "parent",
+							Reactions: map[string]Reaction{"carol": {Types: []string{"😂"}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	game.CountCommentReactions()
+
+	parent, ok := game.GetPositionComment("fen", "", "parent")
+	if !ok {
+		t.Fatalf("GetPositionComment(parent) not found")
+	}
+	if want := map[string]int{"👍": 2, "🔥": 1}; !reflect.DeepEqual(parent.ReactionCounts, want) {
+		t.Errorf("parent ReactionCounts got: %v; want: %v", parent.ReactionCounts, want)
+	}
+
+	reply, ok := game.GetPositionComment("fen", "parent", "reply")
+	if !ok {
+		t.Fatalf("GetPositionComment(reply) not found")
+	}
+	if want := map[string]int{"😂": 1}; !reflect.DeepEqual(reply.ReactionCounts, want) {
+		t.Errorf("reply ReactionCounts got: %v; want: %v", reply.ReactionCounts, want)
+	}
+
+	if _, ok := game.GetPositionComment("fen", "missing", "reply"); ok {
+		t.Errorf("GetPositionComment with a missing parent got ok; want not found")
+	}
+}
@@ -0,0 +1,692 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// The parent id of top-level directories.
+const directoryNilParent = "00000000-0000-0000-0000-000000000000"
+
+// The id of each user's My Games directory.
+const MyGamesDirectoryId = "mygames"
+
+// The id of each user's Shared with Me directory, which is managed by the platform.
+const SharedDirectoryId = "shared"
+
+// The id of each user's All Uploads directory, which is managed by the platform.
+const AllUploadsDirectoryId = "uploads"
+
+type DirectoryVisibility string
+
+const (
+	DirectoryVisibility_Public  DirectoryVisibility = "PUBLIC"
+	DirectoryVisibility_Private DirectoryVisibility = "PRIVATE"
+)
+
+// The max number of items which can be added to a directory in a single UpdateItem request.
+const addDirectoryItemsBatchSize = 50
+
+type DirectoryAccessRole string
+
+const (
+	// Viewers can see all games and sub directories.
+	DirectoryAccessRole_Viewer DirectoryAccessRole = "VIEWER"
+
+	// Editors can add games and remove games they added.
+	DirectoryAccessRole_Editor DirectoryAccessRole = "EDITOR"
+
+	// Admins can perform all directory actions except deleting the directory.
+	DirectoryAccessRole_Admin DirectoryAccessRole = "ADMIN"
+
+	// The owner of the directory. Can perform all directory actions.
+	DirectoryAccessRole_Owner DirectoryAccessRole = "OWNER"
+
+	// The user has no access to the directory.
+	DirectoryAccessRole_None DirectoryAccessRole = ""
+)
+
+// HasDirectoryRole returns true if the current role is greater than or equal to the
+// minimum role.
+func HasDirectoryRole(minRole, currentRole DirectoryAccessRole) bool {
+	switch minRole {
+	case DirectoryAccessRole_Viewer:
+		return currentRole != DirectoryAccessRole_None
+	case DirectoryAccessRole_Editor:
+		return currentRole == DirectoryAccessRole_Editor || currentRole == DirectoryAccessRole_Admin || currentRole == DirectoryAccessRole_Owner
+	case DirectoryAccessRole_Admin:
+		return currentRole == DirectoryAccessRole_Admin || currentRole == DirectoryAccessRole_Owner
+	case DirectoryAccessRole_Owner:
+		return currentRole == DirectoryAccessRole_Owner
+	}
+	return false
+}
+
+// maxDirectoryRole returns the greater of the two given roles.
+func maxDirectoryRole(a, b DirectoryAccessRole) DirectoryAccessRole {
+	if a == DirectoryAccessRole_None || HasDirectoryRole(a, b) {
+		return b
+	}
+	return a
+}
+
+type DirectoryItemType string
+
+const (
+	DirectoryItemType_Directory  DirectoryItemType = "DIRECTORY"
+	DirectoryItemType_OwnedGame  DirectoryItemType = "OWNED_GAME"
+	DirectoryItemType_MasterGame DirectoryItemType = "MASTER_GAME"
+	DirectoryItemType_DojoGame   DirectoryItemType = "DOJO_GAME"
+)
+
+type DirectoryItemMetadata struct {
+	// The cohort of the game. Empty for subdirectories.
+	Cohort DojoCohort `dynamodbav:"cohort,omitempty" json:"cohort,omitempty"`
+
+	// The id of the game. Empty for subdirectories.
+	Id string `dynamodbav:"id,omitempty" json:"id,omitempty"`
+
+	// The username of the owner of the game. Empty for subdirectories.
+	Owner string `dynamodbav:"owner,omitempty" json:"owner,omitempty"`
+
+	// The name of the subdirectory. Empty for games.
+	Name string `dynamodbav:"name,omitempty" json:"name,omitempty"`
+
+	// The display name of the owner of the game. Empty for subdirectories.
+	OwnerDisplayName string `dynamodbav:"ownerDisplayName,omitempty" json:"ownerDisplayName,omitempty"`
+
+	// The time the game was created. Empty for subdirectories.
+	CreatedAt string `dynamodbav:"createdAt,omitempty" json:"createdAt,omitempty"`
+
+	// The White header of the game. Empty for subdirectories.
+	White string `dynamodbav:"white,omitempty" json:"white,omitempty"`
+
+	// The Black header of the game. Empty for subdirectories.
+	Black string `dynamodbav:"black,omitempty" json:"black,omitempty"`
+
+	// The WhiteElo header of the game. Empty for subdirectories.
+	WhiteElo string `dynamodbav:"whiteElo,omitempty" json:"whiteElo,omitempty"`
+
+	// The BlackElo header of the game. Empty for subdirectories.
+	BlackElo string `dynamodbav:"blackElo,omitempty" json:"blackElo,omitempty"`
+
+	// The Result header of the game. Empty for subdirectories.
+	Result string `dynamodbav:"result,omitempty" json:"result,omitempty"`
+
+	// Whether the game is unlisted.
+	Unlisted bool `dynamodbav:"unlisted,omitempty" json:"unlisted,omitempty"`
+
+	// The visibility of the subdirectory. Empty for games.
+	Visibility DirectoryVisibility `dynamodbav:"visibility,omitempty" json:"visibility,omitempty"`
+
+	// The time the subdirectory was last updated. Empty for games.
+	UpdatedAt string `dynamodbav:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
+
+type DirectoryItem struct {
+	// The type of the item.
+	Type DirectoryItemType `dynamodbav:"type" json:"type"`
+
+	// The id of the item. For a subdirectory, this is the id of the directory. For a
+	// game, this is the value cohort/id.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The username of the person who added the item to the directory. If empty, the
+	// directory owner is the adder.
+	AddedBy string `dynamodbav:"addedBy,omitempty" json:"addedBy,omitempty"`
+
+	// The metadata of the item.
+	Metadata DirectoryItemMetadata `dynamodbav:"metadata" json:"metadata"`
+}
+
+// Directory is a folder of games and subdirectories owned by a user. Directories are
+// managed by the directory service; this type contains only the fields needed by the
+// Go handlers.
+type Directory struct {
+	// The username of the owner of the directory.
+	Owner string `dynamodbav:"owner" json:"owner"`
+
+	// The id of the directory.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The id of the parent directory. Top-level directories use uuid.Nil.
+	Parent string `dynamodbav:"parent" json:"parent"`
+
+	// The name of the directory.
+	Name string `dynamodbav:"name" json:"name"`
+
+	// The visibility of the directory.
+	Visibility DirectoryVisibility `dynamodbav:"visibility" json:"visibility"`
+
+	// The time the directory was created, in time.RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The time the directory was last updated, in time.RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+
+	// The items in the directory, mapped by their ids.
+	Items map[string]DirectoryItem `dynamodbav:"items" json:"items"`
+
+	// The ids of the items in the directory in their default order.
+	ItemIds []string `dynamodbav:"itemIds" json:"itemIds"`
+
+	// A map from username to the user's access role in the directory.
+	Access map[string]DirectoryAccessRole `dynamodbav:"access,omitempty" json:"access,omitempty"`
+
+	// A map from club id to the access role of the club's members in the directory. Roles
+	// given to a user directly take precedence over the roles of their clubs.
+	ClubAccess map[string]DirectoryAccessRole `dynamodbav:"clubAccess,omitempty" json:"clubAccess,omitempty"`
+}
+
+// ClubRole returns the highest access role given to any of the provided clubs on the
+// directory itself, ignoring its parents.
+func (d *Directory) ClubRole(clubs []string) DirectoryAccessRole {
+	role := DirectoryAccessRole_None
+	for _, club := range clubs {
+		role = maxDirectoryRole(role, d.ClubAccess[club])
+	}
+	return role
+}
+
+type DirectoryGetter interface {
+	// GetDirectory returns the directory with the provided owner and id.
+	GetDirectory(owner, id string) (*Directory, error)
+
+	// GetDirectoryAccessRole returns the access role of the given user on the given directory.
+	GetDirectoryAccessRole(directory *Directory, username string) (DirectoryAccessRole, error)
+}
+
+type DirectoryEditor interface {
+	// AddDirectoryGames adds the given games to the directory with the provided owner and id.
+	// The directory is added to the Directories field of each game only after the game is
+	// saved in the directory, so the games must already exist.
+	AddDirectoryGames(owner, id string, games []*Game) error
+}
+
+// GetDirectoryGameItem returns the DirectoryItem representing the given game in a directory
+// owned by the given user.
+func GetDirectoryGameItem(owner string, game *Game) DirectoryItem {
+	itemType := DirectoryItemType_DojoGame
+	if game.Owner == owner {
+		itemType = DirectoryItemType_OwnedGame
+	} else if game.Cohort == "masters" {
+		itemType = DirectoryItemType_MasterGame
+	}
+
+	return DirectoryItem{
+		Type: itemType,
+		Id:   fmt.Sprintf("%s/%s", game.Cohort, game.Id),
+		Metadata: DirectoryItemMetadata{
+			Cohort:           game.Cohort,
+			Id:               game.Id,
+			Owner:            game.Owner,
+			OwnerDisplayName: game.OwnerDisplayName,
+			CreatedAt:        game.CreatedAt,
+			White:            game.Headers["White"],
+			Black:            game.Headers["Black"],
+			WhiteElo:         game.Headers["WhiteElo"],
+			BlackElo:         game.Headers["BlackElo"],
+			Result:           game.Headers["Result"],
+			Unlisted:         game.Unlisted,
+		},
+	}
+}
+
+// GetDirectory returns the directory with the provided owner and id.
+func (repo *dynamoRepository) GetDirectory(owner, id string) (*Directory, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"owner": {S: aws.String(owner)},
+			"id":    {S: aws.String(id)},
+		},
+		TableName: aws.String(directoryTable),
+	}
+
+	directory := Directory{}
+	if err := repo.getItem(input, &directory); err != nil {
+		return nil, err
+	}
+	return &directory, nil
+}
+
+// GetDirectoryAccessRole returns the access role of the given user on the given directory.
+// Parent directories are checked recursively until the user or one of their clubs is found.
+func (repo *dynamoRepository) GetDirectoryAccessRole(directory *Directory, username string) (DirectoryAccessRole, error) {
+	var clubs []string
+	fetchedClubs := false
+
+	for directory != nil {
+		if username == directory.Owner {
+			return DirectoryAccessRole_Owner, nil
+		}
+		if role, ok := directory.Access[username]; ok {
+			return role, nil
+		}
+		if len(directory.ClubAccess) > 0 && username != "" {
+			if !fetchedClubs {
+				user, err := repo.GetUser(username)
+				if err != nil {
+					return DirectoryAccessRole_None, err
+				}
+				clubs = user.Clubs
+				fetchedClubs = true
+			}
+			if role := directory.ClubRole(clubs); role != DirectoryAccessRole_None {
+				return role, nil
+			}
+		}
+		if directory.Parent == "" || directory.Parent == directoryNilParent {
+			break
+		}
+
+		parent, err := repo.GetDirectory(directory.Owner, directory.Parent)
+		if err != nil {
+			return DirectoryAccessRole_None, err
+		}
+		directory = parent
+	}
+	return DirectoryAccessRole_None, nil
+}
+
+// AddDirectoryGames adds the given games to the directory with the provided owner and id.
+// The games' Directories field is not updated and must already contain the directory.
+func (repo *dynamoRepository) AddDirectoryGames(owner, id string, games []*Game) error {
+	for start := 0; start < len(games); start += addDirectoryItemsBatchSize {
+		end := start + addDirectoryItemsBatchSize
+		if end > len(games) {
+			end = len(games)
+		}
+
+		names := map[string]*string{
+			"#id":        aws.String("id"),
+			"#items":     aws.String("items"),
+			"#itemIds":   aws.String("itemIds"),
+			"#updatedAt": aws.String("updatedAt"),
+		}
+		values := map[string]*dynamodb.AttributeValue{
+			":updatedAt": {S: aws.String(time.Now().Format(time.RFC3339))},
+		}
+		updates := []string{"#updatedAt = :updatedAt"}
+		conditions := []string{"attribute_exists(#id)"}
+		itemIds := &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+
+		for i, game := range games[start:end] {
+			item := GetDirectoryGameItem(owner, game)
+			av, err := dynamodbattribute.MarshalMap(item)
+			if err != nil {
+				return errors.Wrap(500, "Temporary server error", "Unable to marshal directory item", err)
+			}
+
+			name := fmt.Sprintf("#i%d", i)
+			value := fmt.Sprintf(":i%d", i)
+			names[name] = aws.String(item.Id)
+			values[value] = &dynamodb.AttributeValue{M: av}
+			updates = append(updates, fmt.Sprintf("#items.%s = %s", name, value))
+			conditions = append(conditions, fmt.Sprintf("attribute_not_exists(#items.%s)", name))
+			itemIds.L = append(itemIds.L, &dynamodb.AttributeValue{S: aws.String(item.Id)})
+		}
+
+		updates = append(updates, "#itemIds = list_append(#itemIds, :itemIds)")
+		values[":itemIds"] = itemIds
+
+		input := &dynamodb.UpdateItemInput{
+			Key: map[string]*dynamodb.AttributeValue{
+				"owner": {S: aws.String(owner)},
+				"id":    {S: aws.String(id)},
+			},
+			UpdateExpression:          aws.String("SET " + strings.Join(updates, ", ")),
+			ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			TableName:                 aws.String(directoryTable),
+		}
+		if _, err := repo.svc.UpdateItem(input); err != nil {
+			if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				return errors.Wrap(400, "Invalid request: directory does not exist or already contains some of these games", "DynamoDB conditional check failure", aerr)
+			}
+			return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+		}
+
+		for _, game := range games[start:end] {
+			if err := repo.addGameDirectory(game, owner, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addGameDirectory adds the directory with the provided owner and id to the Directories
+// field of the given game.
+func (repo *dynamoRepository) addGameDirectory(game *Game, owner, id string) error {
+	directory := fmt.Sprintf("%s/%s", owner, id)
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(game.Cohort))},
+			"id":     {S: aws.String(game.Id)},
+		},
+		UpdateExpression:    aws.String("ADD #directories :directories"),
+		ConditionExpression: aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":          aws.String("id"),
+			"#directories": aws.String("directories"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":directories": {SS: []*string{aws.String(directory)}},
+		},
+		TableName: aws.String(gameTable),
+	}
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(404, "Invalid request: game not found", "DynamoDB conditional check failure", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	if !slices.Contains(game.Directories, directory) {
+		game.Directories = append(game.Directories, directory)
+	}
+	return nil
+}
+
+// ValidateDirectoryShare returns a 400 error if the given user and club access cannot be
+// set on the given directory by a user with the given role. Users can be given any role
+// except owner, while clubs can only be viewers or editors. Only the owner can add admins.
+func ValidateDirectoryShare(directory *Directory, callerRole DirectoryAccessRole, access, clubAccess map[string]DirectoryAccessRole) error {
+	if directory.Id == SharedDirectoryId || directory.Id == AllUploadsDirectoryId {
+		return errors.New(400, "Invalid request: this directory cannot be shared", "")
+	}
+
+	for username, role := range access {
+		if username == directory.Owner {
+			return errors.New(400, "Invalid request: the owner cannot be given an access role", "")
+		}
+		switch role {
+		case DirectoryAccessRole_Viewer, DirectoryAccessRole_Editor:
+		case DirectoryAccessRole_Admin:
+			if callerRole != DirectoryAccessRole_Owner && directory.Access[username] != DirectoryAccessRole_Admin {
+				return errors.New(403, "Invalid request: only the owner can add admins", "")
+			}
+		default:
+			return errors.New(400, fmt.Sprintf("Invalid request: role `%s` is invalid for user `%s`", role, username), "")
+		}
+	}
+
+	for club, role := range clubAccess {
+		if club == "" {
+			return errors.New(400, "Invalid request: club id is required", "")
+		}
+		if role != DirectoryAccessRole_Viewer && role != DirectoryAccessRole_Editor {
+			return errors.New(400, fmt.Sprintf("Invalid request: role `%s` is invalid for club `%s`", role, club), "")
+		}
+	}
+	return nil
+}
+
+// CanViewGame returns true if the given user can view the given game. Unlisted games in at
+// least one private directory can only be viewed by their owner, their editors and the
+// users with viewer access to one of their directories. All other games can be viewed by
+// anyone.
+func CanViewGame(repo DirectoryGetter, game *Game, username string) (bool, error) {
+	if !game.Unlisted || game.CanEdit(username) {
+		return true, nil
+	}
+
+	private := false
+	for _, key := range game.Directories {
+		owner, id, ok := strings.Cut(key, "/")
+		if !ok {
+			continue
+		}
+
+		directory, err := repo.GetDirectory(owner, id)
+		if err != nil {
+			var aerr *errors.Error
+			if errors.As(err, &aerr) && aerr.Code == 404 {
+				continue
+			}
+			return false, err
+		}
+		if directory.Visibility != DirectoryVisibility_Private {
+			continue
+		}
+		private = true
+
+		role, err := repo.GetDirectoryAccessRole(directory, username)
+		if err != nil {
+			return false, err
+		}
+		if HasDirectoryRole(DirectoryAccessRole_Viewer, role) {
+			return true, nil
+		}
+	}
+	return !private, nil
+}
+
+// FilterListedGames returns the given games, excluding unlisted games which the given user
+// cannot edit. List results do not include the directories of a game, so an unlisted game
+// shared through a private directory is only returned by GetGame, where CanViewGame applies.
+func FilterListedGames(games []*Game, username string) []*Game {
+	listed := make([]*Game, 0, len(games))
+	for _, game := range games {
+		if !game.Unlisted || game.CanEdit(username) {
+			listed = append(listed, game)
+		}
+	}
+	return listed
+}
+
+type DirectorySharer interface {
+	DirectoryGetter
+
+	// ShareDirectory sets the user and club access of the given directory. The updated
+	// directory is returned.
+	ShareDirectory(owner, id string, access, clubAccess map[string]DirectoryAccessRole) (*Directory, error)
+
+	// AddSharedDirectory adds the given directory to the given user's Shared with Me
+	// directory, creating it if necessary.
+	AddSharedDirectory(username string, directory *Directory) error
+
+	// RemoveSharedDirectory removes the given directory from the given user's Shared with
+	// Me directory.
+	RemoveSharedDirectory(username string, directory *Directory) error
+}
+
+// ShareDirectory sets the user and club access of the given directory. The updated
+// directory is returned.
+func (repo *dynamoRepository) ShareDirectory(owner, id string, access, clubAccess map[string]DirectoryAccessRole) (*Directory, error) {
+	accessAv, err := dynamodbattribute.Marshal(access)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal access", err)
+	}
+	clubAccessAv, err := dynamodbattribute.Marshal(clubAccess)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal club access", err)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"owner": {S: aws.String(owner)},
+			"id":    {S: aws.String(id)},
+		},
+		ConditionExpression: aws.String("attribute_exists(#id)"),
+		UpdateExpression:    aws.String("SET #access = :access, #clubAccess = :clubAccess, #updatedAt = :updatedAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":         aws.String("id"),
+			"#access":     aws.String("access"),
+			"#clubAccess": aws.String("clubAccess"),
+			"#updatedAt":  aws.String("updatedAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":access":     accessAv,
+			":clubAccess": clubAccessAv,
+			":updatedAt":  {S: aws.String(time.Now().Format(time.RFC3339))},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(directoryTable),
+	}
+
+	result, err := repo.svc.UpdateItem(input)
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: directory not found", "DynamoDB conditional check failure", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+
+	directory := Directory{}
+	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &directory); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal UpdateItem result", err)
+	}
+	return &directory, nil
+}
+
+// getSharedDirectoryItem returns the DirectoryItem representing the given directory in
+// another user's Shared with Me directory.
+func getSharedDirectoryItem(directory *Directory) DirectoryItem {
+	return DirectoryItem{
+		Type:    DirectoryItemType_Directory,
+		Id:      directory.Id,
+		AddedBy: directory.Owner,
+		Metadata: DirectoryItemMetadata{
+			Name:       directory.Name,
+			Visibility: directory.Visibility,
+			CreatedAt:  directory.CreatedAt,
+			UpdatedAt:  directory.UpdatedAt,
+		},
+	}
+}
+
+// AddSharedDirectory adds the given directory to the given user's Shared with Me
+// directory, creating it if necessary.
+func (repo *dynamoRepository) AddSharedDirectory(username string, directory *Directory) error {
+	item := getSharedDirectoryItem(directory)
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal directory item", err)
+	}
+	updatedAt := time.Now().Format(time.RFC3339)
+
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"owner": {S: aws.String(username)},
+			"id":    {S: aws.String(SharedDirectoryId)},
+		},
+		ConditionExpression: aws.String("attribute_exists(#id) AND attribute_not_exists(#items.#item)"),
+		UpdateExpression:    aws.String("SET #items.#item = :item, #itemIds = list_append(#itemIds, :itemIds), #updatedAt = :updatedAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":        aws.String("id"),
+			"#items":     aws.String("items"),
+			"#item":      aws.String(item.Id),
+			"#itemIds":   aws.String("itemIds"),
+			"#updatedAt": aws.String("updatedAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":item":      {M: av},
+			":itemIds":   {L: []*dynamodb.AttributeValue{{S: aws.String(item.Id)}}},
+			":updatedAt": {S: aws.String(updatedAt)},
+		},
+		TableName: aws.String(directoryTable),
+	}
+
+	_, err = repo.svc.UpdateItem(input)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*dynamodb.ConditionalCheckFailedException); !ok {
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+
+	// Either the Shared with Me directory does not exist or it already contains the item
+	shared := Directory{
+		Owner:      username,
+		Id:         SharedDirectoryId,
+		Parent:     directoryNilParent,
+		Name:       "Shared with Me",
+		Visibility: DirectoryVisibility_Private,
+		CreatedAt:  updatedAt,
+		UpdatedAt:  updatedAt,
+		Items:      map[string]DirectoryItem{item.Id: item},
+		ItemIds:    []string{item.Id},
+	}
+	sharedAv, err := dynamodbattribute.MarshalMap(shared)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal directory", err)
+	}
+
+	_, err = repo.svc.PutItem(&dynamodb.PutItemInput{
+		Item:                sharedAv,
+		ConditionExpression: aws.String("attribute_not_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id": aws.String("id"),
+		},
+		TableName: aws.String(directoryTable),
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB PutItem failure", err)
+	}
+	return nil
+}
+
+// RemoveSharedDirectory removes the given directory from the given user's Shared with
+// Me directory, both from its items and from its itemIds. A 409 error is returned if the
+// itemIds changed since they were read.
+func (repo *dynamoRepository) RemoveSharedDirectory(username string, directory *Directory) error {
+	shared, err := repo.GetDirectory(username, SharedDirectoryId)
+	if err != nil {
+		var aerr *errors.Error
+		if errors.As(err, &aerr) && aerr.Code == 404 {
+			return nil
+		}
+		return err
+	}
+
+	conditionExpression := "attribute_exists(#id)"
+	updateExpression := "REMOVE #items.#item"
+	expressionAttributeNames := map[string]*string{
+		"#id":        aws.String("id"),
+		"#items":     aws.String("items"),
+		"#item":      aws.String(directory.Id),
+		"#updatedAt": aws.String("updatedAt"),
+	}
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":updatedAt": {S: aws.String(time.Now().Format(time.RFC3339))},
+	}
+	if index := slices.Index(shared.ItemIds, directory.Id); index >= 0 {
+		// The index is checked in case the list changed since it was read
+		path := fmt.Sprintf("#itemIds[%d]", index)
+		conditionExpression += fmt.Sprintf(" AND %s = :itemId", path)
+		updateExpression += ", " + path
+		expressionAttributeNames["#itemIds"] = aws.String("itemIds")
+		expressionAttributeValues[":itemId"] = &dynamodb.AttributeValue{S: aws.String(directory.Id)}
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"owner": {S: aws.String(username)},
+			"id":    {S: aws.String(SharedDirectoryId)},
+		},
+		ConditionExpression:       aws.String(conditionExpression),
+		UpdateExpression:          aws.String(updateExpression + " SET #updatedAt = :updatedAt"),
+		ExpressionAttributeNames:  expressionAttributeNames,
+		ExpressionAttributeValues: expressionAttributeValues,
+		TableName:                 aws.String(directoryTable),
+	}
+
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(409, "Conflict: the Shared with Me directory changed. Try again.", "DynamoDB conditional check failed", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+type mockDirectoryGetter struct {
+	directories map[string]*Directory
+	clubs       map[string][]string
+}
+
+func (m *mockDirectoryGetter) GetDirectory(owner, id string) (*Directory, error) {
+	if d, ok := m.directories[owner+"/"+id]; ok {
+		return d, nil
+	}
+	return nil, errors.New(404, "Invalid request: resource not found", "")
+}
+
+func (m *mockDirectoryGetter) GetDirectoryAccessRole(directory *Directory, username string) (DirectoryAccessRole, error) {
+	if username == directory.Owner {
+		return DirectoryAccessRole_Owner, nil
+	}
+	if role, ok := directory.Access[username]; ok {
+		return role, nil
+	}
+	return directory.ClubRole(m.clubs[username]), nil
+}
+
+func TestDirectoryClubRole(t *testing.T) {
+	directory := &Directory{ClubAccess: map[string]DirectoryAccessRole{
+		"club1": DirectoryAccessRole_Viewer,
+		"club2": DirectoryAccessRole_Editor,
+	}}
+
+	table := []struct {
+		clubs []string
+		want  DirectoryAccessRole
+	}{
+		{clubs: nil, want: DirectoryAccessRole_None},
+		{clubs: []string{"club3"}, want: DirectoryAccessRole_None},
+		{clubs: []string{"club1"}, want: DirectoryAccessRole_Viewer},
+		{clubs: []string{"club2", "club1"}, want: DirectoryAccessRole_Editor},
+	}
+
+	for _, tc := range table {
+		if got := directory.ClubRole(tc.clubs); got != tc.want {
+			t.Errorf("ClubRole(%v) got: %q; want: %q", tc.clubs, got, tc.want)
+		}
+	}
+}
+
+func TestValidateDirectoryShare(t *testing.T) {
+	directory := &Directory{
+		Owner:  "coach",
+		Id:     "students",
+		Access: map[string]DirectoryAccessRole{"assistant": DirectoryAccessRole_Admin},
+	}
+
+	table := []struct {
+		name       string
+		directory  *Directory
+		callerRole DirectoryAccessRole
+		access     map[string]DirectoryAccessRole
+		clubAccess map[string]DirectoryAccessRole
+		wantCode   int
+	}{
+		{
+			name:       "Valid",
+			directory:  directory,
+			callerRole: DirectoryAccessRole_Owner,
+			access:     map[string]DirectoryAccessRole{"student": DirectoryAccessRole_Editor, "assistant": DirectoryAccessRole_Admin},
+			clubAccess: map[string]DirectoryAccessRole{"club": DirectoryAccessRole_Viewer},
+		},
+		{
+			name:       "AdminKeepsExistingAdmin",
+			directory:  directory,
+			callerRole: DirectoryAccessRole_Admin,
+			access:     map[string]DirectoryAccessRole{"assistant": DirectoryAccessRole_Admin},
+		},
+		{
+			name:       "AdminAddsAdmin",
+			directory:  directory,
+			callerRole: DirectoryAccessRole_Admin,
+			access:     map[string]DirectoryAccessRole{"student": DirectoryAccessRole_Admin},
+			wantCode:   403,
+		},
+		{
+			name:       "Owner",
+			directory:  directory,
+			callerRole: DirectoryAccessRole_Owner,
+			access:     map[string]DirectoryAccessRole{"coach": DirectoryAccessRole_Viewer},
+			wantCode:   400,
+		},
+		{
+			name:       "ClubAdmin",
+			directory:  directory,
+			callerRole: DirectoryAccessRole_Owner,
+			clubAccess: map[string]DirectoryAccessRole{"club": DirectoryAccessRole_Admin},
+			wantCode:   400,
+		},
+		{
+			name:       "ManagedDirectory",
+			directory:  &Directory{Owner: "coach", Id: SharedDirectoryId},
+			callerRole: DirectoryAccessRole_Owner,
+			wantCode:   400,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDirectoryShare(tc.directory, tc.callerRole, tc.access, tc.clubAccess)
+			code := 0
+			var aerr *errors.Error
+			if errors.As(err, &aerr) {
+				code = aerr.Code
+			}
+			if code != tc.wantCode {
+				t.Errorf("ValidateDirectoryShare got error: %v; want code: %d", err, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestCanViewGame(t *testing.T) {
+	repo := &mockDirectoryGetter{
+		directories: map[string]*Directory{
+			"coach/private": {
+				Owner:      "coach",
+				Id:         "private",
+				Visibility: DirectoryVisibility_Private,
+				Access:     map[string]DirectoryAccessRole{"student": DirectoryAccessRole_Editor},
+				ClubAccess: map[string]DirectoryAccessRole{"club": DirectoryAccessRole_Viewer},
+			},
+			"coach/public": {Owner: "coach", Id: "public", Visibility: DirectoryVisibility_Public},
+		},
+		clubs: map[string][]string{"member": {"club"}},
+	}
+
+	table := []struct {
+		name     string
+		game     *Game
+		username string
+		want     bool
+	}{
+		{
+			name:     "Listed",
+			game:     &Game{Owner: "student", Directories: []string{"coach/private"}},
+			username: "stranger",
+			want:     true,
+		},
+		{
+			name:     "UnlistedWithoutDirectories",
+			game:     &Game{Owner: "student", Unlisted: true},
+			username: "stranger",
+			want:     true,
+		},
+		{
+			name:     "UnlistedInPublicDirectory",
+			game:     &Game{Owner: "student", Unlisted: true, Directories: []string{"coach/public", "coach/deleted"}},
+			username: "stranger",
+			want:     true,
+		},
+		{
+			name:     "PrivateOwner",
+			game:     &Game{Owner: "student", Unlisted: true, Directories: []string{"coach/private"}},
+			username: "student",
+			want:     true,
+		},
+		{
+			name:     "PrivateDirectoryOwner",
+			game:     &Game{Owner: "student", Unlisted: true, Directories: []string{"coach/private"}},
+			username: "coach",
+			want:     true,
+		},
+		{
+			name:     "PrivateGameEditor",
+			game:     &Game{Owner: "student", Editors: []string{"partner"}, Unlisted: true, Directories: []string{"coach/private"}},
+			username: "partner",
+			want:     true,
+		},
+		{
+			name:     "PrivateClubMember",
+			game:     &Game{Owner: "student", Unlisted: true, Directories: []string{"coach/private"}},
+			username: "member",
+			want:     true,
+		},
+		{
+			name:     "PrivateStranger",
+			game:     &Game{Owner: "student", Unlisted: true, Directories: []string{"coach/public", "coach/private"}},
+			username: "stranger",
+			want:     false,
+		},
+		{
+			name:     "PrivateAnonymous",
+			game:     &Game{Owner: "student", Unlisted: true, Directories: []string{"coach/private"}},
+			username: "",
+			want:     false,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CanViewGame(repo, tc.game, tc.username)
+			if err != nil {
+				t.Fatalf("CanViewGame got error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("CanViewGame got: %t; want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterListedGames(t *testing.T) {
+	games := []*Game{
+		{Owner: "student", Id: "listed"},
+		{Owner: "student", Id: "unlisted", Unlisted: true, Directories: []string{"coach/public"}},
+		{Owner: "student", Id: "shared", Editors: []string{"partner"}, Unlisted: true},
+	}
+
+	table := []struct {
+		username string
+		want     []string
+	}{
+		{username: "student", want: []string{"listed", "unlisted", "shared"}},
+		{username: "partner", want: []string{"listed", "shared"}},
+		{username: "stranger", want: []string{"listed"}},
+		{username: "", want: []string{"listed"}},
+	}
+
+	for _, tc := range table {
+		var got []string
+		for _, g := range FilterListedGames(games, tc.username) {
+			got = append(got, g.Id)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("FilterListedGames(%q) got: %v; want: %v", tc.username, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// The max number of editors of a single game.
+const maxGameEditors = 10
+
+// NormalizeGameEditors returns the given editor usernames trimmed, sorted and without
+// duplicates. A 400 error is returned if the owner is included or there are too many
+// editors.
+func NormalizeGameEditors(owner string, editors []string) ([]string, error) {
+	result := make([]string, 0, len(editors))
+	for _, editor := range editors {
+		editor = strings.TrimSpace(editor)
+		if editor == "" {
+			continue
+		}
+		if editor == owner {
+			return nil, errors.New(400, "Invalid request: the owner of a game cannot be an editor", "")
+		}
+		result = append(result, editor)
+	}
+
+	slices.Sort(result)
+	result = slices.Compact(result)
+	if len(result) > maxGameEditors {
+		return nil, errors.New(400, fmt.Sprintf("Invalid request: games can have at most %d editors", maxGameEditors), "")
+	}
+	return result, nil
+}
+
+// CanEdit returns true if the given user is the owner or an editor of the game.
+func (g *Game) CanEdit(username string) bool {
+	return username != "" && (g.Owner == username || slices.Contains(g.Editors, username))
+}
+
+// Editor returns the username of the owner or editor who last changed the game's PGN.
+func (g *Game) Editor() string {
+	if g.LastEditedBy != "" {
+		return g.LastEditedBy
+	}
+	return g.Owner
+}
+
+// MergeGamePgn merges the given PGN into the PGN of the given game, which changed after the
+// editor who wrote pgn loaded it. base is the version of the game the editor loaded. The
+// changes made since base in both the game and pgn are kept, with the game's current
+// values taking precedence when both changed the same header or annotation. See
+// chess.Game.Merge. The merged PGN and its parsed form are returned.
+func MergeGamePgn(game *Game, base *GameVersion, pgn string) (string, *chess.Game, error) {
+	current, err := game.ParsePgn()
+	if err != nil {
+		return "", nil, err
+	}
+	original, err := (&Game{Cohort: game.Cohort, Id: game.Id, Pgn: base.Pgn}).ParsePgn()
+	if err != nil {
+		return "", nil, err
+	}
+	incoming, err := (&Game{Cohort: game.Cohort, Id: game.Id, Pgn: pgn}).ParsePgn()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := current.Merge(original, incoming); err != nil {
+		return "", nil, errors.Wrap(400, "Invalid request: the PGN does not start from the same position as the game", "", err)
+	}
+	return current.String(), current, nil
+}
+
+type GameEditorSetter interface {
+	// SetGameEditors sets the editors of the given game, if it is owned by the given user.
+	// The updated game is returned.
+	SetGameEditors(owner string, cohort DojoCohort, id string, editors []string) (*Game, error)
+}
+
+type GameAnnotator interface {
+	GameGetter
+
+	// GetGameVersionAt returns the saved version of the given game which was current at
+	// the given updatedAt.
+	GetGameVersionAt(cohort DojoCohort, id, updatedAt string) (*GameVersion, error)
+
+	// AnnotateGame sets the PGN of the given game, if the given user is its owner or one of
+	// its editors and the game has not been updated since updatedAt. The headers and the
+	// fields derived from them are set from the PGN. The updated game is returned.
+	AnnotateGame(editor string, game *Game, parsed *chess.Game, updatedAt string) (*Game, error)
+}
+
+// SetGameEditors sets the editors of the given game, if it is owned by the given user.
+// The updated game is returned.
+func (repo *dynamoRepository) SetGameEditors(owner string, cohort DojoCohort, id string, editors []string) (*Game, error) {
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#owner = :owner"),
+		UpdateExpression:    aws.String("REMOVE #editors"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":   aws.String("owner"),
+			"#editors": aws.String("editors"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(owner)},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(cohort))},
+			"id":     {S: aws.String(id)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+
+	// String sets cannot be empty, so the attribute is removed instead
+	if len(editors) > 0 {
+		input.UpdateExpression = aws.String("SET #editors = :editors")
+		input.ExpressionAttributeValues[":editors"] = &dynamodb.AttributeValue{SS: aws.StringSlice(editors)}
+	}
+
+	result := Game{}
+	if err := repo.updateItem(input, &result); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: game not found or you do not have permission to update it", "DynamoDB UpdateItem failure", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return &result, nil
+}
+
+// AnnotateGame sets the PGN of the given game, if the given user is its owner or one of
+// its editors and the game has not been updated since updatedAt. The headers and the
+// fields derived from them are set from the PGN. The updated game is returned. A 409
+// error is returned if the game was updated since updatedAt.
+func (repo *dynamoRepository) AnnotateGame(editor string, game *Game, parsed *chess.Game, updatedAt string) (*Game, error) {
+	condition := "(#owner = :editor OR contains(#editors, :editor)) AND "
+	names := map[string]*string{
+		"#owner":   aws.String("owner"),
+		"#editors": aws.String("editors"),
+	}
+	var values map[string]*dynamodb.AttributeValue
+	if updatedAt == "" {
+		condition += "attribute_not_exists(#updatedAt)"
+	} else {
+		condition += "#updatedAt = :expectedUpdatedAt"
+		values = map[string]*dynamodb.AttributeValue{
+			":expectedUpdatedAt": {S: aws.String(updatedAt)},
+		}
+	}
+
+	result, err := repo.updateGamePgn(game, parsed, parsed.Headers, editor, condition, names, values)
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(409, "Conflict: the game was changed by someone else", "DynamoDB UpdateItem failure", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeGameEditors(t *testing.T) {
+	table := []struct {
+		name    string
+		editors []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Empty",
+			editors: nil,
+			want:    []string{},
+		},
+		{
+			name:    "SortsAndDedupes",
+			editors: []string{" carol", "bob", "carol", ""},
+			want:    []string{"bob", "carol"},
+		},
+		{
+			name:    "Owner",
+			editors: []string{"bob", "alice"},
+			wantErr: true,
+		},
+		{
+			name:    "TooMany",
+			editors: []string{"a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9", "a10", "a11"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeGameEditors("alice", tc.editors)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("NormalizeGameEditors got: %v; want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeGameEditors got error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("NormalizeGameEditors got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGameCanEdit(t *testing.T) {
+	game := &Game{Owner: "alice", Editors: []string{"bob"}}
+
+	table := []struct {
+		username string
+		want     bool
+	}{
+		{username: "alice", want: true},
+		{username: "bob", want: true},
+		{username: "carol", want: false},
+		{username: "", want: false},
+	}
+
+	for _, tc := range table {
+		if got := game.CanEdit(tc.username); got != tc.want {
+			t.Errorf("CanEdit(%q) got: %t; want: %t", tc.username, got, tc.want)
+		}
+	}
+}
+
+func TestMergeGamePgn(t *testing.T) {
+	game := &Game{
+		Cohort: "1500-1600",
+		Id:     "2024.01.02_abc",
+		Pgn:    "[White \"Alice\"]\n[Black \"Bob\"]\n\n1. e4 {Owner's note} e5 (1... e6) *",
+	}
+	base := &GameVersion{Pgn: "[White \"Alice\"]\n[Black \"Bob\"]\n\n1. e4 e5 (1... e6) *"}
+
+	pgn, parsed, err := MergeGamePgn(game, base, "[White \"Alice\"]\n[Black \"Bob\"]\n\n1. e4 e5 (1... c5 {Partner's idea}) *")
+	if err != nil {
+		t.Fatalf("MergeGamePgn got error: %v", err)
+	}
+	if got, want := parsed.Movetext(), "1. e4 {Owner's note} 1... e5 (1... c5 {Partner's idea}) *"; got != want {
+		t.Errorf("MergeGamePgn movetext got: %q; want: %q", got, want)
+	}
+	if pgn != parsed.String() {
+		t.Errorf("MergeGamePgn pgn got: %q; want: %q", pgn, parsed.String())
+	}
+
+	if _, _, err := MergeGamePgn(game, base, "1. e4 e5 2. Kxe8 *"); err == nil {
+		t.Errorf("MergeGamePgn got nil error; want error for an illegal move")
+	}
+}
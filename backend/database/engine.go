@@ -0,0 +1,362 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// The centipawn value at which evaluations are capped when calculating centipawn loss.
+// Mate scores are treated as this value, so that moves in already winning positions are
+// not classified as mistakes.
+const maxCentipawnEval = 1000
+
+// The max number of plies of the engine's best line stored for mistakes and blunders.
+const maxBestLinePlies = 8
+
+type MoveClassification string
+
+const (
+	MoveClassification_Inaccuracy MoveClassification = "INACCURACY"
+	MoveClassification_Mistake    MoveClassification = "MISTAKE"
+	MoveClassification_Blunder    MoveClassification = "BLUNDER"
+	MoveClassification_None       MoveClassification = ""
+)
+
+// ClassifyMove returns the classification of a move with the given centipawn loss.
+func ClassifyMove(loss int) MoveClassification {
+	switch {
+	case loss >= 300:
+		return MoveClassification_Blunder
+	case loss >= 100:
+		return MoveClassification_Mistake
+	case loss >= 50:
+		return MoveClassification_Inaccuracy
+	}
+	return MoveClassification_None
+}
+
+// cappedCentipawns returns the given score in centipawns from White's perspective, capped
+// at maxCentipawnEval.
+func cappedCentipawns(s chess.Score) int {
+	if s.Mate > 0 {
+		return maxCentipawnEval
+	}
+	if s.Mate < 0 {
+		return -maxCentipawnEval
+	}
+	return max(-maxCentipawnEval, min(maxCentipawnEval, s.Centipawns))
+}
+
+// CentipawnLoss returns the centipawns lost by the given color when a move changed the
+// evaluation from before to after. The loss is never negative.
+func CentipawnLoss(before, after chess.Score, mover chess.Color) int {
+	loss := cappedCentipawns(before) - cappedCentipawns(after)
+	if mover == chess.Black {
+		loss = -loss
+	}
+	return max(loss, 0)
+}
+
+type EngineMoveAnalysis struct {
+	// The move played in the game.
+	San string `dynamodbav:"san" json:"san"`
+
+	// The evaluation of the position after the move, from White's perspective.
+	Eval chess.Score `dynamodbav:"eval" json:"eval"`
+
+	// The engine's best move in the position before the move, in SAN. Empty if the move
+	// played was the best move.
+	BestMove string `dynamodbav:"bestMove,omitempty" json:"bestMove,omitempty"`
+
+	// The centipawns lost by the move compared to the position before it.
+	CentipawnLoss int `dynamodbav:"centipawnLoss,omitempty" json:"centipawnLoss,omitempty"`
+
+	// The classification of the move.
+	Classification MoveClassification `dynamodbav:"classification,omitempty" json:"classification,omitempty"`
+
+	// The engine's best line in the position before the move, in SAN. Set only for
+	// mistakes and blunders.
+	BestLine []string `dynamodbav:"bestLine,omitempty" json:"bestLine,omitempty"`
+}
+
+// EngineAnalysis contains the engine evaluations of each mainline move of a game.
+type EngineAnalysis struct {
+	// The name of the engine which produced the analysis.
+	Engine string `dynamodbav:"engine" json:"engine"`
+
+	// The depth searched in each position.
+	Depth int `dynamodbav:"depth" json:"depth"`
+
+	// The time the analysis was produced, in time.RFC3339 format.
+	AnalyzedAt string `dynamodbav:"analyzedAt" json:"analyzedAt"`
+
+	// The number of plies played before the first mainline move. Non-zero only for games
+	// starting from a custom position.
+	StartPly int `dynamodbav:"startPly,omitempty" json:"startPly,omitempty"`
+
+	// The evaluation of the starting position, from White's perspective.
+	StartEval chess.Score `dynamodbav:"startEval" json:"startEval"`
+
+	// The analysis of each mainline move.
+	Moves []EngineMoveAnalysis `dynamodbav:"moves" json:"moves"`
+}
+
+// NewEngineAnalysis returns the analysis of a game with the given mainline moves. evals
+// and bestLines contain the evaluation and best line (in SAN) of each mainline position,
+// including the starting position, and so must be one longer than sans. The Engine, Depth
+// and AnalyzedAt fields are not set.
+func NewEngineAnalysis(startPly int, sans []string, evals []chess.Score, bestLines [][]string) *EngineAnalysis {
+	analysis := &EngineAnalysis{
+		StartPly:  startPly,
+		StartEval: evals[0],
+		Moves:     make([]EngineMoveAnalysis, 0, len(sans)),
+	}
+
+	for i, san := range sans {
+		move := EngineMoveAnalysis{San: san, Eval: evals[i+1]}
+		if len(bestLines[i]) > 0 && bestLines[i][0] != san {
+			move.BestMove = bestLines[i][0]
+			move.CentipawnLoss = CentipawnLoss(evals[i], evals[i+1], analysis.color(i))
+			move.Classification = ClassifyMove(move.CentipawnLoss)
+			if move.Classification == MoveClassification_Mistake || move.Classification == MoveClassification_Blunder {
+				move.BestLine = bestLines[i][:min(len(bestLines[i]), maxBestLinePlies)]
+			}
+		}
+		analysis.Moves = append(analysis.Moves, move)
+	}
+	return analysis
+}
+
+// color returns the color which played the mainline move at the given index.
+func (a *EngineAnalysis) color(index int) chess.Color {
+	if (a.StartPly+index)%2 == 0 {
+		return chess.White
+	}
+	return chess.Black
+}
+
+// Matches returns true if the analysis covers exactly the mainline of the given game.
+func (a *EngineAnalysis) Matches(parsed *chess.Game) bool {
+	mainline := parsed.Mainline()
+	if len(mainline) != len(a.Moves) {
+		return false
+	}
+	root := parsed.Root.Position
+	if a.StartPly != (root.FullmoveNumber-1)*2+int(root.Turn) {
+		return false
+	}
+	for i, n := range mainline {
+		if n.San != a.Moves[i].San {
+			return false
+		}
+	}
+	return true
+}
+
+type EnginePlayerSummary struct {
+	// The number of inaccuracies played.
+	Inaccuracies int `dynamodbav:"inaccuracies" json:"inaccuracies"`
+
+	// The number of mistakes played.
+	Mistakes int `dynamodbav:"mistakes" json:"mistakes"`
+
+	// The number of blunders played.
+	Blunders int `dynamodbav:"blunders" json:"blunders"`
+
+	// The average centipawn loss per move, rounded down.
+	AverageCentipawnLoss int `dynamodbav:"averageCentipawnLoss" json:"averageCentipawnLoss"`
+}
+
+// EngineSummary is a compact summary of an EngineAnalysis, which is included in the
+// review queue.
+type EngineSummary struct {
+	// The name of the engine which produced the analysis.
+	Engine string `dynamodbav:"engine" json:"engine"`
+
+	// The depth searched in each position.
+	Depth int `dynamodbav:"depth" json:"depth"`
+
+	// The time the analysis was produced, in time.RFC3339 format.
+	AnalyzedAt string `dynamodbav:"analyzedAt" json:"analyzedAt"`
+
+	// The summary of White's moves.
+	White EnginePlayerSummary `dynamodbav:"white" json:"white"`
+
+	// The summary of Black's moves.
+	Black EnginePlayerSummary `dynamodbav:"black" json:"black"`
+}
+
+// Summary returns the EngineSummary of the analysis.
+func (a *EngineAnalysis) Summary() *EngineSummary {
+	summary := &EngineSummary{
+		Engine:     a.Engine,
+		Depth:      a.Depth,
+		AnalyzedAt: a.AnalyzedAt,
+	}
+
+	var whiteMoves, blackMoves, whiteLoss, blackLoss int
+	for i, move := range a.Moves {
+		player := &summary.White
+		if a.color(i) == chess.White {
+			whiteMoves++
+			whiteLoss += move.CentipawnLoss
+		} else {
+			player = &summary.Black
+			blackMoves++
+			blackLoss += move.CentipawnLoss
+		}
+
+		switch move.Classification {
+		case MoveClassification_Inaccuracy:
+			player.Inaccuracies++
+		case MoveClassification_Mistake:
+			player.Mistakes++
+		case MoveClassification_Blunder:
+			player.Blunders++
+		}
+	}
+
+	if whiteMoves > 0 {
+		summary.White.AverageCentipawnLoss = whiteLoss / whiteMoves
+	}
+	if blackMoves > 0 {
+		summary.Black.AverageCentipawnLoss = blackLoss / blackMoves
+	}
+	return summary
+}
+
+type EngineAnalysisSetter interface {
+	// SetGameEngineAnalysis saves the given analysis and its summary on the given game.
+	SetGameEngineAnalysis(cohort DojoCohort, id string, analysis *EngineAnalysis) error
+}
+
+// SetGameEngineAnalysis saves the given analysis and its summary on the given game.
+func (repo *dynamoRepository) SetGameEngineAnalysis(cohort DojoCohort, id string, analysis *EngineAnalysis) error {
+	analysisItem, err := dynamodbattribute.MarshalMap(analysis)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal engine analysis", err)
+	}
+	summaryItem, err := dynamodbattribute.MarshalMap(analysis.Summary())
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal engine summary", err)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(cohort)"),
+		UpdateExpression:    aws.String("SET #engineAnalysis = :engineAnalysis, #engineSummary = :engineSummary"),
+		ExpressionAttributeNames: map[string]*string{
+			"#engineAnalysis": aws.String("engineAnalysis"),
+			"#engineSummary":  aws.String("engineSummary"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":engineAnalysis": {M: analysisItem},
+			":engineSummary":  {M: summaryItem},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(cohort))},
+			"id":     {S: aws.String(id)},
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(404, "Invalid request: game does not exist", "DynamoDB UpdateItem failure", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
+
+// EngineAnalysisRequest is the message sent to the engine analysis queue.
+type EngineAnalysisRequest struct {
+	// The cohort of the game to analyze.
+	Cohort DojoCohort `json:"cohort"`
+
+	// The id of the game to analyze.
+	Id string `json:"id"`
+}
+
+// The URL of the engine analysis queue.
+var engineAnalysisSqsUrl = os.Getenv("engineAnalysisSqsUrl")
+
+type EngineAnalysisRequester interface {
+	// RequestEngineAnalysis sends the given game to the engine analysis queue.
+	RequestEngineAnalysis(cohort DojoCohort, id string) error
+}
+
+// RequestEngineAnalysis sends the given game to the engine analysis queue. The game is
+// analyzed by a separate function, as analysis can take several minutes per game.
+func (repo *dynamoRepository) RequestEngineAnalysis(cohort DojoCohort, id string) error {
+	body, err := json.Marshal(EngineAnalysisRequest{Cohort: cohort, Id: id})
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed to marshal engine analysis request", err)
+	}
+	_, err = sqsService.SendMessage(&sqs.SendMessageInput{
+		MessageBody: aws.String(string(body)),
+		QueueUrl:    aws.String(engineAnalysisSqsUrl),
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to send SQS message", err)
+}
+
+// LoadEngineSummaries sets the EngineSummary of the given games by reading it from the base
+// table, as the summary is not projected onto the review index.
+func (repo *dynamoRepository) LoadEngineSummaries(games []Game) error {
+	gamesByKey := make(map[string]*Game, len(games))
+	for i := range games {
+		gamesByKey[fmt.Sprintf("%s/%s", games[i].Cohort, games[i].Id)] = &games[i]
+	}
+
+	for start := 0; start < len(games); start += 100 {
+		end := min(start+100, len(games))
+
+		keys := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+		for _, g := range games[start:end] {
+			keys = append(keys, map[string]*dynamodb.AttributeValue{
+				"cohort": {S: aws.String(string(g.Cohort))},
+				"id":     {S: aws.String(g.Id)},
+			})
+		}
+
+		input := &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]*dynamodb.KeysAndAttributes{
+				gameTable: {
+					Keys:                 keys,
+					ProjectionExpression: aws.String("#cohort, #id, #engineSummary"),
+					ExpressionAttributeNames: map[string]*string{
+						"#cohort":        aws.String("cohort"),
+						"#id":            aws.String("id"),
+						"#engineSummary": aws.String("engineSummary"),
+					},
+				},
+			},
+		}
+
+		for len(input.RequestItems) > 0 {
+			result, err := repo.svc.BatchGetItem(input)
+			if err != nil {
+				return errors.Wrap(500, "Temporary server error", "Failed call to BatchGetItem", err)
+			}
+
+			var page []Game
+			if err := dynamodbattribute.UnmarshalListOfMaps(result.Responses[gameTable], &page); err != nil {
+				return errors.Wrap(500, "Temporary server error", "Failed to unmarshal BatchGetItem result", err)
+			}
+			for _, g := range page {
+				if game := gamesByKey[fmt.Sprintf("%s/%s", g.Cohort, g.Id)]; game != nil {
+					game.EngineSummary = g.EngineSummary
+				}
+			}
+			input.RequestItems = result.UnprocessedKeys
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestCentipawnLoss(t *testing.T) {
+	table := []struct {
+		name   string
+		before chess.Score
+		after  chess.Score
+		mover  chess.Color
+		want   int
+	}{
+		{name: "WhiteLoss", before: chess.Score{Centipawns: 50}, after: chess.Score{Centipawns: -70}, mover: chess.White, want: 120},
+		{name: "BlackLoss", before: chess.Score{Centipawns: 0}, after: chess.Score{Centipawns: 350}, mover: chess.Black, want: 350},
+		{name: "Improvement", before: chess.Score{Centipawns: 0}, after: chess.Score{Centipawns: 40}, mover: chess.White, want: 0},
+		{name: "WinningToWinning", before: chess.Score{Mate: 3}, after: chess.Score{Centipawns: 1500}, mover: chess.White, want: 0},
+		{name: "MissedMate", before: chess.Score{Mate: -2}, after: chess.Score{Centipawns: 0}, mover: chess.Black, want: 1000},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := CentipawnLoss(tc.before, tc.after, tc.mover); got != tc.want {
+				t.Errorf("CentipawnLoss got: %d; want: %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEngineAnalysisSummary(t *testing.T) {
+	sans := []string{"e4", "e5", "Qh5", "Nf6", "Qxf7"}
+	evals := []chess.Score{
+		{Centipawns: 30},
+		{Centipawns: 30},
+		{Centipawns: 20},
+		{Centipawns: -40},
+		{Centipawns: 300},
+		{Centipawns: 270},
+	}
+	bestLines := [][]string{{"e4"}, {"e5"}, {"Nf3"}, {"Nc6", "Bc4"}, {"Qxe5+"}, nil}
+
+	analysis := NewEngineAnalysis(0, sans, evals, bestLines)
+	analysis.Engine = "Stockfish"
+	summary := analysis.Summary()
+
+	wantWhite := EnginePlayerSummary{Inaccuracies: 1, AverageCentipawnLoss: 30}
+	if summary.White != wantWhite {
+		t.Errorf("White got: %+v; want: %+v", summary.White, wantWhite)
+	}
+	wantBlack := EnginePlayerSummary{Blunders: 1, AverageCentipawnLoss: 170}
+	if summary.Black != wantBlack {
+		t.Errorf("Black got: %+v; want: %+v", summary.Black, wantBlack)
+	}
+	if want := []string{"Nc6", "Bc4"}; !reflect.DeepEqual(analysis.Moves[3].BestLine, want) {
+		t.Errorf("Moves[3].BestLine got: %v; want: %v", analysis.Moves[3].BestLine, want)
+	}
+	if analysis.Moves[2].BestLine != nil {
+		t.Errorf("Moves[2].BestLine got: %v; want: nil", analysis.Moves[2].BestLine)
+	}
+	if summary.Engine != "Stockfish" {
+		t.Errorf("Engine got: %q; want: Stockfish", summary.Engine)
+	}
+}
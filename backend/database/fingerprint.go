@@ -0,0 +1,106 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// GameFingerprint returns a hash of the players, date, starting position and mainline moves
+// of the given game. Two uploads of the same game have the same fingerprint regardless of
+// their annotations, variations or other headers. An empty string is returned for games
+// without any moves, as those cannot be meaningfully compared.
+//
+// The fingerprint must match the one computed by the game create handler in pgnService.
+// Both implementations are tested against testdata/fingerprints.json.
+func GameFingerprint(parsed *chess.Game) string {
+	mainline := parsed.Mainline()
+	if len(mainline) == 0 {
+		return ""
+	}
+
+	sans := make([]string, len(mainline))
+	for i, n := range mainline {
+		sans[i] = strings.TrimRight(n.San, "+#")
+	}
+
+	data := strings.Join([]string{
+		strings.ToLower(strings.TrimSpace(parsed.Header("White"))),
+		strings.ToLower(strings.TrimSpace(parsed.Header("Black"))),
+		strings.TrimSpace(parsed.Header("Date")),
+		strings.TrimSpace(parsed.Header("FEN")),
+		strings.Join(sans, " "),
+	}, "\n")
+
+	checksum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(checksum[:])
+}
+
+type GameFingerprinter interface {
+	// SetGameFingerprint sets the fingerprint of the given game.
+	SetGameFingerprint(cohort DojoCohort, id, fingerprint string) error
+
+	// GetGameKeyByFingerprint returns the key of a game with the given owner and fingerprint,
+	// or nil if the owner has no such game.
+	GetGameKeyByFingerprint(owner, fingerprint string) (*GameKey, error)
+}
+
+// GetGameKeyByFingerprint returns the key of a game with the given owner and fingerprint,
+// or nil if the owner has no such game.
+func (repo *dynamoRepository) GetGameKeyByFingerprint(owner, fingerprint string) (*GameKey, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#fingerprint = :fingerprint AND #owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#fingerprint": aws.String("fingerprint"),
+			"#owner":       aws.String("owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":fingerprint": {S: aws.String(fingerprint)},
+			":owner":       {S: aws.String(owner)},
+		},
+		IndexName: aws.String(gameTableFingerprintIndex),
+		TableName: aws.String(gameTable),
+		Limit:     aws.Int64(1),
+	}
+
+	var keys []GameKey
+	if _, err := repo.query(input, "", &keys); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
+
+// SetGameFingerprint sets the fingerprint of the given game.
+func (repo *dynamoRepository) SetGameFingerprint(cohort DojoCohort, id, fingerprint string) error {
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(cohort)"),
+		UpdateExpression:    aws.String("SET #fingerprint = :fingerprint"),
+		ExpressionAttributeNames: map[string]*string{
+			"#fingerprint": aws.String("fingerprint"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":fingerprint": {S: aws.String(fingerprint)},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(cohort))},
+			"id":     {S: aws.String(id)},
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(404, "Invalid request: game does not exist", "DynamoDB UpdateItem failure", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
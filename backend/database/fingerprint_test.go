@@ -0,0 +1,80 @@
+package database
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// fingerprintVector is a test case shared with the fingerprint tests of the game create
+// handler in pgnService, which must compute the same fingerprints.
+type fingerprintVector struct {
+	Name        string `json:"name"`
+	Pgn         string `json:"pgn"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+func TestGameFingerprint(t *testing.T) {
+	b, err := os.ReadFile("testdata/fingerprints.json")
+	if err != nil {
+		t.Fatalf("Failed to read test vectors: %v", err)
+	}
+	var vectors []fingerprintVector
+	if err := json.Unmarshal(b, &vectors); err != nil {
+		t.Fatalf("Failed to unmarshal test vectors: %v", err)
+	}
+
+	for _, tc := range vectors {
+		t.Run(tc.Name, func(t *testing.T) {
+			parsed, err := chess.Parse(tc.Pgn)
+			if err != nil {
+				t.Fatalf("Parse got error: %v", err)
+			}
+			if got := GameFingerprint(parsed); got != tc.Fingerprint {
+				t.Errorf("GameFingerprint got: %q; want: %q", got, tc.Fingerprint)
+			}
+		})
+	}
+}
+
+func TestGameFingerprintDifferences(t *testing.T) {
+	base := "[White \"Alice\"]\n[Black \"Bob\"]\n[Date \"2024.01.02\"]\n\n1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0"
+
+	table := []struct {
+		name string
+		pgn  string
+	}{
+		{
+			name: "DifferentMoves",
+			pgn:  "[White \"Alice\"]\n[Black \"Bob\"]\n[Date \"2024.01.02\"]\n\n1. e4 e5 2. Qh5 Nc6 3. Bc4 g6 *",
+		},
+		{
+			name: "DifferentDate",
+			pgn:  "[White \"Alice\"]\n[Black \"Bob\"]\n[Date \"2024.01.03\"]\n\n1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0",
+		},
+		{
+			name: "DifferentPlayers",
+			pgn:  "[White \"Bob\"]\n[Black \"Alice\"]\n[Date \"2024.01.02\"]\n\n1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0",
+		},
+	}
+
+	parsed, err := chess.Parse(base)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+	want := GameFingerprint(parsed)
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := chess.Parse(tc.pgn)
+			if err != nil {
+				t.Fatalf("Parse got error: %v", err)
+			}
+			if got := GameFingerprint(parsed); got == want {
+				t.Errorf("GameFingerprint got: %s; want different from base", got)
+			}
+		})
+	}
+}
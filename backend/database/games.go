@@ -5,13 +5,13 @@ import (
 	"fmt"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
 )
 
 type PlayerColor string
@@ -83,6 +83,13 @@ type Comment struct {
 
 	// The content of the comment
 	Content string `dynamodbav:"content" json:"content"`
+
+	// The time the comment was deleted by an admin, in time.RFC3339 format. The content
+	// of deleted comments is replaced by RemovedCommentContent.
+	DeletedAt string `dynamodbav:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+
+	// The username of the admin who deleted the comment.
+	DeletedBy string `dynamodbav:"deletedBy,omitempty" json:"-"`
 }
 
 type CommentOwner struct {
@@ -134,6 +141,24 @@ type PositionComment struct {
 
 	// A PGN starting from the comment's position, which suggests a variation.
 	SuggestedVariation string `dynamodbav:"suggestedVariation,omitempty" json:"suggestedVariation,omitempty"`
+
+	// Reactions left by users on the comment, mapped by their usernames.
+	Reactions map[string]Reaction `dynamodbav:"reactions,omitempty" json:"reactions,omitempty"`
+
+	// The number of users who left each type of reaction on the comment. This is computed
+	// by CountCommentReactions and is not saved in the database.
+	ReactionCounts map[string]int `dynamodbav:"-" json:"reactionCounts,omitempty"`
+
+	// The time the comment was deleted, in time.RFC3339 format. Deleted comments keep
+	// their replies, but their content is replaced by RemovedCommentContent.
+	DeletedAt string `dynamodbav:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+
+	// The username of the owner or admin who deleted the comment.
+	DeletedBy string `dynamodbav:"deletedBy,omitempty" json:"-"`
+
+	// The previous versions of the comment, oldest first. Kept for moderation and not
+	// returned by the API.
+	EditHistory []CommentEdit `dynamodbav:"editHistory,omitempty" json:"-"`
 }
 
 type PositionCommentUpdate struct {
@@ -159,6 +184,24 @@ type PositionCommentUpdate struct {
 	SuggestedVariation string `json:"suggestedVariation"`
 }
 
+// NormalizeSuggestedVariation checks that the given suggested variation is a legal
+// continuation from the position with the given FEN and returns it rewritten as a PGN in
+// SAN with standard move numbers. The variation may include the SetUp and FEN headers of
+// its starting position, whose move counters are then kept. The returned PGN includes
+// these headers, so that clients can replay it from the right position. A 400 error is
+// returned if the variation is invalid.
+func NormalizeSuggestedVariation(fen, variation string) (string, error) {
+	start, err := chess.ParseFen(fen)
+	if err != nil {
+		return "", errors.Wrap(400, "Invalid request: fen is invalid", "", err)
+	}
+	parsed, err := chess.ParseVariation(start, variation)
+	if err != nil {
+		return "", errors.Wrap(400, fmt.Sprintf("Invalid request: suggestedVariation is invalid (%v)", err), "", err)
+	}
+	return parsed.String(), nil
+}
+
 type Game struct {
 	// The Dojo cohort for the game
 	Cohort DojoCohort `dynamodbav:"cohort" json:"cohort"`
@@ -231,11 +274,70 @@ type Game struct {
 	// The game review metadata
 	Review *GameReview `dynamodbav:"review,omitempty" json:"review,omitempty"`
 
+	// The refund issued because the review was not completed by its deadline. Stored
+	// outside of Review so that it is kept when the review is completed.
+	ReviewRefund *GameReviewRefund `dynamodbav:"reviewRefund,omitempty" json:"reviewRefund,omitempty"`
+
 	// A map from the normalized FEN of a position to a map from the id of a comment to the comment.
 	PositionComments map[string]map[string]PositionComment `dynamodbav:"positionComments" json:"positionComments"`
 
 	// A set of directories containing this game, in the form `owner/id`.
 	Directories []string `dynamodbav:"directories,stringset,omitempty" json:"directories,omitempty"`
+
+	// A hash of the players, date and moves of the game, used to detect duplicate uploads.
+	// Omitted for games without moves. See GameFingerprint.
+	Fingerprint string `dynamodbav:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+
+	// A summary of the clock times recorded in the game's mainline. Omitted for games
+	// without clock times.
+	TimeProfile *TimeProfile `dynamodbav:"timeProfile,omitempty" json:"timeProfile,omitempty"`
+
+	// The engine analysis of the game's mainline. Set when the game is submitted for review.
+	EngineAnalysis *EngineAnalysis `dynamodbav:"engineAnalysis,omitempty" json:"engineAnalysis,omitempty"`
+
+	// A summary of EngineAnalysis, shown in the review queue.
+	EngineSummary *EngineSummary `dynamodbav:"engineSummary,omitempty" json:"engineSummary,omitempty"`
+
+	// The tags set by the owner of the game. See NormalizeTags.
+	Tags []string `dynamodbav:"tags,omitempty" json:"tags,omitempty"`
+
+	// The tags assigned to the game automatically based on its content. See GuessSystemTags.
+	SystemTags []string `dynamodbav:"systemTags,omitempty" json:"systemTags,omitempty"`
+
+	// The usernames of the users other than the owner who can edit the game's PGN.
+	Editors []string `dynamodbav:"editors,stringset,omitempty" json:"editors,omitempty"`
+
+	// The username of the owner or editor who last changed the game's PGN. Omitted if the
+	// PGN was last changed before editors were supported, in which case the owner made it.
+	LastEditedBy string `dynamodbav:"lastEditedBy,omitempty" json:"lastEditedBy,omitempty"`
+}
+
+// ParsePgn parses and replays the game's PGN with full legality checking. A 400 error
+// is returned if the PGN is malformed or contains an illegal move.
+func (g *Game) ParsePgn() (*chess.Game, error) {
+	parsed, err := chess.Parse(g.Pgn)
+	if err != nil {
+		return nil, errors.Wrap(400, fmt.Sprintf("Invalid request: PGN is invalid (%v)", err), fmt.Sprintf("Game %s/%s failed to parse", g.Cohort, g.Id), err)
+	}
+	return parsed, nil
+}
+
+// PlayerColor returns the color played in the game by the player with one of the given
+// names, which are compared case-insensitively against the White and Black headers. False
+// is returned if neither or both of the headers match.
+func (g *Game) PlayerColor(names []string) (chess.Color, bool) {
+	white, black := false, false
+	for _, name := range names {
+		white = white || strings.EqualFold(strings.TrimSpace(g.Headers["White"]), name)
+		black = black || strings.EqualFold(strings.TrimSpace(g.Headers["Black"]), name)
+	}
+	switch {
+	case white && !black:
+		return chess.White, true
+	case black && !white:
+		return chess.Black, true
+	}
+	return chess.White, false
 }
 
 type Reviewer struct {
@@ -261,6 +363,16 @@ type GameReview struct {
 
 	// The reviewer of the game.
 	Reviewer *Reviewer `dynamodbav:"reviewer,omitempty" json:"reviewer,omitempty"`
+
+	// The reviewer who claimed the game from the review queue. The claim is only active
+	// until ClaimExpiresAt. Cleared when the review is completed.
+	ClaimedBy *Reviewer `dynamodbav:"claimedBy,omitempty" json:"claimedBy,omitempty"`
+
+	// The date the game was claimed in time.RFC3339 format.
+	ClaimedAt string `dynamodbav:"claimedAt,omitempty" json:"claimedAt,omitempty"`
+
+	// The date the claim on the game expires in time.RFC3339 format.
+	ClaimExpiresAt string `dynamodbav:"claimExpiresAt,omitempty" json:"claimExpiresAt,omitempty"`
 }
 
 type GameUpdate struct {
@@ -296,6 +408,19 @@ type GameDeleter interface {
 type GameGetter interface {
 	// GetGame returns the Game object with the provided cohort and id.
 	GetGame(cohort, id string) (*Game, error)
+
+	// BatchGetGames returns the games with the provided keys, including the PGN text.
+	// Games which do not exist are omitted.
+	BatchGetGames(keys []GameKey) ([]*Game, error)
+}
+
+// GameKey contains the primary key of a game.
+type GameKey struct {
+	// The cohort of the game.
+	Cohort DojoCohort `dynamodbav:"cohort" json:"cohort"`
+
+	// The id of the game.
+	Id string `dynamodbav:"id" json:"id"`
 }
 
 type GameLister interface {
@@ -320,6 +445,24 @@ type GameLister interface {
 
 	// ScanCohort returns a list of all Games in the given cohort, including the PGN text.
 	ScanCohort(cohort DojoCohort, startKey string) ([]*Game, string, error)
+
+	// ListChangedGames returns the cohort and id of the Games in the given cohort which were
+	// created or updated at or after the given time.Rfc3339 value.
+	ListChangedGames(cohort DojoCohort, since, startKey string) ([]*Game, string, error)
+
+	// ListGamesByIdPrefix returns a list of Games in the given cohort whose ids start with
+	// the given prefix, including the PGN text.
+	ListGamesByIdPrefix(cohort DojoCohort, prefix, startKey string) ([]*Game, string, error)
+
+	// ListGamesByTag returns a list of Games in the given cohort with the given tag. Unlisted
+	// games are not included. The PGN text is excluded and must be fetched separately with a
+	// call to GetGame.
+	ListGamesByTag(cohort DojoCohort, tag, startKey string) ([]*Game, string, error)
+
+	// ListGamesByOwnerTag returns a list of Games owned by the given user with the given tag.
+	// Unlisted games are not included, unless isOwner is true. The PGN text is excluded and
+	// must be fetched separately with a call to GetGame.
+	ListGamesByOwnerTag(isOwner bool, owner, tag, startKey string) ([]*Game, string, error)
 }
 
 type GameCommenter interface {
@@ -348,6 +491,62 @@ func (repo *dynamoRepository) GetGame(cohort, id string) (*Game, error) {
 	return &game, nil
 }
 
+// BatchPutGames inserts the provided list of games into the database. The number of
+// successfully inserted games is returned.
+func (repo *dynamoRepository) BatchPutGames(games []*Game) (int, error) {
+	return batchWriteObjects(repo, games, gameTable, func(game *Game, item map[string]*dynamodb.AttributeValue) {
+		// The comment handlers require these to exist as an empty list and map
+		if len(game.Comments) == 0 {
+			item["comments"] = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+		}
+		if len(game.PositionComments) == 0 {
+			item["positionComments"] = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}
+		}
+	})
+}
+
+// BatchGetGames returns the games with the provided keys, including the PGN text. Games
+// which do not exist are omitted. Any number of keys can be provided, but the order of the
+// returned games is not guaranteed.
+func (repo *dynamoRepository) BatchGetGames(keys []GameKey) ([]*Game, error) {
+	games := make([]*Game, 0, len(keys))
+
+	for start := 0; start < len(keys); start += 100 {
+		end := start + 100
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		input := &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]*dynamodb.KeysAndAttributes{
+				gameTable: {Keys: []map[string]*dynamodb.AttributeValue{}},
+			},
+		}
+		for _, key := range keys[start:end] {
+			input.RequestItems[gameTable].Keys = append(input.RequestItems[gameTable].Keys, map[string]*dynamodb.AttributeValue{
+				"cohort": {S: aws.String(string(key.Cohort))},
+				"id":     {S: aws.String(key.Id)},
+			})
+		}
+
+		for len(input.RequestItems) > 0 {
+			result, err := repo.svc.BatchGetItem(input)
+			if err != nil {
+				return nil, errors.Wrap(500, "Temporary server error", "Failed call to BatchGetItem", err)
+			}
+
+			var page []*Game
+			if err := dynamodbattribute.UnmarshalListOfMaps(result.Responses[gameTable], &page); err != nil {
+				return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal BatchGetItem result", err)
+			}
+			games = append(games, page...)
+			input.RequestItems = result.UnprocessedKeys
+		}
+	}
+
+	return games, nil
+}
+
 // DeleteGame removes the specified game from the database, if the game
 // is owned by the calling user.
 func (repo *dynamoRepository) DeleteGame(username, cohort, id string) (*Game, error) {
@@ -704,6 +903,56 @@ func (repo *dynamoRepository) ScanCohort(cohort DojoCohort, startKey string) ([]
 	return games, lastKey, nil
 }
 
+// ListChangedGames returns the cohort and id of the Games in the given cohort which were
+// created or updated at or after the given time.Rfc3339 value. Games are always created
+// with an updatedAt value, so only the updatedAt index needs to be queried.
+func (repo *dynamoRepository) ListChangedGames(cohort DojoCohort, since, startKey string) ([]*Game, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#cohort = :cohort AND #updatedAt >= :since"),
+		ExpressionAttributeNames: map[string]*string{
+			"#cohort":    aws.String("cohort"),
+			"#updatedAt": aws.String("updatedAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":cohort": {S: aws.String(string(cohort))},
+			":since":  {S: aws.String(since)},
+		},
+		IndexName: aws.String(gameTableUpdatedIndex),
+		TableName: aws.String(gameTable),
+	}
+
+	var games []*Game
+	lastKey, err := repo.query(input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ListGamesByIdPrefix returns a list of Games in the given cohort whose ids start with
+// the given prefix, including the PGN text.
+func (repo *dynamoRepository) ListGamesByIdPrefix(cohort DojoCohort, prefix, startKey string) ([]*Game, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#cohort = :cohort AND begins_with(#id, :prefix)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#cohort": aws.String("cohort"),
+			"#id":     aws.String("id"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":cohort": {S: aws.String(string(cohort))},
+			":prefix": {S: aws.String(prefix)},
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	var games []*Game
+	lastKey, err := repo.query(input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
 // PutComment puts the provided comment in the provided Game's position comments.
 // If skipMapCreation is true, then the first conditional request to create the initial
 // comment map for a position is skipped.
@@ -800,94 +1049,39 @@ func (repo *dynamoRepository) PutComment(cohort, id string, comment *PositionCom
 	return &game, nil
 }
 
-// UpdateComment applies the given position comment update to the database. The game after update is returned.
+// UpdateComment applies the given position comment update to the database. The previous
+// content of the comment is saved in its edit history. The game after update is returned.
 func (repo *dynamoRepository) UpdateComment(owner string, update *PositionCommentUpdate) (*Game, error) {
-	exprAttrNames := map[string]*string{
-		"#p":         aws.String("positionComments"),
-		"#fen":       aws.String(update.Fen),
-		"#id":        aws.String(update.Id),
-		"#owner":     aws.String("owner"),
-		"#username":  aws.String("username"),
-		"#content":   aws.String("content"),
-		"#updated":   aws.String("updatedAt"),
-		"#variation": aws.String("suggestedVariation"),
-	}
-	parentPath := getCommentPath(update.ParentIds, exprAttrNames)
-	updateExpr := fmt.Sprintf("SET #p.#fen.%s#id.#content = :c, #p.#fen.%s#id.#variation = :v, #p.#fen.%s#id.#updated = :u", parentPath, parentPath, parentPath)
-
-	input := &dynamodb.UpdateItemInput{
-		ConditionExpression:      aws.String(fmt.Sprintf("#p.#fen.%s#id.#owner.#username = :owner", parentPath)),
-		UpdateExpression:         aws.String(updateExpr),
-		ExpressionAttributeNames: exprAttrNames,
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":owner": {S: aws.String(owner)},
-			":c":     {S: aws.String(update.Content)},
-			":v":     {S: aws.String(update.SuggestedVariation)},
-			":u":     {S: aws.String(time.Now().Format(time.RFC3339))},
-		},
-		Key: map[string]*dynamodb.AttributeValue{
-			"cohort": {
-				S: aws.String(string(update.Cohort)),
-			},
-			"id": {
-				S: aws.String(update.GameId),
-			},
-		},
-		ReturnValues: aws.String("ALL_NEW"),
-		TableName:    aws.String(gameTable),
-	}
-
-	game := Game{}
-	err := repo.updateItem(input, &game)
+	target := PositionCommentTarget(update)
+	previous, err := repo.getPositionComment(target)
 	if err != nil {
-		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
-			return nil, errors.Wrap(400, "Invalid request: comment does not exist or you do not have permission to edit it", "DynamoDB UpdateItem failure", aerr)
-		}
-		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+		return nil, err
 	}
-	return &game, nil
+	return repo.updatePositionComment(target, previous, update.Content, update.SuggestedVariation, owner, "")
 }
 
-// DeleteComment deletes the position comment indicated by the given update, including
-// any replies to the comment. The updated game is returned.
+// DeleteComment deletes the position comment indicated by the given update. The comment
+// is soft deleted, so that any replies to it are kept. The updated game is returned.
 func (repo *dynamoRepository) DeleteComment(owner string, update *PositionCommentUpdate) (*Game, error) {
-	exprAttrNames := map[string]*string{
-		"#p":        aws.String("positionComments"),
-		"#fen":      aws.String(update.Fen),
-		"#id":       aws.String(update.Id),
-		"#owner":    aws.String("owner"),
-		"#username": aws.String("username"),
-	}
-	parentPath := getCommentPath(update.ParentIds, exprAttrNames)
-
-	input := &dynamodb.UpdateItemInput{
-		ConditionExpression:      aws.String(fmt.Sprintf("#p.#fen.%s#id.#owner.#username = :owner", parentPath)),
-		UpdateExpression:         aws.String(fmt.Sprintf("REMOVE #p.#fen.%s#id", parentPath)),
-		ExpressionAttributeNames: exprAttrNames,
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":owner": {S: aws.String(owner)},
-		},
-		Key: map[string]*dynamodb.AttributeValue{
-			"cohort": {
-				S: aws.String(string(update.Cohort)),
-			},
-			"id": {
-				S: aws.String(update.GameId),
-			},
-		},
-		ReturnValues: aws.String("ALL_NEW"),
-		TableName:    aws.String(gameTable),
+	target := PositionCommentTarget(update)
+	previous, err := repo.getPositionComment(target)
+	if err != nil {
+		return nil, err
 	}
+	return repo.deletePositionComment(target, previous, owner, owner)
+}
 
-	game := Game{}
-	err := repo.updateItem(input, &game)
+// getPositionComment returns the current state of the given position comment.
+func (repo *dynamoRepository) getPositionComment(target *CommentTarget) (*PositionComment, error) {
+	game, err := repo.GetGame(string(target.Cohort), target.GameId)
 	if err != nil {
-		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
-			return nil, errors.Wrap(400, "Invalid request: comment does not exist or you do not have permission to delete it", "DynamoDB UpdateItem failure", aerr)
-		}
-		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+		return nil, err
 	}
-	return &game, nil
+	comment, ok := game.GetPositionComment(target.Fen, target.ParentIds, target.CommentId)
+	if !ok {
+		return nil, errors.New(404, "Invalid request: comment not found", "")
+	}
+	return comment, nil
 }
 
 // UpdateGame applies the specified update to the specified game.
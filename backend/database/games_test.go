@@ -0,0 +1,84 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestNormalizeSuggestedVariation(t *testing.T) {
+	const fen = "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq -"
+
+	table := []struct {
+		name      string
+		variation string
+		want      string
+		wantError bool
+	}{
+		{
+			name:      "Movetext",
+			variation: "Nc6 Bb5",
+			want:      "[Result \"*\"]\n[SetUp \"1\"]\n[FEN \"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 0 1\"]\n\n1... Nc6 2. Bb5 *\n",
+		},
+		{
+			name:      "RenderedWithHeaders",
+			variation: "[SetUp \"1\"]\n[FEN \"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2\"]\n\n2... Nc6 3. Bb5 *",
+			want:      "[Result \"*\"]\n[SetUp \"1\"]\n[FEN \"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2\"]\n\n2... Nc6 3. Bb5 *\n",
+		},
+		{
+			name:      "MismatchedFen",
+			variation: "[SetUp \"1\"]\n[FEN \"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1\"]\n\n1. e4 *",
+			wantError: true,
+		},
+		{
+			name:      "Illegal",
+			variation: "Nc6 Bb6",
+			wantError: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeSuggestedVariation(fen, tc.variation)
+			if tc.wantError {
+				if err == nil {
+					t.Fatalf("NormalizeSuggestedVariation got no error; want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeSuggestedVariation got error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("NormalizeSuggestedVariation got: %q; want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlayerColor(t *testing.T) {
+	names := []string{"Member", "member_lichess"}
+
+	table := []struct {
+		name      string
+		white     string
+		black     string
+		want      chess.Color
+		wantFound bool
+	}{
+		{name: "White", white: "member", black: "opponent", want: chess.White, wantFound: true},
+		{name: "Black", white: "opponent", black: "MEMBER_LICHESS", want: chess.Black, wantFound: true},
+		{name: "Neither", white: "opponent", black: "other"},
+		{name: "Both", white: "member", black: "member_lichess"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			game := &Game{Headers: map[string]string{"White": tc.white, "Black": tc.black}}
+			got, found := game.PlayerColor(names)
+			if found != tc.wantFound || (found && got != tc.want) {
+				t.Errorf("PlayerColor got: %v, %t; want: %v, %t", got, found, tc.want, tc.wantFound)
+			}
+		})
+	}
+}
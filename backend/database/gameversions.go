@@ -0,0 +1,463 @@
+package database
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+var gameVersionTable = stage + "-game-versions"
+
+// The version id which refers to the current state of a game rather than a saved version.
+const CurrentGameVersion = "current"
+
+// GameVersion is a snapshot of the PGN and headers of a game, saved before the game was
+// overwritten.
+type GameVersion struct {
+	// The key of the game, in the form cohort/id.
+	GameId string `dynamodbav:"gameId" json:"gameId"`
+
+	// The id of the version. Versions of the same game sort by the time they were replaced.
+	Version string `dynamodbav:"version" json:"version"`
+
+	// The cohort of the game.
+	Cohort DojoCohort `dynamodbav:"cohort" json:"cohort"`
+
+	// The id of the game.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The owner of the game.
+	Owner string `dynamodbav:"owner" json:"owner"`
+
+	// The time the game was last updated before this version was replaced, in time.RFC3339
+	// format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+
+	// The time this version was replaced, in time.RFC3339 format.
+	ReplacedAt string `dynamodbav:"replacedAt" json:"replacedAt"`
+
+	// The username of the owner or editor who saved this version.
+	EditedBy string `dynamodbav:"editedBy,omitempty" json:"editedBy,omitempty"`
+
+	// The PGN headers of the version.
+	Headers map[string]string `dynamodbav:"headers" json:"headers"`
+
+	// The PGN text of the version. Omitted when listing versions.
+	Pgn string `dynamodbav:"pgn" json:"pgn,omitempty"`
+}
+
+// gameVersionKey returns the partition key of the versions of the given game.
+func gameVersionKey(cohort DojoCohort, id string) string {
+	return fmt.Sprintf("%s/%s", cohort, id)
+}
+
+// NewGameVersion returns a version containing the given state of a game, replaced at the
+// given time. sequence must uniquely identify the replacement among those at the same time.
+func NewGameVersion(game *Game, replacedAt time.Time, sequence string) *GameVersion {
+	replaced := replacedAt.UTC().Format(time.RFC3339)
+	return &GameVersion{
+		GameId:     gameVersionKey(game.Cohort, game.Id),
+		Version:    fmt.Sprintf("%s_%s", replaced, sequence),
+		Cohort:     game.Cohort,
+		Id:         game.Id,
+		Owner:      game.Owner,
+		UpdatedAt:  game.UpdatedAt,
+		ReplacedAt: replaced,
+		EditedBy:   game.Editor(),
+		Headers:    game.Headers,
+		Pgn:        game.Pgn,
+	}
+}
+
+// CurrentVersion returns the current state of the given game as a GameVersion.
+func (game *Game) CurrentVersion() *GameVersion {
+	return &GameVersion{
+		GameId:    gameVersionKey(game.Cohort, game.Id),
+		Version:   CurrentGameVersion,
+		Cohort:    game.Cohort,
+		Id:        game.Id,
+		Owner:     game.Owner,
+		UpdatedAt: game.UpdatedAt,
+		EditedBy:  game.Editor(),
+		Headers:   game.Headers,
+		Pgn:       game.Pgn,
+	}
+}
+
+type GameDiffType string
+
+const (
+	GameDiffType_Added   GameDiffType = "ADDED"
+	GameDiffType_Removed GameDiffType = "REMOVED"
+	GameDiffType_Changed GameDiffType = "CHANGED"
+)
+
+// HeaderDiff is a PGN header which differs between two versions of a game.
+type HeaderDiff struct {
+	// The name of the header.
+	Name string `json:"name"`
+
+	// The value of the header in the older version. Empty if the header was added.
+	Before string `json:"before"`
+
+	// The value of the header in the newer version. Empty if the header was removed.
+	After string `json:"after"`
+}
+
+// MoveAnnotation contains the annotations of a single move.
+type MoveAnnotation struct {
+	// The comment after the move, without commands.
+	Comment string `json:"comment,omitempty"`
+
+	// The commands embedded in the comment, such as clk or cal.
+	Commands map[string]string `json:"commands,omitempty"`
+
+	// The Numeric Annotation Glyphs of the move.
+	Nags []int `json:"nags,omitempty"`
+}
+
+// MoveDiff is a move which differs between two versions of a game.
+type MoveDiff struct {
+	// Whether the move was added, removed or had its annotations changed.
+	Type GameDiffType `json:"type"`
+
+	// The ply of the move.
+	Ply int `json:"ply"`
+
+	// The SAN of the moves from the start of the game up to and including this move.
+	Line []string `json:"line"`
+
+	// The annotations of the move in the older version. Nil if the move was added.
+	Before *MoveAnnotation `json:"before,omitempty"`
+
+	// The annotations of the move in the newer version. Nil if the move was removed.
+	After *MoveAnnotation `json:"after,omitempty"`
+}
+
+// GameDiff is the difference between two versions of a game.
+type GameDiff struct {
+	// The headers which were added, removed or changed.
+	Headers []HeaderDiff `json:"headers"`
+
+	// The moves, including variations, which were added, removed or had their annotations
+	// changed. Added and changed moves are listed in the order of the newer version,
+	// followed by removed moves in the order of the older version.
+	Moves []MoveDiff `json:"moves"`
+}
+
+// nodeKey returns a key which identifies the given node by the moves which reached it, so
+// that the same move can be matched across versions of a game.
+func nodeKey(n *chess.Node) string {
+	var moves []string
+	for ; n.Parent != nil; n = n.Parent {
+		moves = append(moves, n.Move.UCI())
+	}
+	slices.Reverse(moves)
+	return strings.Join(moves, " ")
+}
+
+// nodeLine returns the SAN of the moves from the start of the game to the given node.
+func nodeLine(n *chess.Node) []string {
+	var line []string
+	for ; n.Parent != nil; n = n.Parent {
+		line = append(line, n.San)
+	}
+	slices.Reverse(line)
+	return line
+}
+
+// nodeAnnotation returns the annotations of the given node.
+func nodeAnnotation(n *chess.Node) *MoveAnnotation {
+	return &MoveAnnotation{Comment: n.Comment, Commands: n.Commands, Nags: n.Nags}
+}
+
+// equal returns true if the annotations are the same.
+func (a *MoveAnnotation) equal(b *MoveAnnotation) bool {
+	return a.Comment == b.Comment && slices.Equal(a.Nags, b.Nags) && maps.Equal(a.Commands, b.Commands)
+}
+
+// DiffGameVersions returns the difference between the older and newer versions of a game.
+// Moves are matched by the sequence of moves which reached them, so moves in variations
+// are compared as well as the mainline. A 400 error is returned if either PGN is invalid.
+func DiffGameVersions(older, newer *GameVersion) (*GameDiff, error) {
+	olderGame, err := (&Game{Cohort: older.Cohort, Id: older.Id, Pgn: older.Pgn}).ParsePgn()
+	if err != nil {
+		return nil, err
+	}
+	newerGame, err := (&Game{Cohort: newer.Cohort, Id: newer.Id, Pgn: newer.Pgn}).ParsePgn()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &GameDiff{Headers: diffHeaders(older.Headers, newer.Headers), Moves: []MoveDiff{}}
+
+	olderNodes := make(map[string]*chess.Node)
+	olderGame.Walk(func(n *chess.Node) { olderNodes[nodeKey(n)] = n })
+
+	newerKeys := make(map[string]bool)
+	newerGame.Walk(func(n *chess.Node) {
+		key := nodeKey(n)
+		newerKeys[key] = true
+
+		after := nodeAnnotation(n)
+		if o, ok := olderNodes[key]; !ok {
+			diff.Moves = append(diff.Moves, MoveDiff{Type: GameDiffType_Added, Ply: n.Ply, Line: nodeLine(n), After: after})
+		} else if before := nodeAnnotation(o); !before.equal(after) {
+			diff.Moves = append(diff.Moves, MoveDiff{Type: GameDiffType_Changed, Ply: n.Ply, Line: nodeLine(n), Before: before, After: after})
+		}
+	})
+
+	olderGame.Walk(func(n *chess.Node) {
+		if !newerKeys[nodeKey(n)] {
+			diff.Moves = append(diff.Moves, MoveDiff{Type: GameDiffType_Removed, Ply: n.Ply, Line: nodeLine(n), Before: nodeAnnotation(n)})
+		}
+	})
+	return diff, nil
+}
+
+// diffHeaders returns the headers which differ between older and newer, ordered by name.
+func diffHeaders(older, newer map[string]string) []HeaderDiff {
+	names := make(map[string]bool)
+	for name := range older {
+		names[name] = true
+	}
+	for name := range newer {
+		names[name] = true
+	}
+
+	diffs := []HeaderDiff{}
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		if before, after := older[name], newer[name]; before != after {
+			diffs = append(diffs, HeaderDiff{Name: name, Before: before, After: after})
+		}
+	}
+	return diffs
+}
+
+type GameVersionPutter interface {
+	// PutGameVersion saves the given game version. Saving a version which already exists
+	// is a no-op, so that retried stream records do not overwrite it.
+	PutGameVersion(version *GameVersion) error
+}
+
+// PutGameVersion saves the given game version. Saving a version which already exists
+// is a no-op, so that retried stream records do not overwrite it.
+func (repo *dynamoRepository) PutGameVersion(version *GameVersion) error {
+	item, err := dynamodbattribute.MarshalMap(version)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal game version", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		ConditionExpression: aws.String("attribute_not_exists(version)"),
+		Item:                item,
+		TableName:           aws.String(gameVersionTable),
+	}
+	if _, err := repo.svc.PutItem(input); err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB PutItem failure", err)
+	}
+	return nil
+}
+
+type GameVersionRepository interface {
+	GameGetter
+
+	// ListGameVersions returns the saved versions of the given game, newest first,
+	// without their PGN text.
+	ListGameVersions(cohort DojoCohort, id, startKey string) ([]GameVersion, string, error)
+
+	// GetGameVersion returns the given saved version of the given game.
+	GetGameVersion(cohort DojoCohort, id, version string) (*GameVersion, error)
+
+	// RestoreGameVersion overwrites the PGN and headers of the given game with those of
+	// the given version. The caller must own the game. The updated game is returned.
+	RestoreGameVersion(owner string, version *GameVersion) (*Game, error)
+}
+
+// ListGameVersions returns the saved versions of the given game, newest first, without
+// their PGN text.
+func (repo *dynamoRepository) ListGameVersions(cohort DojoCohort, id, startKey string) ([]GameVersion, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#gameId = :gameId"),
+		ProjectionExpression:   aws.String("#gameId,#version,#cohort,#id,#owner,#updatedAt,#replacedAt,#editedBy,#headers"),
+		ExpressionAttributeNames: map[string]*string{
+			"#gameId":     aws.String("gameId"),
+			"#version":    aws.String("version"),
+			"#cohort":     aws.String("cohort"),
+			"#id":         aws.String("id"),
+			"#owner":      aws.String("owner"),
+			"#updatedAt":  aws.String("updatedAt"),
+			"#replacedAt": aws.String("replacedAt"),
+			"#editedBy":   aws.String("editedBy"),
+			"#headers":    aws.String("headers"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":gameId": {S: aws.String(gameVersionKey(cohort, id))},
+		},
+		ScanIndexForward: aws.Bool(false),
+		TableName:        aws.String(gameVersionTable),
+	}
+
+	var versions []GameVersion
+	lastKey, err := repo.query(input, startKey, &versions)
+	if err != nil {
+		return nil, "", err
+	}
+	return versions, lastKey, nil
+}
+
+// GetGameVersion returns the given saved version of the given game.
+func (repo *dynamoRepository) GetGameVersion(cohort DojoCohort, id, version string) (*GameVersion, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"gameId":  {S: aws.String(gameVersionKey(cohort, id))},
+			"version": {S: aws.String(version)},
+		},
+		TableName: aws.String(gameVersionTable),
+	}
+
+	result := GameVersion{}
+	if err := repo.getItem(input, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetGameVersionAt returns the saved version of the given game which was current at the
+// given updatedAt. A 404 error is returned if no such version has been saved, such as when
+// the games table stream has not yet processed the update which replaced it.
+func (repo *dynamoRepository) GetGameVersionAt(cohort DojoCohort, id, updatedAt string) (*GameVersion, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#gameId = :gameId"),
+		FilterExpression:       aws.String("#updatedAt = :updatedAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#gameId":    aws.String("gameId"),
+			"#updatedAt": aws.String("updatedAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":gameId":    {S: aws.String(gameVersionKey(cohort, id))},
+			":updatedAt": {S: aws.String(updatedAt)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		TableName:        aws.String(gameVersionTable),
+	}
+
+	var startKey string
+	for {
+		var versions []GameVersion
+		lastKey, err := repo.query(input, startKey, &versions)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) > 0 {
+			return &versions[0], nil
+		}
+		if lastKey == "" {
+			break
+		}
+		startKey = lastKey
+	}
+	return nil, errors.New(404, "Game version not found", fmt.Sprintf("No version of %s/%s at %s", cohort, id, updatedAt))
+}
+
+// RestoreGameVersion overwrites the PGN and headers of the given game with those of the
+// given version. The player and date fields derived from the headers and the fingerprint
+// are updated to match. The caller must own the game. The updated game is returned.
+func (repo *dynamoRepository) RestoreGameVersion(owner string, version *GameVersion) (*Game, error) {
+	game := &Game{Cohort: version.Cohort, Id: version.Id, Pgn: version.Pgn}
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := repo.updateGamePgn(game, parsed, version.Headers, owner, "attribute_exists(id) AND #owner = :editor",
+		map[string]*string{"#owner": aws.String("owner")}, nil)
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: game not found or you do not have permission to restore it", "DynamoDB UpdateItem failure", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return result, nil
+}
+
+// updateGamePgn sets the PGN of the given game to game.Pgn and its headers to the given
+// headers. The player and date fields derived from the headers and the fingerprint are
+// updated to match, and the given editor is recorded as the last editor. The condition
+// may refer to #updatedAt and :editor in addition to the given names and values. The raw
+// DynamoDB error is returned so that callers can handle a failed condition.
+func (repo *dynamoRepository) updateGamePgn(game *Game, parsed *chess.Game, headers map[string]string, editor, condition string, conditionNames map[string]*string, conditionValues map[string]*dynamodb.AttributeValue) (*Game, error) {
+	headersAv, err := dynamodbattribute.Marshal(headers)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal headers", err)
+	}
+
+	white := strings.ToLower(headers["White"])
+	if white == "" {
+		white = "?"
+	}
+	black := strings.ToLower(headers["Black"])
+	if black == "" {
+		black = "?"
+	}
+
+	updateExpr := "SET #pgn = :pgn, #headers = :headers, #white = :white, #black = :black, #date = :date, #updatedAt = :updatedAt, #lastEditedBy = :editor"
+	names := map[string]*string{
+		"#pgn":          aws.String("pgn"),
+		"#headers":      aws.String("headers"),
+		"#white":        aws.String("white"),
+		"#black":        aws.String("black"),
+		"#date":         aws.String("date"),
+		"#updatedAt":    aws.String("updatedAt"),
+		"#lastEditedBy": aws.String("lastEditedBy"),
+		"#fingerprint":  aws.String("fingerprint"),
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":editor":    {S: aws.String(editor)},
+		":pgn":       {S: aws.String(game.Pgn)},
+		":headers":   headersAv,
+		":white":     {S: aws.String(white)},
+		":black":     {S: aws.String(black)},
+		":date":      {S: aws.String(headers["Date"])},
+		":updatedAt": {S: aws.String(time.Now().UTC().Format(time.RFC3339Nano))},
+	}
+	maps.Copy(names, conditionNames)
+	maps.Copy(values, conditionValues)
+	// The fingerprint is a GSI key, so it cannot be set to an empty string
+	if fingerprint := GameFingerprint(parsed); fingerprint != "" {
+		updateExpr += ", #fingerprint = :fingerprint"
+		values[":fingerprint"] = &dynamodb.AttributeValue{S: aws.String(fingerprint)}
+	} else {
+		updateExpr += " REMOVE #fingerprint"
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression:       aws.String(condition),
+		UpdateExpression:          aws.String(updateExpr),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(game.Cohort))},
+			"id":     {S: aws.String(game.Id)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+
+	result := Game{}
+	if err := repo.updateItem(input, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewGameVersion(t *testing.T) {
+	game := &Game{Cohort: "1500-1600", Id: "2024.01.02_abc", Owner: "alice", UpdatedAt: "2024-01-02T00:00:00Z", Pgn: "1. e4 *"}
+	replacedAt := time.Date(2024, 1, 3, 4, 5, 6, 7, time.FixedZone("EST", -5*60*60))
+
+	got := NewGameVersion(game, replacedAt, "100")
+	if got.GameId != "1500-1600/2024.01.02_abc" {
+		t.Errorf("GameId got: %q; want: %q", got.GameId, "1500-1600/2024.01.02_abc")
+	}
+	if got.Version != "2024-01-03T09:05:06Z_100" {
+		t.Errorf("Version got: %q; want: %q", got.Version, "2024-01-03T09:05:06Z_100")
+	}
+	if got.UpdatedAt != game.UpdatedAt || got.Pgn != game.Pgn {
+		t.Errorf("NewGameVersion got: %+v; want the game's updatedAt and PGN", got)
+	}
+	if got.EditedBy != "alice" {
+		t.Errorf("EditedBy got: %q; want the owner %q", got.EditedBy, "alice")
+	}
+
+	game.LastEditedBy = "bob"
+	if got := NewGameVersion(game, replacedAt, "101"); got.EditedBy != "bob" {
+		t.Errorf("EditedBy got: %q; want the last editor %q", got.EditedBy, "bob")
+	}
+}
+
+func TestDiffGameVersions(t *testing.T) {
+	older := &GameVersion{
+		Headers: map[string]string{"White": "Alice", "Black": "Bob", "Event": "Casual"},
+		Pgn:     "1. e4 e5 {Solid} 2. Nf3 (2. f4 exf4) 2... Nc6 3. Bb5 *",
+	}
+	newer := &GameVersion{
+		Headers: map[string]string{"White": "Alice", "Black": "Robert", "Site": "Lichess"},
+		Pgn:     "1. e4 e5 {Solid [%cal Gg1f3]} 2. Nf3 Nc6 $1 3. Bc4 *",
+	}
+
+	diff, err := DiffGameVersions(older, newer)
+	if err != nil {
+		t.Fatalf("DiffGameVersions got error: %v", err)
+	}
+
+	wantHeaders := []HeaderDiff{
+		{Name: "Black", Before: "Bob", After: "Robert"},
+		{Name: "Event", Before: "Casual"},
+		{Name: "Site", After: "Lichess"},
+	}
+	if !reflect.DeepEqual(diff.Headers, wantHeaders) {
+		t.Errorf("Headers got: %+v; want: %+v", diff.Headers, wantHeaders)
+	}
+
+	wantMoves := []MoveDiff{
+		{
+			Type:   GameDiffType_Changed,
+			Ply:    2,
+			Line:   []string{"e4", "e5"},
+			Before: &MoveAnnotation{Comment: "Solid"},
+			After:  &MoveAnnotation{Comment: "Solid", Commands: map[string]string{"cal": "Gg1f3"}},
+		},
+		{
+			Type:   GameDiffType_Changed,
+			Ply:    4,
+			Line:   []string{"e4", "e5", "Nf3", "Nc6"},
+			Before: &MoveAnnotation{},
+			After:  &MoveAnnotation{Nags: []int{1}},
+		},
+		{Type: GameDiffType_Added, Ply: 5, Line: []string{"e4", "e5", "Nf3", "Nc6", "Bc4"}, After: &MoveAnnotation{}},
+		{Type: GameDiffType_Removed, Ply: 3, Line: []string{"e4", "e5", "f4"}, Before: &MoveAnnotation{}},
+		{Type: GameDiffType_Removed, Ply: 5, Line: []string{"e4", "e5", "Nf3", "Nc6", "Bb5"}, Before: &MoveAnnotation{}},
+		{Type: GameDiffType_Removed, Ply: 4, Line: []string{"e4", "e5", "f4", "exf4"}, Before: &MoveAnnotation{}},
+	}
+	if len(diff.Moves) != len(wantMoves) {
+		t.Fatalf("Moves got: %+v; want: %+v", diff.Moves, wantMoves)
+	}
+	for i := range wantMoves {
+		if !reflect.DeepEqual(diff.Moves[i], wantMoves[i]) {
+			t.Errorf("Moves[%d] got: %+v; want: %+v", i, diff.Moves[i], wantMoves[i])
+		}
+	}
+}
+
+func TestDiffGameVersionsInvalidPgn(t *testing.T) {
+	_, err := DiffGameVersions(&GameVersion{Pgn: "1. e4 *"}, &GameVersion{Pgn: "1. e5 *"})
+	if err == nil {
+		t.Errorf("DiffGameVersions got nil error; want error")
+	}
+}
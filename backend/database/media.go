@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
@@ -33,6 +35,17 @@ type MediaStore interface {
 	Download(bucket, key string, file *os.File) error
 }
 
+// ExportStore provides an interface for saving game exports.
+type ExportStore interface {
+	// UploadExport saves the data read from body at the provided key. The export is
+	// saved in the game database bucket.
+	UploadExport(key string, body io.Reader) error
+
+	// GetExport returns a reader for the export with the provided key. A 404 error is
+	// returned if the export does not exist. The caller must close the reader.
+	GetExport(key string) (io.ReadCloser, error)
+}
+
 // s3MediaStore implements a media store using AWS S3.
 type s3MediaStore struct {
 	uploader   *s3manager.Uploader
@@ -46,6 +59,7 @@ var S3 = &s3MediaStore{
 }
 
 var picturesBucket = fmt.Sprintf("chess-dojo-%s-pictures", stage)
+var gameDatabaseBucket = fmt.Sprintf("chess-dojo-%s-game-database", stage)
 
 // UploadImage saves the provided image data at the provided key.
 // The image is saved in the default bucket for pictures.
@@ -100,3 +114,30 @@ func (ms *s3MediaStore) Download(bucket, key string, file *os.File) error {
 	})
 	return errors.Wrap(500, "Temporary server error", "Failed to download file", err)
 }
+
+// UploadExport saves the data read from body at the provided key. The export is
+// saved in the game database bucket.
+func (ms *s3MediaStore) UploadExport(key string, body io.Reader) error {
+	_, err := ms.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(gameDatabaseBucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to upload export", err)
+}
+
+// GetExport returns a reader for the export with the provided key. A 404 error is
+// returned if the export does not exist. The caller must close the reader.
+func (ms *s3MediaStore) GetExport(key string) (io.ReadCloser, error) {
+	output, err := ms.uploader.S3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(gameDatabaseBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, errors.Wrap(404, "Invalid request: export not found", "", err)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to get export", err)
+	}
+	return output.Body, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The max number of users notified of their mentions in a single comment.
+const maxCommentMentions = 10
+
+// The max number of possible mentions in a single comment which are looked up in the
+// users table.
+const maxMentionCandidates = 100
+
+// Matches either mention markup of the form @[Display Name](username), capturing the
+// username in the first group, or a plain @username which is not part of an email
+// address, capturing the username in the second group.
+var mentionRegex = regexp.MustCompile(`@\[[^\]\n]*\]\(([^)\s]+)\)|(?:^|[^\w.@])@([\w.-]+)`)
+
+// ParseMentions returns the usernames possibly mentioned in the given comment content, in
+// the order they are first mentioned. Mentions are either a plain @username or use the
+// markup @[Display Name](username). The author of the comment is excluded, and at most
+// maxMentionCandidates usernames are returned. The usernames are not checked to exist.
+func ParseMentions(content, author string) []string {
+	var usernames []string
+	for _, match := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		username := match[1]
+		if username == "" {
+			// A trailing period ends the sentence rather than the username
+			username = strings.TrimRight(match[2], ".")
+		}
+		if username == "" || username == author || slices.Contains(usernames, username) {
+			continue
+		}
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentionCandidates {
+			break
+		}
+	}
+	return usernames
+}
+
+type MentionResolver interface {
+	// BatchGetUsersProjection returns the users with the provided usernames and projection
+	// expression.
+	BatchGetUsersProjection(usernames []string, projectionExpression string) ([]*User, error)
+}
+
+// ResolveMentions returns the usernames of the existing users mentioned in the given
+// comment content, in the order they are first mentioned. See ParseMentions. At most
+// maxCommentMentions usernames are returned.
+func ResolveMentions(repo MentionResolver, content, author string) ([]string, error) {
+	candidates := ParseMentions(content, author)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	users, err := repo.BatchGetUsersProjection(candidates, "username")
+	if err != nil {
+		return nil, err
+	}
+
+	var usernames []string
+	for _, candidate := range candidates {
+		if slices.ContainsFunc(users, func(u *User) bool { return u.Username == candidate }) {
+			usernames = append(usernames, candidate)
+			if len(usernames) == maxCommentMentions {
+				break
+			}
+		}
+	}
+	return usernames, nil
+}
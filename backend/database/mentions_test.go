@@ -0,0 +1,139 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	table := []struct {
+		name    string
+		content string
+		author  string
+		want    []string
+	}{
+		{
+			name:    "NoMentions",
+			content: "Why not 12. Nf3 here?",
+			author:  "alice",
+		},
+		{
+			name:    "PlainMention",
+			content: "@bob what do you think?",
+			author:  "alice",
+			want:    []string{"bob"},
+		},
+		{
+			name:    "PlainMentionEndsSentence",
+			content: "Ask @bob_smith. Or @carol-2, @dave!",
+			author:  "alice",
+			want:    []string{"bob_smith", "carol-2", "dave"},
+		},
+		{
+			name:    "EmailAddress",
+			content: "Email bob@example.com or @",
+			author:  "alice",
+		},
+		{
+			name:    "SingleMention",
+			content: "@[Bob Smith](bob) what do you think?",
+			author:  "alice",
+			want:    []string{"bob"},
+		},
+		{
+			name:    "MultipleMentions",
+			content: "@[Carol](carol) and @[Bob](bob), see @[Carol](carol)'s line",
+			author:  "alice",
+			want:    []string{"carol", "bob"},
+		},
+		{
+			name:    "MixedMentions",
+			content: "@[Bob](bob) and @carol, not @bob again",
+			author:  "alice",
+			want:    []string{"bob", "carol"},
+		},
+		{
+			name:    "ExcludesAuthor",
+			content: "@[Alice](alice) @[Bob](bob) @alice",
+			author:  "alice",
+			want:    []string{"bob"},
+		},
+		{
+			name:    "InvalidMarkup",
+			content: "@[Bob]() @[Bob](b ob) @[Bob\n](bob)",
+			author:  "alice",
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseMentions(tc.content, tc.author)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("ParseMentions got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+type fakeMentionResolver struct {
+	users map[string]bool
+}
+
+func (r *fakeMentionResolver) BatchGetUsersProjection(usernames []string, projectionExpression string) ([]*User, error) {
+	var users []*User
+	for _, username := range usernames {
+		if r.users[username] {
+			users = append(users, &User{Username: username})
+		}
+	}
+	return users, nil
+}
+
+func TestResolveMentions(t *testing.T) {
+	repo := &fakeMentionResolver{users: map[string]bool{"bob": true, "carol": true}}
+	var manyMentions []string
+	var manyUsernames []string
+	for i := range maxCommentMentions + 2 {
+		username := fmt.Sprintf("user%d", i)
+		repo.users[username] = true
+		manyMentions = append(manyMentions, "@"+username)
+		if i < maxCommentMentions {
+			manyUsernames = append(manyUsernames, username)
+		}
+	}
+
+	table := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "NoMentions",
+			content: "Why not 12. Nf3 here?",
+		},
+		{
+			name:    "SkipsUnknownUsers",
+			content: "@everyone ask @carol and @[Bob](bob)",
+			want:    []string{"carol", "bob"},
+		},
+		{
+			name:    "TooManyMentions",
+			content: "@nobody " + strings.Join(manyMentions, " "),
+			want:    manyUsernames,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveMentions(repo, tc.content, "alice")
+			if err != nil {
+				t.Fatalf("ResolveMentions got error: %v", err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("ResolveMentions got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,575 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+var commentReportTable = stage + "-comment-reports"
+
+const commentReportTableStatusIndex = "StatusIdx"
+
+// The content shown in place of a deleted comment, so that replies to it keep their context.
+const RemovedCommentContent = "[removed]"
+
+// The max length of the details of a comment report.
+const maxReportDetailsLength = 1000
+
+// CommentEdit is a previous version of a position comment, saved when the comment is
+// edited or deleted.
+type CommentEdit struct {
+	// The content of the comment before the change.
+	Content string `dynamodbav:"content" json:"content"`
+
+	// The suggested variation of the comment before the change.
+	SuggestedVariation string `dynamodbav:"suggestedVariation,omitempty" json:"suggestedVariation,omitempty"`
+
+	// The time the replaced version was saved, in time.RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+}
+
+// CommentTargetType is the kind of object a comment was left on.
+type CommentTargetType string
+
+const (
+	CommentTargetType_Game     CommentTargetType = "GAME"
+	CommentTargetType_Timeline CommentTargetType = "TIMELINE"
+	CommentTargetType_Event    CommentTargetType = "EVENT"
+)
+
+// CommentTarget identifies a single comment on a game, timeline entry or event.
+type CommentTarget struct {
+	// The kind of object the comment was left on.
+	Type CommentTargetType `dynamodbav:"type" json:"type"`
+
+	// The cohort of the game. Set only for game comments.
+	Cohort DojoCohort `dynamodbav:"cohort,omitempty" json:"cohort,omitempty"`
+
+	// The id of the game. Set only for game comments.
+	GameId string `dynamodbav:"gameId,omitempty" json:"gameId,omitempty"`
+
+	// The normalized FEN of the comment. Set only for game comments.
+	Fen string `dynamodbav:"fen,omitempty" json:"fen,omitempty"`
+
+	// A comma-separated list of the parent comment ids. Set only for game comment replies.
+	ParentIds string `dynamodbav:"parentIds,omitempty" json:"parentIds,omitempty"`
+
+	// The owner of the timeline entry. Set only for timeline comments.
+	Owner string `dynamodbav:"owner,omitempty" json:"owner,omitempty"`
+
+	// The id of the timeline entry or event. Set only for timeline comments and event
+	// messages.
+	Id string `dynamodbav:"id,omitempty" json:"id,omitempty"`
+
+	// The id of the comment.
+	CommentId string `dynamodbav:"commentId" json:"commentId"`
+}
+
+// Validate returns a 400 error if the target is missing any field required by its type.
+func (t *CommentTarget) Validate() error {
+	if t.CommentId == "" {
+		return errors.New(400, "Invalid request: commentId is required", "")
+	}
+
+	switch t.Type {
+	case CommentTargetType_Game:
+		if t.Cohort == "" || t.GameId == "" || t.Fen == "" {
+			return errors.New(400, "Invalid request: cohort, gameId and fen are required for game comments", "")
+		}
+	case CommentTargetType_Timeline:
+		if t.Owner == "" || t.Id == "" {
+			return errors.New(400, "Invalid request: owner and id are required for timeline comments", "")
+		}
+	case CommentTargetType_Event:
+		if t.Id == "" {
+			return errors.New(400, "Invalid request: id is required for event messages", "")
+		}
+	default:
+		return errors.New(400, fmt.Sprintf("Invalid request: type `%s` is invalid", t.Type), "")
+	}
+	return nil
+}
+
+// Key returns a string uniquely identifying the target comment.
+func (t *CommentTarget) Key() string {
+	switch t.Type {
+	case CommentTargetType_Game:
+		return fmt.Sprintf("%s|%s|%s|%s|%s", t.Type, t.Cohort, t.GameId, t.Fen, t.CommentId)
+	case CommentTargetType_Timeline:
+		return fmt.Sprintf("%s|%s|%s|%s", t.Type, t.Owner, t.Id, t.CommentId)
+	default:
+		return fmt.Sprintf("%s|%s|%s", t.Type, t.Id, t.CommentId)
+	}
+}
+
+// PositionCommentTarget returns the CommentTarget of the position comment in the given
+// update.
+func PositionCommentTarget(update *PositionCommentUpdate) *CommentTarget {
+	return &CommentTarget{
+		Type:      CommentTargetType_Game,
+		Cohort:    update.Cohort,
+		GameId:    update.GameId,
+		Fen:       update.Fen,
+		ParentIds: update.ParentIds,
+		CommentId: update.Id,
+	}
+}
+
+// ReportedComment is the current state of a comment targeted by a report.
+type ReportedComment struct {
+	// The username of the poster of the comment.
+	Owner string `json:"owner"`
+
+	// The content of the comment.
+	Content string `json:"content"`
+
+	// Whether the comment has been deleted.
+	Deleted bool `json:"deleted"`
+}
+
+type CommentReportReason string
+
+const (
+	CommentReportReason_Spam          CommentReportReason = "SPAM"
+	CommentReportReason_Harassment    CommentReportReason = "HARASSMENT"
+	CommentReportReason_Inappropriate CommentReportReason = "INAPPROPRIATE"
+	CommentReportReason_Other         CommentReportReason = "OTHER"
+)
+
+// IsValid returns true if the reason is one of the supported reasons.
+func (r CommentReportReason) IsValid() bool {
+	switch r {
+	case CommentReportReason_Spam, CommentReportReason_Harassment, CommentReportReason_Inappropriate, CommentReportReason_Other:
+		return true
+	}
+	return false
+}
+
+type CommentReportStatus string
+
+const (
+	CommentReportStatus_Open     CommentReportStatus = "OPEN"
+	CommentReportStatus_Resolved CommentReportStatus = "RESOLVED"
+)
+
+type CommentReportAction string
+
+const (
+	// The reported comment was deleted.
+	CommentReportAction_Removed CommentReportAction = "REMOVED"
+
+	// The report was dismissed without changing the comment.
+	CommentReportAction_Dismissed CommentReportAction = "DISMISSED"
+)
+
+// CommentReport is a report by a user that a comment breaks the community guidelines.
+type CommentReport struct {
+	// The id of the report, in the form targetKey|reporter, so that a user can report a
+	// comment only once.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The reported comment.
+	Target CommentTarget `dynamodbav:"target" json:"target"`
+
+	// The username of the user who made the report.
+	Reporter string `dynamodbav:"reporter" json:"reporter"`
+
+	// The display name of the user who made the report.
+	ReporterDisplayName string `dynamodbav:"reporterDisplayName" json:"reporterDisplayName"`
+
+	// The reason for the report.
+	Reason CommentReportReason `dynamodbav:"reason" json:"reason"`
+
+	// Optional details on the reason for the report.
+	Details string `dynamodbav:"details,omitempty" json:"details,omitempty"`
+
+	// The username of the poster of the reported comment.
+	CommentOwner string `dynamodbav:"commentOwner" json:"commentOwner"`
+
+	// The content of the reported comment when it was reported.
+	CommentContent string `dynamodbav:"commentContent" json:"commentContent"`
+
+	// The status of the report. This is the hash key of the status index.
+	Status CommentReportStatus `dynamodbav:"status" json:"status"`
+
+	// The time the report was created, in time.RFC3339 format. This is the range key of
+	// the status index.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The username of the admin who resolved the report.
+	ResolvedBy string `dynamodbav:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+
+	// The time the report was resolved, in time.RFC3339 format.
+	ResolvedAt string `dynamodbav:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+
+	// The action taken to resolve the report.
+	Action CommentReportAction `dynamodbav:"action,omitempty" json:"action,omitempty"`
+}
+
+// NewCommentReport returns an open report of the given comment by the given user. A 400
+// error is returned if the reason or details are invalid or the comment was already
+// deleted.
+func NewCommentReport(target *CommentTarget, comment *ReportedComment, reporter *User, reason CommentReportReason, details string) (*CommentReport, error) {
+	if !reason.IsValid() {
+		return nil, errors.New(400, fmt.Sprintf("Invalid request: reason `%s` is invalid", reason), "")
+	}
+	details = strings.TrimSpace(details)
+	if utf8.RuneCountInString(details) > maxReportDetailsLength {
+		return nil, errors.New(400, fmt.Sprintf("Invalid request: details must be at most %d characters", maxReportDetailsLength), "")
+	}
+	if comment.Deleted {
+		return nil, errors.New(400, "Invalid request: this comment has already been removed", "")
+	}
+	if comment.Owner == reporter.Username {
+		return nil, errors.New(400, "Invalid request: you cannot report your own comment", "")
+	}
+
+	return &CommentReport{
+		Id:                  fmt.Sprintf("%s|%s", target.Key(), reporter.Username),
+		Target:              *target,
+		Reporter:            reporter.Username,
+		ReporterDisplayName: reporter.DisplayName,
+		Reason:              reason,
+		Details:             details,
+		CommentOwner:        comment.Owner,
+		CommentContent:      comment.Content,
+		Status:              CommentReportStatus_Open,
+		CreatedAt:           time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+// findComment returns the index of the comment with the given id in comments, or -1 if it
+// does not exist.
+func findComment(comments []Comment, id string) int {
+	for i, c := range comments {
+		if c.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+type CommentModerator interface {
+	UserGetter
+
+	// GetReportedComment returns the current state of the given comment.
+	GetReportedComment(target *CommentTarget) (*ReportedComment, error)
+
+	// RemoveComment deletes the given comment on behalf of the given admin.
+	RemoveComment(target *CommentTarget, removedBy string) error
+
+	// CreateCommentReport saves the given report. A 400 error is returned if the reporter
+	// already reported the comment.
+	CreateCommentReport(report *CommentReport) error
+
+	// ListCommentReports returns the reports with the given status, oldest first.
+	ListCommentReports(status CommentReportStatus, startKey string) ([]CommentReport, string, error)
+
+	// GetCommentReport returns the report with the given id.
+	GetCommentReport(id string) (*CommentReport, error)
+
+	// ResolveCommentReport marks the given open report as resolved by the given admin with
+	// the given action. The updated report is returned.
+	ResolveCommentReport(id, resolvedBy string, action CommentReportAction) (*CommentReport, error)
+}
+
+// GetReportedComment returns the current state of the given comment.
+func (repo *dynamoRepository) GetReportedComment(target *CommentTarget) (*ReportedComment, error) {
+	switch target.Type {
+	case CommentTargetType_Game:
+		game, err := repo.GetGame(string(target.Cohort), target.GameId)
+		if err != nil {
+			return nil, err
+		}
+		comment, ok := game.GetPositionComment(target.Fen, target.ParentIds, target.CommentId)
+		if !ok {
+			return nil, errors.New(404, "Invalid request: comment not found", "")
+		}
+		return &ReportedComment{Owner: comment.Owner.Username, Content: comment.Content, Deleted: comment.DeletedAt != ""}, nil
+
+	case CommentTargetType_Timeline:
+		entry, err := repo.GetTimelineEntry(target.Owner, target.Id)
+		if err != nil {
+			return nil, err
+		}
+		return reportedComment(entry.Comments, target.CommentId)
+
+	case CommentTargetType_Event:
+		event, err := repo.GetEvent(target.Id)
+		if err != nil {
+			return nil, err
+		}
+		return reportedComment(event.Messages, target.CommentId)
+	}
+	return nil, errors.New(400, fmt.Sprintf("Invalid request: type `%s` is invalid", target.Type), "")
+}
+
+// reportedComment returns the comment with the given id in comments as a ReportedComment.
+func reportedComment(comments []Comment, id string) (*ReportedComment, error) {
+	i := findComment(comments, id)
+	if i < 0 {
+		return nil, errors.New(404, "Invalid request: comment not found", "")
+	}
+	return &ReportedComment{Owner: comments[i].Owner, Content: comments[i].Content, Deleted: comments[i].DeletedAt != ""}, nil
+}
+
+// RemoveComment deletes the given comment on behalf of the given admin. Comments are
+// soft deleted, so that replies to them keep their place.
+func (repo *dynamoRepository) RemoveComment(target *CommentTarget, removedBy string) error {
+	switch target.Type {
+	case CommentTargetType_Game:
+		game, err := repo.GetGame(string(target.Cohort), target.GameId)
+		if err != nil {
+			return err
+		}
+		comment, ok := game.GetPositionComment(target.Fen, target.ParentIds, target.CommentId)
+		if !ok {
+			return errors.New(404, "Invalid request: comment not found", "")
+		}
+		_, err = repo.deletePositionComment(target, comment, "", removedBy)
+		return err
+
+	case CommentTargetType_Timeline:
+		entry, err := repo.GetTimelineEntry(target.Owner, target.Id)
+		if err != nil {
+			return err
+		}
+		return repo.deleteListComment(timelineTable, map[string]*dynamodb.AttributeValue{
+			"owner": {S: aws.String(target.Owner)},
+			"id":    {S: aws.String(target.Id)},
+		}, "comments", entry.Comments, target.CommentId, removedBy)
+
+	case CommentTargetType_Event:
+		event, err := repo.GetEvent(target.Id)
+		if err != nil {
+			return err
+		}
+		return repo.deleteListComment(eventTable, map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(target.Id)},
+		}, "messages", event.Messages, target.CommentId, removedBy)
+	}
+	return errors.New(400, fmt.Sprintf("Invalid request: type `%s` is invalid", target.Type), "")
+}
+
+// deleteListComment soft deletes the comment with the given id from the given list
+// attribute of the item with the given key. comments is the current value of the list.
+func (repo *dynamoRepository) deleteListComment(table string, key map[string]*dynamodb.AttributeValue, attribute string, comments []Comment, id, removedBy string) error {
+	i := findComment(comments, id)
+	if i < 0 {
+		return errors.New(404, "Invalid request: comment not found", "")
+	}
+
+	path := fmt.Sprintf("#comments[%d]", i)
+	input := &dynamodb.UpdateItemInput{
+		// The index is checked in case the list changed since it was read
+		ConditionExpression: aws.String(fmt.Sprintf("%s.#id = :id", path)),
+		UpdateExpression:    aws.String(fmt.Sprintf("SET %s.#content = :content, %s.#deletedAt = :deletedAt, %s.#deletedBy = :deletedBy", path, path, path)),
+		ExpressionAttributeNames: map[string]*string{
+			"#comments":  aws.String(attribute),
+			"#id":        aws.String("id"),
+			"#content":   aws.String("content"),
+			"#deletedAt": aws.String("deletedAt"),
+			"#deletedBy": aws.String("deletedBy"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id":        {S: aws.String(id)},
+			":content":   {S: aws.String(RemovedCommentContent)},
+			":deletedAt": {S: aws.String(time.Now().Format(time.RFC3339))},
+			":deletedBy": {S: aws.String(removedBy)},
+		},
+		Key:       key,
+		TableName: aws.String(table),
+	}
+
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(409, "Conflict: the comments changed while removing the comment. Try again.", "DynamoDB conditional check failed", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
+
+// updatePositionComment sets the content and suggested variation of the given position
+// comment and saves its previous version in its edit history. previous is the current
+// state of the comment, which must not have changed. If owner is not empty, the comment
+// must belong to owner. If deletedBy is not empty, the comment is marked as deleted by
+// that user. The updated game is returned.
+func (repo *dynamoRepository) updatePositionComment(target *CommentTarget, previous *PositionComment, content, variation, owner, deletedBy string) (*Game, error) {
+	history, err := dynamodbattribute.Marshal([]CommentEdit{{
+		Content:            previous.Content,
+		SuggestedVariation: previous.SuggestedVariation,
+		UpdatedAt:          previous.UpdatedAt,
+	}})
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal comment edit history", err)
+	}
+
+	names := map[string]*string{
+		"#p":         aws.String("positionComments"),
+		"#fen":       aws.String(target.Fen),
+		"#id":        aws.String(target.CommentId),
+		"#content":   aws.String("content"),
+		"#variation": aws.String("suggestedVariation"),
+		"#updated":   aws.String("updatedAt"),
+		"#history":   aws.String("editHistory"),
+		"#deletedAt": aws.String("deletedAt"),
+	}
+	now := time.Now().Format(time.RFC3339)
+	values := map[string]*dynamodb.AttributeValue{
+		":c":        {S: aws.String(content)},
+		":v":        {S: aws.String(variation)},
+		":u":        {S: aws.String(now)},
+		":h":        history,
+		":empty":    {L: []*dynamodb.AttributeValue{}},
+		":previous": {S: aws.String(previous.Content)},
+	}
+
+	path := fmt.Sprintf("#p.#fen.%s#id", getCommentPath(target.ParentIds, names))
+	updateExpr := fmt.Sprintf("SET %s.#content = :c, %s.#variation = :v, %s.#updated = :u, %s.#history = list_append(if_not_exists(%s.#history, :empty), :h)", path, path, path, path, path)
+	condition := fmt.Sprintf("%s.#content = :previous AND attribute_not_exists(%s.#deletedAt)", path, path)
+
+	if owner != "" {
+		names["#owner"] = aws.String("owner")
+		names["#username"] = aws.String("username")
+		values[":owner"] = &dynamodb.AttributeValue{S: aws.String(owner)}
+		condition += fmt.Sprintf(" AND %s.#owner.#username = :owner", path)
+	}
+	if deletedBy != "" {
+		names["#deletedBy"] = aws.String("deletedBy")
+		values[":deletedBy"] = &dynamodb.AttributeValue{S: aws.String(deletedBy)}
+		updateExpr += fmt.Sprintf(", %s.#deletedAt = :u, %s.#deletedBy = :deletedBy", path, path)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression:       aws.String(condition),
+		UpdateExpression:          aws.String(updateExpr),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(target.Cohort))},
+			"id":     {S: aws.String(target.GameId)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+
+	game := Game{}
+	if err := repo.updateItem(input, &game); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(409, "Conflict: the comment was changed, deleted or is not yours to change", "DynamoDB UpdateItem failure", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return &game, nil
+}
+
+// deletePositionComment soft deletes the given position comment on behalf of deletedBy.
+// The content is replaced with RemovedCommentContent and the previous content is saved in
+// the comment's edit history. If owner is not empty, the comment must belong to owner.
+func (repo *dynamoRepository) deletePositionComment(target *CommentTarget, previous *PositionComment, owner, deletedBy string) (*Game, error) {
+	return repo.updatePositionComment(target, previous, RemovedCommentContent, "", owner, deletedBy)
+}
+
+// CreateCommentReport saves the given report. A 400 error is returned if the reporter
+// already reported the comment.
+func (repo *dynamoRepository) CreateCommentReport(report *CommentReport) error {
+	item, err := dynamodbattribute.MarshalMap(report)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal report", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+		Item:                item,
+		TableName:           aws.String(commentReportTable),
+	}
+	if _, err := repo.svc.PutItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(400, "Invalid request: you have already reported this comment", "DynamoDB conditional check failed", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB PutItem failure", err)
+	}
+	return nil
+}
+
+// ListCommentReports returns the reports with the given status, oldest first.
+func (repo *dynamoRepository) ListCommentReports(status CommentReportStatus, startKey string) ([]CommentReport, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {S: aws.String(string(status))},
+		},
+		IndexName: aws.String(commentReportTableStatusIndex),
+		TableName: aws.String(commentReportTable),
+	}
+
+	var reports []CommentReport
+	lastKey, err := repo.query(input, startKey, &reports)
+	if err != nil {
+		return nil, "", err
+	}
+	return reports, lastKey, nil
+}
+
+// GetCommentReport returns the report with the given id.
+func (repo *dynamoRepository) GetCommentReport(id string) (*CommentReport, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		TableName: aws.String(commentReportTable),
+	}
+
+	report := CommentReport{}
+	if err := repo.getItem(input, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ResolveCommentReport marks the given open report as resolved by the given admin with
+// the given action. The updated report is returned.
+func (repo *dynamoRepository) ResolveCommentReport(id, resolvedBy string, action CommentReportAction) (*CommentReport, error) {
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#status = :open"),
+		UpdateExpression:    aws.String("SET #status = :resolved, #resolvedBy = :resolvedBy, #resolvedAt = :resolvedAt, #action = :action"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status":     aws.String("status"),
+			"#resolvedBy": aws.String("resolvedBy"),
+			"#resolvedAt": aws.String("resolvedAt"),
+			"#action":     aws.String("action"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":open":       {S: aws.String(string(CommentReportStatus_Open))},
+			":resolved":   {S: aws.String(string(CommentReportStatus_Resolved))},
+			":resolvedBy": {S: aws.String(resolvedBy)},
+			":resolvedAt": {S: aws.String(time.Now().Format(time.RFC3339))},
+			":action":     {S: aws.String(string(action))},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(commentReportTable),
+	}
+
+	report := CommentReport{}
+	if err := repo.updateItem(input, &report); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: report not found or already resolved", "DynamoDB conditional check failed", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import "testing"
+
+func TestCommentTargetValidate(t *testing.T) {
+	table := []struct {
+		name    string
+		target  CommentTarget
+		wantErr bool
+	}{
+		{
+			name:   "Game",
+			target: CommentTarget{Type: CommentTargetType_Game, Cohort: "1500-1600", GameId: "game", Fen: "fen", CommentId: "c"},
+		},
+		{
+			name:    "GameMissingFen",
+			target:  CommentTarget{Type: CommentTargetType_Game, Cohort: "1500-1600", GameId: "game", CommentId: "c"},
+			wantErr: true,
+		},
+		{
+			name:   "Timeline",
+			target: CommentTarget{Type: CommentTargetType_Timeline, Owner: "alice", Id: "entry", CommentId: "c"},
+		},
+		{
+			name:    "TimelineMissingOwner",
+			target:  CommentTarget{Type: CommentTargetType_Timeline, Id: "entry", CommentId: "c"},
+			wantErr: true,
+		},
+		{
+			name:   "Event",
+			target: CommentTarget{Type: CommentTargetType_Event, Id: "event", CommentId: "c"},
+		},
+		{
+			name:    "MissingCommentId",
+			target:  CommentTarget{Type: CommentTargetType_Event, Id: "event"},
+			wantErr: true,
+		},
+		{
+			name:    "InvalidType",
+			target:  CommentTarget{Type: "CLUB", Id: "club", CommentId: "c"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.target.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate got error: %v; want error: %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewCommentReport(t *testing.T) {
+	target := &CommentTarget{Type: CommentTargetType_Timeline, Owner: "alice", Id: "entry", CommentId: "c"}
+	reporter := &User{Username: "carol", DisplayName: "Carol"}
+	comment := &ReportedComment{Owner: "bob", Content: "Rude comment"}
+
+	report, err := NewCommentReport(target, comment, reporter, CommentReportReason_Harassment, "  Insulting  ")
+	if err != nil {
+		t.Fatalf("NewCommentReport got error: %v", err)
+	}
+	if want := "TIMELINE|alice|entry|c|carol"; report.Id != want {
+		t.Errorf("Id got: %q; want: %q", report.Id, want)
+	}
+	if report.Status != CommentReportStatus_Open || report.CommentOwner != "bob" || report.CommentContent != "Rude comment" || report.Details != "Insulting" {
+		t.Errorf("NewCommentReport got: %+v", report)
+	}
+
+	table := []struct {
+		name     string
+		comment  *ReportedComment
+		reporter *User
+		reason   CommentReportReason
+	}{
+		{name: "InvalidReason", comment: comment, reporter: reporter, reason: "BORING"},
+		{name: "Deleted", comment: &ReportedComment{Owner: "bob", Content: RemovedCommentContent, Deleted: true}, reporter: reporter, reason: CommentReportReason_Spam},
+		{name: "OwnComment", comment: comment, reporter: &User{Username: "bob"}, reason: CommentReportReason_Spam},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewCommentReport(target, tc.comment, tc.reporter, tc.reason, ""); err == nil {
+				t.Errorf("NewCommentReport got nil error; want error")
+			}
+		})
+	}
+}
@@ -18,6 +18,9 @@ const (
 	// Notifications generated by replies to a comment on a game
 	NotificationType_GameCommentReply NotificationType = "GAME_COMMENT_REPLY"
 
+	// Notifications generated by emoji reactions on a comment on a game
+	NotificationType_GameCommentReaction NotificationType = "GAME_COMMENT_REACTION"
+
 	// Notifications generated by getting a new follower
 	NotificationType_NewFollower NotificationType = "NEW_FOLLOWER"
 
@@ -39,6 +42,9 @@ const (
 	// Notifications generated by a sensei game review
 	NotificationType_GameReviewComplete NotificationType = "GAME_REVIEW_COMPLETE"
 
+	// Notifications generated by a partial refund of a late game review
+	NotificationType_GameReviewRefund NotificationType = "GAME_REVIEW_REFUND"
+
 	// Notifications generated by an event being booked
 	NotificationType_EventBooked NotificationType = "EVENT_BOOKED"
 
@@ -47,6 +53,9 @@ const (
 
 	// Notifications generated by a user creating a subscription
 	NotificationType_SubscriptionCreated NotificationType = "SUBSCRIPTION_CREATED"
+
+	// Notifications generated by a user being mentioned in a comment
+	NotificationType_Mention NotificationType = "MENTION"
 )
 
 // Data for a notification
@@ -73,6 +82,9 @@ type Notification struct {
 	// Metadata for a game review notification
 	GameReviewMetadata *GameReviewMetadata `dynamodbav:"gameReviewMetadata,omitempty" json:"gameReviewMetadata,omitempty"`
 
+	// Metadata for a game review refund notification
+	GameReviewRefundMetadata *GameReviewRefundMetadata `dynamodbav:"gameReviewRefundMetadata,omitempty" json:"gameReviewRefundMetadata,omitempty"`
+
 	// Metadata for a new follower notification
 	NewFollowerMetadata *NewFollowerMetadata `dynamodbav:"newFollowerMetadata,omitempty" json:"newFollowerMetadata,omitempty"`
 
@@ -90,6 +102,9 @@ type Notification struct {
 
 	// Metadata for round robin start notifications
 	RoundRobinStartMetadata *RoundRobinStartMetadata `dynamodbav:"roundRobinStartMetadata,omitempty" json:"roundRobinStartMetadata,omitempty"`
+
+	// Metadata for mention notifications
+	MentionMetadata *MentionMetadata `dynamodbav:"mentionMetadata,omitempty" json:"mentionMetadata,omitempty"`
 }
 
 // Metadata for a game comment notification.
@@ -113,6 +128,15 @@ type GameReviewMetadata struct {
 	Reviewer Reviewer `dynamodbav:"reviewer" json:"reviewer"`
 }
 
+// Metadata for a game review refund notification
+type GameReviewRefundMetadata struct {
+	// Inherits all fields from GameCommentMetadata
+	GameCommentMetadata
+
+	// The percentage of the review's price that was refunded
+	Percentage int64 `dynamodbav:"percentage" json:"percentage"`
+}
+
 // Metadata for a new follower notification.
 type NewFollowerMetadata struct {
 	// The username of the follower
@@ -190,6 +214,22 @@ type RoundRobinStartMetadata struct {
 	Name string `dynamodbav:"name" json:"name"`
 }
 
+// Metadata for mention notifications
+type MentionMetadata struct {
+	// The type of object containing the comment with the mention
+	Type CommentTargetType `dynamodbav:"type" json:"type"`
+	// The cohort of the game. Only set for game comments.
+	Cohort DojoCohort `dynamodbav:"cohort,omitempty" json:"cohort,omitempty"`
+	// The owner of the timeline entry. Only set for timeline comments.
+	Owner string `dynamodbav:"owner,omitempty" json:"owner,omitempty"`
+	// The id of the game, timeline entry or event
+	Id string `dynamodbav:"id" json:"id"`
+	// The title of the game, timeline entry or event
+	Title string `dynamodbav:"title" json:"title"`
+	// The display name of the user who last mentioned the notified user
+	MentionedBy string `dynamodbav:"mentionedBy" json:"mentionedBy"`
+}
+
 func SendEventBookedNotification(event *Event) error {
 	e := struct {
 		Type    string `json:"type"`
@@ -236,6 +276,30 @@ func SendGameCommentEvent(game *Game, comment *PositionComment) error {
 	return sendSqsEvent(event)
 }
 
+// SendGameCommentReactionEvent sends an event notifying the owner of the given comment
+// that a reaction was left on it.
+func SendGameCommentReactionEvent(game *Game, comment *PositionComment) error {
+	event := struct {
+		Type string `json:"type"`
+		Game struct {
+			Cohort string `json:"cohort"`
+			Id     string `json:"id"`
+		} `json:"game"`
+		Owner string `json:"owner"`
+	}{
+		Type: string(NotificationType_GameCommentReaction),
+		Game: struct {
+			Cohort string "json:\"cohort\""
+			Id     string "json:\"id\""
+		}{
+			Cohort: string(game.Cohort),
+			Id:     game.Id,
+		},
+		Owner: comment.Owner.Username,
+	}
+	return sendSqsEvent(event)
+}
+
 func SendGameReviewCompleteEvent(game *Game) error {
 	event := struct {
 		Type string `json:"type"`
@@ -256,6 +320,30 @@ func SendGameReviewCompleteEvent(game *Game) error {
 	return sendSqsEvent(event)
 }
 
+// SendGameReviewRefundEvent sends an event notifying the owner of the given game that the
+// given percentage of the price of its late review was refunded.
+func SendGameReviewRefundEvent(game *Game, percentage int64) error {
+	event := struct {
+		Type string `json:"type"`
+		Game struct {
+			Cohort string `json:"cohort"`
+			Id     string `json:"id"`
+		} `json:"game"`
+		Percentage int64 `json:"percentage"`
+	}{
+		Type: string(NotificationType_GameReviewRefund),
+		Game: struct {
+			Cohort string "json:\"cohort\""
+			Id     string "json:\"id\""
+		}{
+			Cohort: string(game.Cohort),
+			Id:     game.Id,
+		},
+		Percentage: percentage,
+	}
+	return sendSqsEvent(event)
+}
+
 func SendFollowerEvent(f *FollowerEntry, cohort DojoCohort) error {
 	type follower struct {
 		Username    string `json:"username"`
@@ -359,6 +447,29 @@ func SendSubscriptionCreatedEvent(username string) error {
 	return sendSqsEvent(e)
 }
 
+// SendMentionEvent sends a notification event for the given usernames, who were mentioned
+// in the given comment by the given user. Nothing is sent if usernames is empty.
+func SendMentionEvent(target *CommentTarget, author, authorDisplayName string, usernames []string) error {
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	event := struct {
+		Type        string        `json:"type"`
+		Target      CommentTarget `json:"target"`
+		Author      string        `json:"author"`
+		DisplayName string        `json:"displayName"`
+		Usernames   []string      `json:"usernames"`
+	}{
+		Type:        string(NotificationType_Mention),
+		Target:      *target,
+		Author:      author,
+		DisplayName: authorDisplayName,
+		Usernames:   usernames,
+	}
+	return sendSqsEvent(event)
+}
+
 func sendSqsEvent(event any) error {
 	body, err := json.Marshal(event)
 	if err != nil {
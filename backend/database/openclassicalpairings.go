@@ -0,0 +1,399 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// The result of an Open Classical pairing in which the player with white received a bye
+// because no opponent was available.
+const OpenClassicalResult_Bye = "Bye"
+
+// The maximum number of steps the Swiss pairing search may take before giving up.
+const maxSwissPairingSteps = 1_000_000
+
+// openClassicalResult is the outcome of an Open Classical pairing from one player's
+// perspective.
+type openClassicalResult struct {
+	// The number of points the player earned.
+	score float64
+
+	// Whether the game was actually played, as opposed to a bye or forfeit.
+	played bool
+
+	// Whether a result has been set on the pairing.
+	known bool
+}
+
+// getOpenClassicalResult returns the outcome of the given pairing result for the player
+// with the given color.
+func getOpenClassicalResult(result string, white bool) openClassicalResult {
+	switch result {
+	case OpenClassicalResult_Bye:
+		return openClassicalResult{score: 0.5, known: true}
+	case "1-0", "0-1":
+		return openClassicalResult{score: decisiveScore(result, white), played: true, known: true}
+	case "1/2-1/2":
+		return openClassicalResult{score: 0.5, played: true, known: true}
+	case "1-0F", "0-1F":
+		return openClassicalResult{score: decisiveScore(strings.TrimSuffix(result, "F"), white), known: true}
+	case "1/2-1/2F":
+		return openClassicalResult{score: 0.5, known: true}
+	case "0-0":
+		return openClassicalResult{score: 0, known: true}
+	}
+	return openClassicalResult{}
+}
+
+// decisiveScore returns the score of the player with the given color in a 1-0 or 0-1 result.
+func decisiveScore(result string, white bool) float64 {
+	if (result == "1-0") == white {
+		return 1
+	}
+	return 0
+}
+
+// swissPlayer contains a player's history in an Open Classical section, as needed to pair
+// the player in the next round.
+type swissPlayer struct {
+	// The player being paired.
+	player OpenClassicalPlayer
+
+	// The player's score before the round being paired.
+	score float64
+
+	// The Dojo usernames of the players this player has already been paired against.
+	opponents map[string]bool
+
+	// The colors the player had in played games, in round order. Each entry is 'w' or 'b'.
+	colors []byte
+
+	// Whether the player already received a bye from the pairing engine.
+	hadBye bool
+}
+
+// colorPreference returns the color the player should receive next and the strength of
+// that preference. A strength of 3 is absolute (the player's color difference would
+// exceed 2 or they would play the same color three times in a row), 2 is strong (the
+// player has played one color more often than the other) and 1 is mild (the player
+// played the other color last round). A strength of 0 means the player has no preference.
+func (p *swissPlayer) colorPreference() (byte, int) {
+	n := len(p.colors)
+	if n == 0 {
+		return 0, 0
+	}
+
+	diff := 0
+	for _, c := range p.colors {
+		if c == 'w' {
+			diff++
+		} else {
+			diff--
+		}
+	}
+
+	last := p.colors[n-1]
+	repeated := n >= 2 && p.colors[n-2] == last
+	switch {
+	case diff > 1 || (repeated && last == 'w'):
+		return 'b', 3
+	case diff < -1 || (repeated && last == 'b'):
+		return 'w', 3
+	case diff > 0:
+		return 'b', 2
+	case diff < 0:
+		return 'w', 2
+	case last == 'w':
+		return 'b', 1
+	default:
+		return 'w', 1
+	}
+}
+
+// colorsCompatible returns true if the given players can be paired without violating
+// an absolute color preference.
+func colorsCompatible(a, b *swissPlayer) bool {
+	ca, sa := a.colorPreference()
+	cb, sb := b.colorPreference()
+	return !(sa == 3 && sb == 3 && ca == cb)
+}
+
+// assignColors returns the given players as white and black. The player with the
+// stronger color preference gets their preferred color, with ties going to the
+// higher-ranked player a. If neither player has a preference, colors alternate by board.
+func assignColors(a, b *swissPlayer, board int) (white, black *swissPlayer) {
+	ca, sa := a.colorPreference()
+	cb, sb := b.colorPreference()
+
+	var aWhite bool
+	switch {
+	case sa == 0 && sb == 0:
+		aWhite = board%2 == 0
+	case ca != cb && sa > 0:
+		aWhite = ca == 'w'
+	case ca != cb:
+		aWhite = cb == 'b'
+	case sb > sa:
+		aWhite = cb == 'b'
+	default:
+		aWhite = ca == 'w'
+	}
+
+	if aWhite {
+		return a, b
+	}
+	return b, a
+}
+
+// getSwissPlayers returns the history of every player in the section before the given
+// round (1-based). Players who were not paired in a round while still active in the
+// tournament receive half a point, matching the treatment of requested byes. A 400 error
+// is returned if an earlier round contains a pairing without a result.
+func getSwissPlayers(section *OpenClassicalSection, round int) (map[string]*swissPlayer, error) {
+	players := make(map[string]*swissPlayer, len(section.Players))
+	for username, player := range section.Players {
+		players[username] = &swissPlayer{player: player, opponents: make(map[string]bool)}
+	}
+
+	for idx, r := range section.Rounds[:round-1] {
+		paired := make(map[string]bool)
+		for _, pairing := range r.Pairings {
+			white := players[pairing.White.Username]
+			black := players[pairing.Black.Username]
+			paired[pairing.White.Username] = true
+			paired[pairing.Black.Username] = true
+
+			if pairing.Result == OpenClassicalResult_Bye {
+				if white != nil {
+					white.score += 0.5
+					white.hadBye = true
+				}
+				continue
+			}
+
+			if !getOpenClassicalResult(pairing.Result, true).known {
+				return nil, errors.New(400, fmt.Sprintf("Invalid request: round %d has pairings without results", idx+1), "")
+			}
+
+			for _, p := range []struct {
+				player   *swissPlayer
+				opponent string
+				white    bool
+			}{
+				{white, pairing.Black.Username, true},
+				{black, pairing.White.Username, false},
+			} {
+				if p.player == nil {
+					continue
+				}
+				result := getOpenClassicalResult(pairing.Result, p.white)
+				p.player.score += result.score
+				p.player.opponents[p.opponent] = true
+				if result.played {
+					if p.white {
+						p.player.colors = append(p.player.colors, 'w')
+					} else {
+						p.player.colors = append(p.player.colors, 'b')
+					}
+				}
+			}
+		}
+
+		for username, p := range players {
+			if !paired[username] && (p.player.LastActiveRound == 0 || p.player.LastActiveRound >= idx+1) {
+				p.score += 0.5
+			}
+		}
+	}
+
+	return players, nil
+}
+
+// GenerateOpenClassicalPairings returns Swiss pairings for the given round (1-based) of the
+// section, using the results of the rounds before it. Players who are withdrawn or banned,
+// or who requested a bye for the round, are not paired.
+//
+// Players are ranked by score and then rating and paired Dutch-style: within each score
+// group, the top half plays the bottom half, with players floating down to the next score
+// group when their group cannot be paired. Players are never paired against the same
+// opponent twice and colors are balanced according to each player's color history. If an
+// odd number of players remain, the lowest-ranked player who has not yet received a bye
+// receives one.
+//
+// A 400 error is returned if an earlier round has missing results or if no valid pairings
+// exist.
+func GenerateOpenClassicalPairings(section *OpenClassicalSection, round int) ([]OpenClassicalPairing, error) {
+	if round < 1 || round-1 > len(section.Rounds) {
+		return nil, errors.New(400, fmt.Sprintf("Invalid request: round %d cannot be paired before round %d", round, len(section.Rounds)+1), "")
+	}
+
+	players, err := getSwissPlayers(section, round)
+	if err != nil {
+		return nil, err
+	}
+
+	var eligible []*swissPlayer
+	for _, p := range players {
+		if p.player.Status != "" {
+			continue
+		}
+		if len(p.player.ByeRequests) >= round && p.player.ByeRequests[round-1] {
+			continue
+		}
+		eligible = append(eligible, p)
+	}
+	if len(eligible) < 2 {
+		return nil, errors.New(400, "Invalid request: not enough players to generate pairings", "")
+	}
+
+	slices.SortFunc(eligible, func(a, b *swissPlayer) int {
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+		if a.player.Rating != b.player.Rating {
+			return b.player.Rating - a.player.Rating
+		}
+		return strings.Compare(a.player.Username, b.player.Username)
+	})
+
+	pairer := &swissPairer{}
+	if len(eligible)%2 == 0 {
+		if pairs, ok := pairer.pair(eligible); ok {
+			return getPairings(pairs, nil), nil
+		}
+	} else {
+		for _, bye := range getByeCandidates(eligible) {
+			rest := slices.DeleteFunc(slices.Clone(eligible), func(p *swissPlayer) bool { return p == bye })
+			if pairs, ok := pairer.pair(rest); ok {
+				return getPairings(pairs, bye), nil
+			}
+		}
+	}
+
+	return nil, errors.New(400, "Invalid request: unable to generate pairings without rematches", fmt.Sprintf("Swiss pairing search took %d steps", pairer.steps))
+}
+
+// OpenClassicalPairingsHash returns a hash of the given pairings. It allows pairings which
+// were previewed to be committed only if the same pairings are generated again.
+func OpenClassicalPairingsHash(pairings []OpenClassicalPairing) (string, error) {
+	data, err := json.Marshal(pairings)
+	if err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to marshal pairings", err)
+	}
+	checksum := sha256.Sum256(data)
+	return hex.EncodeToString(checksum[:]), nil
+}
+
+// getByeCandidates returns the players who may receive a bye, in the order they should be
+// tried. Players are tried from the lowest rank up, with players who already received a
+// bye tried last.
+func getByeCandidates(ranked []*swissPlayer) []*swissPlayer {
+	candidates := make([]*swissPlayer, 0, len(ranked))
+	var repeats []*swissPlayer
+	for i := len(ranked) - 1; i >= 0; i-- {
+		if ranked[i].hadBye {
+			repeats = append(repeats, ranked[i])
+		} else {
+			candidates = append(candidates, ranked[i])
+		}
+	}
+	return append(candidates, repeats...)
+}
+
+// getPairings converts the given pairs into Open Classical pairings, assigning colors and
+// appending a bye for the given player if they are not nil.
+func getPairings(pairs [][2]*swissPlayer, bye *swissPlayer) []OpenClassicalPairing {
+	pairings := make([]OpenClassicalPairing, 0, len(pairs)+1)
+	for board, pair := range pairs {
+		white, black := assignColors(pair[0], pair[1], board)
+		pairings = append(pairings, OpenClassicalPairing{
+			White: white.player.OpenClassicalPlayerSummary,
+			Black: black.player.OpenClassicalPlayerSummary,
+		})
+	}
+	if bye != nil {
+		pairings = append(pairings, OpenClassicalPairing{
+			White:    bye.player.OpenClassicalPlayerSummary,
+			Result:   OpenClassicalResult_Bye,
+			Verified: true,
+		})
+	}
+	return pairings
+}
+
+// swissPairer searches for pairings of a ranked list of players.
+type swissPairer struct {
+	// The number of steps taken by the search so far.
+	steps int
+}
+
+// pair returns pairings for every player in the given ranked list. The first player of each
+// pair is the higher-ranked player. False is returned if no valid pairings exist or the
+// search exceeds maxSwissPairingSteps.
+func (s *swissPairer) pair(players []*swissPlayer) ([][2]*swissPlayer, bool) {
+	if len(players) == 0 {
+		return nil, true
+	}
+
+	s.steps++
+	if s.steps > maxSwissPairingSteps {
+		return nil, false
+	}
+
+	top, rest := players[0], players[1:]
+	for _, opponent := range getOpponentCandidates(top, rest) {
+		remaining := slices.DeleteFunc(slices.Clone(rest), func(p *swissPlayer) bool { return p == opponent })
+		if pairs, ok := s.pair(remaining); ok {
+			return append([][2]*swissPlayer{{top, opponent}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// getOpponentCandidates returns the possible opponents of the top-ranked player, in the
+// order they should be tried. Players in the top player's score group are tried first,
+// starting with the player at the same position in the bottom half of the group, followed
+// by the remaining players in rank order. Opponents who would violate an absolute color
+// preference are tried only after all others. Previous opponents are excluded.
+func getOpponentCandidates(top *swissPlayer, rest []*swissPlayer) []*swissPlayer {
+	groupSize := 1
+	for groupSize <= len(rest) && rest[groupSize-1].score == top.score {
+		groupSize++
+	}
+
+	ordered := make([]*swissPlayer, 0, len(rest))
+	if groupSize > 1 {
+		// rest[i] is at index i+1 of the score group, which includes the top player.
+		half := groupSize / 2
+		for i := half; i < groupSize; i++ {
+			ordered = append(ordered, rest[i-1])
+		}
+		for i := half - 1; i >= 1; i-- {
+			ordered = append(ordered, rest[i-1])
+		}
+	}
+	ordered = append(ordered, rest[groupSize-1:]...)
+
+	candidates := make([]*swissPlayer, 0, len(ordered))
+	var incompatible []*swissPlayer
+	for _, p := range ordered {
+		if top.opponents[p.player.Username] {
+			continue
+		}
+		if colorsCompatible(top, p) {
+			candidates = append(candidates, p)
+		} else {
+			incompatible = append(incompatible, p)
+		}
+	}
+	return append(candidates, incompatible...)
+}
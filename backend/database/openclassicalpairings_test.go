@@ -0,0 +1,240 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func newTestOpenClassicalPlayer(username string, rating int) OpenClassicalPlayer {
+	return OpenClassicalPlayer{
+		OpenClassicalPlayerSummary: OpenClassicalPlayerSummary{Username: username, Rating: rating},
+	}
+}
+
+func newTestOpenClassicalPairing(white, black, result string) OpenClassicalPairing {
+	return OpenClassicalPairing{
+		White:  OpenClassicalPlayerSummary{Username: white},
+		Black:  OpenClassicalPlayerSummary{Username: black},
+		Result: result,
+	}
+}
+
+func newTestOpenClassicalSection(players []OpenClassicalPlayer, rounds ...[]OpenClassicalPairing) *OpenClassicalSection {
+	section := &OpenClassicalSection{Players: make(map[string]OpenClassicalPlayer)}
+	for _, p := range players {
+		section.Players[p.Username] = p
+	}
+	for _, pairings := range rounds {
+		section.Rounds = append(section.Rounds, OpenClassicalRound{Pairings: pairings})
+	}
+	return section
+}
+
+func TestGenerateOpenClassicalPairings(t *testing.T) {
+	a := newTestOpenClassicalPlayer("a", 2000)
+	b := newTestOpenClassicalPlayer("b", 1900)
+	c := newTestOpenClassicalPlayer("c", 1800)
+	d := newTestOpenClassicalPlayer("d", 1700)
+	e := newTestOpenClassicalPlayer("e", 1600)
+
+	withdrawn := c
+	withdrawn.Status = OpenClassicalPlayerStatus_Withdrawn
+	requestedBye := d
+	requestedBye.ByeRequests = []bool{true}
+
+	table := []struct {
+		name    string
+		section *OpenClassicalSection
+		round   int
+		want    [][2]string
+		wantErr bool
+	}{
+		{
+			name:    "FirstRound",
+			section: newTestOpenClassicalSection([]OpenClassicalPlayer{a, b, c, d}),
+			round:   1,
+			want:    [][2]string{{"a", "c"}, {"d", "b"}},
+		},
+		{
+			name:    "OddPlayers",
+			section: newTestOpenClassicalSection([]OpenClassicalPlayer{a, b, c}),
+			round:   1,
+			want:    [][2]string{{"a", "b"}, {"c", ""}},
+		},
+		{
+			name: "ByeAlreadyReceived",
+			section: newTestOpenClassicalSection(
+				[]OpenClassicalPlayer{a, b, c},
+				[]OpenClassicalPairing{
+					newTestOpenClassicalPairing("a", "b", "1-0"),
+					newTestOpenClassicalPairing("c", "", OpenClassicalResult_Bye),
+				},
+			),
+			round: 2,
+			want:  [][2]string{{"c", "a"}, {"b", ""}},
+		},
+		{
+			name:    "InactiveAndRequestedByes",
+			section: newTestOpenClassicalSection([]OpenClassicalPlayer{a, b, withdrawn, requestedBye, e}),
+			round:   1,
+			want:    [][2]string{{"a", "b"}, {"e", ""}},
+		},
+		{
+			name: "ScoreGroups",
+			section: newTestOpenClassicalSection(
+				[]OpenClassicalPlayer{a, b, c, d},
+				[]OpenClassicalPairing{
+					newTestOpenClassicalPairing("a", "c", "1-0"),
+					newTestOpenClassicalPairing("d", "b", "0-1"),
+				},
+			),
+			round: 2,
+			want:  [][2]string{{"b", "a"}, {"c", "d"}},
+		},
+		{
+			name: "AvoidsRematch",
+			section: newTestOpenClassicalSection(
+				[]OpenClassicalPlayer{a, b, c, d},
+				[]OpenClassicalPairing{
+					newTestOpenClassicalPairing("a", "c", "1/2-1/2"),
+					newTestOpenClassicalPairing("d", "b", "1/2-1/2"),
+				},
+			),
+			round: 2,
+			want:  [][2]string{{"d", "a"}, {"b", "c"}},
+		},
+		{
+			name: "ForfeitDoesNotAffectColors",
+			section: newTestOpenClassicalSection(
+				[]OpenClassicalPlayer{a, b, c, d},
+				[]OpenClassicalPairing{
+					newTestOpenClassicalPairing("a", "c", "1-0F"),
+					newTestOpenClassicalPairing("d", "b", "0-1F"),
+				},
+			),
+			round: 2,
+			want:  [][2]string{{"a", "b"}, {"d", "c"}},
+		},
+		{
+			name: "MissingResult",
+			section: newTestOpenClassicalSection(
+				[]OpenClassicalPlayer{a, b},
+				[]OpenClassicalPairing{newTestOpenClassicalPairing("a", "b", "")},
+			),
+			round:   2,
+			wantErr: true,
+		},
+		{
+			name: "OnlyRematchesAvailable",
+			section: newTestOpenClassicalSection(
+				[]OpenClassicalPlayer{a, b},
+				[]OpenClassicalPairing{newTestOpenClassicalPairing("a", "b", "1-0")},
+			),
+			round:   2,
+			wantErr: true,
+		},
+		{
+			name:    "RoundOutOfOrder",
+			section: newTestOpenClassicalSection([]OpenClassicalPlayer{a, b}),
+			round:   2,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			pairings, err := GenerateOpenClassicalPairings(tc.section, tc.round)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GenerateOpenClassicalPairings got err: %v; want err: %t", err, tc.wantErr)
+			}
+
+			var got [][2]string
+			for _, p := range pairings {
+				got = append(got, [2]string{p.White.Username, p.Black.Username})
+				if (p.Result == OpenClassicalResult_Bye) != (p.Black.Username == "") {
+					t.Errorf("GenerateOpenClassicalPairings got pairing %v with result %q", p, p.Result)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("GenerateOpenClassicalPairings got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOpenClassicalPairingsHash(t *testing.T) {
+	pairings := []OpenClassicalPairing{
+		newTestOpenClassicalPairing("a", "c", ""),
+		newTestOpenClassicalPairing("d", "b", ""),
+	}
+
+	table := []struct {
+		name     string
+		pairings []OpenClassicalPairing
+		wantSame bool
+	}{
+		{
+			name:     "SamePairings",
+			pairings: slices.Clone(pairings),
+			wantSame: true,
+		},
+		{
+			name:     "DifferentOrder",
+			pairings: []OpenClassicalPairing{pairings[1], pairings[0]},
+		},
+		{
+			name: "DifferentColors",
+			pairings: []OpenClassicalPairing{
+				newTestOpenClassicalPairing("c", "a", ""),
+				pairings[1],
+			},
+		},
+		{
+			name: "ByeAdded",
+			pairings: append(slices.Clone(pairings),
+				newTestOpenClassicalPairing("e", "", OpenClassicalResult_Bye)),
+		},
+	}
+
+	want, err := OpenClassicalPairingsHash(pairings)
+	if err != nil {
+		t.Fatalf("OpenClassicalPairingsHash got error: %v", err)
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := OpenClassicalPairingsHash(tc.pairings)
+			if err != nil {
+				t.Fatalf("OpenClassicalPairingsHash got error: %v", err)
+			}
+			if (got == want) != tc.wantSame {
+				t.Errorf("OpenClassicalPairingsHash got same hash: %t; want: %t", got == want, tc.wantSame)
+			}
+		})
+	}
+}
+
+func TestColorPreference(t *testing.T) {
+	table := []struct {
+		colors       string
+		wantColor    byte
+		wantStrength int
+	}{
+		{colors: ""},
+		{colors: "w", wantColor: 'b', wantStrength: 2},
+		{colors: "wb", wantColor: 'w', wantStrength: 1},
+		{colors: "bwb", wantColor: 'w', wantStrength: 2},
+		{colors: "bww", wantColor: 'b', wantStrength: 3},
+		{colors: "wbwbbb", wantColor: 'w', wantStrength: 3},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.colors, func(t *testing.T) {
+			p := &swissPlayer{colors: []byte(tc.colors)}
+			color, strength := p.colorPreference()
+			if color != tc.wantColor || strength != tc.wantStrength {
+				t.Errorf("colorPreference got: (%c, %d); want: (%c, %d)", color, strength, tc.wantColor, tc.wantStrength)
+			}
+		})
+	}
+}
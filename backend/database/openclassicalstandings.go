@@ -0,0 +1,210 @@
+package database
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// The maximum rating difference awarded for a perfect or zero performance.
+const maxPerformanceDifference = 800
+
+// OpenClassicalStanding is a player's position in the standings of an Open Classical section.
+type OpenClassicalStanding struct {
+	// The player's rank in the section. Players tied on points and every tiebreak share a rank.
+	Rank int `json:"rank"`
+
+	// The player.
+	Player OpenClassicalPlayerSummary `json:"player"`
+
+	// The player's status in the tournament.
+	Status OpenClassicalPlayerStatus `json:"status"`
+
+	// The player's total points.
+	Points float64 `json:"points"`
+
+	// The sum of the player's opponents' points.
+	Buchholz float64 `json:"buchholz"`
+
+	// The player's Buchholz score, excluding the lowest contribution.
+	BuchholzCut1 float64 `json:"buchholzCut1"`
+
+	// The sum of the points of the opponents the player beat, plus half the points of the
+	// opponents the player drew.
+	SonnebornBerger float64 `json:"sonnebornBerger"`
+
+	// The rating the player performed at in the games they played. Zero if the player has
+	// not played any games.
+	PerformanceRating int `json:"performanceRating"`
+
+	// The number of games the player actually played, excluding byes and forfeits.
+	GamesPlayed int `json:"gamesPlayed"`
+}
+
+// standingRound is a player's result in a single round of an Open Classical section.
+type standingRound struct {
+	// The Dojo username of the opponent. Empty if the player had no opponent.
+	opponent string
+
+	// The result of the round from the player's perspective.
+	result openClassicalResult
+}
+
+// GetOpenClassicalStandings returns the standings of the section after the given round
+// (1-based). Rounds after the last round of the section are ignored.
+//
+// Byes, forfeits and rounds in which an active player was not paired score as in the
+// pairings, but do not count as played games. Withdrawn and banned players score no points
+// in rounds after their last active round. Pairings without a result score no points.
+//
+// Tiebreaks follow the FIDE treatment of unplayed games: each of a player's unplayed
+// rounds counts as a game against a virtual opponent with the player's own points, and
+// each of an opponent's unplayed rounds counts as a draw when computing the opponent's
+// contribution. Players are ordered by points, Buchholz cut-1, Buchholz, Sonneborn-Berger
+// and then rating.
+func GetOpenClassicalStandings(section *OpenClassicalSection, round int) []OpenClassicalStanding {
+	round = max(0, min(round, len(section.Rounds)))
+
+	rounds := make(map[string][]standingRound, len(section.Players))
+	for username := range section.Players {
+		rounds[username] = make([]standingRound, round)
+	}
+
+	for idx, r := range section.Rounds[:round] {
+		paired := make(map[string]bool)
+		for _, pairing := range r.Pairings {
+			paired[pairing.White.Username] = true
+			paired[pairing.Black.Username] = true
+
+			if pairing.Result == OpenClassicalResult_Bye {
+				if _, ok := rounds[pairing.White.Username]; ok {
+					rounds[pairing.White.Username][idx] = standingRound{result: getOpenClassicalResult(pairing.Result, true)}
+				}
+				continue
+			}
+
+			if _, ok := rounds[pairing.White.Username]; ok {
+				rounds[pairing.White.Username][idx] = standingRound{
+					opponent: pairing.Black.Username,
+					result:   getOpenClassicalResult(pairing.Result, true),
+				}
+			}
+			if _, ok := rounds[pairing.Black.Username]; ok {
+				rounds[pairing.Black.Username][idx] = standingRound{
+					opponent: pairing.White.Username,
+					result:   getOpenClassicalResult(pairing.Result, false),
+				}
+			}
+		}
+
+		for username, player := range section.Players {
+			if paired[username] {
+				continue
+			}
+			score := 0.0
+			if player.LastActiveRound == 0 || player.LastActiveRound >= idx+1 {
+				score = 0.5
+			}
+			rounds[username][idx] = standingRound{result: openClassicalResult{score: score, known: true}}
+		}
+	}
+
+	points := make(map[string]float64, len(rounds))
+	adjustedPoints := make(map[string]float64, len(rounds))
+	for username, playerRounds := range rounds {
+		for _, r := range playerRounds {
+			points[username] += r.result.score
+			if r.result.played {
+				adjustedPoints[username] += r.result.score
+			} else if r.result.known {
+				adjustedPoints[username] += 0.5
+			}
+		}
+	}
+
+	standings := make([]OpenClassicalStanding, 0, len(section.Players))
+	for username, player := range section.Players {
+		standing := OpenClassicalStanding{
+			Player: player.OpenClassicalPlayerSummary,
+			Status: player.Status,
+			Points: points[username],
+		}
+
+		var contributions []float64
+		var opponentRatings, score float64
+		for _, r := range rounds[username] {
+			if !r.result.known {
+				continue
+			}
+
+			opponentPoints := standing.Points
+			if r.result.played {
+				opponentPoints = adjustedPoints[r.opponent]
+				opponentRatings += float64(section.Players[r.opponent].Rating)
+				score += r.result.score
+				standing.GamesPlayed++
+			}
+			contributions = append(contributions, opponentPoints)
+			standing.Buchholz += opponentPoints
+			standing.SonnebornBerger += opponentPoints * r.result.score
+		}
+
+		standing.BuchholzCut1 = standing.Buchholz
+		if len(contributions) > 0 {
+			standing.BuchholzCut1 -= slices.Min(contributions)
+		}
+		if standing.GamesPlayed > 0 {
+			standing.PerformanceRating = getPerformanceRating(opponentRatings/float64(standing.GamesPlayed), score/float64(standing.GamesPlayed))
+		}
+		standings = append(standings, standing)
+	}
+
+	slices.SortFunc(standings, func(a, b OpenClassicalStanding) int {
+		if c := compareStandings(a, b); c != 0 {
+			return c
+		}
+		if a.Player.Rating != b.Player.Rating {
+			return b.Player.Rating - a.Player.Rating
+		}
+		return strings.Compare(a.Player.Username, b.Player.Username)
+	})
+
+	for i := range standings {
+		if i > 0 && compareStandings(standings[i-1], standings[i]) == 0 {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
+	return standings
+}
+
+// compareStandings compares the given standings by points and then tiebreaks, ordering
+// the better standing first.
+func compareStandings(a, b OpenClassicalStanding) int {
+	for _, v := range [][2]float64{
+		{a.Points, b.Points},
+		{a.BuchholzCut1, b.BuchholzCut1},
+		{a.Buchholz, b.Buchholz},
+		{a.SonnebornBerger, b.SonnebornBerger},
+	} {
+		if v[0] != v[1] {
+			if v[0] > v[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// getPerformanceRating returns the performance rating of a player who scored the given
+// fraction of points against opponents with the given average rating. The rating
+// difference is the inverse of the Elo expected score, limited to maxPerformanceDifference.
+func getPerformanceRating(averageRating, fraction float64) int {
+	difference := float64(maxPerformanceDifference)
+	if fraction < 1 {
+		difference = math.Max(-maxPerformanceDifference, math.Min(maxPerformanceDifference, -400*math.Log10(1/fraction-1)))
+	}
+	return int(math.Round(averageRating + difference))
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetOpenClassicalStandings(t *testing.T) {
+	a := newTestOpenClassicalPlayer("a", 2000)
+	b := newTestOpenClassicalPlayer("b", 1900)
+	c := newTestOpenClassicalPlayer("c", 1800)
+	d := newTestOpenClassicalPlayer("d", 1700)
+	e := newTestOpenClassicalPlayer("e", 1600)
+
+	withdrawn := c
+	withdrawn.Status = OpenClassicalPlayerStatus_Withdrawn
+	withdrawn.LastActiveRound = 1
+
+	forfeits := newTestOpenClassicalSection(
+		[]OpenClassicalPlayer{a, b, c, d},
+		[]OpenClassicalPairing{
+			newTestOpenClassicalPairing("a", "b", "1-0"),
+			newTestOpenClassicalPairing("c", "d", "1/2-1/2"),
+		},
+		[]OpenClassicalPairing{
+			newTestOpenClassicalPairing("a", "c", "1-0F"),
+			newTestOpenClassicalPairing("d", "b", "0-1"),
+		},
+	)
+
+	withdrawals := newTestOpenClassicalSection(
+		[]OpenClassicalPlayer{a, b, withdrawn, e},
+		[]OpenClassicalPairing{
+			newTestOpenClassicalPairing("a", "c", "0-1"),
+			newTestOpenClassicalPairing("b", "e", "1/2-1/2"),
+		},
+		[]OpenClassicalPairing{
+			newTestOpenClassicalPairing("a", "b", "1-0"),
+		},
+	)
+
+	table := []struct {
+		name    string
+		section *OpenClassicalSection
+		round   int
+		want    []OpenClassicalStanding
+	}{
+		{
+			name:    "Forfeits",
+			section: forfeits,
+			round:   2,
+			want: []OpenClassicalStanding{
+				{Rank: 1, Player: a.OpenClassicalPlayerSummary, Points: 2, Buchholz: 3, BuchholzCut1: 2, SonnebornBerger: 3, PerformanceRating: 2700, GamesPlayed: 1},
+				{Rank: 2, Player: b.OpenClassicalPlayerSummary, Points: 1, Buchholz: 2, BuchholzCut1: 1.5, SonnebornBerger: 0.5, PerformanceRating: 1850, GamesPlayed: 2},
+				{Rank: 3, Player: d.OpenClassicalPlayerSummary, Points: 0.5, Buchholz: 2, BuchholzCut1: 1, SonnebornBerger: 0.5, PerformanceRating: 1659, GamesPlayed: 2},
+				{Rank: 4, Player: c.OpenClassicalPlayerSummary, Points: 0.5, Buchholz: 1, BuchholzCut1: 0.5, SonnebornBerger: 0.25, PerformanceRating: 1700, GamesPlayed: 1},
+			},
+		},
+		{
+			name:    "Withdrawals",
+			section: withdrawals,
+			round:   2,
+			want: []OpenClassicalStanding{
+				{Rank: 1, Player: a.OpenClassicalPlayerSummary, Points: 1, Buchholz: 2, BuchholzCut1: 1.5, SonnebornBerger: 0.5, PerformanceRating: 1850, GamesPlayed: 2},
+				{Rank: 2, Player: withdrawn.OpenClassicalPlayerSummary, Status: OpenClassicalPlayerStatus_Withdrawn, Points: 1, Buchholz: 2, BuchholzCut1: 1, SonnebornBerger: 1, PerformanceRating: 2800, GamesPlayed: 1},
+				{Rank: 3, Player: e.OpenClassicalPlayerSummary, Points: 1, Buchholz: 1.5, BuchholzCut1: 1, SonnebornBerger: 0.75, PerformanceRating: 1900, GamesPlayed: 1},
+				{Rank: 4, Player: b.OpenClassicalPlayerSummary, Points: 0.5, Buchholz: 2, BuchholzCut1: 1, SonnebornBerger: 0.5, PerformanceRating: 1609, GamesPlayed: 2},
+			},
+		},
+		{
+			name:    "EarlierRound",
+			section: withdrawals,
+			round:   1,
+			want: []OpenClassicalStanding{
+				{Rank: 1, Player: withdrawn.OpenClassicalPlayerSummary, Status: OpenClassicalPlayerStatus_Withdrawn, Points: 1, SonnebornBerger: 0, PerformanceRating: 2800, GamesPlayed: 1},
+				{Rank: 2, Player: b.OpenClassicalPlayerSummary, Points: 0.5, Buchholz: 0.5, SonnebornBerger: 0.25, PerformanceRating: 1600, GamesPlayed: 1},
+				{Rank: 2, Player: e.OpenClassicalPlayerSummary, Points: 0.5, Buchholz: 0.5, SonnebornBerger: 0.25, PerformanceRating: 1900, GamesPlayed: 1},
+				{Rank: 4, Player: a.OpenClassicalPlayerSummary, Points: 0, Buchholz: 1, SonnebornBerger: 0, PerformanceRating: 1000, GamesPlayed: 1},
+			},
+		},
+		{
+			name:    "PendingResult",
+			section: newTestOpenClassicalSection([]OpenClassicalPlayer{a, b}, []OpenClassicalPairing{newTestOpenClassicalPairing("a", "b", "")}),
+			round:   1,
+			want: []OpenClassicalStanding{
+				{Rank: 1, Player: a.OpenClassicalPlayerSummary},
+				{Rank: 1, Player: b.OpenClassicalPlayerSummary},
+			},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := GetOpenClassicalStandings(tc.section, tc.round)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("GetOpenClassicalStandings got: %+v; want: %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetPerformanceRating(t *testing.T) {
+	table := []struct {
+		name          string
+		averageRating float64
+		fraction      float64
+		want          int
+	}{
+		{name: "Even", averageRating: 1500, fraction: 0.5, want: 1500},
+		{name: "Perfect", averageRating: 1500, fraction: 1, want: 2300},
+		{name: "Zero", averageRating: 1500, fraction: 0, want: 700},
+		{name: "ThreeQuarters", averageRating: 1500, fraction: 0.75, want: 1691},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := getPerformanceRating(tc.averageRating, tc.fraction)
+			if got != tc.want {
+				t.Errorf("getPerformanceRating got: %d; want: %d", got, tc.want)
+			}
+		})
+	}
+}
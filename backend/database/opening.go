@@ -0,0 +1,87 @@
+package database
+
+import (
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// Matches a well-formed ECO code, such as C60.
+var ecoRegex = regexp.MustCompile(`^[A-E][0-9]{2}$`)
+
+// OpeningUpdate returns the opening of the given game and true if the game's ECO and
+// Opening headers should be set to it. Existing headers are only replaced if they are
+// missing or inconsistent with the classified opening:
+//
+//   - A missing or malformed ECO code is replaced.
+//   - An ECO code equal to the classified code is kept, and a missing Opening header is
+//     filled. An existing Opening header is kept.
+//   - Any other ECO code is replaced only if the opening table contains every ECO code,
+//     since the game's mainline then does not end in an opening with that code. A partial
+//     table may lack the more specific line the game is in, so the code is kept.
+//
+// False is returned if the game's opening cannot be classified.
+func OpeningUpdate(game *Game, parsed *chess.Game) (chess.Opening, bool) {
+	opening, ok := chess.ClassifyOpening(parsed)
+	if !ok {
+		return opening, false
+	}
+
+	eco := game.Headers["ECO"]
+	switch {
+	case !ecoRegex.MatchString(eco):
+		return opening, true
+	case eco == opening.Eco:
+		return opening, game.Headers["Opening"] == ""
+	default:
+		return opening, chess.HasAllEcos()
+	}
+}
+
+type GameOpeningSetter interface {
+	// SetGameOpening sets the ECO and Opening headers of the given game, both in its headers
+	// and in the tag pairs of its PGN. A 409 error is returned if the game's PGN changed.
+	SetGameOpening(game *Game, opening chess.Opening) error
+}
+
+// SetGameOpening sets the ECO and Opening headers of the given game, both in its headers
+// and in the tag pairs of its PGN, so that the next save of the PGN keeps them. The rest
+// of the PGN is unchanged. A 409 error is returned if the game's PGN changed since it was
+// read, so that a concurrent edit is not overwritten.
+func (repo *dynamoRepository) SetGameOpening(game *Game, opening chess.Opening) error {
+	pgn := chess.SetPgnHeader(game.Pgn, "ECO", opening.Eco)
+	pgn = chess.SetPgnHeader(pgn, "Opening", opening.Name)
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(#headers) AND #pgn = :oldPgn"),
+		UpdateExpression:    aws.String("SET #headers.#eco = :eco, #headers.#opening = :opening, #pgn = :pgn"),
+		ExpressionAttributeNames: map[string]*string{
+			"#headers": aws.String("headers"),
+			"#eco":     aws.String("ECO"),
+			"#opening": aws.String("Opening"),
+			"#pgn":     aws.String("pgn"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":eco":     {S: aws.String(opening.Eco)},
+			":opening": {S: aws.String(opening.Name)},
+			":pgn":     {S: aws.String(pgn)},
+			":oldPgn":  {S: aws.String(game.Pgn)},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(game.Cohort))},
+			"id":     {S: aws.String(game.Id)},
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(409, "Conflict: the game was changed or deleted", "DynamoDB UpdateItem failure", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestOpeningUpdate(t *testing.T) {
+	const najdorf = "1. e4 c5 2. Nf3 d6 3. d4 cxd4 4. Nxd4 Nf6 5. Nc3 a6 6. Bg5 e6 *"
+	classified := chess.Opening{Eco: "B90", Name: "Sicilian Defense: Najdorf Variation"}
+	complete := chess.HasAllEcos()
+
+	table := []struct {
+		name    string
+		pgn     string
+		headers map[string]string
+		want    chess.Opening
+		wantOk  bool
+	}{
+		{
+			name:   "Missing",
+			pgn:    najdorf,
+			want:   classified,
+			wantOk: true,
+		},
+		{
+			name:    "Malformed",
+			pgn:     najdorf,
+			headers: map[string]string{"ECO": "?", "Opening": "Sicilian Defense"},
+			want:    classified,
+			wantOk:  true,
+		},
+		{
+			name:    "Current",
+			pgn:     najdorf,
+			headers: map[string]string{"ECO": "B90", "Opening": "Sicilian Defense: Najdorf Variation"},
+			want:    classified,
+		},
+		{
+			name:    "MissingName",
+			pgn:     najdorf,
+			headers: map[string]string{"ECO": "B90"},
+			want:    classified,
+			wantOk:  true,
+		},
+		{
+			name:    "CustomName",
+			pgn:     najdorf,
+			headers: map[string]string{"ECO": "B90", "Opening": "Sicilian: Najdorf"},
+			want:    classified,
+		},
+		{
+			name:    "LessSpecific",
+			pgn:     najdorf,
+			headers: map[string]string{"ECO": "B20", "Opening": "Sicilian Defense"},
+			want:    classified,
+			wantOk:  complete,
+		},
+		{
+			name:    "MoreSpecific",
+			pgn:     najdorf,
+			headers: map[string]string{"ECO": "B96", "Opening": "Sicilian Defense: Najdorf Variation"},
+			want:    classified,
+			wantOk:  complete,
+		},
+		{
+			name:    "OtherVolume",
+			pgn:     najdorf,
+			headers: map[string]string{"ECO": "C96"},
+			want:    classified,
+			wantOk:  complete,
+		},
+		{
+			name:    "Unclassified",
+			pgn:     "*",
+			headers: map[string]string{"ECO": "A00"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := chess.Parse(tc.pgn)
+			if err != nil {
+				t.Fatalf("Parse got error: %v", err)
+			}
+			game := &Game{Headers: tc.headers, Pgn: tc.pgn}
+
+			got, ok := OpeningUpdate(game, parsed)
+			if got != tc.want || ok != tc.wantOk {
+				t.Errorf("OpeningUpdate got: %+v, %v; want: %+v, %v", got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
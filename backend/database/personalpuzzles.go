@@ -0,0 +1,258 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+var personalPuzzleTable = stage + "-personal-puzzles"
+
+// The evaluation in centipawns, from the member's perspective, at or below which a
+// position is considered losing.
+const losingCentipawns = -100
+
+// The max number of plies in the solution of a personal puzzle.
+const maxPuzzleSolutionPlies = 5
+
+// The Glicko-2 rating, rating deviation and volatility of new personal puzzles.
+const (
+	personalPuzzleRating          = 1500
+	personalPuzzleRatingDeviation = 350
+	personalPuzzleVolatility      = 0.06
+)
+
+// PersonalPuzzle is a puzzle generated from a position where a member made a losing
+// mistake in one of their own games. Its fields match those of the puzzles in the puzzle
+// service, so that it can be played in the same way.
+type PersonalPuzzle struct {
+	// The username of the member who made the mistake.
+	Username string `dynamodbav:"username" json:"username"`
+
+	// The id of the puzzle, in the form cohort/gameId/ply.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The cohort of the game the puzzle was taken from.
+	Cohort DojoCohort `dynamodbav:"cohort" json:"cohort"`
+
+	// The id of the game the puzzle was taken from.
+	GameId string `dynamodbav:"gameId" json:"gameId"`
+
+	// The index of the member's mistake in the game's mainline.
+	Ply int `dynamodbav:"ply" json:"ply"`
+
+	// The FEN of the starting position of the puzzle, which is before the opponent's move
+	// preceding the mistake.
+	Fen string `dynamodbav:"fen" json:"fen"`
+
+	// The moves of the puzzle in UCI notation, starting with the opponent's move and
+	// followed by the engine's solution.
+	Moves []string `dynamodbav:"moves" json:"moves"`
+
+	// The guessed themes of the puzzle.
+	Themes []string `dynamodbav:"themes" json:"themes"`
+
+	// The Glicko-2 rating of the puzzle.
+	Rating float64 `dynamodbav:"rating" json:"rating"`
+
+	// The Glicko-2 rating deviation of the puzzle.
+	RatingDeviation float64 `dynamodbav:"ratingDeviation" json:"ratingDeviation"`
+
+	// The Glicko-2 volatility of the puzzle.
+	Volatility float64 `dynamodbav:"volatility" json:"volatility"`
+
+	// The number of times the puzzle has been played.
+	Plays int `dynamodbav:"plays" json:"plays"`
+
+	// The number of times the puzzle has been solved.
+	SuccessfulPlays int `dynamodbav:"successfulPlays" json:"successfulPlays"`
+
+	// The time the puzzle was created, in time.RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+}
+
+// memberCentipawns returns the given score in capped centipawns from the given color's
+// perspective.
+func memberCentipawns(s chess.Score, color chess.Color) int {
+	if color == chess.Black {
+		return -cappedCentipawns(s)
+	}
+	return cappedCentipawns(s)
+}
+
+// ExtractPersonalPuzzles returns a puzzle for each losing mistake made by the owner of the
+// given game, who played the given color, using the game's engine analysis. A losing
+// mistake is a mistake or blunder which turned a position that was not losing for the
+// owner into one that was. No puzzles are returned if the analysis does not match the
+// parsed game.
+func ExtractPersonalPuzzles(game *Game, parsed *chess.Game, color chess.Color) []PersonalPuzzle {
+	analysis := game.EngineAnalysis
+	if analysis == nil || !analysis.Matches(parsed) {
+		return nil
+	}
+
+	positions := parsed.Positions()
+	mainline := parsed.Mainline()
+	createdAt := time.Now().Format(time.RFC3339)
+	var puzzles []PersonalPuzzle
+
+	for i, move := range analysis.Moves {
+		if i == 0 || analysis.color(i) != color || len(move.BestLine) == 0 {
+			continue
+		}
+		if move.Classification != MoveClassification_Mistake && move.Classification != MoveClassification_Blunder {
+			continue
+		}
+
+		before := analysis.Moves[i-1].Eval
+		if memberCentipawns(before, color) <= losingCentipawns || memberCentipawns(move.Eval, color) > losingCentipawns {
+			continue
+		}
+
+		previous := positions[i-1]
+		opponentMove, err := previous.ParseSan(mainline[i-1].San)
+		if err != nil {
+			continue
+		}
+		solution, err := sanLineToMoves(positions[i], move.BestLine)
+		if err != nil || len(solution) == 0 {
+			continue
+		}
+		// End the solution on the member's move.
+		solution = solution[:min(len(solution), maxPuzzleSolutionPlies)]
+		if len(solution)%2 == 0 {
+			solution = solution[:len(solution)-1]
+		}
+
+		moves := []string{opponentMove.UCI()}
+		for _, m := range solution {
+			moves = append(moves, m.UCI())
+		}
+
+		puzzles = append(puzzles, PersonalPuzzle{
+			Username:        game.Owner,
+			Id:              fmt.Sprintf("%s/%s/%d", game.Cohort, game.Id, i),
+			Cohort:          game.Cohort,
+			GameId:          game.Id,
+			Ply:             i,
+			Fen:             previous.Fen(),
+			Moves:           moves,
+			Themes:          GuessPuzzleThemes(positions[i], solution, before),
+			Rating:          personalPuzzleRating,
+			RatingDeviation: personalPuzzleRatingDeviation,
+			Volatility:      personalPuzzleVolatility,
+			CreatedAt:       createdAt,
+		})
+	}
+	return puzzles
+}
+
+// sanLineToMoves returns the moves of the given SAN line, played from the given position.
+func sanLineToMoves(position *chess.Position, line []string) ([]chess.Move, error) {
+	moves := make([]chess.Move, 0, len(line))
+	for _, san := range line {
+		move, err := position.ParseSan(san)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+		position, _ = position.Play(move)
+	}
+	return moves, nil
+}
+
+// nonPawnMaterial returns the total value of the knights, bishops, rooks and queens of
+// both colors in the given position.
+func nonPawnMaterial(position *chess.Position) int {
+	values := map[chess.PieceType]int{chess.Knight: 3, chess.Bishop: 3, chess.Rook: 5, chess.Queen: 9}
+	total := 0
+	for sq := chess.Square(0); sq < 64; sq++ {
+		total += values[position.PieceAt(sq).Type]
+	}
+	return total
+}
+
+// GuessPuzzleThemes returns the likely themes of a puzzle starting from the given position,
+// where solution is the engine's line for the side to move and eval is the evaluation of
+// the position from White's perspective. The themes use the same names as the puzzle
+// service, so that personal puzzles feed the same theme ratings.
+func GuessPuzzleThemes(position *chess.Position, solution []chess.Move, eval chess.Score) []string {
+	var themes []string
+
+	switch material := nonPawnMaterial(position); {
+	case material <= 26:
+		themes = append(themes, "endgame")
+	case position.FullmoveNumber <= 12:
+		themes = append(themes, "opening")
+	default:
+		themes = append(themes, "middlegame")
+	}
+
+	mate := eval.Mate
+	if position.Turn == chess.Black {
+		mate = -mate
+	}
+	centipawns := memberCentipawns(eval, position.Turn)
+	switch {
+	case mate > 0:
+		themes = append(themes, "mate")
+		if mate <= 5 {
+			themes = append(themes, fmt.Sprintf("mateIn%d", mate))
+		}
+	case centipawns >= 300:
+		themes = append(themes, "crushing")
+	case centipawns >= 100:
+		themes = append(themes, "advantage")
+	default:
+		themes = append(themes, "equality")
+	}
+
+	if len(solution) > 0 && solution[0].Promotion != chess.NoPieceType {
+		themes = append(themes, "promotion")
+	}
+
+	switch (len(solution) + 1) / 2 {
+	case 1:
+		themes = append(themes, "oneMove")
+	case 2:
+		themes = append(themes, "short")
+	default:
+		themes = append(themes, "long")
+	}
+	return themes
+}
+
+type PersonalPuzzlePutter interface {
+	// PutPersonalPuzzles saves the given personal puzzles. Puzzles which already exist are
+	// not overwritten, so that their ratings and play counts are kept.
+	PutPersonalPuzzles(puzzles []PersonalPuzzle) error
+}
+
+// PutPersonalPuzzles saves the given personal puzzles. Puzzles which already exist are
+// not overwritten, so that their ratings and play counts are kept.
+func (repo *dynamoRepository) PutPersonalPuzzles(puzzles []PersonalPuzzle) error {
+	for _, puzzle := range puzzles {
+		item, err := dynamodbattribute.MarshalMap(puzzle)
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Unable to marshal personal puzzle", err)
+		}
+
+		input := &dynamodb.PutItemInput{
+			ConditionExpression: aws.String("attribute_not_exists(id)"),
+			Item:                item,
+			TableName:           aws.String(personalPuzzleTable),
+		}
+		if _, err := repo.svc.PutItem(input); err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				continue
+			}
+			return errors.Wrap(500, "Temporary server error", "DynamoDB PutItem failure", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestExtractPersonalPuzzles(t *testing.T) {
+	game := &Game{
+		Cohort: "1000-1100",
+		Id:     "2024.01.02_abc",
+		Owner:  "member",
+		Pgn:    "1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0",
+	}
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		t.Fatalf("ParsePgn got error: %v", err)
+	}
+
+	sans := []string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#"}
+	evals := []chess.Score{
+		{Centipawns: 30},
+		{Centipawns: 30},
+		{Centipawns: 20},
+		{Centipawns: 0},
+		{Centipawns: 0},
+		{Centipawns: 50},
+		{Mate: 1},
+		{Mate: 1},
+	}
+	bestLines := [][]string{{"e4"}, {"e5"}, {"Nf3"}, {"Nc6"}, {"Bc4"}, {"g6", "Qf3"}, {"Qxf7#"}, nil}
+	game.EngineAnalysis = NewEngineAnalysis(0, sans, evals, bestLines)
+
+	table := []struct {
+		name  string
+		color chess.Color
+		want  []PersonalPuzzle
+	}{
+		{
+			name:  "White",
+			color: chess.White,
+		},
+		{
+			name:  "Black",
+			color: chess.Black,
+			want: []PersonalPuzzle{
+				{
+					Username:        "member",
+					Id:              "1000-1100/2024.01.02_abc/5",
+					Cohort:          "1000-1100",
+					GameId:          "2024.01.02_abc",
+					Ply:             5,
+					Fen:             "r1bqkbnr/pppp1ppp/2n5/4p2Q/4P3/8/PPPP1PPP/RNB1KBNR w KQkq - 2 3",
+					Moves:           []string{"f1c4", "g7g6"},
+					Themes:          []string{"opening", "equality", "oneMove"},
+					Rating:          personalPuzzleRating,
+					RatingDeviation: personalPuzzleRatingDeviation,
+					Volatility:      personalPuzzleVolatility,
+				},
+			},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ExtractPersonalPuzzles(game, parsed, tc.color)
+			for i := range got {
+				got[i].CreatedAt = ""
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ExtractPersonalPuzzles got: %+v; want: %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGuessPuzzleThemes(t *testing.T) {
+	table := []struct {
+		name     string
+		fen      string
+		solution []string
+		eval     chess.Score
+		want     []string
+	}{
+		{
+			name:     "MateInOne",
+			fen:      "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+			solution: []string{"Qxf7#"},
+			eval:     chess.Score{Mate: 1},
+			want:     []string{"opening", "mate", "mateIn1", "oneMove"},
+		},
+		{
+			name:     "EndgamePromotion",
+			fen:      "8/5P1k/8/8/8/8/6K1/8 w - - 0 60",
+			solution: []string{"f8=Q", "Kg6", "Qf4"},
+			eval:     chess.Score{Centipawns: 900},
+			want:     []string{"endgame", "crushing", "promotion", "short"},
+		},
+		{
+			name:     "BlackAdvantage",
+			fen:      "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 2 20",
+			solution: []string{"Nf6", "Nc3", "Bb4", "d3", "d6"},
+			eval:     chess.Score{Centipawns: -150},
+			want:     []string{"middlegame", "advantage", "long"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			position, err := chess.ParseFen(tc.fen)
+			if err != nil {
+				t.Fatalf("ParseFen got error: %v", err)
+			}
+			solution, err := sanLineToMoves(position, tc.solution)
+			if err != nil {
+				t.Fatalf("sanLineToMoves got error: %v", err)
+			}
+			if got := GuessPuzzleThemes(position, solution, tc.eval); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GuessPuzzleThemes got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
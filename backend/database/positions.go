@@ -0,0 +1,395 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+var positionTable = stage + "-positions"
+
+// The maximum number of sample games returned in a PositionSummary.
+const positionSummaryGames = 20
+
+type PositionResult string
+
+const (
+	PositionResult_White    PositionResult = "white"
+	PositionResult_Black    PositionResult = "black"
+	PositionResult_Draws    PositionResult = "draws"
+	PositionResult_Analysis PositionResult = "analysis"
+)
+
+// positionResult returns the PositionResult matching the given PGN game result. Positions
+// which are not in the mainline of the game, or games without a decisive result or draw,
+// are counted as analysis.
+func positionResult(result string, mainline bool) PositionResult {
+	if !mainline {
+		return PositionResult_Analysis
+	}
+	switch result {
+	case "1-0":
+		return PositionResult_White
+	case "0-1":
+		return PositionResult_Black
+	case "1/2-1/2":
+		return PositionResult_Draws
+	}
+	return PositionResult_Analysis
+}
+
+type PositionMove struct {
+	// The SAN of the move played from the position.
+	San string `dynamodbav:"san" json:"san"`
+
+	// Whether the move was played in the mainline of the game.
+	Mainline bool `dynamodbav:"mainline" json:"mainline"`
+}
+
+// PositionGame is a single entry in the position index, linking a position to a game
+// in which it occurs.
+type PositionGame struct {
+	// The normalized FEN of the position. This is the hash key of the positions table.
+	NormalizedFen string `dynamodbav:"normalizedFen" json:"normalizedFen"`
+
+	// The id of the entry, in the form cohort#gameId. This is the range key of the
+	// positions table.
+	Id string `dynamodbav:"id" json:"-"`
+
+	// The entry's position in date order, in the form date#cohort#gameId. This is the
+	// range key of the DateIdx.
+	DateId string `dynamodbav:"dateId" json:"-"`
+
+	// The entry's position in date order within its cohort, in the form
+	// cohort#date#gameId. This is the range key of the CohortDateIdx.
+	CohortDateId string `dynamodbav:"cohortDateId" json:"-"`
+
+	// The cohort of the game.
+	Cohort DojoCohort `dynamodbav:"cohort" json:"cohort"`
+
+	// The id of the game.
+	GameId string `dynamodbav:"gameId" json:"gameId"`
+
+	// The username of the owner of the game.
+	Owner string `dynamodbav:"owner" json:"owner"`
+
+	// The display name of the owner of the game.
+	OwnerDisplayName string `dynamodbav:"ownerDisplayName" json:"ownerDisplayName"`
+
+	// The player with the white pieces.
+	White string `dynamodbav:"white" json:"white"`
+
+	// The player with the black pieces.
+	Black string `dynamodbav:"black" json:"black"`
+
+	// The date the game was played, in the form 2023.01.02.
+	Date string `dynamodbav:"date" json:"date"`
+
+	// The result of the game from the perspective of this position.
+	Result PositionResult `dynamodbav:"result" json:"result"`
+
+	// Whether the position occurs in the mainline of the game.
+	Mainline bool `dynamodbav:"mainline" json:"mainline"`
+
+	// The moves played from this position in the game, including variations.
+	Moves []PositionMove `dynamodbav:"moves" json:"moves"`
+}
+
+// The date used to sort index entries for games without a date. Sorts after every valid
+// date when listing in descending order.
+const unknownIndexDate = "0000.00.00"
+
+// positionGameId returns the range key of a PositionGame for the given game.
+func positionGameId(cohort DojoCohort, gameId string) string {
+	return fmt.Sprintf("%s#%s", cohort, gameId)
+}
+
+// indexDate returns the date used to sort index entries, such as a PositionGame, for a
+// game played on the given date.
+func indexDate(date string) string {
+	if date == "" {
+		return unknownIndexDate
+	}
+	return date
+}
+
+// ExtractPositionGames returns the position index entries for the given game and its
+// parsed PGN. Every position in the game, including those only reached in variations,
+// produces exactly one entry.
+func ExtractPositionGames(game *Game, parsed *chess.Game) []PositionGame {
+	entries := make(map[string]*PositionGame)
+	var order []string
+
+	add := func(n *chess.Node, mainline bool) {
+		fen := n.Position.NormalizedFen()
+		entry, ok := entries[fen]
+		if !ok {
+			entry = &PositionGame{
+				NormalizedFen:    fen,
+				Id:               positionGameId(game.Cohort, game.Id),
+				DateId:           fmt.Sprintf("%s#%s#%s", indexDate(game.Date), game.Cohort, game.Id),
+				CohortDateId:     fmt.Sprintf("%s#%s#%s", game.Cohort, indexDate(game.Date), game.Id),
+				Cohort:           game.Cohort,
+				GameId:           game.Id,
+				Owner:            game.Owner,
+				OwnerDisplayName: game.OwnerDisplayName,
+				White:            game.White,
+				Black:            game.Black,
+				Date:             game.Date,
+			}
+			entries[fen] = entry
+			order = append(order, fen)
+		}
+		entry.Mainline = entry.Mainline || mainline
+
+	children:
+		for _, c := range n.Children {
+			childMainline := mainline && c == n.Children[0]
+			for i := range entry.Moves {
+				if entry.Moves[i].San == c.San {
+					entry.Moves[i].Mainline = entry.Moves[i].Mainline || childMainline
+					continue children
+				}
+			}
+			entry.Moves = append(entry.Moves, PositionMove{San: c.San, Mainline: childMainline})
+		}
+	}
+
+	add(parsed.Root, true)
+	parsed.Walk(func(n *chess.Node) {
+		add(n, n.IsMainline())
+	})
+
+	result := make([]PositionGame, 0, len(order))
+	for _, fen := range order {
+		entry := entries[fen]
+		entry.Result = positionResult(parsed.Result, entry.Mainline)
+		result = append(result, *entry)
+	}
+	return result
+}
+
+// PositionResults contains the number of games with each result.
+type PositionResults struct {
+	White    int `json:"white"`
+	Black    int `json:"black"`
+	Draws    int `json:"draws"`
+	Analysis int `json:"analysis"`
+}
+
+// add increments the count of the given result.
+func (r *PositionResults) add(result PositionResult) {
+	switch result {
+	case PositionResult_White:
+		r.White++
+	case PositionResult_Black:
+		r.Black++
+	case PositionResult_Draws:
+		r.Draws++
+	default:
+		r.Analysis++
+	}
+}
+
+// Total returns the total number of games counted in the results.
+func (r PositionResults) Total() int {
+	return r.White + r.Black + r.Draws + r.Analysis
+}
+
+type PositionMoveSummary struct {
+	// The SAN of the move.
+	San string `json:"san"`
+
+	// The results of the games in which the move was played.
+	Results PositionResults `json:"results"`
+}
+
+// PositionSummary contains the aggregated statistics of a position over the games
+// in which it occurs.
+type PositionSummary struct {
+	// The normalized FEN of the position.
+	NormalizedFen string `json:"normalizedFen"`
+
+	// The results of the games containing the position.
+	Results PositionResults `json:"results"`
+
+	// The moves played from the position, ordered by frequency.
+	Moves []PositionMoveSummary `json:"moves"`
+
+	// The most recent games containing the position.
+	Games []PositionGame `json:"games"`
+
+	// The number of games included in the summary.
+	SampleSize int `json:"sampleSize"`
+
+	// Whether the position may occur in more games than were included in the summary. If
+	// true, the results and moves are counted only over the SampleSize most recent games
+	// and are not the totals of the position.
+	Sampled bool `json:"sampled"`
+
+	// The startKey of the request which summarizes the next, older games containing the
+	// position. Only present if Sampled is true.
+	LastEvaluatedKey string `json:"lastEvaluatedKey,omitempty"`
+}
+
+// SummarizePosition aggregates the given position index entries into a PositionSummary.
+// The entries are expected to be sorted by date in descending order. lastKey is the key
+// of the next entries, if the position occurs in more games than the given entries. A
+// move played in a variation of a game is counted as analysis, even if the position itself
+// was reached in the mainline.
+func SummarizePosition(fen string, entries []PositionGame, lastKey string) *PositionSummary {
+	summary := &PositionSummary{
+		NormalizedFen:    fen,
+		Moves:            make([]PositionMoveSummary, 0),
+		Games:            make([]PositionGame, 0, positionSummaryGames),
+		SampleSize:       len(entries),
+		Sampled:          lastKey != "",
+		LastEvaluatedKey: lastKey,
+	}
+
+	moves := make(map[string]*PositionResults)
+	var order []string
+
+	for _, entry := range entries {
+		summary.Results.add(entry.Result)
+		if len(summary.Games) < positionSummaryGames {
+			summary.Games = append(summary.Games, entry)
+		}
+
+		for _, move := range entry.Moves {
+			results, ok := moves[move.San]
+			if !ok {
+				results = &PositionResults{}
+				moves[move.San] = results
+				order = append(order, move.San)
+			}
+			if move.Mainline {
+				results.add(entry.Result)
+			} else {
+				results.add(PositionResult_Analysis)
+			}
+		}
+	}
+
+	for _, san := range order {
+		summary.Moves = append(summary.Moves, PositionMoveSummary{San: san, Results: *moves[san]})
+	}
+	sort.SliceStable(summary.Moves, func(i, j int) bool {
+		return summary.Moves[i].Results.Total() > summary.Moves[j].Results.Total()
+	})
+	return summary
+}
+
+type PositionIndexer interface {
+	// PutPositionGames inserts the provided position index entries into the database.
+	PutPositionGames(entries []PositionGame) (int, error)
+
+	// DeletePositionGames removes the provided position index entries from the database.
+	DeletePositionGames(entries []PositionGame) error
+}
+
+type PositionLister interface {
+	// ListPositionGames returns up to limit position index entries for the given normalized FEN,
+	// sorted by date in descending order and starting at startKey. cohort, startDate and
+	// endDate are optional filters, with the dates applying to the date the game was played.
+	// The returned key is the startKey of the next entries, or empty if there are none.
+	ListPositionGames(fen string, cohort DojoCohort, startDate, endDate, startKey string, limit int) ([]PositionGame, string, error)
+}
+
+// PutPositionGames inserts the provided position index entries into the database.
+func (repo *dynamoRepository) PutPositionGames(entries []PositionGame) (int, error) {
+	return batchWriteObjects(repo, entries, positionTable)
+}
+
+// DeletePositionGames removes the provided position index entries from the database.
+func (repo *dynamoRepository) DeletePositionGames(entries []PositionGame) error {
+	var reqs []*dynamodb.WriteRequest
+	for _, entry := range entries {
+		reqs = append(reqs, &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{
+				Key: map[string]*dynamodb.AttributeValue{
+					"normalizedFen": {S: aws.String(entry.NormalizedFen)},
+					"id":            {S: aws.String(entry.Id)},
+				},
+			},
+		})
+
+		if len(reqs) == 25 {
+			if err := repo.batchWrite(reqs, positionTable); err != nil {
+				return err
+			}
+			reqs = nil
+		}
+	}
+
+	if len(reqs) > 0 {
+		return repo.batchWrite(reqs, positionTable)
+	}
+	return nil
+}
+
+// ListPositionGames returns up to limit position index entries for the given normalized FEN,
+// sorted by date in descending order and starting at startKey. cohort, startDate and
+// endDate are optional filters. The dates filter on the date the game was played, in the
+// form 2023.01.02. The returned key is the startKey of the next entries, or empty if there
+// are none.
+func (repo *dynamoRepository) ListPositionGames(fen string, cohort DojoCohort, startDate, endDate, startKey string, limit int) ([]PositionGame, string, error) {
+	indexName, rangeKey, prefix := "DateIdx", "dateId", ""
+	if cohort != "" {
+		indexName, rangeKey, prefix = "CohortDateIdx", "cohortDateId", string(cohort)+"#"
+	}
+
+	keyConditionExpression := "#fen = :fen"
+	expressionAttributeNames := map[string]*string{
+		"#fen": aws.String("normalizedFen"),
+	}
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":fen": {S: aws.String(fen)},
+	}
+
+	if prefix != "" || startDate != "" || endDate != "" {
+		// Range keys continue with # after the date, which sorts before ~
+		if startDate == "" {
+			startDate = "0"
+		}
+		if endDate == "" {
+			endDate = "~"
+		} else {
+			endDate += "#~"
+		}
+		keyConditionExpression += " AND #range BETWEEN :start AND :end"
+		expressionAttributeNames["#range"] = aws.String(rangeKey)
+		expressionAttributeValues[":start"] = &dynamodb.AttributeValue{S: aws.String(prefix + startDate)}
+		expressionAttributeValues[":end"] = &dynamodb.AttributeValue{S: aws.String(prefix + endDate)}
+	}
+
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String(keyConditionExpression),
+		ExpressionAttributeNames:  expressionAttributeNames,
+		ExpressionAttributeValues: expressionAttributeValues,
+		IndexName:                 aws.String(indexName),
+		ScanIndexForward:          aws.Bool(false),
+		TableName:                 aws.String(positionTable),
+	}
+
+	var entries []PositionGame
+	for {
+		// The limit of the last page is reduced so that its key is exactly after limit entries
+		input.Limit = aws.Int64(int64(limit - len(entries)))
+		var page []PositionGame
+		lastKey, err := repo.query(input, startKey, &page)
+		if err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, page...)
+
+		startKey = lastKey
+		if len(entries) >= limit || lastKey == "" {
+			break
+		}
+	}
+	return entries, startKey, nil
+}
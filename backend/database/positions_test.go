@@ -0,0 +1,112 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestExtractPositionGames(t *testing.T) {
+	game := &Game{
+		Cohort: "1500-1600",
+		Id:     "2024.01.02_abc",
+		Date:   "2024.01.02",
+		Pgn:    "[Result \"1-0\"]\n\n1. e4 e5 (1... c5 2. Nf3) 2. Nf3 Nc6 (2... Nf6) 1-0",
+	}
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		t.Fatalf("ParsePgn got error: %v", err)
+	}
+
+	entries := ExtractPositionGames(game, parsed)
+	byFen := make(map[string]PositionGame)
+	for _, entry := range entries {
+		if _, ok := byFen[entry.NormalizedFen]; ok {
+			t.Errorf("ExtractPositionGames got duplicate entry for %s", entry.NormalizedFen)
+		}
+		byFen[entry.NormalizedFen] = entry
+		if entry.Id != "1500-1600#2024.01.02_abc" {
+			t.Errorf("Entry id got: %s; want: 1500-1600#2024.01.02_abc", entry.Id)
+		}
+		if entry.DateId != "2024.01.02#1500-1600#2024.01.02_abc" {
+			t.Errorf("Entry dateId got: %s; want: 2024.01.02#1500-1600#2024.01.02_abc", entry.DateId)
+		}
+		if entry.CohortDateId != "1500-1600#2024.01.02#2024.01.02_abc" {
+			t.Errorf("Entry cohortDateId got: %s; want: 1500-1600#2024.01.02#2024.01.02_abc", entry.CohortDateId)
+		}
+	}
+
+	// Starting position, e4, e5, c5, Nf3 (after e5), Nf3 (after c5), Nc6, Nf6
+	if len(entries) != 8 {
+		t.Errorf("ExtractPositionGames got %d entries; want 8", len(entries))
+	}
+
+	start := byFen[chess.StartingFen]
+	if !start.Mainline || start.Result != PositionResult_White {
+		t.Errorf("Starting position got mainline %t, result %s; want true, white", start.Mainline, start.Result)
+	}
+
+	afterE4 := byFen["rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"]
+	want := []PositionMove{{San: "e5", Mainline: true}, {San: "c5", Mainline: false}}
+	if len(afterE4.Moves) != len(want) {
+		t.Fatalf("After e4 moves got: %v; want: %v", afterE4.Moves, want)
+	}
+	for i := range want {
+		if afterE4.Moves[i] != want[i] {
+			t.Errorf("After e4 moves[%d] got: %v; want: %v", i, afterE4.Moves[i], want[i])
+		}
+	}
+
+	afterC5 := byFen["rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1"]
+	if afterC5.Mainline || afterC5.Result != PositionResult_Analysis {
+		t.Errorf("After c5 got mainline %t, result %s; want false, analysis", afterC5.Mainline, afterC5.Result)
+	}
+}
+
+func TestSummarizePosition(t *testing.T) {
+	entries := []PositionGame{
+		{
+			GameId: "3",
+			Result: PositionResult_White,
+			Moves:  []PositionMove{{San: "e4", Mainline: true}, {San: "d4"}},
+		},
+		{
+			GameId: "2",
+			Result: PositionResult_Draws,
+			Moves:  []PositionMove{{San: "d4", Mainline: true}},
+		},
+		{
+			GameId: "1",
+			Result: PositionResult_Black,
+			Moves:  []PositionMove{{San: "d4", Mainline: true}},
+		},
+	}
+
+	summary := SummarizePosition(chess.StartingFen, entries, "next")
+
+	if summary.SampleSize != 3 || !summary.Sampled || summary.LastEvaluatedKey != "next" {
+		t.Errorf("Sample got: (%d, %t, %q); want: (3, true, next)", summary.SampleSize, summary.Sampled, summary.LastEvaluatedKey)
+	}
+
+	wantResults := PositionResults{White: 1, Black: 1, Draws: 1}
+	if summary.Results != wantResults {
+		t.Errorf("Results got: %+v; want: %+v", summary.Results, wantResults)
+	}
+
+	wantMoves := []PositionMoveSummary{
+		{San: "d4", Results: PositionResults{Black: 1, Draws: 1, Analysis: 1}},
+		{San: "e4", Results: PositionResults{White: 1}},
+	}
+	if len(summary.Moves) != len(wantMoves) {
+		t.Fatalf("Moves got: %+v; want: %+v", summary.Moves, wantMoves)
+	}
+	for i := range wantMoves {
+		if summary.Moves[i] != wantMoves[i] {
+			t.Errorf("Moves[%d] got: %+v; want: %+v", i, summary.Moves[i], wantMoves[i])
+		}
+	}
+
+	if len(summary.Games) != 3 || summary.Games[0].GameId != "3" {
+		t.Errorf("Games got: %+v; want all 3 games in order", summary.Games)
+	}
+}
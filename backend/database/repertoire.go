@@ -0,0 +1,258 @@
+package database
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// The max number of example games kept for each deviation in a RepertoireReport.
+const maxDeviationExamples = 5
+
+// Repertoire is the set of moves prepared by a member for one color, keyed by the
+// normalized FEN of the position they are played from. Since moves are keyed by position,
+// games which reach a prepared position by a different move order stay in the repertoire.
+type Repertoire struct {
+	// The color the repertoire is played with.
+	Color chess.Color
+
+	// The prepared moves, mapped from normalized FEN to UCI to SAN.
+	moves map[string]map[string]string
+}
+
+// NewRepertoire returns an empty repertoire for the given color.
+func NewRepertoire(color chess.Color) *Repertoire {
+	return &Repertoire{Color: color, moves: make(map[string]map[string]string)}
+}
+
+// IsEmpty returns true if the repertoire has no prepared moves.
+func (r *Repertoire) IsEmpty() bool {
+	return len(r.moves) == 0
+}
+
+// AddPgn adds the moves of the given PGN to the repertoire, including its variations.
+func (r *Repertoire) AddPgn(pgn string) error {
+	parsed, err := chess.Parse(pgn)
+	if err != nil {
+		return err
+	}
+	parsed.Walk(func(n *chess.Node) {
+		fen := n.Parent.Position.NormalizedFen()
+		if r.moves[fen] == nil {
+			r.moves[fen] = make(map[string]string)
+		}
+		r.moves[fen][n.Move.UCI()] = n.San
+	})
+	return nil
+}
+
+// prepared returns the SAN of the moves prepared in the given position, in sorted order.
+func (r *Repertoire) prepared(fen string) []string {
+	sans := make([]string, 0, len(r.moves[fen]))
+	for _, san := range r.moves[fen] {
+		sans = append(sans, san)
+	}
+	sort.Strings(sans)
+	return sans
+}
+
+// RepertoireDeviation is the first move of a game which left the prepared repertoire.
+type RepertoireDeviation struct {
+	// The normalized FEN of the position the deviation was played from.
+	Fen string `json:"fen"`
+
+	// The ply of the deviating move.
+	Ply int `json:"ply"`
+
+	// The SAN of the moves played before the deviation.
+	Line []string `json:"line"`
+
+	// The SAN of the deviating move.
+	Played string `json:"played"`
+
+	// The SAN of the moves prepared in the position.
+	Prepared []string `json:"prepared"`
+
+	// Whether the member played the deviating move, rather than their opponent.
+	ByMember bool `json:"byMember"`
+}
+
+// FindDeviation returns the first mainline move of the given game which is not prepared in
+// the repertoire, and the number of plies played within the repertoire before it. If the
+// game reaches a position with no prepared moves, the prepared line was completed and nil
+// is returned. The repertoire's color is assumed to be played by the member.
+func (r *Repertoire) FindDeviation(parsed *chess.Game) (*RepertoireDeviation, int) {
+	mainline := parsed.Mainline()
+	var line []string
+
+	for i, n := range mainline {
+		position := n.Parent.Position
+		fen := position.NormalizedFen()
+		prepared := r.moves[fen]
+		if len(prepared) == 0 {
+			return nil, i
+		}
+		if _, ok := prepared[n.Move.UCI()]; ok {
+			line = append(line, n.San)
+			continue
+		}
+		return &RepertoireDeviation{
+			Fen:      fen,
+			Ply:      n.Ply,
+			Line:     line,
+			Played:   n.San,
+			Prepared: r.prepared(fen),
+			ByMember: position.Turn == r.Color,
+		}, i
+	}
+	return nil, len(mainline)
+}
+
+// RepertoireDeviationCount is a deviation from the repertoire shared by several games.
+type RepertoireDeviationCount struct {
+	RepertoireDeviation
+
+	// The color of the repertoire which was left.
+	Color PlayerColor `json:"color"`
+
+	// The number of games with the deviation.
+	Count int `json:"count"`
+
+	// Some of the games with the deviation.
+	Games []GameKey `json:"games"`
+}
+
+// RepertoireReport compares a member's games to the opening repertoire they studied.
+type RepertoireReport struct {
+	// The number of games played with a color the member has a repertoire for.
+	GamesAnalyzed int `json:"gamesAnalyzed"`
+
+	// The number of games in which the member left the repertoire first.
+	MemberDeviations int `json:"memberDeviations"`
+
+	// The number of games in which the opponent left the repertoire first.
+	OpponentDeviations int `json:"opponentDeviations"`
+
+	// The number of games which reached the end of a prepared line, or ended while still
+	// within the repertoire.
+	CompletedLines int `json:"completedLines"`
+
+	// The average number of plies played within the repertoire per game.
+	AveragePliesInRepertoire float64 `json:"averagePliesInRepertoire"`
+
+	// The deviations, ordered by the number of games with them.
+	Deviations []RepertoireDeviationCount `json:"deviations"`
+}
+
+// BuildRepertoires returns the white and black repertoires of the opening modules the
+// member has progress on in the given courses. A module's color is the course's color or,
+// for courses without one, the module's board orientation. Modules whose color is unknown
+// and PGNs which cannot be parsed are skipped.
+func BuildRepertoires(courses []*Course, progress map[string]*UserOpeningModule) (*Repertoire, *Repertoire) {
+	white, black := NewRepertoire(chess.White), NewRepertoire(chess.Black)
+
+	for _, course := range courses {
+		for _, chapter := range course.Chapters {
+			for _, module := range chapter.Modules {
+				if _, ok := progress[module.Id]; !ok || module.Id == "" {
+					continue
+				}
+
+				var repertoire *Repertoire
+				switch {
+				case course.Color == CourseColor_White:
+					repertoire = white
+				case course.Color == CourseColor_Black:
+					repertoire = black
+				case strings.EqualFold(module.BoardOrientation, "white"):
+					repertoire = white
+				case strings.EqualFold(module.BoardOrientation, "black"):
+					repertoire = black
+				default:
+					continue
+				}
+
+				for _, pgn := range module.Pgns {
+					repertoire.AddPgn(pgn)
+				}
+			}
+		}
+	}
+	return white, black
+}
+
+// BuildRepertoireReport returns the RepertoireReport of the given games. Each game's
+// orientation is used as the color played by the member. Games which never enter the
+// repertoire for their color and games which cannot be parsed are skipped.
+func BuildRepertoireReport(white, black *Repertoire, games []*Game) *RepertoireReport {
+	report := &RepertoireReport{Deviations: []RepertoireDeviationCount{}}
+	counts := make(map[string]*RepertoireDeviationCount)
+	totalPlies := 0
+
+	for _, game := range games {
+		repertoire := white
+		if game.Orientation == string(Black) {
+			repertoire = black
+		}
+		if repertoire.IsEmpty() {
+			continue
+		}
+
+		parsed, err := game.ParsePgn()
+		if err != nil {
+			continue
+		}
+
+		deviation, plies := repertoire.FindDeviation(parsed)
+		if deviation == nil && plies == 0 {
+			// The starting position is not in the repertoire, such as for games from a
+			// custom position
+			continue
+		}
+		report.GamesAnalyzed++
+		totalPlies += plies
+		if deviation == nil {
+			report.CompletedLines++
+			continue
+		}
+		if deviation.ByMember {
+			report.MemberDeviations++
+		} else {
+			report.OpponentDeviations++
+		}
+
+		color := White
+		if repertoire.Color == chess.Black {
+			color = Black
+		}
+		key := deviation.Fen + " " + deviation.Played
+		count, ok := counts[key]
+		if !ok {
+			count = &RepertoireDeviationCount{RepertoireDeviation: *deviation, Color: color}
+			counts[key] = count
+		}
+		count.Count++
+		if len(count.Games) < maxDeviationExamples {
+			count.Games = append(count.Games, GameKey{Cohort: game.Cohort, Id: game.Id})
+		}
+	}
+
+	if report.GamesAnalyzed > 0 {
+		report.AveragePliesInRepertoire = float64(totalPlies) / float64(report.GamesAnalyzed)
+	}
+	for _, count := range counts {
+		report.Deviations = append(report.Deviations, *count)
+	}
+	sort.Slice(report.Deviations, func(i, j int) bool {
+		a, b := report.Deviations[i], report.Deviations[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Ply != b.Ply {
+			return a.Ply < b.Ply
+		}
+		return a.Played < b.Played
+	})
+	return report
+}
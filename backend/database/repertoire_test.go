@@ -0,0 +1,97 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestBuildRepertoires(t *testing.T) {
+	courses := []*Course{
+		{
+			Color: CourseColor_White,
+			Chapters: []*Chapter{{Modules: []*CourseModule{
+				{Id: "studied", Pgns: []string{"1. e4 e5 2. Nf3 *"}},
+				{Id: "unstudied", Pgns: []string{"1. d4 *"}},
+			}}},
+		},
+		{
+			Color: CourseColor_None,
+			Chapters: []*Chapter{{Modules: []*CourseModule{
+				{Id: "black", BoardOrientation: "black", Pgns: []string{"1. e4 c5 *", "not a pgn"}},
+				{Id: "unknown", Pgns: []string{"1. c4 *"}},
+			}}},
+		},
+	}
+	progress := map[string]*UserOpeningModule{"studied": {}, "black": {}, "unknown": {}}
+
+	white, black := BuildRepertoires(courses, progress)
+	if got := white.prepared(chess.NewPosition().NormalizedFen()); !reflect.DeepEqual(got, []string{"e4"}) {
+		t.Errorf("white prepared moves got: %v; want: [e4]", got)
+	}
+	if got := black.prepared(chess.NewPosition().NormalizedFen()); !reflect.DeepEqual(got, []string{"e4"}) {
+		t.Errorf("black prepared moves got: %v; want: [e4]", got)
+	}
+	if len(black.moves) != 2 {
+		t.Errorf("black repertoire got %d positions; want 2", len(black.moves))
+	}
+}
+
+func TestBuildRepertoireReport(t *testing.T) {
+	white := NewRepertoire(chess.White)
+	if err := white.AddPgn("1. e4 e5 2. Nf3 Nc6 (2... d6 3. d4) 3. Bb5 (3. Bc4) *"); err != nil {
+		t.Fatalf("AddPgn got error: %v", err)
+	}
+	black := NewRepertoire(chess.Black)
+
+	games := []*Game{
+		{Cohort: "1500-1600", Id: "1", Orientation: "white", Pgn: "1. e4 e5 2. Nf3 Nc6 3. Nc3 *"},
+		{Cohort: "1500-1600", Id: "2", Orientation: "white", Pgn: "1. e4 e5 2. Nf3 Nc6 3. Nc3 Nf6 *"},
+		{Cohort: "1500-1600", Id: "3", Orientation: "white", Pgn: "1. e4 c5 *"},
+		{Cohort: "1500-1600", Id: "4", Orientation: "white", Pgn: "1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 *"},
+		{Cohort: "1500-1600", Id: "5", Orientation: "black", Pgn: "1. e4 e5 *"},
+		{Cohort: "1500-1600", Id: "6", Orientation: "white", Pgn: "[SetUp \"1\"]\n[FEN \"4k3/8/8/8/8/8/8/4K2R w K - 0 1\"]\n\n1. O-O *"},
+	}
+
+	report := BuildRepertoireReport(white, black, games)
+
+	if report.GamesAnalyzed != 4 || report.MemberDeviations != 2 || report.OpponentDeviations != 1 || report.CompletedLines != 1 {
+		t.Errorf("BuildRepertoireReport got counts: %+v; want 4 analyzed, 2 member, 1 opponent, 1 completed", report)
+	}
+	if report.AveragePliesInRepertoire != 3.5 {
+		t.Errorf("AveragePliesInRepertoire got: %v; want: 3.5", report.AveragePliesInRepertoire)
+	}
+
+	want := []RepertoireDeviationCount{
+		{
+			RepertoireDeviation: RepertoireDeviation{
+				Fen:      "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 1",
+				Ply:      5,
+				Line:     []string{"e4", "e5", "Nf3", "Nc6"},
+				Played:   "Nc3",
+				Prepared: []string{"Bb5", "Bc4"},
+				ByMember: true,
+			},
+			Color: White,
+			Count: 2,
+			Games: []GameKey{{Cohort: "1500-1600", Id: "1"}, {Cohort: "1500-1600", Id: "2"}},
+		},
+		{
+			RepertoireDeviation: RepertoireDeviation{
+				Fen:      "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+				Ply:      2,
+				Line:     []string{"e4"},
+				Played:   "c5",
+				Prepared: []string{"e5"},
+				ByMember: false,
+			},
+			Color: White,
+			Count: 1,
+			Games: []GameKey{{Cohort: "1500-1600", Id: "3"}},
+		},
+	}
+	if !reflect.DeepEqual(report.Deviations, want) {
+		t.Errorf("Deviations got: %+v; want: %+v", report.Deviations, want)
+	}
+}
@@ -52,6 +52,8 @@ const gameTableWhiteIndex = "WhiteIndex"
 const gameTableBlackIndex = "BlackIndex"
 const gameTableFeaturedIndex = "FeaturedIndex"
 const gameTableReviewIndex = "ReviewIndex"
+const gameTableFingerprintIndex = "FingerprintIdx"
+const gameTableUpdatedIndex = "UpdatedIdx"
 
 const tournamentTableOpenClassicalIndex = "OpenClassicalIndex"
 
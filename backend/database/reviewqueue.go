@@ -0,0 +1,289 @@
+package database
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// The time after which a claim on a game in the review queue expires, allowing other
+// reviewers to claim it.
+const GameReviewClaimDuration = 24 * time.Hour
+
+// The time after ReviewRequestedAt by which each type of review should be completed. Set
+// in days by the quickGameReviewDeadlineDays and deepGameReviewDeadlineDays environment
+// variables, defaulting to one week.
+var gameReviewDeadlines = map[GameReviewType]time.Duration{
+	GameReviewType_Quick:    getReviewDeadline("quickGameReviewDeadlineDays"),
+	GameReviewType_DeepDive: getReviewDeadline("deepGameReviewDeadlineDays"),
+}
+
+// getReviewDeadline returns the number of days in the given environment variable as a
+// duration. One week is returned if the variable is unset or invalid.
+func getReviewDeadline(env string) time.Duration {
+	days, err := strconv.Atoi(os.Getenv(env))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// The order in which review types are prioritized within the review queue. Lower values
+// are reviewed first.
+var gameReviewTypePriority = map[GameReviewType]int{
+	GameReviewType_DeepDive: 0,
+	GameReviewType_Quick:    1,
+}
+
+// GameReviewDeadline returns the time by which a review of the given type requested at the
+// given time should be completed.
+func GameReviewDeadline(reviewType GameReviewType, requestedAt time.Time) time.Time {
+	deadline, ok := gameReviewDeadlines[reviewType]
+	if !ok {
+		deadline = gameReviewDeadlines[GameReviewType_Quick]
+	}
+	return requestedAt.Add(deadline)
+}
+
+// ReviewDeadline returns the time by which the game's requested review should be
+// completed. False is returned if the game has no valid ReviewRequestedAt.
+func (g *Game) ReviewDeadline() (time.Time, bool) {
+	requestedAt, err := time.Parse(time.RFC3339, g.ReviewRequestedAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var reviewType GameReviewType
+	if g.Review != nil {
+		reviewType = g.Review.Type
+	}
+	return GameReviewDeadline(reviewType, requestedAt), true
+}
+
+// ActiveClaim returns the reviewer who has claimed the review, if the claim has not
+// expired at the given time. Nil is returned if there is no active claim.
+func (r *GameReview) ActiveClaim(now time.Time) *Reviewer {
+	if r == nil || r.ClaimedBy == nil {
+		return nil
+	}
+	expiresAt, err := time.Parse(time.RFC3339, r.ClaimExpiresAt)
+	if err != nil || !now.Before(expiresAt) {
+		return nil
+	}
+	return r.ClaimedBy
+}
+
+// ReviewQueueEntry is a game in the review queue, along with its deadline and claim.
+type ReviewQueueEntry struct {
+	// The game waiting for review. Contains only the fields projected onto the review index.
+	Game Game `json:"game"`
+
+	// The time the review should be completed by in time.RFC3339 format. Empty if the
+	// game has no valid ReviewRequestedAt.
+	Deadline string `json:"deadline,omitempty"`
+
+	// Whether the deadline has passed.
+	Overdue bool `json:"overdue"`
+
+	// The reviewer who currently has the game claimed. Nil if the game is unclaimed or
+	// the claim expired.
+	ClaimedBy *Reviewer `json:"claimedBy,omitempty"`
+}
+
+// NewReviewQueue returns the given pending games in the order they should be reviewed at
+// the given time. Overdue games come first, ordered by how long they are overdue.
+// Remaining games are ordered by review type, with deep dives first, and then by the
+// time the review was requested.
+func NewReviewQueue(games []Game, now time.Time) []ReviewQueueEntry {
+	queue := make([]ReviewQueueEntry, 0, len(games))
+	deadlines := make(map[string]time.Time, len(games))
+	for _, game := range games {
+		entry := ReviewQueueEntry{Game: game, ClaimedBy: game.Review.ActiveClaim(now)}
+		if deadline, ok := game.ReviewDeadline(); ok {
+			entry.Deadline = deadline.Format(time.RFC3339)
+			entry.Overdue = now.After(deadline)
+			deadlines[game.Id] = deadline
+		}
+		queue = append(queue, entry)
+	}
+
+	slices.SortStableFunc(queue, func(a, b ReviewQueueEntry) int {
+		if a.Overdue != b.Overdue {
+			if a.Overdue {
+				return -1
+			}
+			return 1
+		}
+		if a.Overdue {
+			return deadlines[a.Game.Id].Compare(deadlines[b.Game.Id])
+		}
+		if p, q := reviewTypePriority(&a.Game), reviewTypePriority(&b.Game); p != q {
+			return p - q
+		}
+		return strings.Compare(a.Game.ReviewRequestedAt, b.Game.ReviewRequestedAt)
+	})
+	return queue
+}
+
+// reviewTypePriority returns the priority of the game's review type within the review
+// queue. Lower values are reviewed first.
+func reviewTypePriority(g *Game) int {
+	if g.Review != nil {
+		if priority, ok := gameReviewTypePriority[g.Review.Type]; ok {
+			return priority
+		}
+	}
+	return len(gameReviewTypePriority)
+}
+
+// ReviewerWorkload summarizes the games a reviewer currently has claimed.
+type ReviewerWorkload struct {
+	// The reviewer.
+	Reviewer Reviewer `json:"reviewer"`
+
+	// The number of games the reviewer has claimed.
+	Claimed int `json:"claimed"`
+
+	// The number of claimed games which are overdue.
+	Overdue int `json:"overdue"`
+}
+
+// GetReviewerWorkloads returns the workload of every reviewer with an active claim in the
+// given queue, ordered by the number of claimed games in descending order.
+func GetReviewerWorkloads(queue []ReviewQueueEntry) []ReviewerWorkload {
+	var workloads []ReviewerWorkload
+	for _, entry := range queue {
+		if entry.ClaimedBy == nil {
+			continue
+		}
+
+		i := slices.IndexFunc(workloads, func(w ReviewerWorkload) bool {
+			return w.Reviewer.Username == entry.ClaimedBy.Username
+		})
+		if i < 0 {
+			workloads = append(workloads, ReviewerWorkload{Reviewer: *entry.ClaimedBy})
+			i = len(workloads) - 1
+		}
+		workloads[i].Claimed++
+		if entry.Overdue {
+			workloads[i].Overdue++
+		}
+	}
+
+	slices.SortStableFunc(workloads, func(a, b ReviewerWorkload) int {
+		if a.Claimed != b.Claimed {
+			return b.Claimed - a.Claimed
+		}
+		return strings.Compare(a.Reviewer.Username, b.Reviewer.Username)
+	})
+	return workloads
+}
+
+type GameReviewQueuer interface {
+	UserGetter
+
+	// ListGamesForReview returns a list of games that have been submitted for review by
+	// the senseis.
+	ListGamesForReview(startKey string) ([]Game, string, error)
+
+	// LoadEngineSummaries sets the EngineSummary of the given games by reading it from the
+	// base table, as the summary is not projected onto the review index.
+	LoadEngineSummaries(games []Game) error
+}
+
+type GameReviewClaimer interface {
+	UserGetter
+
+	// ClaimGameReview claims the given game in the review queue for the given reviewer
+	// until expiresAt.
+	ClaimGameReview(reviewer *Reviewer, cohort, id string, now, expiresAt time.Time) (*Game, error)
+
+	// ReleaseGameReview removes the given reviewer's claim on the given game.
+	ReleaseGameReview(username, cohort, id string) (*Game, error)
+}
+
+// ClaimGameReview claims the given game in the review queue for the given reviewer until
+// expiresAt. A reviewer may extend their own claim. A 409 error is returned if the game is
+// not waiting for review or is claimed by another reviewer at the given time.
+func (repo *dynamoRepository) ClaimGameReview(reviewer *Reviewer, cohort, id string, now, expiresAt time.Time) (*Game, error) {
+	item, err := dynamodbattribute.MarshalMap(reviewer)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to marshal reviewer", err)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#reviewStatus = :pending AND attribute_exists(#review) AND " +
+			"(attribute_not_exists(#review.#claimedBy) OR #review.#claimExpiresAt <= :now OR #review.#claimedBy.#username = :username)"),
+		UpdateExpression: aws.String("SET #review.#claimedBy = :reviewer, #review.#claimedAt = :now, #review.#claimExpiresAt = :expiresAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#reviewStatus":   aws.String("reviewStatus"),
+			"#review":         aws.String("review"),
+			"#claimedBy":      aws.String("claimedBy"),
+			"#claimedAt":      aws.String("claimedAt"),
+			"#claimExpiresAt": aws.String("claimExpiresAt"),
+			"#username":       aws.String("username"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pending":   {S: aws.String(string(GameReviewStatus_Pending))},
+			":reviewer":  {M: item},
+			":now":       {S: aws.String(now.Format(time.RFC3339))},
+			":expiresAt": {S: aws.String(expiresAt.Format(time.RFC3339))},
+			":username":  {S: aws.String(reviewer.Username)},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(cohort)},
+			"id":     {S: aws.String(id)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+
+	game := Game{}
+	if err := repo.updateItem(input, &game); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(409, "Conflict: the game is not waiting for review or is claimed by another reviewer", "DynamoDB conditional check failed", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return &game, nil
+}
+
+// ReleaseGameReview removes the given reviewer's claim on the given game. A 409 error is
+// returned if the game is not claimed by the reviewer.
+func (repo *dynamoRepository) ReleaseGameReview(username, cohort, id string) (*Game, error) {
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#review.#claimedBy.#username = :username"),
+		UpdateExpression:    aws.String("REMOVE #review.#claimedBy, #review.#claimedAt, #review.#claimExpiresAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#review":         aws.String("review"),
+			"#claimedBy":      aws.String("claimedBy"),
+			"#claimedAt":      aws.String("claimedAt"),
+			"#claimExpiresAt": aws.String("claimExpiresAt"),
+			"#username":       aws.String("username"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(cohort)},
+			"id":     {S: aws.String(id)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+
+	game := Game{}
+	if err := repo.updateItem(input, &game); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(409, "Conflict: you have not claimed this game", "DynamoDB conditional check failed", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return &game, nil
+}
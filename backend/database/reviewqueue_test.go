@@ -0,0 +1,157 @@
+package database
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReviewDeadline(t *testing.T) {
+	requestedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	table := []struct {
+		name   string
+		game   Game
+		want   time.Time
+		wantOk bool
+	}{
+		{
+			name:   "Quick",
+			game:   Game{ReviewRequestedAt: requestedAt.Format(time.RFC3339), Review: &GameReview{Type: GameReviewType_Quick}},
+			want:   requestedAt.Add(gameReviewDeadlines[GameReviewType_Quick]),
+			wantOk: true,
+		},
+		{
+			name:   "DeepDive",
+			game:   Game{ReviewRequestedAt: requestedAt.Format(time.RFC3339), Review: &GameReview{Type: GameReviewType_DeepDive}},
+			want:   requestedAt.Add(gameReviewDeadlines[GameReviewType_DeepDive]),
+			wantOk: true,
+		},
+		{
+			name:   "MissingReview",
+			game:   Game{ReviewRequestedAt: requestedAt.Format(time.RFC3339)},
+			want:   requestedAt.Add(gameReviewDeadlines[GameReviewType_Quick]),
+			wantOk: true,
+		},
+		{
+			name: "MissingRequestedAt",
+			game: Game{Review: &GameReview{Type: GameReviewType_Quick}},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.game.ReviewDeadline()
+			if ok != tc.wantOk || !got.Equal(tc.want) {
+				t.Errorf("ReviewDeadline got: (%v, %t); want: (%v, %t)", got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestActiveClaim(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	reviewer := &Reviewer{Username: "sensei"}
+
+	table := []struct {
+		name   string
+		review *GameReview
+		want   *Reviewer
+	}{
+		{name: "NilReview"},
+		{name: "Unclaimed", review: &GameReview{}},
+		{
+			name:   "Active",
+			review: &GameReview{ClaimedBy: reviewer, ClaimExpiresAt: now.Add(time.Hour).Format(time.RFC3339)},
+			want:   reviewer,
+		},
+		{
+			name:   "Expired",
+			review: &GameReview{ClaimedBy: reviewer, ClaimExpiresAt: now.Format(time.RFC3339)},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.review.ActiveClaim(now); got != tc.want {
+				t.Errorf("ActiveClaim got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewReviewQueue(t *testing.T) {
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	game := func(id string, reviewType GameReviewType, age time.Duration) Game {
+		return Game{
+			Id:                id,
+			ReviewRequestedAt: now.Add(-age).Format(time.RFC3339),
+			Review:            &GameReview{Type: reviewType},
+		}
+	}
+	day := 24 * time.Hour
+
+	games := []Game{
+		game("quick-new", GameReviewType_Quick, day),
+		game("deep-new", GameReviewType_DeepDive, day),
+		game("quick-overdue", GameReviewType_Quick, 8*day),
+		game("quick-old", GameReviewType_Quick, 3*day),
+		game("deep-overdue", GameReviewType_DeepDive, 10*day),
+		game("deep-old", GameReviewType_DeepDive, 5*day),
+	}
+
+	queue := NewReviewQueue(games, now)
+	var got []string
+	for _, entry := range queue {
+		got = append(got, entry.Game.Id)
+	}
+	want := []string{"deep-overdue", "quick-overdue", "deep-old", "deep-new", "quick-old", "quick-new"}
+	if !slices.Equal(got, want) {
+		t.Errorf("NewReviewQueue got order: %v; want: %v", got, want)
+	}
+	if !queue[0].Overdue || !queue[1].Overdue || queue[2].Overdue {
+		t.Errorf("NewReviewQueue got overdue: %t, %t, %t; want: true, true, false", queue[0].Overdue, queue[1].Overdue, queue[2].Overdue)
+	}
+}
+
+func TestGetReviewerWorkloads(t *testing.T) {
+	alice := &Reviewer{Username: "alice"}
+	bob := &Reviewer{Username: "bob"}
+	queue := []ReviewQueueEntry{
+		{ClaimedBy: bob, Overdue: true},
+		{ClaimedBy: alice},
+		{},
+		{ClaimedBy: alice, Overdue: true},
+		{ClaimedBy: alice},
+	}
+
+	got := GetReviewerWorkloads(queue)
+	want := []ReviewerWorkload{
+		{Reviewer: *alice, Claimed: 3, Overdue: 1},
+		{Reviewer: *bob, Claimed: 1, Overdue: 1},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetReviewerWorkloads got: %v; want: %v", got, want)
+	}
+}
+
+func TestGetReviewDeadline(t *testing.T) {
+	table := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "Unset", want: 7 * 24 * time.Hour},
+		{name: "Days", value: "3", want: 3 * 24 * time.Hour},
+		{name: "Invalid", value: "three", want: 7 * 24 * time.Hour},
+		{name: "Negative", value: "-1", want: 7 * 24 * time.Hour},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("testGameReviewDeadlineDays", tc.value)
+			if got := getReviewDeadline("testGameReviewDeadlineDays"); got != tc.want {
+				t.Errorf("getReviewDeadline got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// GameReviewRefund is a partial refund of a game review which was not completed by its
+// deadline.
+type GameReviewRefund struct {
+	// The Stripe id of the refund.
+	StripeId string `dynamodbav:"stripeId" json:"-"`
+
+	// The amount refunded, in the smallest unit of Currency.
+	Amount int64 `dynamodbav:"amount" json:"amount"`
+
+	// The three-letter ISO code of the currency of the refund.
+	Currency string `dynamodbav:"currency" json:"currency"`
+
+	// The percentage of the review price that was refunded.
+	Percentage int64 `dynamodbav:"percentage" json:"percentage"`
+
+	// The deadline the review missed in time.RFC3339 format.
+	Deadline string `dynamodbav:"deadline" json:"deadline"`
+
+	// The date the refund was issued in time.RFC3339 format.
+	RefundedAt string `dynamodbav:"refundedAt" json:"refundedAt"`
+}
+
+type GameReviewRefunder interface {
+	GameGetter
+
+	// ListGamesForReview returns a list of games that have been submitted for review by
+	// the senseis.
+	ListGamesForReview(startKey string) ([]Game, string, error)
+
+	// RecordGameReviewRefund saves the given refund on the given game.
+	RecordGameReviewRefund(cohort DojoCohort, id string, refund *GameReviewRefund) error
+}
+
+// RecordGameReviewRefund saves the given refund on the given game. A 409 error is returned
+// if the game already has a refund.
+func (repo *dynamoRepository) RecordGameReviewRefund(cohort DojoCohort, id string, refund *GameReviewRefund) error {
+	item, err := dynamodbattribute.MarshalMap(refund)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed to marshal game review refund", err)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(#id) AND attribute_not_exists(#reviewRefund)"),
+		UpdateExpression:    aws.String("SET #reviewRefund = :refund"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":           aws.String("id"),
+			"#reviewRefund": aws.String("reviewRefund"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":refund": {M: item},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(cohort))},
+			"id":     {S: aws.String(id)},
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	_, err = repo.svc.UpdateItem(input)
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(409, "Conflict: the game review was already refunded", "DynamoDB conditional check failed", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+var searchTable = stage + "-game-search"
+
+// The max number of terms in a search query.
+const maxSearchTerms = 8
+
+// The max number of comment occurrences of a term counted towards its score, so that
+// repeating a word in many comments does not outweigh a match in the headers.
+const maxCommentOccurrences = 5
+
+// SearchField is a part of a game indexed for search.
+type SearchField string
+
+const (
+	SearchField_Player  SearchField = "player"
+	SearchField_Event   SearchField = "event"
+	SearchField_Site    SearchField = "site"
+	SearchField_Opening SearchField = "opening"
+	SearchField_Comment SearchField = "comment"
+)
+
+// The score of a single occurrence of a term in each field.
+var searchFieldWeights = map[SearchField]int{
+	SearchField_Player:  10,
+	SearchField_Event:   5,
+	SearchField_Site:    3,
+	SearchField_Opening: 5,
+	SearchField_Comment: 1,
+}
+
+// Common words which are not indexed or searched for.
+var searchStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "if": true, "in": true, "is": true, "it": true,
+	"no": true, "not": true, "of": true, "on": true, "or": true, "so": true, "that": true,
+	"the": true, "then": true, "this": true, "to": true, "was": true, "with": true,
+}
+
+// TokenizeSearchText returns the search terms in the given text, in order of occurrence.
+// Terms are lowercased words of letters and digits. Stop words and single characters are
+// excluded.
+func TokenizeSearchText(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) > 1 && !searchStopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// GameSearchEntry is a single entry in the search index, linking a term to a game which
+// contains it. It contains the fields of the game needed to list it.
+type GameSearchEntry struct {
+	// The search term. This is the hash key of the search table.
+	Term string `dynamodbav:"term" json:"-"`
+
+	// The id of the entry, in the form cohort#gameId. This is the range key of the search
+	// table.
+	Id string `dynamodbav:"id" json:"-"`
+
+	// The entry's position in date order, in the form date#cohort#gameId. This is the
+	// range key of the DateIdx.
+	DateId string `dynamodbav:"dateId" json:"-"`
+
+	// The entry's position in date order within its cohort, in the form
+	// cohort#date#gameId. This is the range key of the CohortDateIdx.
+	CohortDateId string `dynamodbav:"cohortDateId" json:"-"`
+
+	// The score of the term in the game.
+	Score int `dynamodbav:"score" json:"-"`
+
+	// The fields of the game containing the term.
+	Fields []SearchField `dynamodbav:"fields" json:"-"`
+
+	// The cohort of the game.
+	Cohort DojoCohort `dynamodbav:"cohort" json:"cohort"`
+
+	// The id of the game.
+	GameId string `dynamodbav:"gameId" json:"id"`
+
+	// The username of the owner of the game.
+	Owner string `dynamodbav:"owner" json:"owner"`
+
+	// The display name of the owner of the game.
+	OwnerDisplayName string `dynamodbav:"ownerDisplayName" json:"ownerDisplayName"`
+
+	// The player with the white pieces.
+	White string `dynamodbav:"white" json:"white"`
+
+	// The player with the black pieces.
+	Black string `dynamodbav:"black" json:"black"`
+
+	// The date the game was played, in the form 2023.01.02.
+	Date string `dynamodbav:"date" json:"date"`
+
+	// The PGN headers of the game.
+	Headers map[string]string `dynamodbav:"headers" json:"headers"`
+}
+
+// searchEntryId returns the range key of a GameSearchEntry for the given game.
+func searchEntryId(cohort DojoCohort, gameId string) string {
+	return fmt.Sprintf("%s#%s", cohort, gameId)
+}
+
+// ExtractSearchEntries returns the search index entries for the given game and its parsed
+// PGN, one per term. The player names, Event, Site and Opening headers and the comments of
+// the PGN, including those in variations, are indexed. Each occurrence of a term adds the
+// weight of its field to the entry's score.
+func ExtractSearchEntries(game *Game, parsed *chess.Game) []GameSearchEntry {
+	entries := make(map[string]*GameSearchEntry)
+	comments := make(map[string]int)
+
+	add := func(field SearchField, text string) {
+		for _, term := range TokenizeSearchText(text) {
+			entry, ok := entries[term]
+			if !ok {
+				entry = &GameSearchEntry{
+					Term:             term,
+					Id:               searchEntryId(game.Cohort, game.Id),
+					DateId:           fmt.Sprintf("%s#%s#%s", indexDate(game.Date), game.Cohort, game.Id),
+					CohortDateId:     fmt.Sprintf("%s#%s#%s", game.Cohort, indexDate(game.Date), game.Id),
+					Cohort:           game.Cohort,
+					GameId:           game.Id,
+					Owner:            game.Owner,
+					OwnerDisplayName: game.OwnerDisplayName,
+					White:            game.White,
+					Black:            game.Black,
+					Date:             game.Date,
+					Headers:          game.Headers,
+				}
+				entries[term] = entry
+			}
+			if field == SearchField_Comment {
+				if comments[term] >= maxCommentOccurrences {
+					continue
+				}
+				comments[term]++
+			}
+			entry.Score += searchFieldWeights[field]
+			if !slices.Contains(entry.Fields, field) {
+				entry.Fields = append(entry.Fields, field)
+			}
+		}
+	}
+
+	// The stored headers are preferred, since some are set without updating the PGN
+	header := func(name string) string {
+		if value, ok := game.Headers[name]; ok {
+			return value
+		}
+		return parsed.Header(name)
+	}
+	add(SearchField_Player, header("White"))
+	add(SearchField_Player, header("Black"))
+	add(SearchField_Event, header("Event"))
+	add(SearchField_Site, header("Site"))
+	add(SearchField_Opening, header("Opening"))
+	add(SearchField_Comment, parsed.Root.Comment)
+	parsed.Walk(func(n *chess.Node) {
+		add(SearchField_Comment, n.Comment)
+	})
+
+	result := make([]GameSearchEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Term < result[j].Term
+	})
+	return result
+}
+
+// GameSearchResult is a game matching a search query.
+type GameSearchResult struct {
+	GameSearchEntry
+
+	// The total score of the query terms in the game.
+	Score int `json:"score"`
+
+	// The fields of the game matching any query term.
+	MatchedFields []SearchField `json:"matchedFields"`
+}
+
+// RankSearchResults returns the games which contain every term of the query, ordered
+// by score and then by date in descending order. entriesByTerm maps each query term to
+// its search index entries.
+func RankSearchResults(terms []string, entriesByTerm map[string][]GameSearchEntry) []GameSearchResult {
+	if len(terms) == 0 {
+		return []GameSearchResult{}
+	}
+
+	results := make(map[string]*GameSearchResult)
+	matches := make(map[string]int)
+	for _, term := range terms {
+		for _, entry := range entriesByTerm[term] {
+			result, ok := results[entry.Id]
+			if !ok {
+				result = &GameSearchResult{GameSearchEntry: entry}
+				results[entry.Id] = result
+			}
+			matches[entry.Id]++
+			result.Score += entry.Score
+			for _, field := range entry.Fields {
+				if !slices.Contains(result.MatchedFields, field) {
+					result.MatchedFields = append(result.MatchedFields, field)
+				}
+			}
+		}
+	}
+
+	ranked := make([]GameSearchResult, 0, len(results))
+	for id, result := range results {
+		if matches[id] == len(terms) {
+			slices.Sort(result.MatchedFields)
+			ranked = append(ranked, *result)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Date != b.Date {
+			return a.Date > b.Date
+		}
+		return a.Id < b.Id
+	})
+	return ranked
+}
+
+// ParseSearchQuery returns the distinct terms of the given search query. False is returned
+// if the query contains no searchable terms.
+func ParseSearchQuery(query string) ([]string, bool) {
+	terms := TokenizeSearchText(query)
+	slices.Sort(terms)
+	terms = slices.Compact(terms)
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms, len(terms) > 0
+}
+
+type GameSearchIndexer interface {
+	// PutGameSearchEntries inserts the provided search index entries into the database.
+	PutGameSearchEntries(entries []GameSearchEntry) (int, error)
+
+	// DeleteGameSearchEntries removes the provided search index entries from the database.
+	DeleteGameSearchEntries(entries []GameSearchEntry) error
+}
+
+type GameSearcher interface {
+	// ListGameSearchEntries returns up to limit search index entries for the given term,
+	// sorted by the date the game was played in descending order. cohort is an optional
+	// filter. The returned bool is true if more entries exist than the limit.
+	ListGameSearchEntries(term string, cohort DojoCohort, limit int) ([]GameSearchEntry, bool, error)
+}
+
+// PutGameSearchEntries inserts the provided search index entries into the database.
+func (repo *dynamoRepository) PutGameSearchEntries(entries []GameSearchEntry) (int, error) {
+	return batchWriteObjects(repo, entries, searchTable)
+}
+
+// DeleteGameSearchEntries removes the provided search index entries from the database.
+func (repo *dynamoRepository) DeleteGameSearchEntries(entries []GameSearchEntry) error {
+	var reqs []*dynamodb.WriteRequest
+	for _, entry := range entries {
+		reqs = append(reqs, &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{
+				Key: map[string]*dynamodb.AttributeValue{
+					"term": {S: aws.String(entry.Term)},
+					"id":   {S: aws.String(entry.Id)},
+				},
+			},
+		})
+
+		if len(reqs) == 25 {
+			if err := repo.batchWrite(reqs, searchTable); err != nil {
+				return err
+			}
+			reqs = nil
+		}
+	}
+
+	if len(reqs) > 0 {
+		return repo.batchWrite(reqs, searchTable)
+	}
+	return nil
+}
+
+// ListGameSearchEntries returns up to limit search index entries for the given term,
+// sorted by the date the game was played in descending order. cohort is an optional
+// filter. The returned bool is true if more entries exist than the limit.
+func (repo *dynamoRepository) ListGameSearchEntries(term string, cohort DojoCohort, limit int) ([]GameSearchEntry, bool, error) {
+	keyConditionExpression := "#term = :term"
+	expressionAttributeNames := map[string]*string{
+		"#term": aws.String("term"),
+	}
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":term": {S: aws.String(term)},
+	}
+
+	indexName := "DateIdx"
+	if cohort != "" {
+		indexName = "CohortDateIdx"
+		keyConditionExpression += " AND begins_with(#cohortDateId, :prefix)"
+		expressionAttributeNames["#cohortDateId"] = aws.String("cohortDateId")
+		expressionAttributeValues[":prefix"] = &dynamodb.AttributeValue{S: aws.String(string(cohort) + "#")}
+	}
+
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String(keyConditionExpression),
+		ExpressionAttributeNames:  expressionAttributeNames,
+		ExpressionAttributeValues: expressionAttributeValues,
+		IndexName:                 aws.String(indexName),
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int64(int64(limit + 1)),
+		TableName:                 aws.String(searchTable),
+	}
+
+	var entries []GameSearchEntry
+	var startKey string
+	for {
+		var page []GameSearchEntry
+		lastKey, err := repo.query(input, startKey, &page)
+		if err != nil {
+			return nil, false, err
+		}
+		entries = append(entries, page...)
+
+		if len(entries) > limit || lastKey == "" {
+			break
+		}
+		startKey = lastKey
+	}
+
+	if len(entries) > limit {
+		return entries[:limit], true, nil
+	}
+	return entries, false, nil
+}
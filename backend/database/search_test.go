@@ -0,0 +1,122 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestTokenizeSearchText(t *testing.T) {
+	table := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "Empty",
+			text: "",
+			want: []string{},
+		},
+		{
+			name: "Punctuation",
+			text: "The minority attack, as in the Carlsbad (QGD)!",
+			want: []string{"minority", "attack", "carlsbad", "qgd"},
+		},
+		{
+			name: "Names",
+			text: "Nepomniachtchi, Ian",
+			want: []string{"nepomniachtchi", "ian"},
+		},
+		{
+			name: "SingleCharacters",
+			text: "a b 2024 x",
+			want: []string{"2024"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := TokenizeSearchText(tc.text)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("TokenizeSearchText got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractSearchEntries(t *testing.T) {
+	game := &Game{
+		Cohort: "1500-1600",
+		Id:     "2024.01.02_abc",
+		Headers: map[string]string{
+			"White":   "Smith, John",
+			"Black":   "Attack, Alice",
+			"Opening": "Queen's Gambit Declined",
+		},
+		Pgn: "[White \"Smith, John\"]\n[Black \"Attack, Alice\"]\n\n{ Minority attack game } 1. d4 { attack } d5 (1... Nf6 { minority attack }) *",
+	}
+	parsed, err := chess.Parse(game.Pgn)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+
+	entries := ExtractSearchEntries(game, parsed)
+
+	byTerm := make(map[string]GameSearchEntry)
+	for _, entry := range entries {
+		byTerm[entry.Term] = entry
+		if entry.Id != "1500-1600#2024.01.02_abc" {
+			t.Errorf("ExtractSearchEntries got id %q; want 1500-1600#2024.01.02_abc", entry.Id)
+		}
+		if entry.DateId != "0000.00.00#1500-1600#2024.01.02_abc" {
+			t.Errorf("ExtractSearchEntries got dateId %q; want 0000.00.00#1500-1600#2024.01.02_abc", entry.DateId)
+		}
+	}
+
+	attack := byTerm["attack"]
+	if attack.Score != 13 || !reflect.DeepEqual(attack.Fields, []SearchField{SearchField_Player, SearchField_Comment}) {
+		t.Errorf("ExtractSearchEntries got attack entry: %d %v; want: 13 [player comment]", attack.Score, attack.Fields)
+	}
+	minority := byTerm["minority"]
+	if minority.Score != 2 || !reflect.DeepEqual(minority.Fields, []SearchField{SearchField_Comment}) {
+		t.Errorf("ExtractSearchEntries got minority entry: %d %v; want: 2 [comment]", minority.Score, minority.Fields)
+	}
+	if byTerm["gambit"].Score != 5 {
+		t.Errorf("ExtractSearchEntries got gambit score: %d; want: 5", byTerm["gambit"].Score)
+	}
+	if _, ok := byTerm["game"]; !ok {
+		t.Errorf("ExtractSearchEntries did not index the root comment")
+	}
+}
+
+func TestRankSearchResults(t *testing.T) {
+	entry := func(id, date string, score int, fields ...SearchField) GameSearchEntry {
+		return GameSearchEntry{Id: id, GameId: id, Date: date, Score: score, Fields: fields}
+	}
+	entriesByTerm := map[string][]GameSearchEntry{
+		"minority": {
+			entry("1", "2024.01.01", 1, SearchField_Comment),
+			entry("2", "2024.01.02", 1, SearchField_Comment),
+			entry("3", "2024.01.03", 3, SearchField_Comment),
+		},
+		"attack": {
+			entry("1", "2024.01.01", 10, SearchField_Player),
+			entry("2", "2024.01.02", 1, SearchField_Comment),
+			entry("4", "2024.01.04", 10, SearchField_Player),
+		},
+	}
+
+	got := RankSearchResults([]string{"attack", "minority"}, entriesByTerm)
+
+	var ids []string
+	for _, result := range got {
+		ids = append(ids, result.GameId)
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "2"}) {
+		t.Fatalf("RankSearchResults got ids: %v; want: [1 2]", ids)
+	}
+	if got[0].Score != 11 || !reflect.DeepEqual(got[0].MatchedFields, []SearchField{SearchField_Comment, SearchField_Player}) {
+		t.Errorf("RankSearchResults got first result: %d %v; want: 11 [comment player]", got[0].Score, got[0].MatchedFields)
+	}
+}
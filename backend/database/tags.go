@@ -0,0 +1,392 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+var gameTagTable = stage + "-game-tags"
+
+const (
+	gameTagTableCohortIndex = "CohortTagIdx"
+	gameTagTableOwnerIndex  = "OwnerTagIdx"
+)
+
+// The max number of owner-defined tags on a game.
+const maxGameTags = 10
+
+// The max length of a single tag.
+const maxGameTagLength = 30
+
+// System tags are assigned to games automatically based on their content.
+const (
+	SystemTag_Endgame     = "endgame"
+	SystemTag_TimeTrouble = "time trouble"
+	SystemTag_Tournament  = "tournament"
+)
+
+// The max total value of the knights, bishops, rooks and queens on the board for a
+// position to count as an endgame.
+const endgameMaterial = 26
+
+// NormalizeTag returns the given tag trimmed, lowercased and with repeated spaces removed.
+// A 400 error is returned if the tag is empty, too long or contains characters other than
+// letters, digits, spaces and hyphens.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+	if tag == "" {
+		return "", errors.New(400, "Invalid request: tags cannot be empty", "")
+	}
+	if len([]rune(tag)) > maxGameTagLength {
+		return "", errors.New(400, fmt.Sprintf("Invalid request: tags cannot be longer than %d characters", maxGameTagLength), "")
+	}
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", errors.New(400, fmt.Sprintf("Invalid request: tag %q contains invalid characters", tag), "")
+		}
+	}
+	return tag, nil
+}
+
+// isTagRune returns true if the given rune is allowed in a tag.
+func isTagRune(r rune) bool {
+	return r == ' ' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// NormalizeTags returns the given tags normalized with NormalizeTag, sorted and without
+// duplicates. A 400 error is returned if any tag is invalid or if there are too many tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, normalized)
+	}
+	slices.Sort(result)
+	result = slices.Compact(result)
+	if len(result) > maxGameTags {
+		return nil, errors.New(400, fmt.Sprintf("Invalid request: games cannot have more than %d tags", maxGameTags), "")
+	}
+	return result, nil
+}
+
+// GuessSystemTags returns the system tags of the given game and its parsed PGN, in
+// sorted order. Games are tagged as endgames if their mainline reaches an endgame,
+// as time trouble if either player's clock entered time trouble and as tournament
+// games if they have an Event header which does not name a casual or online game.
+func GuessSystemTags(game *Game, parsed *chess.Game) []string {
+	tags := make([]string, 0, 3)
+
+	for _, p := range parsed.Positions() {
+		if nonPawnMaterial(p) <= endgameMaterial {
+			tags = append(tags, SystemTag_Endgame)
+			break
+		}
+	}
+
+	if isTournamentEvent(game.Headers["Event"]) {
+		tags = append(tags, SystemTag_Tournament)
+	}
+
+	profile := ExtractTimeProfile(parsed)
+	if profile != nil && (profile.WhiteTimeTrouble > 0 || profile.BlackTimeTrouble > 0) {
+		tags = append(tags, SystemTag_TimeTrouble)
+	}
+
+	slices.Sort(tags)
+	return tags
+}
+
+// The prefixes of Event headers which are set by online sites or for casual games.
+var nonTournamentEvents = []string{
+	"?",
+	"casual",
+	"rated",
+	"live chess",
+	"let's play",
+	"play vs",
+	"vs computer",
+}
+
+// isTournamentEvent returns true if the given Event header names a tournament.
+func isTournamentEvent(event string) bool {
+	event = strings.ToLower(strings.TrimSpace(event))
+	if event == "" {
+		return false
+	}
+	for _, prefix := range nonTournamentEvents {
+		if strings.HasPrefix(event, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllTags returns the owner-defined and system tags of the game, sorted and without
+// duplicates.
+func (g *Game) AllTags() []string {
+	tags := slices.Concat(g.Tags, g.SystemTags)
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// GameTag is a single entry in the tag index, linking a tag to a game which has it. It
+// contains the fields of the game needed to list it, so that it can be returned as a Game.
+type GameTag struct {
+	// The tag. This is the hash key of the tag table.
+	Tag string `dynamodbav:"tag"`
+
+	// The key of the game, in the form cohort/id. This is the range key of the tag table.
+	GameKey string `dynamodbav:"gameKey"`
+
+	// The cohort and tag, in the form cohort#tag. This is the hash key of the cohort index.
+	CohortTag string `dynamodbav:"cohortTag"`
+
+	// The owner and tag, in the form owner#tag. This is the hash key of the owner index.
+	OwnerTag string `dynamodbav:"ownerTag"`
+
+	// The cohort of the game.
+	Cohort DojoCohort `dynamodbav:"cohort"`
+
+	// The id of the game. This is the range key of the cohort and owner indices.
+	Id string `dynamodbav:"id"`
+
+	// The username of the owner of the game.
+	Owner string `dynamodbav:"owner"`
+
+	// The display name of the owner of the game.
+	OwnerDisplayName string `dynamodbav:"ownerDisplayName"`
+
+	// The player with the white pieces.
+	White string `dynamodbav:"white"`
+
+	// The player with the black pieces.
+	Black string `dynamodbav:"black"`
+
+	// The date the game was played, in the form 2023.01.02.
+	Date string `dynamodbav:"date"`
+
+	// The date and time the game was created, in time.RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt"`
+
+	// The PGN headers of the game.
+	Headers map[string]string `dynamodbav:"headers"`
+
+	// Whether the game is unlisted.
+	Unlisted bool `dynamodbav:"unlisted"`
+
+	// The owner-defined tags of the game.
+	Tags []string `dynamodbav:"tags,omitempty"`
+
+	// The system tags of the game.
+	SystemTags []string `dynamodbav:"systemTags,omitempty"`
+}
+
+// ExtractGameTags returns the tag index entries for the given game, one per tag.
+func ExtractGameTags(game *Game) []GameTag {
+	tags := game.AllTags()
+	entries := make([]GameTag, 0, len(tags))
+	for _, tag := range tags {
+		entries = append(entries, GameTag{
+			Tag:              tag,
+			GameKey:          fmt.Sprintf("%s/%s", game.Cohort, game.Id),
+			CohortTag:        fmt.Sprintf("%s#%s", game.Cohort, tag),
+			OwnerTag:         fmt.Sprintf("%s#%s", game.Owner, tag),
+			Cohort:           game.Cohort,
+			Id:               game.Id,
+			Owner:            game.Owner,
+			OwnerDisplayName: game.OwnerDisplayName,
+			White:            game.White,
+			Black:            game.Black,
+			Date:             game.Date,
+			CreatedAt:        game.CreatedAt,
+			Headers:          game.Headers,
+			Unlisted:         game.Unlisted,
+			Tags:             game.Tags,
+			SystemTags:       game.SystemTags,
+		})
+	}
+	return entries
+}
+
+type GameTagSetter interface {
+	// SetGameTags sets the owner-defined tags of the given game, if it is owned by the
+	// given user. The updated game is returned.
+	SetGameTags(owner string, cohort DojoCohort, id string, tags []string) (*Game, error)
+}
+
+type GameTagIndexer interface {
+	// SetGameSystemTags sets the system tags of the given game.
+	SetGameSystemTags(cohort DojoCohort, id string, tags []string) error
+
+	// PutGameTags inserts the provided tag index entries into the database.
+	PutGameTags(entries []GameTag) (int, error)
+
+	// DeleteGameTags removes the provided tag index entries from the database.
+	DeleteGameTags(entries []GameTag) error
+}
+
+// setGameTagList sets the given list attribute of the given game to tags, or removes the
+// attribute if tags is empty. The condition is applied to the update.
+func (repo *dynamoRepository) setGameTagList(cohort DojoCohort, id, attribute string, tags []string, condition string, conditionValues map[string]*dynamodb.AttributeValue) (*Game, error) {
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String(condition),
+		UpdateExpression:    aws.String("REMOVE #tags"),
+		ExpressionAttributeNames: map[string]*string{
+			"#tags": aws.String(attribute),
+		},
+		ExpressionAttributeValues: conditionValues,
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(cohort))},
+			"id":     {S: aws.String(id)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(gameTable),
+	}
+
+	if len(tags) > 0 {
+		av, err := dynamodbattribute.Marshal(tags)
+		if err != nil {
+			return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal tags", err)
+		}
+		if input.ExpressionAttributeValues == nil {
+			input.ExpressionAttributeValues = make(map[string]*dynamodb.AttributeValue)
+		}
+		input.ExpressionAttributeValues[":tags"] = av
+		input.UpdateExpression = aws.String("SET #tags = :tags")
+	}
+
+	result, err := repo.svc.UpdateItem(input)
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: game does not exist or you do not have permission to update it", "DynamoDB conditional check failed", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+
+	game := Game{}
+	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &game); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal UpdateItem result", err)
+	}
+	return &game, nil
+}
+
+// SetGameTags sets the owner-defined tags of the given game, if it is owned by the
+// given user. The updated game is returned.
+func (repo *dynamoRepository) SetGameTags(owner string, cohort DojoCohort, id string, tags []string) (*Game, error) {
+	return repo.setGameTagList(cohort, id, "tags", tags, "#owner = :owner", map[string]*dynamodb.AttributeValue{
+		":owner": {S: aws.String(owner)},
+	})
+}
+
+// SetGameSystemTags sets the system tags of the given game.
+func (repo *dynamoRepository) SetGameSystemTags(cohort DojoCohort, id string, tags []string) error {
+	_, err := repo.setGameTagList(cohort, id, "systemTags", tags, "attribute_exists(cohort)", nil)
+	return err
+}
+
+// PutGameTags inserts the provided tag index entries into the database.
+func (repo *dynamoRepository) PutGameTags(entries []GameTag) (int, error) {
+	return batchWriteObjects(repo, entries, gameTagTable)
+}
+
+// DeleteGameTags removes the provided tag index entries from the database.
+func (repo *dynamoRepository) DeleteGameTags(entries []GameTag) error {
+	var reqs []*dynamodb.WriteRequest
+	for _, entry := range entries {
+		reqs = append(reqs, &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{
+				Key: map[string]*dynamodb.AttributeValue{
+					"tag":     {S: aws.String(entry.Tag)},
+					"gameKey": {S: aws.String(entry.GameKey)},
+				},
+			},
+		})
+
+		if len(reqs) == 25 {
+			if err := repo.batchWrite(reqs, gameTagTable); err != nil {
+				return err
+			}
+			reqs = nil
+		}
+	}
+
+	if len(reqs) > 0 {
+		return repo.batchWrite(reqs, gameTagTable)
+	}
+	return nil
+}
+
+// ListGamesByTag returns a list of Games in the given cohort with the given tag, sorted
+// by id in descending order. Unlisted games are not included. The PGN text is excluded
+// and must be fetched separately with a call to GetGame.
+func (repo *dynamoRepository) ListGamesByTag(cohort DojoCohort, tag, startKey string) ([]*Game, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#cohortTag = :cohortTag"),
+		FilterExpression:       aws.String("attribute_not_exists(unlisted) OR #unlisted <> :unlisted"),
+		ExpressionAttributeNames: map[string]*string{
+			"#cohortTag": aws.String("cohortTag"),
+			"#unlisted":  aws.String("unlisted"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":cohortTag": {S: aws.String(fmt.Sprintf("%s#%s", cohort, tag))},
+			":unlisted":  {BOOL: aws.Bool(true)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		IndexName:        aws.String(gameTagTableCohortIndex),
+		TableName:        aws.String(gameTagTable),
+	}
+
+	var games []*Game
+	lastKey, err := repo.query(input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ListGamesByOwnerTag returns a list of Games owned by the given user with the given tag,
+// sorted by id in descending order. Unlisted games are not included, unless isOwner is true.
+// The PGN text is excluded and must be fetched separately with a call to GetGame.
+func (repo *dynamoRepository) ListGamesByOwnerTag(isOwner bool, owner, tag, startKey string) ([]*Game, string, error) {
+	expressionAttributeNames := map[string]*string{
+		"#ownerTag": aws.String("ownerTag"),
+	}
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":ownerTag": {S: aws.String(fmt.Sprintf("%s#%s", owner, tag))},
+	}
+
+	var filterExpression *string
+	if !isOwner {
+		filterExpression = aws.String("attribute_not_exists(unlisted) OR #unlisted <> :unlisted")
+		expressionAttributeNames["#unlisted"] = aws.String("unlisted")
+		expressionAttributeValues[":unlisted"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+	}
+
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String("#ownerTag = :ownerTag"),
+		FilterExpression:          filterExpression,
+		ExpressionAttributeNames:  expressionAttributeNames,
+		ExpressionAttributeValues: expressionAttributeValues,
+		ScanIndexForward:          aws.Bool(false),
+		IndexName:                 aws.String(gameTagTableOwnerIndex),
+		TableName:                 aws.String(gameTagTable),
+	}
+
+	var games []*Game
+	lastKey, err := repo.query(input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	table := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Empty",
+			tags: nil,
+			want: []string{},
+		},
+		{
+			name: "NormalizesAndSorts",
+			tags: []string{"  Minority   Attack ", "rook-endgame", "minority attack", "Ñandú 2"},
+			want: []string{"minority attack", "rook-endgame", "ñandú 2"},
+		},
+		{
+			name:    "EmptyTag",
+			tags:    []string{"endgame", "   "},
+			wantErr: true,
+		},
+		{
+			name:    "InvalidCharacters",
+			tags:    []string{"endgame#1"},
+			wantErr: true,
+		},
+		{
+			name:    "TooLong",
+			tags:    []string{"this tag is much too long to be accepted"},
+			wantErr: true,
+		},
+		{
+			name:    "TooMany",
+			tags:    []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeTags(tc.tags)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NormalizeTags got error: %v; want error: %t", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("NormalizeTags got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGuessSystemTags(t *testing.T) {
+	table := []struct {
+		name string
+		pgn  string
+		want []string
+	}{
+		{
+			name: "None",
+			pgn:  "[Event \"Rated Blitz game\"]\n\n1. e4 e5 *",
+			want: []string{},
+		},
+		{
+			name: "Tournament",
+			pgn:  "[Event \"Dojo Open Classical\"]\n\n1. e4 e5 *",
+			want: []string{SystemTag_Tournament},
+		},
+		{
+			name: "Endgame",
+			pgn:  "[SetUp \"1\"]\n[FEN \"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1\"]\n\n1. O-O *",
+			want: []string{SystemTag_Endgame},
+		},
+		{
+			name: "TimeTrouble",
+			pgn:  "[Event \"?\"]\n[TimeControl \"600\"]\n\n1. e4 { [%clk 0:09:50] } e5 { [%clk 0:00:30] } *",
+			want: []string{SystemTag_TimeTrouble},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			game := &Game{Pgn: tc.pgn}
+			parsed, err := game.ParsePgn()
+			if err != nil {
+				t.Fatalf("ParsePgn got error: %v", err)
+			}
+			game.Headers = parsed.Headers
+
+			got := GuessSystemTags(game, parsed)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GuessSystemTags got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractGameTags(t *testing.T) {
+	game := &Game{
+		Cohort:     "1500-1600",
+		Id:         "2024.01.02_abc",
+		Owner:      "owner",
+		Tags:       []string{"minority attack", "endgame"},
+		SystemTags: []string{"endgame", "tournament"},
+	}
+
+	entries := ExtractGameTags(game)
+
+	var tags []string
+	for _, entry := range entries {
+		tags = append(tags, entry.Tag)
+		if entry.GameKey != "1500-1600/2024.01.02_abc" {
+			t.Errorf("ExtractGameTags got game key %q; want 1500-1600/2024.01.02_abc", entry.GameKey)
+		}
+		if entry.CohortTag != "1500-1600#"+entry.Tag || entry.OwnerTag != "owner#"+entry.Tag {
+			t.Errorf("ExtractGameTags got index keys %q, %q for tag %q", entry.CohortTag, entry.OwnerTag, entry.Tag)
+		}
+	}
+	want := []string{"endgame", "minority attack", "tournament"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("ExtractGameTags got tags: %v; want: %v", tags, want)
+	}
+}
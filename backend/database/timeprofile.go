@@ -0,0 +1,282 @@
+package database
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// The number of full moves counted as the opening in a TimeUsageSummary.
+const openingMoves = 15
+
+// TimeProfile is a compact summary of the clock times recorded in a game's mainline.
+type TimeProfile struct {
+	// The base time of the game in seconds, taken from the TimeControl header or, if that
+	// is missing, the first clock reading.
+	InitialSeconds int `dynamodbav:"initialSeconds" json:"initialSeconds"`
+
+	// The increment of the game in seconds, taken from the TimeControl header.
+	IncrementSeconds int `dynamodbav:"incrementSeconds,omitempty" json:"incrementSeconds,omitempty"`
+
+	// The number of plies played before the first mainline move. Non-zero only for games
+	// starting from a custom position.
+	StartPly int `dynamodbav:"startPly,omitempty" json:"startPly,omitempty"`
+
+	// The seconds spent on each mainline move, or -1 if unknown.
+	MoveSeconds []int `dynamodbav:"moveSeconds" json:"moveSeconds"`
+
+	// The move number on which white first entered time trouble, or 0 if it never did.
+	WhiteTimeTrouble int `dynamodbav:"whiteTimeTrouble,omitempty" json:"whiteTimeTrouble,omitempty"`
+
+	// The move number on which black first entered time trouble, or 0 if it never did.
+	BlackTimeTrouble int `dynamodbav:"blackTimeTrouble,omitempty" json:"blackTimeTrouble,omitempty"`
+
+	// The indices in MoveSeconds of the long thinks in the game.
+	LongThinks []int `dynamodbav:"longThinks,omitempty" json:"longThinks,omitempty"`
+}
+
+// color returns the color which played the mainline move at the given index.
+func (p *TimeProfile) color(index int) chess.Color {
+	if (p.StartPly+index)%2 == 0 {
+		return chess.White
+	}
+	return chess.Black
+}
+
+// moveNumber returns the full move number of the mainline move at the given index.
+func (p *TimeProfile) moveNumber(index int) int {
+	return (p.StartPly+index)/2 + 1
+}
+
+// timeTrouble returns the move number on which the given color entered time trouble.
+func (p *TimeProfile) timeTrouble(color chess.Color) int {
+	if color == chess.White {
+		return p.WhiteTimeTrouble
+	}
+	return p.BlackTimeTrouble
+}
+
+// timeTroubleThreshold returns the remaining clock time below which a player is in time
+// trouble: 10% of the initial time, but at least one minute.
+func timeTroubleThreshold(initial time.Duration) time.Duration {
+	return max(initial/10, time.Minute)
+}
+
+// longThinkThreshold returns the time spent on a single move at or above which the move
+// is a long think: 5% of the initial time, but at least two minutes.
+func longThinkThreshold(initial time.Duration) time.Duration {
+	return max(initial/20, 2*time.Minute)
+}
+
+// parseTimeControl returns the base time and increment of the first period of the given
+// PGN TimeControl header, such as 5400+30 or 40/7200:1800. False is returned if the header
+// does not contain a base time.
+func parseTimeControl(header string) (time.Duration, time.Duration, bool) {
+	period, _, _ := strings.Cut(strings.TrimSpace(header), ":")
+	if _, after, found := strings.Cut(period, "/"); found {
+		period = after
+	}
+	baseValue, incrementValue, _ := strings.Cut(period, "+")
+
+	base, err := strconv.ParseFloat(baseValue, 64)
+	if err != nil || base <= 0 {
+		return 0, 0, false
+	}
+	increment, _ := strconv.ParseFloat(incrementValue, 64)
+	return time.Duration(base * float64(time.Second)), time.Duration(increment * float64(time.Second)), true
+}
+
+// ExtractTimeProfile returns the time profile of the given game, or nil if its mainline
+// contains no %clk commands.
+func ExtractTimeProfile(parsed *chess.Game) *TimeProfile {
+	mainline := parsed.Mainline()
+	initial, increment, hasTimeControl := parseTimeControl(parsed.Header("TimeControl"))
+
+	start := parsed.Root.Position
+	profile := &TimeProfile{
+		IncrementSeconds: int(increment / time.Second),
+		StartPly:         (start.FullmoveNumber - 1) * 2,
+		MoveSeconds:      make([]int, len(mainline)),
+	}
+	if start.Turn == chess.Black {
+		profile.StartPly++
+	}
+
+	var previous [2]time.Duration
+	var hasPrevious [2]bool
+	if hasTimeControl {
+		previous = [2]time.Duration{initial, initial}
+		hasPrevious = [2]bool{true, true}
+	}
+
+	clocks := 0
+	for i, n := range mainline {
+		color := profile.color(i)
+		clock, ok := n.Clock()
+		if !ok {
+			profile.MoveSeconds[i] = -1
+			hasPrevious[color] = false
+			continue
+		}
+
+		clocks++
+		if initial == 0 {
+			initial = clock
+		}
+
+		profile.MoveSeconds[i] = -1
+		if hasPrevious[color] {
+			spent := max(previous[color]+increment-clock, 0)
+			profile.MoveSeconds[i] = int(math.Round(spent.Seconds()))
+			if spent >= longThinkThreshold(initial) {
+				profile.LongThinks = append(profile.LongThinks, i)
+			}
+		}
+		previous[color] = clock
+		hasPrevious[color] = true
+
+		if clock < timeTroubleThreshold(initial) {
+			if color == chess.White && profile.WhiteTimeTrouble == 0 {
+				profile.WhiteTimeTrouble = profile.moveNumber(i)
+			} else if color == chess.Black && profile.BlackTimeTrouble == 0 {
+				profile.BlackTimeTrouble = profile.moveNumber(i)
+			}
+		}
+	}
+
+	if clocks == 0 {
+		return nil
+	}
+	profile.InitialSeconds = int(math.Round(initial.Seconds()))
+	return profile
+}
+
+// TimeUsageSummary aggregates the time profiles of a user's games from the perspective of
+// the color the user played.
+type TimeUsageSummary struct {
+	// The number of games with a time profile.
+	GamesAnalyzed int `json:"gamesAnalyzed"`
+
+	// The average number of seconds spent per move on moves 1-15.
+	AverageOpeningSeconds float64 `json:"averageOpeningSeconds"`
+
+	// The average number of seconds spent per move over the whole game.
+	AverageMoveSeconds float64 `json:"averageMoveSeconds"`
+
+	// The percentage (0-100) of games in which the user entered time trouble.
+	TimeTroublePercentage float64 `json:"timeTroublePercentage"`
+
+	// The average move number on which the user entered time trouble, counting only games
+	// in which they did.
+	AverageTimeTroubleMove float64 `json:"averageTimeTroubleMove"`
+
+	// The average number of long thinks per game.
+	AverageLongThinks float64 `json:"averageLongThinks"`
+}
+
+// SummarizeTimeUsage returns the TimeUsageSummary of the given games. Each game's
+// orientation is used as the color played by the user. Games without a time profile
+// are skipped.
+func SummarizeTimeUsage(games []*Game) TimeUsageSummary {
+	var summary TimeUsageSummary
+	var openingSeconds, openingMoveCount, totalSeconds, totalMoveCount, timeTroubleGames, timeTroubleMoves, longThinks int
+
+	for _, game := range games {
+		profile := game.TimeProfile
+		if profile == nil {
+			continue
+		}
+		summary.GamesAnalyzed++
+
+		color := chess.White
+		if game.Orientation == string(Black) {
+			color = chess.Black
+		}
+
+		for i, seconds := range profile.MoveSeconds {
+			if seconds < 0 || profile.color(i) != color {
+				continue
+			}
+			totalSeconds += seconds
+			totalMoveCount++
+			if profile.moveNumber(i) <= openingMoves {
+				openingSeconds += seconds
+				openingMoveCount++
+			}
+		}
+		for _, i := range profile.LongThinks {
+			if profile.color(i) == color {
+				longThinks++
+			}
+		}
+		if move := profile.timeTrouble(color); move > 0 {
+			timeTroubleGames++
+			timeTroubleMoves += move
+		}
+	}
+
+	if summary.GamesAnalyzed == 0 {
+		return summary
+	}
+	if openingMoveCount > 0 {
+		summary.AverageOpeningSeconds = float64(openingSeconds) / float64(openingMoveCount)
+	}
+	if totalMoveCount > 0 {
+		summary.AverageMoveSeconds = float64(totalSeconds) / float64(totalMoveCount)
+	}
+	if timeTroubleGames > 0 {
+		summary.AverageTimeTroubleMove = float64(timeTroubleMoves) / float64(timeTroubleGames)
+	}
+	summary.TimeTroublePercentage = 100 * float64(timeTroubleGames) / float64(summary.GamesAnalyzed)
+	summary.AverageLongThinks = float64(longThinks) / float64(summary.GamesAnalyzed)
+	return summary
+}
+
+type TimeProfileSetter interface {
+	// SetGameTimeProfile sets the time profile of the given game. If profile is nil, the
+	// time profile is removed.
+	SetGameTimeProfile(cohort DojoCohort, id string, profile *TimeProfile) error
+}
+
+// SetGameTimeProfile sets the time profile of the given game. If profile is nil, the
+// time profile is removed.
+func (repo *dynamoRepository) SetGameTimeProfile(cohort DojoCohort, id string, profile *TimeProfile) error {
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(cohort)"),
+		UpdateExpression:    aws.String("REMOVE #timeProfile"),
+		ExpressionAttributeNames: map[string]*string{
+			"#timeProfile": aws.String("timeProfile"),
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {S: aws.String(string(cohort))},
+			"id":     {S: aws.String(id)},
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	if profile != nil {
+		item, err := dynamodbattribute.MarshalMap(profile)
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Unable to marshal time profile", err)
+		}
+		input.UpdateExpression = aws.String("SET #timeProfile = :timeProfile")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":timeProfile": {M: item},
+		}
+	}
+
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.Wrap(404, "Invalid request: game does not exist", "DynamoDB UpdateItem failure", aerr)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	table := []struct {
+		header    string
+		base      time.Duration
+		increment time.Duration
+		ok        bool
+	}{
+		{header: "5400+30", base: 90 * time.Minute, increment: 30 * time.Second, ok: true},
+		{header: "1800", base: 30 * time.Minute, ok: true},
+		{header: "40/7200:1800", base: 2 * time.Hour, ok: true},
+		{header: "-", ok: false},
+		{header: "", ok: false},
+	}
+
+	for _, tc := range table {
+		base, increment, ok := parseTimeControl(tc.header)
+		if base != tc.base || increment != tc.increment || ok != tc.ok {
+			t.Errorf("parseTimeControl(%q) got: %v, %v, %t; want: %v, %v, %t", tc.header, base, increment, ok, tc.base, tc.increment, tc.ok)
+		}
+	}
+}
+
+func TestExtractTimeProfile(t *testing.T) {
+	pgn := `[TimeControl "1800+10"]
+
+1. e4 { [%clk 0:30:00] } e5 { [%clk 0:29:50] } 2. Nf3 { [%clk 0:25:00] } Nc6 { [%clk 0:29:40] }
+3. Bb5 { [%clk 0:02:30] } a6 4. Ba4 { [%clk 0:02:35] } *`
+
+	parsed, err := chess.Parse(pgn)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+
+	got := ExtractTimeProfile(parsed)
+	want := &TimeProfile{
+		InitialSeconds:   1800,
+		IncrementSeconds: 10,
+		MoveSeconds:      []int{10, 20, 310, 20, 1360, -1, 5},
+		WhiteTimeTrouble: 3,
+		LongThinks:       []int{2, 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractTimeProfile got: %+v; want: %+v", got, want)
+	}
+
+	parsed, _ = chess.Parse("1. e4 e5 *")
+	if got := ExtractTimeProfile(parsed); got != nil {
+		t.Errorf("ExtractTimeProfile without clocks got: %+v; want nil", got)
+	}
+}
+
+func TestSummarizeTimeUsage(t *testing.T) {
+	games := []*Game{
+		{
+			Orientation: "white",
+			TimeProfile: &TimeProfile{
+				InitialSeconds:   1800,
+				MoveSeconds:      []int{10, 20, 30, 40},
+				WhiteTimeTrouble: 20,
+				LongThinks:       []int{1, 2},
+			},
+		},
+		{
+			Orientation: "black",
+			TimeProfile: &TimeProfile{
+				InitialSeconds: 1800,
+				MoveSeconds:    []int{10, 20, -1, 60},
+			},
+		},
+		{Orientation: "white"},
+	}
+
+	got := SummarizeTimeUsage(games)
+	want := TimeUsageSummary{
+		GamesAnalyzed:          2,
+		AverageOpeningSeconds:  30,
+		AverageMoveSeconds:     30,
+		TimeTroublePercentage:  50,
+		AverageTimeTroubleMove: 20,
+		AverageLongThinks:      0.5,
+	}
+	if got != want {
+		t.Errorf("SummarizeTimeUsage got: %+v; want: %+v", got, want)
+	}
+}
@@ -645,24 +645,32 @@ func (repo *dynamoRepository) OpenClassicalCloseRegistrations() (*OpenClassical,
 	return result, nil
 }
 
-// Adds a new round to the given region and section of the current open classical, using the
-// provided pairings.
-func (repo *dynamoRepository) OpenClassicalAddRound(region, section string, pairings []OpenClassicalPairing) (*OpenClassical, error) {
-	round := OpenClassicalRound{
+// Adds the given round to the given region and section of the current open classical, using
+// the provided pairings. round is 1-indexed and must be the next round of the section. A 409
+// error is returned if the section does not have exactly round-1 rounds, such as when the round
+// was already added by another request.
+func (repo *dynamoRepository) OpenClassicalAddRound(region, section string, round int, pairings []OpenClassicalPairing) (*OpenClassical, error) {
+	newRound := OpenClassicalRound{
 		PairingEmailsSent: false,
 		Pairings:          pairings,
 	}
-	item, err := dynamodbattribute.MarshalMap(round)
+	item, err := dynamodbattribute.MarshalMap(newRound)
 	if err != nil {
 		return nil, errors.Wrap(500, "Temporary server error", "Failed to marshal round", err)
 	}
 
+	condition := "size(#sections.#s.#rounds) = :count"
+	if round == 1 {
+		condition = "attribute_not_exists(#sections.#s.#rounds) OR " + condition
+	}
+
 	input := &dynamodb.UpdateItemInput{
 		Key: map[string]*dynamodb.AttributeValue{
 			"type":     {S: aws.String(string(LeaderboardType_OpenClassical))},
 			"startsAt": {S: aws.String(CurrentLeaderboard)},
 		},
-		UpdateExpression: aws.String("SET #sections.#s.#rounds = list_append(if_not_exists(#sections.#s.#rounds, :empty_list), :r)"),
+		ConditionExpression: aws.String(condition),
+		UpdateExpression:    aws.String("SET #sections.#s.#rounds = list_append(if_not_exists(#sections.#s.#rounds, :empty_list), :r)"),
 		ExpressionAttributeNames: map[string]*string{
 			"#sections": aws.String("sections"),
 			"#s":        aws.String(fmt.Sprintf("%s_%s", region, section)),
@@ -671,6 +679,7 @@ func (repo *dynamoRepository) OpenClassicalAddRound(region, section string, pair
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 			":empty_list": {L: []*dynamodb.AttributeValue{}},
 			":r":          {L: []*dynamodb.AttributeValue{{M: item}}},
+			":count":      {N: aws.String(fmt.Sprint(round - 1))},
 		},
 		TableName:    aws.String(tournamentTable),
 		ReturnValues: aws.String("ALL_NEW"),
@@ -678,6 +687,9 @@ func (repo *dynamoRepository) OpenClassicalAddRound(region, section string, pair
 
 	result := &OpenClassical{}
 	if err := repo.updateItem(input, result); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(409, fmt.Sprintf("Conflict: round %d was already added to the section", round), "DynamoDB UpdateItem failure", aerr)
+		}
 		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem call", err)
 	}
 	return result, nil
@@ -256,6 +256,17 @@ type User struct {
 	// Whether to enable zen mode on the site
 	EnableZenMode bool `dynamodbav:"enableZenMode,omitempty" json:"enableZenMode"`
 
+	// Whether to exclude the user's games from the anonymized research dataset
+	ResearchOptOut bool `dynamodbav:"researchOptOut,omitempty" json:"researchOptOut,omitempty"`
+
+	// Whether to automatically import the user's classical games from their linked Lichess and
+	// Chess.com accounts each night
+	AutoImportGames bool `dynamodbav:"autoImportGames,omitempty" json:"autoImportGames,omitempty"`
+
+	// The time (in time.RFC3339) of the most recent automatically imported game, mapped by
+	// the rating system it was imported from
+	GameImportCursors map[RatingSystem]string `dynamodbav:"gameImportCursors,omitempty" json:"gameImportCursors,omitempty"`
+
 	// The user's preferred timezone on the calendar
 	TimezoneOverride string `dynamodbav:"timezoneOverride" json:"timezoneOverride"`
 
@@ -523,6 +534,12 @@ type SiteNotificationSettings struct {
 	// Whether to disable notifications on game comment replies
 	DisableGameCommentReplies bool `dynamodbav:"disableGameCommentReplies" json:"disableGameCommentReplies"`
 
+	// Whether to disable notifications on reactions to game comments
+	DisableGameCommentReaction bool `dynamodbav:"disableGameCommentReaction" json:"disableGameCommentReaction"`
+
+	// Whether to disable notifications when the user is mentioned in a comment
+	DisableMention bool `dynamodbav:"disableMention" json:"disableMention"`
+
 	// Whether to disable notifications on game reviews
 	DisableGameReview bool `dynamodbav:"disableGameReview" json:"disableGameReview"`
 
@@ -620,6 +637,24 @@ func (u *User) GetRatingChange() int {
 	return current - start
 }
 
+// PlayerNames returns the names the user may appear under in the White and Black headers
+// of their games, which are their display name and their username in each rating system.
+func (u *User) PlayerNames() []string {
+	if u == nil {
+		return nil
+	}
+	var names []string
+	if u.DisplayName != "" {
+		names = append(names, u.DisplayName)
+	}
+	for _, rating := range u.Ratings {
+		if rating != nil && rating.Username != "" {
+			names = append(names, rating.Username)
+		}
+	}
+	return names
+}
+
 func (u *User) getDisplayName() string {
 	if u == nil {
 		return ""
@@ -732,6 +767,16 @@ type UserUpdate struct {
 	// Whether to enable zen mode on the site
 	EnableZenMode *bool `dynamodbav:"enableZenMode,omitempty" json:"enableZenMode,omitempty"`
 
+	// Whether to exclude the user's games from the anonymized research dataset
+	ResearchOptOut *bool `dynamodbav:"researchOptOut,omitempty" json:"researchOptOut,omitempty"`
+
+	// Whether to automatically import the user's classical games from their linked accounts
+	AutoImportGames *bool `dynamodbav:"autoImportGames,omitempty" json:"autoImportGames,omitempty"`
+
+	// The time of the most recent automatically imported game, mapped by rating system. Cannot be
+	// manually passed by the user.
+	GameImportCursors *map[RatingSystem]string `dynamodbav:"gameImportCursors,omitempty" json:"-"`
+
 	// The user's preferred timezone on the calendar
 	TimezoneOverride *string `dynamodbav:"timezoneOverride,omitempty" json:"timezoneOverride,omitempty"`
 
@@ -1325,6 +1370,36 @@ func (repo *dynamoRepository) ListUserRatings(cohort DojoCohort, startKey string
 	return users, lastKey, nil
 }
 
+const gameImportProjection = "username, displayName, dojoCohort, previousCohort, ratings, gameSchedule, gameImportCursors"
+
+// ListGameImportUsers returns a list of Users matching the provided cohort who have enabled
+// the automatic game import, up to 1MB of data. Only the fields necessary for the import are
+// returned. startKey is an optional parameter that can be used to perform pagination.
+func (repo *dynamoRepository) ListGameImportUsers(cohort DojoCohort, startKey string) ([]*User, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#cohort = :cohort"),
+		FilterExpression:       aws.String("#autoImport = :true"),
+		ExpressionAttributeNames: map[string]*string{
+			"#cohort":     aws.String("dojoCohort"),
+			"#autoImport": aws.String("autoImportGames"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":cohort": {S: aws.String(string(cohort))},
+			":true":   {BOOL: aws.Bool(true)},
+		},
+		ProjectionExpression: aws.String(gameImportProjection),
+		IndexName:            aws.String("CohortIdx"),
+		TableName:            aws.String(userTable),
+	}
+
+	var users []*User
+	lastKey, err := repo.query(input, startKey, &users)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, lastKey, nil
+}
+
 func (repo *dynamoRepository) UpdateUserRatings(users []*User) error {
 	if len(users) > 25 {
 		return errors.New(500, "Temporary server error", "UpdateUserRatings has max limit of 25 users")
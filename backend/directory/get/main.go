@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.DirectoryGetter = database.DynamoDB
+
+type GetDirectoryResponse struct {
+	// The directory, without the items the caller cannot see.
+	Directory *database.Directory `json:"directory"`
+
+	// The caller's access role in the directory.
+	AccessRole database.DirectoryAccessRole `json:"accessRole,omitempty"`
+}
+
+// Handler returns a directory and the caller's access role in it, including access given
+// to the caller's clubs. Private directories require viewer access. Callers without viewer
+// access to a public directory do not see its unlisted games or the private subdirectories
+// they have no access to.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	owner := event.PathParameters["owner"]
+	id := event.PathParameters["id"]
+	if owner == "" || id == "" {
+		err := errors.New(400, "Invalid request: owner and id are required", "")
+		return api.Failure(err), nil
+	}
+
+	directory, err := repository.GetDirectory(owner, id)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	role, err := repository.GetDirectoryAccessRole(directory, info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	if !database.HasDirectoryRole(database.DirectoryAccessRole_Viewer, role) {
+		if directory.Visibility == database.DirectoryVisibility_Private {
+			err := errors.New(403, "This directory is private. Ask the owner to make it public or share it with you.", "")
+			return api.Failure(err), nil
+		}
+		if err := filterPrivateItems(directory, info.Username); err != nil {
+			return api.Failure(err), nil
+		}
+	}
+
+	return api.Success(&GetDirectoryResponse{Directory: directory, AccessRole: role}), nil
+}
+
+// filterPrivateItems removes the unlisted games and the private subdirectories which the
+// given user cannot view from the given directory.
+func filterPrivateItems(directory *database.Directory, username string) error {
+	itemIds := make([]string, 0, len(directory.ItemIds))
+	for _, id := range directory.ItemIds {
+		item, ok := directory.Items[id]
+		if !ok {
+			continue
+		}
+
+		visible := !item.Metadata.Unlisted
+		if item.Type == database.DirectoryItemType_Directory && item.Metadata.Visibility != database.DirectoryVisibility_Public {
+			visible = false
+			subdirectory, err := repository.GetDirectory(directory.Owner, id)
+			if err != nil {
+				var aerr *errors.Error
+				if !errors.As(err, &aerr) || aerr.Code != 404 {
+					return err
+				}
+			} else {
+				role, err := repository.GetDirectoryAccessRole(subdirectory, username)
+				if err != nil {
+					return err
+				}
+				visible = database.HasDirectoryRole(database.DirectoryAccessRole_Viewer, role)
+			}
+		}
+
+		if visible {
+			itemIds = append(itemIds, id)
+		} else {
+			delete(directory.Items, id)
+		}
+	}
+	directory.ItemIds = itemIds
+	return nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
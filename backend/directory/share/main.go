@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.DirectorySharer = database.DynamoDB
+
+type ShareDirectoryRequest struct {
+	// A map from username to the user's new access role in the directory.
+	Access map[string]database.DirectoryAccessRole `json:"access"`
+
+	// A map from club id to the new access role of the club's members in the directory.
+	ClubAccess map[string]database.DirectoryAccessRole `json:"clubAccess"`
+}
+
+// Handler replaces the user and club access of a directory. The caller must be an admin
+// of the directory. Users who gain or lose access have the directory added to or removed
+// from their Shared with Me directory. Club members are not added to Shared with Me, since
+// club membership changes over time.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		err := errors.New(400, "Invalid request: username is required", "")
+		return api.Failure(err), nil
+	}
+
+	owner := event.PathParameters["owner"]
+	id := event.PathParameters["id"]
+	if owner == "" || id == "" {
+		err := errors.New(400, "Invalid request: owner and id are required", "")
+		return api.Failure(err), nil
+	}
+
+	var request ShareDirectoryRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		err = errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)
+		return api.Failure(err), nil
+	}
+	if request.Access == nil {
+		request.Access = make(map[string]database.DirectoryAccessRole)
+	}
+	if request.ClubAccess == nil {
+		request.ClubAccess = make(map[string]database.DirectoryAccessRole)
+	}
+
+	directory, err := repository.GetDirectory(owner, id)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	role, err := repository.GetDirectoryAccessRole(directory, info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if !database.HasDirectoryRole(database.DirectoryAccessRole_Admin, role) {
+		err := errors.New(403, "Missing required admin permissions on the directory", "")
+		return api.Failure(err), nil
+	}
+	if err := database.ValidateDirectoryShare(directory, role, request.Access, request.ClubAccess); err != nil {
+		return api.Failure(err), nil
+	}
+
+	updated, err := repository.ShareDirectory(owner, id, request.Access, request.ClubAccess)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	for username := range directory.Access {
+		if _, ok := request.Access[username]; !ok {
+			if err := repository.RemoveSharedDirectory(username, updated); err != nil {
+				log.Errorf("Failed to remove directory from shared with me of %s: %v", username, err)
+			}
+		}
+	}
+	for username := range request.Access {
+		if _, ok := directory.Access[username]; !ok {
+			if err := repository.AddSharedDirectory(username, updated); err != nil {
+				log.Errorf("Failed to add directory to shared with me of %s: %v", username, err)
+			}
+		}
+	}
+
+	return api.Success(updated), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -4,11 +4,13 @@ import {
     DirectoryAccessRole,
 } from '@jackstenglein/chess-dojo-common/src/database/directory';
 import { NIL as uuidNil } from 'uuid';
+import { ApiError } from './api';
+import { getUser } from './database';
 import { fetchDirectory } from './get';
 
 /**
  * Returns true if the provided username has the provided access role (or higher) on the given directory.
- * Recursively checks parent directories until the given user is found.
+ * Recursively checks parent directories until the given user or one of their clubs is found.
  * @param owner The owner of the directory to check.
  * @param id The id of the directory to check.
  * @param username The username of the user to check.
@@ -31,12 +33,14 @@ export async function checkAccess(params: {
 
 /**
  * Gets the access role for the provided username on the given directory. Recursively checks parent
- * directories until the given user is found.
+ * directories until the given user or one of their clubs is found. Roles given to the user directly
+ * take precedence over the roles of their clubs.
  * @param owner The owner of the directory to check.
  * @param id The id of the directory to check.
  * @param username The username of the user to check.
  * @param directory The initial directory to check. If undefined, it will be fetched.
  * @param skipRecursion Whether to skip recursion and only check access for the given directory.
+ * @param clubs The clubs of the user to check. If undefined, they are fetched when first needed.
  * @returns The access role of the provided username for the given directory.
  */
 export async function getAccessRole({
@@ -45,12 +49,14 @@ export async function getAccessRole({
     username,
     directory,
     skipRecursion,
+    clubs,
 }: {
     owner: string;
     id: string;
     username: string;
     directory?: Directory;
     skipRecursion?: boolean;
+    clubs?: string[];
 }): Promise<DirectoryAccessRole | undefined> {
     if (username === owner) {
         return DirectoryAccessRole.Owner;
@@ -65,9 +71,55 @@ export async function getAccessRole({
         return directory.access?.[username];
     }
 
+    if (username && directory.clubAccess && Object.keys(directory.clubAccess).length > 0) {
+        clubs = clubs ?? (await getUserClubs(username));
+        const clubRole = getClubRole(directory, clubs);
+        if (clubRole) {
+            return clubRole;
+        }
+    }
+
     if (!skipRecursion && directory.parent !== uuidNil) {
-        return getAccessRole({ owner, id: directory.parent, username });
+        return getAccessRole({ owner, id: directory.parent, username, clubs });
     }
 
     return undefined;
 }
+
+/**
+ * Returns the highest access role given to any of the provided clubs on the given directory,
+ * ignoring its parents.
+ * @param directory The directory to check.
+ * @param clubs The ids of the clubs to check.
+ * @returns The highest access role of the clubs, or undefined if none of them have access.
+ */
+export function getClubRole(
+    directory: Directory,
+    clubs: string[],
+): DirectoryAccessRole | undefined {
+    let role: DirectoryAccessRole | undefined = undefined;
+    for (const club of clubs) {
+        const clubRole = directory.clubAccess?.[club];
+        if (clubRole && !compareRoles(clubRole, role)) {
+            role = clubRole;
+        }
+    }
+    return role;
+}
+
+/**
+ * Returns the ids of the clubs the given user is a member of. A user that does not
+ * exist is a member of no clubs.
+ * @param username The username of the user to fetch.
+ */
+async function getUserClubs(username: string): Promise<string[]> {
+    try {
+        const user = await getUser(username);
+        return user.clubs ?? [];
+    } catch (err) {
+        if (err instanceof ApiError && err.statusCode === 404) {
+            return [];
+        }
+        throw err;
+    }
+}
@@ -1,4 +1,11 @@
-import { BatchGetItemCommand, GetItemCommand, PutItemCommand } from '@aws-sdk/client-dynamodb';
+import {
+    BatchGetItemCommand,
+    BatchGetItemCommandInput,
+    GetItemCommand,
+    PutItemCommand,
+    QueryCommand,
+    QueryCommandOutput,
+} from '@aws-sdk/client-dynamodb';
 import { Lambda } from '@aws-sdk/client-lambda';
 import { GetObjectCommand, PutObjectCommand, S3Client } from '@aws-sdk/client-s3';
 import { getSignedUrl } from '@aws-sdk/s3-request-presigner';
@@ -15,6 +22,8 @@ import {
     exportDirectoryRunStatus,
     ExportDirectorySchema,
 } from '@jackstenglein/chess-dojo-common/src/database/directory';
+import { GameInfo, GameKey } from '@jackstenglein/chess-dojo-common/src/database/game';
+import { User } from '@jackstenglein/chess-dojo-common/src/database/user';
 import AdmZip from 'adm-zip';
 import { APIGatewayProxyHandlerV2 } from 'aws-lambda';
 import { appendFileSync, closeSync, openSync } from 'fs';
@@ -27,7 +36,7 @@ import {
     requireUserInfo,
     success,
 } from './api';
-import { directoryTable, dynamo, gameTable, UpdateItemBuilder } from './database';
+import { directoryTable, dynamo, gameTable, UpdateItemBuilder, USER_TABLE } from './database';
 
 const s3Bucket = `chess-dojo-${process.env.stage}-game-database`;
 const directoryExportTable = `${process.env.stage}-directory-exports`;
@@ -86,8 +95,9 @@ export const startExport: APIGatewayProxyHandlerV2 = async (event) => {
 /**
  * Runs a directory export job. This function first builds a list of games to fetch by
  * recursively (if necessary) traversing through all provided directories and appending
- * their games to the provided list of games in the request. Then each game is fetched,
- * and its PGN is added to a temporary file. Finally, the temporary file is saved to S3.
+ * their games, and the caller's own games if requested, to the provided list of games in
+ * the request. Then each game is fetched, and its PGN is added to a temporary file if it
+ * matches the filters of the request. Finally, the temporary file is saved to S3.
  * @param event The directory export run object created by the startExport function.
  */
 export const runExport = async (event: object) => {
@@ -95,8 +105,11 @@ export const runExport = async (event: object) => {
     const run = exportDirectoryRunSchema.parse(event);
 
     try {
-        const games = (run.request.games ?? []).concat(
-            ...(await fetchGameInfoFromDirectories(run.username, run.request)),
+        const games = uniqueGames(
+            (run.request.games ?? []).concat(
+                ...(await fetchGameInfoFromDirectories(run.username, run.request)),
+                ...(run.request.ownerGames ? await fetchOwnerGameInfo(run.username) : []),
+            ),
         );
 
         if (games.length === 0) {
@@ -116,6 +129,8 @@ export const runExport = async (event: object) => {
         );
 
         const shouldRerender = Object.values(run.request.options ?? {}).some((v) => v);
+        const filter = new ExportFilter(run.username, run.request.filters);
+        let exported = 0;
         const fd = openSync(`/tmp/${run.id}.pgn`, 'a');
         console.log('Fetching %d total games: ', games.length, JSON.stringify(games));
         for (let i = 0; i < games.length; i += 100) {
@@ -125,7 +140,8 @@ export const runExport = async (event: object) => {
                 RequestItems: {
                     [gameTable]: {
                         Keys: batch.map((g) => ({ cohort: { S: g.cohort }, id: { S: g.id } })),
-                        ProjectionExpression: 'pgn',
+                        ProjectionExpression: 'pgn, #owner, #date, headers, unlisted',
+                        ExpressionAttributeNames: { '#owner': 'owner', '#date': 'date' },
                     },
                 },
             });
@@ -136,12 +152,16 @@ export const runExport = async (event: object) => {
                     publicMessage: 'No responses from game table',
                 });
             }
-            const pgns: string[] = response.Responses[gameTable].map((g) =>
-                shouldRerender
-                    ? new Chess({ pgn: unmarshall(g).pgn }).renderPgn(run.request.options)
-                    : unmarshall(g).pgn,
+            const batchGames = response.Responses[gameTable].map(
+                (g) => unmarshall(g) as ExportGame,
+            );
+            const pgns: string[] = (await filter.apply(batchGames)).map((g) =>
+                shouldRerender ? new Chess({ pgn: g.pgn }).renderPgn(run.request.options) : g.pgn,
             );
-            appendFileSync(fd, pgns.join('\n\n\n') + '\n\n\n');
+            if (pgns.length > 0) {
+                appendFileSync(fd, pgns.join('\n\n\n') + '\n\n\n');
+                exported += pgns.length;
+            }
 
             const input = new UpdateItemBuilder()
                 .key('username', run.username)
@@ -153,6 +173,13 @@ export const runExport = async (event: object) => {
         }
         closeSync(fd);
 
+        if (exported === 0) {
+            throw new ApiError({
+                statusCode: 400,
+                publicMessage: 'Invalid request: no games match the provided filters',
+            });
+        }
+
         const zip = new AdmZip();
         zip.addLocalFile(`/tmp/${run.id}.pgn`, '', 'dojo-export.pgn');
 
@@ -189,6 +216,162 @@ export const runExport = async (event: object) => {
     }
 };
 
+/** The fields of a game fetched by runExport. */
+type ExportGame = Pick<GameInfo, 'owner' | 'date' | 'headers' | 'unlisted'> & { pgn: string };
+
+/** The fields of a user fetched by ExportFilter. */
+type ExportUser = Pick<User, 'username' | 'displayName' | 'ratings'>;
+
+/**
+ * Returns the given games without duplicates, in their original order.
+ * @param games The games to deduplicate.
+ * @returns The unique games.
+ */
+function uniqueGames(games: GameKey[]): GameKey[] {
+    const seen = new Set<string>();
+    return games.filter((g) => {
+        const key = `${g.cohort}/${g.id}`;
+        if (seen.has(key)) {
+            return false;
+        }
+        seen.add(key);
+        return true;
+    });
+}
+
+/**
+ * Returns a list of cohort and id for each game owned by the given user.
+ * @param username The username of the user that triggered the export run.
+ * @returns A list of cohort and id for each game owned by the user.
+ */
+async function fetchOwnerGameInfo(username: string): Promise<GameKey[]> {
+    const games: GameKey[] = [];
+    let startKey: QueryCommandOutput['LastEvaluatedKey'] = undefined;
+
+    do {
+        const output: QueryCommandOutput = await dynamo.send(
+            new QueryCommand({
+                TableName: gameTable,
+                IndexName: 'OwnerIdx',
+                KeyConditionExpression: '#owner = :owner',
+                ExpressionAttributeNames: { '#owner': 'owner' },
+                ExpressionAttributeValues: { ':owner': { S: username } },
+                ProjectionExpression: 'cohort, id',
+                ExclusiveStartKey: startKey,
+            }),
+        );
+        games.push(...(output.Items ?? []).map((item) => unmarshall(item) as GameKey));
+        startKey = output.LastEvaluatedKey;
+    } while (startKey);
+
+    return games;
+}
+
+/**
+ * Applies the filters of an export request to the fetched games. Unlisted games owned by
+ * other users are always excluded.
+ */
+class ExportFilter {
+    /** The names each game owner may appear under in the White and Black headers. */
+    private playerNames: Record<string, string[]> = {};
+
+    /**
+     * @param username The username of the user that triggered the export run.
+     * @param filters The filters of the export request.
+     */
+    constructor(
+        private username: string,
+        private filters: ExportDirectoryRequest['filters'],
+    ) {}
+
+    /**
+     * Returns the given games which match the filters, in their original order.
+     * @param games The games to filter.
+     * @returns The matching games.
+     */
+    async apply(games: ExportGame[]): Promise<ExportGame[]> {
+        const color = this.filters?.color;
+        if (color === 'white' || color === 'black') {
+            await this.fetchPlayerNames(games.map((g) => g.owner));
+        }
+        return games.filter((g) => this.matches(g));
+    }
+
+    /**
+     * Returns true if the given game matches the filters.
+     * @param game The game to check.
+     */
+    private matches(game: ExportGame): boolean {
+        if (game.unlisted && game.owner !== this.username) {
+            return false;
+        }
+
+        const { startDate, endDate, color, results } = this.filters ?? {};
+        if (startDate && game.date < startDate) {
+            return false;
+        }
+        if (endDate && game.date > endDate) {
+            return false;
+        }
+        if (results?.length && !(results as string[]).includes(game.headers?.Result ?? '')) {
+            return false;
+        }
+        if (color === 'white' || color === 'black') {
+            return this.ownerColor(game) === color;
+        }
+        return true;
+    }
+
+    /**
+     * Returns the color played by the owner of the given game, found by matching the
+     * owner's player names against the White and Black headers. Undefined is returned if
+     * neither or both headers match.
+     * @param game The game to check.
+     */
+    private ownerColor(game: ExportGame): 'white' | 'black' | undefined {
+        const names = this.playerNames[game.owner] ?? [];
+        const white = names.includes(game.headers?.White?.trim().toLowerCase() ?? '');
+        const black = names.includes(game.headers?.Black?.trim().toLowerCase() ?? '');
+        if (white === black) {
+            return undefined;
+        }
+        return white ? 'white' : 'black';
+    }
+
+    /**
+     * Fetches the player names of the given users which have not already been fetched. A
+     * user's player names are their display name and their username in each rating system.
+     * @param usernames The usernames to fetch.
+     */
+    private async fetchPlayerNames(usernames: string[]) {
+        const missing = [...new Set(usernames)].filter((u) => !this.playerNames[u]);
+        for (let i = 0; i < missing.length; i += 100) {
+            let keys: BatchGetItemCommandInput['RequestItems'] | undefined = {
+                [USER_TABLE]: {
+                    Keys: missing.slice(i, i + 100).map((u) => ({ username: { S: u } })),
+                    ProjectionExpression: 'username, displayName, ratings',
+                },
+            };
+            while (keys && Object.keys(keys).length) {
+                const output = await dynamo.send(new BatchGetItemCommand({ RequestItems: keys }));
+                for (const item of output.Responses?.[USER_TABLE] ?? []) {
+                    const user = unmarshall(item) as ExportUser;
+                    this.playerNames[user.username] = [
+                        user.displayName,
+                        ...Object.values(user.ratings ?? {}).map((r) => r?.username),
+                    ]
+                        .filter((name): name is string => Boolean(name))
+                        .map((name) => name.trim().toLowerCase());
+                }
+                keys = output.UnprocessedKeys;
+            }
+        }
+        for (const username of missing) {
+            this.playerNames[username] = this.playerNames[username] ?? [];
+        }
+    }
+}
+
 /**
  * Returns a list of cohort and id for each game in the directories provided in the request.
  * If specified in the request, subdirectories are recursively checked as well.
@@ -211,7 +394,7 @@ async function fetchGameInfoFromDirectories(username: string, request: ExportDir
                     RequestItems: {
                         [directoryTable]: {
                             Keys: batch.map((d) => ({ owner: { S: d.owner }, id: { S: d.id } })),
-                            ProjectionExpression: '#owner, id, parent, #items, access',
+                            ProjectionExpression: '#owner, id, parent, #items, access, clubAccess',
                             ExpressionAttributeNames: { '#owner': 'owner', '#items': 'items' },
                         },
                     },
@@ -230,13 +413,13 @@ async function fetchGameInfoFromDirectories(username: string, request: ExportDir
             for (const directory of directories) {
                 if (
                     topLevel &&
-                    !checkAccess({
+                    !(await checkAccess({
                         owner: directory.owner,
                         id: directory.id,
                         username,
                         role: DirectoryAccessRole.Viewer,
                         directory,
-                    })
+                    }))
                 ) {
                     throw new ApiError({
                         statusCode: 403,
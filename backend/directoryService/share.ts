@@ -56,22 +56,25 @@ export const handler: APIGatewayProxyHandlerV2 = async (event) => {
 };
 
 /**
- * Sets the access on the given directory.
+ * Sets the user and club access on the given directory.
  * @param request The owner, id and new access of the directory.
  * @returns The updated directory.
  */
 async function shareDirectory(request: ShareDirectoryRequest) {
     const updatedAt = new Date().toISOString();
-    const input = new UpdateItemBuilder()
+    const builder = new UpdateItemBuilder()
         .key('owner', request.owner)
         .key('id', request.id)
         .set('updatedAt', updatedAt)
         .set('access', request.access)
         .condition(attributeExists('id'))
         .table(directoryTable)
-        .return('ALL_OLD')
-        .build();
+        .return('ALL_OLD');
+    if (request.clubAccess) {
+        builder.set('clubAccess', request.clubAccess);
+    }
 
+    const input = builder.build();
     console.log('Input: %j', input);
     const result = await dynamo.send(input);
     const directory = unmarshall(result.Attributes!) as Directory;
@@ -80,6 +83,9 @@ async function shareDirectory(request: ShareDirectoryRequest) {
 
     directory.updatedAt = updatedAt;
     directory.access = request.access;
+    if (request.clubAccess) {
+        directory.clubAccess = request.clubAccess;
+    }
     return directory;
 }
 
@@ -14,7 +14,12 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.EventMessager = database.DynamoDB
+type CommentRepository interface {
+	database.EventMessager
+	database.MentionResolver
+}
+
+var repository CommentRepository = database.DynamoDB
 
 func main() {
 	lambda.Start(handler)
@@ -61,5 +66,16 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
+	target := &database.CommentTarget{
+		Type:      database.CommentTargetType_Event,
+		Id:        e.Id,
+		CommentId: comment.Id,
+	}
+	if mentions, err := database.ResolveMentions(repository, comment.Content, comment.Owner); err != nil {
+		log.Error("Failed to resolve mentions:", err)
+	} else if err := database.SendMentionEvent(target, comment.Owner, comment.OwnerDisplayName, mentions); err != nil {
+		log.Error("Failed to send mention notification event: ", err)
+	}
+
 	return api.Success(e), nil
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.GameAnnotator = database.DynamoDB
+
+// The max number of times the PGN is merged into a game which changed concurrently
+// before the request fails.
+const maxAttempts = 3
+
+type AnnotateGameRequest struct {
+	// The cohort of the game to annotate.
+	Cohort database.DojoCohort `json:"cohort"`
+
+	// The id of the game to annotate.
+	Id string `json:"id"`
+
+	// The new PGN of the game.
+	Pgn string `json:"pgn"`
+
+	// The updatedAt field of the game when the editor loaded it. Used to detect
+	// concurrent changes by other editors.
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type AnnotateGameResponse struct {
+	// The updated game.
+	Game *database.Game `json:"game"`
+
+	// Whether the PGN was merged with concurrent changes by other editors.
+	Merged bool `json:"merged"`
+}
+
+// Handler saves the PGN of a game on behalf of its owner or one of its editors. If the game
+// changed since the editor loaded it, the editor's PGN is merged into the current PGN, using
+// the saved version the editor loaded as the common base, so that neither change is lost.
+// Each write is conditioned on the game's updatedAt, so a change which races with the merge
+// causes it to be retried.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		err := errors.New(400, "Invalid request: username is required", "")
+		return api.Failure(err), nil
+	}
+
+	var request AnnotateGameRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		err = errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)
+		return api.Failure(err), nil
+	}
+	if request.Cohort == "" || request.Id == "" || request.Pgn == "" {
+		err := errors.New(400, "Invalid request: cohort, id and pgn are required", "")
+		return api.Failure(err), nil
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var response *AnnotateGameResponse
+		response, err = annotate(info.Username, &request)
+		if err == nil {
+			return api.Success(response), nil
+		}
+
+		var aerr *errors.Error
+		if !errors.As(err, &aerr) || aerr.Code != 409 {
+			break
+		}
+		log.Infof("Game %s/%s changed during attempt %d: %v", request.Cohort, request.Id, attempt, err)
+	}
+	return api.Failure(err), nil
+}
+
+// annotate fetches the current game and saves the requested PGN, merging it into the
+// current PGN if the game changed since the request's updatedAt. A 409 error is returned
+// if the version at the request's updatedAt has not been saved yet.
+func annotate(editor string, request *AnnotateGameRequest) (*AnnotateGameResponse, error) {
+	game, err := repository.GetGame(string(request.Cohort), request.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !game.CanEdit(editor) {
+		return nil, errors.New(403, "You do not have permission to edit this game", "")
+	}
+
+	update := &database.Game{Cohort: game.Cohort, Id: game.Id, Pgn: request.Pgn}
+	parsed, err := update.ParsePgn()
+	if err != nil {
+		return nil, err
+	}
+
+	merged := game.UpdatedAt != request.UpdatedAt && game.Pgn != request.Pgn
+	if merged {
+		base, err := repository.GetGameVersionAt(game.Cohort, game.Id, request.UpdatedAt)
+		if err != nil {
+			var aerr *errors.Error
+			if errors.As(err, &aerr) && aerr.Code == 404 {
+				// The version is saved asynchronously, so it may not exist yet
+				return nil, errors.Wrap(409, "Conflict: the game was changed by someone else", "", err)
+			}
+			return nil, err
+		}
+
+		update.Pgn, parsed, err = database.MergeGamePgn(game, base, request.Pgn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	game, err = repository.AnnotateGame(editor, update, parsed, game.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &AnnotateGameResponse{Game: game, Merged: merged}, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
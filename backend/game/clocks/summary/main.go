@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The max number of a user's most recent games included in the summary.
+const maxSummaryGames = 200
+
+type TimeUsageRepository interface {
+	database.GameLister
+	database.GameGetter
+}
+
+var repository TimeUsageRepository = database.DynamoDB
+
+type TimeUsageResponse struct {
+	database.TimeUsageSummary
+
+	// The number of games considered, including games without clock times.
+	GamesConsidered int `json:"gamesConsidered"`
+}
+
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	owner := event.PathParameters["owner"]
+	if owner == "" {
+		return api.Failure(errors.New(400, "Invalid request: owner is required", "")), nil
+	}
+	startDate := event.QueryStringParameters["startDate"]
+	endDate := event.QueryStringParameters["endDate"]
+
+	var keys []database.GameKey
+	var startKey string
+	for ok := true; ok && len(keys) < maxSummaryGames; ok = startKey != "" {
+		games, lastKey, err := repository.ListGamesByOwner(owner == info.Username, owner, startDate, endDate, startKey)
+		if err != nil {
+			return api.Failure(err), nil
+		}
+		for _, g := range games {
+			if len(keys) < maxSummaryGames {
+				keys = append(keys, database.GameKey{Cohort: g.Cohort, Id: g.Id})
+			}
+		}
+		startKey = lastKey
+	}
+
+	games, err := repository.BatchGetGames(keys)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	return api.Success(&TimeUsageResponse{
+		TimeUsageSummary: database.SummarizeTimeUsage(games),
+		GamesConsidered:  len(keys),
+	}), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
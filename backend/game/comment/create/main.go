@@ -12,10 +12,16 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.GameCommenter = database.DynamoDB
+type CommentRepository interface {
+	database.GameCommenter
+	database.MentionResolver
+}
+
+var repository CommentRepository = database.DynamoDB
 
 func main() {
 	lambda.Start(handler)
@@ -62,6 +68,21 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		log.Error("Failed to send game comment notification event:", err)
 	}
 
+	target := &database.CommentTarget{
+		Type:      database.CommentTargetType_Game,
+		Cohort:    game.Cohort,
+		GameId:    game.Id,
+		Fen:       comment.Fen,
+		ParentIds: comment.ParentIds,
+		CommentId: comment.Id,
+	}
+	if mentions, err := database.ResolveMentions(repository, comment.Content, comment.Owner.Username); err != nil {
+		log.Error("Failed to resolve mentions:", err)
+	} else if err := database.SendMentionEvent(target, comment.Owner.Username, comment.Owner.DisplayName, mentions); err != nil {
+		log.Error("Failed to send mention notification event:", err)
+	}
+
+	game.CountCommentReactions()
 	if strings.HasPrefix(event.RawPath, "/game/v2/") {
 		response := struct {
 			Game    database.Game            `json:"game"`
@@ -97,6 +118,9 @@ func getComment(event api.Request) (database.PositionComment, error) {
 	if comment.Fen == "" {
 		return comment, errors.New(400, "Invalid request: fen is required", "")
 	}
+	if _, err := chess.ParseFen(comment.Fen); err != nil {
+		return comment, errors.Wrap(400, "Invalid request: fen is invalid", "", err)
+	}
 
 	if comment.Ply < 0 {
 		return comment, errors.New(400, "Invalid request: ply must be non-negative", "")
@@ -106,6 +130,14 @@ func getComment(event api.Request) (database.PositionComment, error) {
 		return comment, errors.New(400, "Invalid request: one of content and suggestedVariation must be non-empty", "")
 	}
 
+	if comment.SuggestedVariation != "" {
+		variation, err := database.NormalizeSuggestedVariation(comment.Fen, comment.SuggestedVariation)
+		if err != nil {
+			return comment, err
+		}
+		comment.SuggestedVariation = variation
+	}
+
 	comment.Id = uuid.NewString()
 	comment.CreatedAt = time.Now().Format(time.RFC3339)
 	comment.UpdatedAt = comment.CreatedAt
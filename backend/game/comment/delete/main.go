@@ -36,6 +36,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
+	game.CountCommentReactions()
 	return api.Success(game), nil
 }
 
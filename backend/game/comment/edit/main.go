@@ -36,6 +36,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
+	game.CountCommentReactions()
 	return api.Success(game), nil
 }
 
@@ -61,5 +62,13 @@ func getUpdate(event api.Request) (database.PositionCommentUpdate, error) {
 		return update, errors.New(400, "Invalid request: one of content and suggestedVariation must not be empty", "")
 	}
 
+	if update.SuggestedVariation != "" {
+		variation, err := database.NormalizeSuggestedVariation(update.Fen, update.SuggestedVariation)
+		if err != nil {
+			return update, err
+		}
+		update.SuggestedVariation = variation
+	}
+
 	return update, nil
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.GameCommentReactor = database.DynamoDB
+
+func main() {
+	lambda.Start(handler)
+}
+
+// handler toggles a reaction type on a position comment or reply for the caller. The
+// owner of the comment is notified when a reaction is added.
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	request := database.PositionCommentReaction{}
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return api.Failure(errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)), nil
+	}
+	if !database.IsValidCohort(request.Cohort) {
+		return api.Failure(errors.New(400, "Invalid request: cohort is invalid", "")), nil
+	}
+	if request.GameId == "" || request.Fen == "" || request.Id == "" {
+		return api.Failure(errors.New(400, "Invalid request: gameId, fen and id are required", "")), nil
+	}
+
+	game, err := repository.GetGame(string(request.Cohort), request.GameId)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	comment, ok := game.GetPositionComment(request.Fen, request.ParentIds, request.Id)
+	if !ok {
+		return api.Failure(errors.New(404, "Invalid request: comment not found", "")), nil
+	}
+
+	var existing *database.Reaction
+	if r, ok := comment.Reactions[info.Username]; ok {
+		existing = &r
+	}
+	types, added, err := database.ToggleReactionType(existing, request.Type)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if len(types) == 0 && existing == nil {
+		game.CountCommentReactions()
+		return api.Success(game), nil
+	}
+
+	reactor, err := repository.GetUser(info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	reaction := database.Reaction{
+		Username:    reactor.Username,
+		DisplayName: reactor.DisplayName,
+		Cohort:      reactor.DojoCohort,
+		UpdatedAt:   time.Now().Format(time.RFC3339),
+		Types:       types,
+	}
+
+	game, err = repository.SetCommentReaction(&request, &reaction)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	if added && comment.Owner.Username != reactor.Username {
+		if err := database.SendGameCommentReactionEvent(game, comment); err != nil {
+			log.Error("Failed to send game comment reaction notification event: ", err)
+		}
+	}
+
+	game.CountCommentReactions()
+	return api.Success(game), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type fakeRepository struct {
+	database.GameLister
+	games        []*database.Game
+	fingerprints map[string]string
+}
+
+func (r *fakeRepository) ScanCohort(cohort database.DojoCohort, startKey string) ([]*database.Game, string, error) {
+	var result []*database.Game
+	for _, g := range r.games {
+		if g.Cohort == cohort {
+			result = append(result, g)
+		}
+	}
+	return result, "", nil
+}
+
+func (r *fakeRepository) SetGameFingerprint(cohort database.DojoCohort, id, fingerprint string) error {
+	r.fingerprints[id] = fingerprint
+	return nil
+}
+
+func (r *fakeRepository) GetGameKeyByFingerprint(owner, fingerprint string) (*database.GameKey, error) {
+	return nil, nil
+}
+
+func TestFindDuplicates(t *testing.T) {
+	pgn := "[White \"Alice\"]\n[Black \"Bob\"]\n[Date \"2024.01.02\"]\n\n1. e4 e5 2. Nf3 *"
+	repo := &fakeRepository{
+		games: []*database.Game{
+			{Cohort: "1500-1600", Id: "b", Owner: "alice", CreatedAt: "2024-01-03T00:00:00Z", Pgn: "[Event \"Lichess\"]\n" + pgn},
+			{Cohort: "1500-1600", Id: "a", Owner: "alice", CreatedAt: "2024-01-02T00:00:00Z", Pgn: pgn},
+			{Cohort: "1600-1700", Id: "c", Owner: "alice", CreatedAt: "2024-02-01T00:00:00Z", Pgn: pgn},
+			{Cohort: "1500-1600", Id: "d", Owner: "bob", CreatedAt: "2024-01-02T00:00:00Z", Pgn: pgn},
+			{Cohort: "1500-1600", Id: "e", Owner: "alice", CreatedAt: "2024-01-02T00:00:00Z", Pgn: "[White \"Alice\"]\n\n*"},
+			{Cohort: "1500-1600", Id: "f", Owner: "alice", CreatedAt: "2024-01-02T00:00:00Z", Pgn: "1. e5 *"},
+		},
+		fingerprints: make(map[string]string),
+	}
+	repository = repo
+
+	report, err := findDuplicates()
+	if err != nil {
+		t.Fatalf("findDuplicates got error: %v", err)
+	}
+
+	if report.GamesScanned != 6 {
+		t.Errorf("GamesScanned got: %d; want: 6", report.GamesScanned)
+	}
+	if report.FingerprintsUpdated != 4 || len(repo.fingerprints) != 4 {
+		t.Errorf("FingerprintsUpdated got: %d (%d saved); want: 4", report.FingerprintsUpdated, len(repo.fingerprints))
+	}
+	if report.DuplicateCount != 2 || len(report.Groups) != 1 {
+		t.Fatalf("Report got %d duplicates in %d groups; want 2 in 1", report.DuplicateCount, len(report.Groups))
+	}
+
+	group := report.Groups[0]
+	if group.Owner != "alice" || len(group.Games) != 3 {
+		t.Fatalf("Group got: %+v; want alice with 3 games", group)
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		if group.Games[i].Id != id {
+			t.Errorf("Group.Games[%d] got: %s; want: %s", i, group.Games[i].Id, id)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type Event events.CloudWatchEvent
+
+type DuplicateRepository interface {
+	database.GameLister
+	database.GameFingerprinter
+}
+
+var repository DuplicateRepository = database.DynamoDB
+var store database.ExportStore = database.S3
+
+// The key of the duplicate game report in the export store.
+const reportKey = "reports/duplicate-games.json"
+
+// DuplicateGame contains the identifying fields of a game in a DuplicateGroup.
+type DuplicateGame struct {
+	Cohort    database.DojoCohort `json:"cohort"`
+	Id        string              `json:"id"`
+	White     string              `json:"white"`
+	Black     string              `json:"black"`
+	Date      string              `json:"date"`
+	CreatedAt string              `json:"createdAt"`
+	Unlisted  bool                `json:"unlisted"`
+}
+
+// DuplicateGroup is a set of games owned by the same user with the same fingerprint.
+type DuplicateGroup struct {
+	// The username of the owner of the games.
+	Owner string `json:"owner"`
+
+	// The shared fingerprint of the games.
+	Fingerprint string `json:"fingerprint"`
+
+	// The games in the group, ordered by creation date. The first game is the original.
+	Games []DuplicateGame `json:"games"`
+}
+
+// DuplicateReport is the output of the duplicate detection job.
+type DuplicateReport struct {
+	// The time the report was created, in time.RFC3339 format.
+	CreatedAt string `json:"createdAt"`
+
+	// The number of games scanned.
+	GamesScanned int `json:"gamesScanned"`
+
+	// The number of games whose fingerprint was missing or out of date and was updated.
+	FingerprintsUpdated int `json:"fingerprintsUpdated"`
+
+	// The number of games which could be removed without losing any unique games.
+	DuplicateCount int `json:"duplicateCount"`
+
+	// The groups of duplicate games, ordered by owner.
+	Groups []DuplicateGroup `json:"groups"`
+}
+
+// findDuplicates scans every game in the database, backfilling any missing or stale
+// fingerprints, and returns a report of the games sharing an owner and fingerprint.
+func findDuplicates() (*DuplicateReport, error) {
+	report := &DuplicateReport{CreatedAt: time.Now().Format(time.RFC3339)}
+	groups := make(map[string]*DuplicateGroup)
+
+	for _, cohort := range database.Cohorts {
+		var startKey string
+		for ok := true; ok; ok = startKey != "" {
+			games, lastKey, err := repository.ScanCohort(cohort, startKey)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, g := range games {
+				report.GamesScanned++
+
+				parsed, err := g.ParsePgn()
+				if err != nil {
+					log.Warnf("Skipping corrupt game %s/%s: %v", g.Cohort, g.Id, err)
+					continue
+				}
+				fingerprint := database.GameFingerprint(parsed)
+				if fingerprint == "" {
+					continue
+				}
+
+				if fingerprint != g.Fingerprint {
+					if err := repository.SetGameFingerprint(g.Cohort, g.Id, fingerprint); err != nil {
+						log.Errorf("Failed to set fingerprint of game %s/%s: %v", g.Cohort, g.Id, err)
+					} else {
+						report.FingerprintsUpdated++
+					}
+				}
+
+				key := g.Owner + "#" + fingerprint
+				group, ok := groups[key]
+				if !ok {
+					group = &DuplicateGroup{Owner: g.Owner, Fingerprint: fingerprint}
+					groups[key] = group
+				}
+				group.Games = append(group.Games, DuplicateGame{
+					Cohort:    g.Cohort,
+					Id:        g.Id,
+					White:     g.White,
+					Black:     g.Black,
+					Date:      g.Date,
+					CreatedAt: g.CreatedAt,
+					Unlisted:  g.Unlisted,
+				})
+			}
+
+			startKey = lastKey
+		}
+	}
+
+	for _, group := range groups {
+		if len(group.Games) < 2 {
+			continue
+		}
+		sort.Slice(group.Games, func(i, j int) bool {
+			return group.Games[i].CreatedAt < group.Games[j].CreatedAt
+		})
+		report.Groups = append(report.Groups, *group)
+		report.DuplicateCount += len(group.Games) - 1
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Owner != report.Groups[j].Owner {
+			return report.Groups[i].Owner < report.Groups[j].Owner
+		}
+		return report.Groups[i].Games[0].CreatedAt < report.Groups[j].Games[0].CreatedAt
+	})
+	return report, nil
+}
+
+func Handler(ctx context.Context, event Event) (Event, error) {
+	log.Infof("Event: %#v", event)
+	log.SetRequestId(event.ID)
+
+	report, err := findDuplicates()
+	if err != nil {
+		log.Errorf("Failed to find duplicate games: %v", err)
+		return event, err
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		err = errors.Wrap(500, "Temporary server error", "Failed to marshal report", err)
+		log.Error(err)
+		return event, err
+	}
+	if err := store.UploadExport(reportKey, bytes.NewReader(b)); err != nil {
+		log.Errorf("Failed to upload report: %v", err)
+		return event, err
+	}
+
+	log.Infof("Scanned %d games, updated %d fingerprints and found %d duplicates in %d groups. Report saved to %s",
+		report.GamesScanned, report.FingerprintsUpdated, report.DuplicateCount, len(report.Groups), reportKey)
+	return event, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.GameEditorSetter = database.DynamoDB
+
+type SetEditorsRequest struct {
+	// The cohort of the game.
+	Cohort database.DojoCohort `json:"cohort"`
+
+	// The id of the game.
+	Id string `json:"id"`
+
+	// The usernames of the new editors of the game, which replace any existing editors.
+	Editors []string `json:"editors"`
+}
+
+// Handler sets the users who can edit a game's PGN alongside its owner. Only the owner can
+// set the editors.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		err := errors.New(400, "Invalid request: username is required", "")
+		return api.Failure(err), nil
+	}
+
+	var request SetEditorsRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		err = errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)
+		return api.Failure(err), nil
+	}
+	if request.Cohort == "" || request.Id == "" {
+		err := errors.New(400, "Invalid request: cohort and id are required", "")
+		return api.Failure(err), nil
+	}
+
+	editors, err := database.NormalizeGameEditors(info.Username, request.Editors)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	game, err := repository.SetGameEditors(info.Username, request.Cohort, request.Id, editors)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(game), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
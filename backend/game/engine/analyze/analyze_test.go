@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/engine"
+)
+
+type fakeEngine struct{}
+
+func (fakeEngine) Name() string { return "FakeEngine" }
+
+// Analyze scores every position 0 with the first legal move as the best move.
+func (fakeEngine) Analyze(fen string, depth int) (*engine.Result, error) {
+	position, err := chess.ParseFen(fen)
+	if err != nil {
+		return nil, err
+	}
+	move := position.LegalMoves()[0].UCI()
+	return &engine.Result{BestMove: move, PV: []string{move}}, nil
+}
+
+func (fakeEngine) Close() error { return nil }
+
+type fakeRepository struct {
+	database.GameGetter
+	games    map[string]*database.Game
+	analyzed map[string]bool
+}
+
+func (r *fakeRepository) GetGame(cohort, id string) (*database.Game, error) {
+	if game, ok := r.games[id]; ok {
+		return game, nil
+	}
+	return nil, errors.New(404, "Invalid request: resource not found", "")
+}
+
+func (r *fakeRepository) SetGameEngineAnalysis(cohort database.DojoCohort, id string, analysis *database.EngineAnalysis) error {
+	r.analyzed[id] = true
+	return nil
+}
+
+func TestAnalyzeGame(t *testing.T) {
+	const pgn = "1. e4 e5 2. Nf3 *"
+	parsed, err := chess.Parse(pgn)
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+	var sans []string
+	for _, move := range parsed.Mainline() {
+		sans = append(sans, move.San)
+	}
+	current := database.NewEngineAnalysis(0, sans, make([]chess.Score, len(sans)+1), make([][]string, len(sans)+1))
+
+	newEngine = func() (engine.Engine, error) { return fakeEngine{}, nil }
+	t.Setenv("enginePath", "/opt/bin/stockfish")
+
+	table := []struct {
+		name string
+		game *database.Game
+		body string
+		want bool
+	}{
+		{
+			name: "Pending",
+			game: &database.Game{Id: "game", ReviewStatus: database.GameReviewStatus_Pending, Pgn: pgn},
+			want: true,
+		},
+		{
+			name: "NotPending",
+			game: &database.Game{Id: "game", ReviewStatus: database.GameReviewStatus_None, Pgn: pgn},
+		},
+		{
+			name: "UpToDate",
+			game: &database.Game{Id: "game", ReviewStatus: database.GameReviewStatus_Pending, Pgn: pgn, EngineAnalysis: current},
+		},
+		{
+			name: "Deleted",
+		},
+		{
+			name: "InvalidBody",
+			game: &database.Game{Id: "game", ReviewStatus: database.GameReviewStatus_Pending, Pgn: pgn},
+			body: "{",
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeRepository{games: make(map[string]*database.Game), analyzed: make(map[string]bool)}
+			if tc.game != nil {
+				repo.games[tc.game.Id] = tc.game
+			}
+			repository = repo
+
+			body := tc.body
+			if body == "" {
+				body = `{"cohort": "1500-1600", "id": "game"}`
+			}
+			if err := analyzeGame(body); err != nil {
+				t.Fatalf("analyzeGame got error: %v", err)
+			}
+			if repo.analyzed["game"] != tc.want {
+				t.Errorf("analyzeGame saved analysis: %t; want: %t", repo.analyzed["game"], tc.want)
+			}
+		})
+	}
+}
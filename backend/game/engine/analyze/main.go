@@ -0,0 +1,131 @@
+// Implements the consumer of the engine analysis queue, which runs engine analysis over
+// games pending Sensei review and saves it on the game. Messages are sent by the games
+// table stream handler.
+//
+// Analysis is disabled unless the enginePath environment variable points to a UCI engine
+// binary, which is provided by the engine Lambda layer (see game/engine/layer.sh).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/engine"
+)
+
+// The search depth used if the engineDepth environment variable is not set.
+const defaultDepth = 18
+
+type EngineAnalysisRepository interface {
+	database.GameGetter
+	database.EngineAnalysisSetter
+}
+
+var repository EngineAnalysisRepository = database.DynamoDB
+
+// newEngine starts the engine used to analyze games.
+var newEngine = func() (engine.Engine, error) {
+	return engine.NewUCIEngine(os.Getenv("enginePath"))
+}
+
+func main() {
+	lambda.Start(Handler)
+}
+
+// Handler analyzes the games in the given SQS event. The queue's batch size is 1, so a
+// failed analysis only retries its own game.
+func Handler(ctx context.Context, event events.SQSEvent) error {
+	log.Debugf("Event: %#v", event)
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		log.SetRequestId(lc.AwsRequestID)
+	}
+
+	for _, record := range event.Records {
+		if err := analyzeGame(record.Body); err != nil {
+			log.Errorf("Failed to analyze message %s: %v", record.MessageId, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// analyzeGame runs engine analysis over the game in the given request, if it is still
+// pending review and does not already have an analysis of its current mainline.
+func analyzeGame(body string) error {
+	if os.Getenv("enginePath") == "" {
+		log.Warnf("Skipping request %s: enginePath is not set", body)
+		return nil
+	}
+
+	var request database.EngineAnalysisRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		log.Errorf("Skipping invalid request %q: %v", body, err)
+		return nil
+	}
+
+	game, err := repository.GetGame(string(request.Cohort), request.Id)
+	if err != nil {
+		if isNotFound(err) {
+			log.Infof("Skipping deleted game %s/%s", request.Cohort, request.Id)
+			return nil
+		}
+		return err
+	}
+	if game.ReviewStatus != database.GameReviewStatus_Pending {
+		log.Infof("Skipping game %s/%s which is no longer pending review", game.Cohort, game.Id)
+		return nil
+	}
+
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		log.Warnf("Skipping corrupt game %s/%s: %v", game.Cohort, game.Id, err)
+		return nil
+	}
+	if game.EngineAnalysis != nil && game.EngineAnalysis.Matches(parsed) {
+		log.Infof("Skipping game %s/%s with up-to-date analysis", game.Cohort, game.Id)
+		return nil
+	}
+
+	e, err := newEngine()
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	analysis, err := engine.AnalyzeGame(e, parsed, getDepth())
+	if err != nil {
+		return err
+	}
+
+	if err := repository.SetGameEngineAnalysis(game.Cohort, game.Id, analysis); err != nil {
+		if isNotFound(err) {
+			log.Infof("Skipping deleted game %s/%s", game.Cohort, game.Id)
+			return nil
+		}
+		return err
+	}
+	log.Infof("Saved engine analysis of game %s/%s", game.Cohort, game.Id)
+	return nil
+}
+
+// isNotFound returns true if the given error is a 404 error.
+func isNotFound(err error) bool {
+	var aerr *errors.Error
+	return errors.As(err, &aerr) && aerr.Code == 404
+}
+
+// getDepth returns the search depth set by the engineDepth environment variable.
+func getDepth() int {
+	if depth, err := strconv.Atoi(os.Getenv("engineDepth")); err == nil && depth > 0 {
+		return depth
+	}
+	return defaultDepth
+}
@@ -0,0 +1,107 @@
+// Package engine runs chess engines over the mainline of games to produce a
+// database.EngineAnalysis.
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// Result is the outcome of searching a single position.
+type Result struct {
+	// The evaluation of the position, from White's perspective.
+	Score chess.Score
+
+	// The best move in the position in UCI notation.
+	BestMove string
+
+	// The principal variation of the search in UCI notation, starting with the best move.
+	// May be empty if the engine did not report one.
+	PV []string
+}
+
+// Engine searches chess positions. Implementations need not be safe for concurrent use.
+type Engine interface {
+	// Name returns the name reported by the engine.
+	Name() string
+
+	// Analyze searches the position with the given FEN to the given depth.
+	Analyze(fen string, depth int) (*Result, error)
+
+	// Close stops the engine and releases its resources.
+	Close() error
+}
+
+// AnalyzeGame runs the given engine over each mainline position of the given game and
+// returns the resulting analysis. Checkmate and stalemate positions are evaluated without
+// calling the engine. A checkmated position is recorded as mate in 1 for the side which
+// delivered mate, since a score cannot represent mate in 0.
+func AnalyzeGame(e Engine, parsed *chess.Game, depth int) (*database.EngineAnalysis, error) {
+	mainline := parsed.Mainline()
+	positions := parsed.Positions()
+
+	sans := make([]string, len(mainline))
+	for i, n := range mainline {
+		sans[i] = n.San
+	}
+
+	evals := make([]chess.Score, len(positions))
+	bestLines := make([][]string, len(positions))
+	for i, position := range positions {
+		if position.IsCheckmate() {
+			if position.Turn == chess.White {
+				evals[i] = chess.Score{Mate: -1}
+			} else {
+				evals[i] = chess.Score{Mate: 1}
+			}
+			continue
+		}
+		if position.IsStalemate() {
+			continue
+		}
+
+		result, err := e.Analyze(position.Fen(), depth)
+		if err != nil {
+			return nil, err
+		}
+		evals[i] = result.Score
+
+		line := result.PV
+		if len(line) == 0 || line[0] != result.BestMove {
+			line = []string{result.BestMove}
+		}
+		bestLines[i], err = uciToSan(position, line)
+		if err != nil {
+			return nil, fmt.Errorf("engine returned invalid best line in %q: %w", position.Fen(), err)
+		}
+	}
+
+	root := parsed.Root.Position
+	analysis := database.NewEngineAnalysis((root.FullmoveNumber-1)*2+int(root.Turn), sans, evals, bestLines)
+	analysis.Engine = e.Name()
+	analysis.Depth = depth
+	analysis.AnalyzedAt = time.Now().Format(time.RFC3339)
+	return analysis, nil
+}
+
+// uciToSan converts the given line of UCI moves, played from the given position, to SAN.
+// The best move must be legal. Later moves of the line are dropped from the first illegal
+// move, since some engines report stale principal variations.
+func uciToSan(position *chess.Position, line []string) ([]string, error) {
+	sans := make([]string, 0, len(line))
+	for i, uci := range line {
+		move, err := position.ParseUCI(uci)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			break
+		}
+		sans = append(sans, position.San(move))
+		position, _ = position.Play(move)
+	}
+	return sans, nil
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The environment variable which makes the test binary act as a fake UCI engine. Its value
+// is the path of the script the engine replays.
+const fakeEngineEnv = "FAKE_UCI_ENGINE_SCRIPT"
+
+func TestMain(m *testing.M) {
+	if script := os.Getenv(fakeEngineEnv); script != "" {
+		if err := runFakeEngine(script, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeEngine speaks the UCI protocol on r and w, answering each search with the score and
+// principal variation listed for the position in the given script. Positions missing from
+// the script are scored 0 with the first legal move as the best move.
+func runFakeEngine(script string, r io.Reader, w io.Writer) error {
+	data, err := os.ReadFile(script)
+	if err != nil {
+		return err
+	}
+	responses := make(map[string][2]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, "|")
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid script line %q", line)
+		}
+		responses[strings.TrimSpace(parts[0])] = [2]string{strings.TrimSpace(parts[1]), strings.TrimSpace(parts[2])}
+	}
+
+	fen := chess.StartingFen
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		command := scanner.Text()
+		switch {
+		case command == "uci":
+			fmt.Fprintln(w, "id name FakeEngine 1.0")
+			fmt.Fprintln(w, "uciok")
+		case command == "isready":
+			fmt.Fprintln(w, "readyok")
+		case strings.HasPrefix(command, "position fen "):
+			fen = strings.TrimPrefix(command, "position fen ")
+		case strings.HasPrefix(command, "go"):
+			response, ok := responses[fen]
+			if !ok {
+				position, err := chess.ParseFen(fen)
+				if err != nil {
+					return err
+				}
+				response = [2]string{"cp 0", position.LegalMoves()[0].UCI()}
+			}
+			bestMove, _, _ := strings.Cut(response[1], " ")
+			fmt.Fprintf(w, "info depth 1 score %s lowerbound pv %s\n", response[0], bestMove)
+			fmt.Fprintf(w, "info depth 10 score %s nodes 1000 pv %s\n", response[0], response[1])
+			fmt.Fprintf(w, "bestmove %s\n", bestMove)
+		case command == "quit":
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// newFakeEngine starts the test binary as a fake UCI engine replaying the given script.
+func newFakeEngine(t *testing.T, script string) *UCIEngine {
+	t.Setenv(fakeEngineEnv, script)
+	e, err := NewUCIEngine(os.Args[0], "-test.run=^$")
+	if err != nil {
+		t.Fatalf("NewUCIEngine got error: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestUCIEngineAnalyze(t *testing.T) {
+	e := newFakeEngine(t, "testdata/scholars_mate.txt")
+	if e.Name() != "FakeEngine 1.0" {
+		t.Errorf("Name got: %q; want: FakeEngine 1.0", e.Name())
+	}
+
+	table := []struct {
+		name string
+		fen  string
+		want Result
+	}{
+		{
+			name: "WhiteToMove",
+			fen:  chess.StartingFen,
+			want: Result{Score: chess.Score{Centipawns: 30}, BestMove: "e2e4", PV: []string{"e2e4"}},
+		},
+		{
+			name: "BlackToMove",
+			fen:  "r1bqkbnr/pppp1ppp/2n5/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 3 3",
+			want: Result{Score: chess.Score{Centipawns: 50}, BestMove: "g7g6", PV: []string{"g7g6", "h5f3"}},
+		},
+		{
+			name: "Mate",
+			fen:  "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+			want: Result{Score: chess.Score{Mate: 1}, BestMove: "h5f7", PV: []string{"h5f7"}},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := e.Analyze(tc.fen, 10)
+			if err != nil {
+				t.Fatalf("Analyze got error: %v", err)
+			}
+			if !reflect.DeepEqual(*result, tc.want) {
+				t.Errorf("Analyze got: %+v; want: %+v", *result, tc.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeGame(t *testing.T) {
+	e := newFakeEngine(t, "testdata/scholars_mate.txt")
+	parsed, err := chess.Parse("1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0")
+	if err != nil {
+		t.Fatalf("Parse got error: %v", err)
+	}
+
+	analysis, err := AnalyzeGame(e, parsed, 10)
+	if err != nil {
+		t.Fatalf("AnalyzeGame got error: %v", err)
+	}
+
+	if analysis.Engine != "FakeEngine 1.0" || analysis.Depth != 10 {
+		t.Errorf("AnalyzeGame got engine %q, depth %d; want FakeEngine 1.0, 10", analysis.Engine, analysis.Depth)
+	}
+	if analysis.StartEval != (chess.Score{Centipawns: 30}) {
+		t.Errorf("StartEval got: %+v; want: +30", analysis.StartEval)
+	}
+
+	want := []database.EngineMoveAnalysis{
+		{San: "e4", Eval: chess.Score{Centipawns: 30}},
+		{San: "e5", Eval: chess.Score{Centipawns: 20}},
+		{San: "Qh5", Eval: chess.Score{}, BestMove: "Nf3", CentipawnLoss: 20},
+		{San: "Nc6", Eval: chess.Score{}},
+		{San: "Bc4", Eval: chess.Score{Centipawns: 50}},
+		{San: "Nf6", Eval: chess.Score{Mate: 1}, BestMove: "g6", CentipawnLoss: 950, Classification: database.MoveClassification_Blunder, BestLine: []string{"g6", "Qf3"}},
+		{San: "Qxf7#", Eval: chess.Score{Mate: 1}},
+	}
+	if len(analysis.Moves) != len(want) {
+		t.Fatalf("Moves got: %+v; want: %+v", analysis.Moves, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(analysis.Moves[i], want[i]) {
+			t.Errorf("Moves[%d] got: %+v; want: %+v", i, analysis.Moves[i], want[i])
+		}
+	}
+	if !analysis.Matches(parsed) {
+		t.Errorf("Matches got false; want true")
+	}
+}
@@ -0,0 +1,24 @@
+#!/usr/bin/env bash
+
+# Builds layer.zip, the Lambda layer which provides the Stockfish binary used by the
+# analyzeEngine function at /opt/bin/stockfish. Stockfish is compiled statically in an arm64
+# Amazon Linux container to match the architecture of the games service, so Docker must be
+# able to run arm64 images (natively or through emulation). Run it before deploying the
+# games service.
+
+set -euo pipefail
+
+version=${STOCKFISH_VERSION:-sf_17.1}
+
+cd "$(dirname "$0")"
+rm -rf layer layer.zip
+mkdir -p layer/bin
+
+docker run --rm --platform linux/arm64 -v "$PWD/layer/bin:/out" amazonlinux:2023 bash -c "
+    dnf install -y -q gcc-c++ make git glibc-static libstdc++-static &&
+    git clone -q --depth 1 --branch $version https://github.com/official-stockfish/Stockfish.git &&
+    make -C Stockfish/src -j build ARCH=armv8 EXTRALDFLAGS=-static &&
+    cp Stockfish/src/stockfish /out/stockfish"
+
+(cd layer && zip -qr ../layer.zip bin)
+rm -rf layer
@@ -0,0 +1,240 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+)
+
+// The default time to wait for each response from a UCI engine.
+const defaultUCITimeout = 2 * time.Minute
+
+// UCIEngine is an Engine backed by an external process which speaks the Universal Chess
+// Interface protocol over stdin and stdout.
+type UCIEngine struct {
+	// The name reported by the engine in its id name line.
+	name string
+
+	// The engine process.
+	cmd *exec.Cmd
+
+	// The engine's stdin.
+	stdin io.WriteCloser
+
+	// The lines written by the engine to stdout. Closed when stdout is closed.
+	lines chan string
+
+	// The maximum time to wait for each response from the engine.
+	timeout time.Duration
+}
+
+// NewUCIEngine starts the UCI engine at the given path with the given arguments and waits
+// for it to become ready.
+func NewUCIEngine(path string, args ...string) (*UCIEngine, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start engine %q: %w", path, err)
+	}
+
+	e := &UCIEngine{
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan string),
+		timeout: defaultUCITimeout,
+	}
+	go e.readLines(stdout)
+
+	if err := e.send("uci"); err != nil {
+		e.Close()
+		return nil, err
+	}
+	err = e.readUntil("uciok", func(line string) {
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			e.name = strings.TrimSpace(name)
+		}
+	})
+	if err != nil {
+		e.Close()
+		return nil, err
+	}
+
+	if err := e.send("ucinewgame", "isready"); err != nil {
+		e.Close()
+		return nil, err
+	}
+	if err := e.readUntil("readyok", nil); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// readLines sends each line of r to e.lines until r is closed.
+func (e *UCIEngine) readLines(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.lines <- scanner.Text()
+	}
+	close(e.lines)
+}
+
+// send writes the given commands to the engine.
+func (e *UCIEngine) send(commands ...string) error {
+	for _, command := range commands {
+		if _, err := io.WriteString(e.stdin, command+"\n"); err != nil {
+			return fmt.Errorf("failed to send %q to engine: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// readUntil reads lines from the engine until one starts with the given token. handle,
+// if not nil, is called with each line, including the last.
+func (e *UCIEngine) readUntil(token string, handle func(line string)) error {
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return fmt.Errorf("engine exited while waiting for %s", token)
+			}
+			if handle != nil {
+				handle(line)
+			}
+			if line == token || strings.HasPrefix(line, token+" ") {
+				return nil
+			}
+		case <-timer.C:
+			return fmt.Errorf("timed out waiting for %s from engine", token)
+		}
+	}
+}
+
+// Name returns the name reported by the engine.
+func (e *UCIEngine) Name() string {
+	return e.name
+}
+
+// Analyze searches the position with the given FEN to the given depth.
+func (e *UCIEngine) Analyze(fen string, depth int) (*Result, error) {
+	position, err := chess.ParseFen(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.send("position fen "+fen, fmt.Sprintf("go depth %d", depth)); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	var parseErr error
+	err = e.readUntil("bestmove", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+		switch fields[0] {
+		case "info":
+			if score, ok, err := parseInfoScore(fields, position.Turn); err != nil {
+				parseErr = err
+			} else if ok {
+				result.Score = score
+				result.PV = parseInfoPV(fields)
+			}
+		case "bestmove":
+			if len(fields) > 1 && fields[1] != "(none)" {
+				result.BestMove = fields[1]
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	if result.BestMove == "" {
+		return nil, fmt.Errorf("engine returned no best move in %q", fen)
+	}
+	return result, nil
+}
+
+// parseInfoScore returns the score of the given UCI info line, converted to White's
+// perspective. False is returned if the line has no exact score.
+func parseInfoScore(fields []string, turn chess.Color) (chess.Score, bool, error) {
+	for i := 1; i+2 < len(fields); i++ {
+		if fields[i] != "score" {
+			continue
+		}
+		if i+3 < len(fields) && (fields[i+3] == "lowerbound" || fields[i+3] == "upperbound") {
+			return chess.Score{}, false, nil
+		}
+
+		value, err := strconv.Atoi(fields[i+2])
+		if err != nil {
+			return chess.Score{}, false, fmt.Errorf("invalid engine score %q", strings.Join(fields, " "))
+		}
+		if turn == chess.Black {
+			value = -value
+		}
+
+		switch fields[i+1] {
+		case "cp":
+			return chess.Score{Centipawns: value}, true, nil
+		case "mate":
+			if value == 0 {
+				return chess.Score{}, false, nil
+			}
+			return chess.Score{Mate: value}, true, nil
+		}
+		return chess.Score{}, false, fmt.Errorf("invalid engine score %q", strings.Join(fields, " "))
+	}
+	return chess.Score{}, false, nil
+}
+
+// parseInfoPV returns the principal variation of the given UCI info line, or nil if the
+// line has none.
+func parseInfoPV(fields []string) []string {
+	for i, field := range fields {
+		if field == "pv" {
+			return fields[i+1:]
+		}
+	}
+	return nil
+}
+
+// Close stops the engine and releases its resources.
+func (e *UCIEngine) Close() error {
+	e.send("quit")
+	e.stdin.Close()
+	done := make(chan error, 1)
+	go func() {
+		for range e.lines {
+		}
+		done <- e.cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		e.cmd.Process.Kill()
+		return <-done
+	}
+}
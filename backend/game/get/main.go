@@ -13,7 +13,12 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.GameGetter = database.DynamoDB
+type GameGetter interface {
+	database.GameGetter
+	database.DirectoryGetter
+}
+
+var repository GameGetter = database.DynamoDB
 var stage = os.Getenv("stage")
 
 func main() {
@@ -51,5 +56,14 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
+	info := api.GetUserInfo(event)
+	if ok, err := database.CanViewGame(repository, game, info.Username); err != nil {
+		return api.Failure(err), nil
+	} else if !ok {
+		err := errors.New(403, "This game is in a private directory. Ask the owner to share it with you.", "")
+		return api.Failure(err), nil
+	}
+
+	game.CountCommentReactions()
 	return api.Success(game), nil
 }
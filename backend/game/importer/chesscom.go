@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// ChesscomFetcher fetches games from the Chess.com published data API.
+type ChesscomFetcher struct{}
+
+type chesscomArchive struct {
+	Games []chesscomGame `json:"games"`
+}
+
+type chesscomGame struct {
+	Url         string `json:"url"`
+	Pgn         string `json:"pgn"`
+	TimeControl string `json:"time_control"`
+	EndTime     int64  `json:"end_time"`
+	Rules       string `json:"rules"`
+}
+
+// FetchGames returns the finished classical games played by the given user strictly
+// after the given time, ordered by the time they were played. Chess.com does not have a
+// classical time control, so live games with a base time of at least 30 minutes are returned.
+func (ChesscomFetcher) FetchGames(username string, since time.Time) ([]ImportedGame, error) {
+	var games []ImportedGame
+
+	now := time.Now().UTC()
+	month := time.Date(since.Year(), since.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for ; !month.After(now); month = month.AddDate(0, 1, 0) {
+		archive, err := fetchChesscomArchive(username, month)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, game := range archive.Games {
+			playedAt := time.Unix(game.EndTime, 0).UTC()
+			if game.Rules != "chess" || !playedAt.After(since) || !isChesscomClassical(game.TimeControl) {
+				continue
+			}
+			games = append(games, ImportedGame{Url: game.Url, Pgn: game.Pgn, PlayedAt: playedAt})
+		}
+	}
+
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].PlayedAt.Before(games[j].PlayedAt)
+	})
+	return games, nil
+}
+
+// fetchChesscomArchive returns the given user's games in the given month.
+func fetchChesscomArchive(username string, month time.Time) (*chesscomArchive, error) {
+	archiveUrl := fmt.Sprintf("https://api.chess.com/pub/player/%s/games/%d/%02d", url.PathEscape(strings.ToLower(username)), month.Year(), month.Month())
+	resp, err := client.Get(archiveUrl)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to fetch Chess.com archive", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &chesscomArchive{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(500, "Temporary server error", fmt.Sprintf("Chess.com archive request %s returned status %d", archiveUrl, resp.StatusCode))
+	}
+
+	var archive chesscomArchive
+	if err := json.NewDecoder(resp.Body).Decode(&archive); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal Chess.com archive", err)
+	}
+	return &archive, nil
+}
+
+// isChesscomClassical returns true if the given Chess.com time control is a live time
+// control with a base time of at least 30 minutes. Daily games use the form 1/86400 and
+// are excluded.
+func isChesscomClassical(timeControl string) bool {
+	if strings.Contains(timeControl, "/") {
+		return false
+	}
+	base, _, _ := strings.Cut(timeControl, "+")
+	seconds, err := strconv.Atoi(base)
+	return err == nil && seconds >= minClassicalSeconds
+}
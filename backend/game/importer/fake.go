@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FakeFetcher is a Fetcher which does not make network requests. It is used in tests and
+// in local environments where the external sites should not be called.
+type FakeFetcher struct {
+	// The site reported in the generated games.
+	Site string
+
+	// The games returned for each username, in lowercase. If a username is not present,
+	// a single generated game played at midnight UTC of the current day is returned.
+	Games map[string][]ImportedGame
+}
+
+// FetchGames returns the fake games of the given user played strictly after the given time.
+func (f *FakeFetcher) FetchGames(username string, since time.Time) ([]ImportedGame, error) {
+	games, ok := f.Games[strings.ToLower(username)]
+	if !ok {
+		games = []ImportedGame{f.generateGame(username, time.Now().UTC().Truncate(24*time.Hour))}
+	}
+
+	var result []ImportedGame
+	for _, g := range games {
+		if g.PlayedAt.After(since) {
+			result = append(result, g)
+		}
+	}
+	return result, nil
+}
+
+// generateGame returns a short classical game played by the given user at the given time.
+func (f *FakeFetcher) generateGame(username string, playedAt time.Time) ImportedGame {
+	pgn := fmt.Sprintf(`[Event "Rated Classical game"]
+[Site "%s"]
+[Date "%s"]
+[White "%s"]
+[Black "FakeOpponent"]
+[Result "1-0"]
+[WhiteElo "1500"]
+[BlackElo "1500"]
+[TimeControl "1800+30"]
+
+1. e4 { [%%clk 0:30:00] } e5 { [%%clk 0:30:00] } 2. Qh5 { [%%clk 0:29:40] } Nc6 { [%%clk 0:29:10] } 3. Bc4 { [%%clk 0:29:20] } Nf6 { [%%clk 0:28:00] } 4. Qxf7# { [%%clk 0:29:15] } 1-0`,
+		f.Site, playedAt.Format("2006.01.02"), username)
+
+	return ImportedGame{
+		Url:      fmt.Sprintf("https://%s/fake/%s/%d", f.Site, strings.ToLower(username), playedAt.Unix()),
+		Pgn:      pgn,
+		PlayedAt: playedAt,
+	}
+}
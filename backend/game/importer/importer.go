@@ -0,0 +1,50 @@
+// Package importer fetches games played on external sites so that they can be imported
+// into the Dojo database.
+package importer
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var client = http.Client{Timeout: 10 * time.Second}
+
+// The minimum base time of a game, in seconds, for it to be considered classical.
+const minClassicalSeconds = 30 * 60
+
+// ImportedGame is a game fetched from an external site.
+type ImportedGame struct {
+	// The URL of the game on the external site.
+	Url string
+
+	// The PGN of the game.
+	Pgn string
+
+	// The time the game was played.
+	PlayedAt time.Time
+}
+
+// Fetcher fetches the games played by a user on an external site.
+type Fetcher interface {
+	// FetchGames returns the finished classical games played by the given user strictly
+	// after the given time, ordered by the time they were played.
+	FetchGames(username string, since time.Time) ([]ImportedGame, error)
+}
+
+// NewFetchers returns the Fetchers for each supported rating system. If kind is "fake",
+// fake fetchers which do not make any network requests are returned. Otherwise, the
+// fetchers make requests to the real sites.
+func NewFetchers(kind string) map[database.RatingSystem]Fetcher {
+	if kind == "fake" {
+		return map[database.RatingSystem]Fetcher{
+			database.Lichess:  &FakeFetcher{Site: "lichess.org"},
+			database.Chesscom: &FakeFetcher{Site: "chess.com"},
+		}
+	}
+	return map[database.RatingSystem]Fetcher{
+		database.Lichess:  LichessFetcher{},
+		database.Chesscom: ChesscomFetcher{},
+	}
+}
@@ -0,0 +1,52 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsChesscomClassical(t *testing.T) {
+	table := []struct {
+		timeControl string
+		want        bool
+	}{
+		{timeControl: "1800", want: true},
+		{timeControl: "1800+10", want: true},
+		{timeControl: "2700+45", want: true},
+		{timeControl: "600+5", want: false},
+		{timeControl: "1/86400", want: false},
+		{timeControl: "", want: false},
+	}
+
+	for _, tc := range table {
+		if got := isChesscomClassical(tc.timeControl); got != tc.want {
+			t.Errorf("isChesscomClassical(%q) got: %t; want: %t", tc.timeControl, got, tc.want)
+		}
+	}
+}
+
+func TestFakeFetcher(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	fetcher := &FakeFetcher{
+		Site: "lichess.org",
+		Games: map[string][]ImportedGame{
+			"alice": {
+				{Url: "1", PlayedAt: day},
+				{Url: "2", PlayedAt: day.Add(time.Hour)},
+			},
+		},
+	}
+
+	games, err := fetcher.FetchGames("Alice", day)
+	if err != nil {
+		t.Fatalf("FetchGames got error: %v", err)
+	}
+	if len(games) != 1 || games[0].Url != "2" {
+		t.Errorf("FetchGames got: %+v; want only the game played after since", games)
+	}
+
+	games, _ = fetcher.FetchGames("bob", day)
+	if len(games) != 1 || games[0].Pgn == "" {
+		t.Errorf("FetchGames for unknown user got: %+v; want one generated game", games)
+	}
+}
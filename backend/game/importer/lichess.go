@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// The max number of games fetched from Lichess in a single run.
+const maxLichessGames = 100
+
+// LichessFetcher fetches games from the Lichess API.
+type LichessFetcher struct{}
+
+type lichessGame struct {
+	Id        string `json:"id"`
+	Variant   string `json:"variant"`
+	CreatedAt int64  `json:"createdAt"`
+	Pgn       string `json:"pgn"`
+}
+
+// FetchGames returns the finished classical games played by the given user strictly
+// after the given time, ordered by the time they were played.
+func (LichessFetcher) FetchGames(username string, since time.Time) ([]ImportedGame, error) {
+	params := url.Values{}
+	params.Set("since", fmt.Sprint(since.UnixMilli()+1))
+	params.Set("perfType", "classical")
+	params.Set("finished", "true")
+	params.Set("clocks", "true")
+	params.Set("pgnInJson", "true")
+	params.Set("sort", "dateAsc")
+	params.Set("max", fmt.Sprint(maxLichessGames))
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://lichess.org/api/games/user/%s?%s", url.PathEscape(username), params.Encode()), nil)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to create Lichess request", err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to fetch Lichess games", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(500, "Temporary server error", fmt.Sprintf("Lichess games request for %q returned status %d", username, resp.StatusCode))
+	}
+
+	var games []ImportedGame
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var game lichessGame
+		if err := json.Unmarshal(scanner.Bytes(), &game); err != nil {
+			return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal Lichess game", err)
+		}
+		if game.Variant != "" && game.Variant != "standard" {
+			continue
+		}
+
+		games = append(games, ImportedGame{
+			Url:      "https://lichess.org/" + game.Id,
+			Pgn:      game.Pgn,
+			PlayedAt: time.UnixMilli(game.CreatedAt).UTC(),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to read Lichess games", err)
+	}
+	return games, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/importer"
+)
+
+type Event events.CloudWatchEvent
+
+type ImportRepository interface {
+	database.GameFingerprinter
+	database.DirectoryEditor
+	database.GameDeleter
+
+	// ListGameImportUsers returns the users in the given cohort who enabled the automatic game import.
+	ListGameImportUsers(cohort database.DojoCohort, startKey string) ([]*database.User, string, error)
+
+	// UpdateUser applies the specified update to the user with the provided username.
+	UpdateUser(username string, update *database.UserUpdate) (*database.User, error)
+
+	// BatchPutGames inserts the provided list of games into the database.
+	BatchPutGames(games []*database.Game) (int, error)
+}
+
+var repository ImportRepository = database.DynamoDB
+var fetchers = importer.NewFetchers(os.Getenv("gameImportFetcher"))
+
+// The rating systems whose linked accounts games are imported from.
+var importSystems = []database.RatingSystem{database.Lichess, database.Chesscom}
+
+// How far back games are imported the first time a user's account is imported.
+const initialImportWindow = 7 * 24 * time.Hour
+
+var pgnDateRegex = regexp.MustCompile(`^\d{4}\.\d{2}\.\d{2}$`)
+
+// userImport contains the result of importing a single user's games.
+type userImport struct {
+	// The new games to save.
+	games []*database.Game
+
+	// The user's updated import cursors.
+	cursors map[database.RatingSystem]string
+
+	// Whether any of the cursors were changed.
+	cursorsChanged bool
+
+	// The user's updated game schedule, or nil if it was not changed.
+	schedule []database.GameScheduleEntry
+}
+
+// newGame converts the given imported game into an unlisted Dojo game owned by the given user.
+// The game has no timeline entry until the user publishes it.
+func newGame(user *database.User, username string, imported importer.ImportedGame, now time.Time) (*database.Game, error) {
+	parsed, err := chess.Parse(imported.Pgn)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pgnDateRegex.MatchString(parsed.Header("Date")) {
+		parsed.SetHeader("Date", imported.PlayedAt.Format("2006.01.02"))
+	}
+	if parsed.Header("White") == "" {
+		parsed.SetHeader("White", "?")
+	}
+	if parsed.Header("Black") == "" {
+		parsed.SetHeader("Black", "??")
+	}
+	parsed.SetHeader("Result", parsed.Result)
+	parsed.SetHeader("PlyCount", strconv.Itoa(len(parsed.Mainline())))
+	if parsed.Header("Site") == "" || parsed.Header("Site") == "?" {
+		parsed.SetHeader("Site", imported.Url)
+	}
+
+	orientation := string(database.White)
+	if strings.EqualFold(parsed.Header("Black"), username) {
+		orientation = string(database.Black)
+	}
+
+	headers := make(map[string]string, len(parsed.Headers))
+	for k, v := range parsed.Headers {
+		headers[k] = v
+	}
+
+	createdAt := now.Format(time.RFC3339)
+	return &database.Game{
+		Cohort:              user.DojoCohort,
+		Id:                  now.Format("2006.01.02") + "_" + uuid.NewString(),
+		White:               strings.ToLower(parsed.Header("White")),
+		Black:               strings.ToLower(parsed.Header("Black")),
+		Date:                parsed.Header("Date"),
+		CreatedAt:           createdAt,
+		UpdatedAt:           createdAt,
+		Owner:               user.Username,
+		OwnerDisplayName:    user.DisplayName,
+		OwnerPreviousCohort: user.PreviousCohort,
+		Headers:             headers,
+		Pgn:                 parsed.String(),
+		Orientation:         orientation,
+		Unlisted:            true,
+		Fingerprint:         database.GameFingerprint(parsed),
+	}, nil
+}
+
+// consumeScheduleEntry removes one game played on the given PGN date from the given game
+// schedule. The updated schedule is returned, along with whether the date was scheduled.
+func consumeScheduleEntry(schedule []database.GameScheduleEntry, pgnDate string) ([]database.GameScheduleEntry, bool) {
+	date := strings.ReplaceAll(pgnDate, ".", "-")
+	for i, entry := range schedule {
+		if !strings.HasPrefix(entry.Date, date) || entry.Count <= 0 {
+			continue
+		}
+
+		result := make([]database.GameScheduleEntry, 0, len(schedule))
+		result = append(result, schedule[:i]...)
+		if entry.Count > 1 {
+			entry.Count--
+			result = append(result, entry)
+		}
+		result = append(result, schedule[i+1:]...)
+		return result, true
+	}
+	return schedule, false
+}
+
+// importUser fetches and converts the new games of the given user. Fetch failures for one
+// site are logged and do not prevent importing from the other sites.
+func importUser(user *database.User, now time.Time) *userImport {
+	result := &userImport{cursors: make(map[database.RatingSystem]string)}
+	for system, cursor := range user.GameImportCursors {
+		result.cursors[system] = cursor
+	}
+
+	schedule := user.GameSchedule
+	scheduleChanged := false
+	fingerprints := make(map[string]bool)
+
+	for _, system := range importSystems {
+		rating := user.Ratings[system]
+		fetcher := fetchers[system]
+		if rating == nil || strings.TrimSpace(rating.Username) == "" || fetcher == nil {
+			continue
+		}
+		username := strings.TrimSpace(rating.Username)
+
+		since := now.Add(-initialImportWindow)
+		if cursor, err := time.Parse(time.RFC3339, result.cursors[system]); err == nil {
+			since = cursor
+		}
+
+		imported, err := fetcher.FetchGames(username, since)
+		if err != nil {
+			log.Errorf("Failed to fetch %s games for %q (user %s): %v", system, username, user.Username, err)
+			continue
+		}
+
+		for _, ig := range imported {
+			if ig.PlayedAt.After(since) {
+				since = ig.PlayedAt
+			}
+
+			game, err := newGame(user, username, ig, now)
+			if err != nil {
+				log.Warnf("Skipping invalid %s game %s for user %s: %v", system, ig.Url, user.Username, err)
+				continue
+			}
+
+			if game.Fingerprint != "" {
+				if fingerprints[game.Fingerprint] {
+					continue
+				}
+				fingerprints[game.Fingerprint] = true
+
+				existing, err := repository.GetGameKeyByFingerprint(user.Username, game.Fingerprint)
+				if err != nil {
+					log.Errorf("Failed to check for duplicate of %s: %v", ig.Url, err)
+					continue
+				}
+				if existing != nil {
+					log.Debugf("Skipping %s as it duplicates %s/%s", ig.Url, existing.Cohort, existing.Id)
+					continue
+				}
+			}
+
+			var scheduled bool
+			schedule, scheduled = consumeScheduleEntry(schedule, game.Date)
+			scheduleChanged = scheduleChanged || scheduled
+
+			result.games = append(result.games, game)
+		}
+
+		if len(imported) > 0 {
+			result.cursors[system] = since.Format(time.RFC3339)
+			result.cursorsChanged = true
+		}
+	}
+
+	if scheduleChanged {
+		result.schedule = schedule
+		if result.schedule == nil {
+			result.schedule = []database.GameScheduleEntry{}
+		}
+	}
+	return result
+}
+
+// saveImport saves the result of importing the given user's games. If the games cannot be
+// added to the user's My Games directory, the games outside of it are deleted and the
+// user's import cursors are not updated, so the next run imports them again.
+func saveImport(user *database.User, result *userImport) error {
+	if len(result.games) > 0 {
+		if _, err := repository.BatchPutGames(result.games); err != nil {
+			return err
+		}
+		if err := repository.AddDirectoryGames(user.Username, database.MyGamesDirectoryId, result.games); err != nil {
+			deleteOrphanedGames(user.Username, result.games)
+			return err
+		}
+	}
+
+	if !result.cursorsChanged && result.schedule == nil {
+		return nil
+	}
+
+	update := &database.UserUpdate{GameImportCursors: &result.cursors}
+	if result.schedule != nil {
+		update.GameSchedule = &result.schedule
+	}
+	_, err := repository.UpdateUser(user.Username, update)
+	return err
+}
+
+// deleteOrphanedGames deletes the given games which were not added to the My Games
+// directory of the given user. The games only record the directory once they are saved in
+// it, so these games are not in any directory.
+func deleteOrphanedGames(username string, games []*database.Game) {
+	directory := username + "/" + database.MyGamesDirectoryId
+	for _, game := range games {
+		if slices.Contains(game.Directories, directory) {
+			continue
+		}
+		if _, err := repository.DeleteGame(username, string(game.Cohort), game.Id); err != nil {
+			log.Errorf("Failed to delete game %s/%s outside of My Games: %v", game.Cohort, game.Id, err)
+		}
+	}
+}
+
+func Handler(ctx context.Context, event Event) (Event, error) {
+	log.Infof("Event: %#v", event)
+	log.SetRequestId(event.ID)
+
+	now := time.Now().UTC()
+	total := 0
+
+	for _, cohort := range database.Cohorts {
+		var startKey string
+		for ok := true; ok; ok = startKey != "" {
+			users, lastKey, err := repository.ListGameImportUsers(cohort, startKey)
+			if err != nil {
+				log.Errorf("Failed to list users: %v", err)
+				return event, err
+			}
+
+			for _, user := range users {
+				result := importUser(user, now)
+				if err := saveImport(user, result); err != nil {
+					log.Errorf("Failed to save imported games for %s: %v", user.Username, err)
+					continue
+				}
+				total += len(result.games)
+			}
+			startKey = lastKey
+		}
+	}
+
+	log.Infof("Imported %d games", total)
+	return event, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
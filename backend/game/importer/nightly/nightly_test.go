@@ -0,0 +1,193 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/importer"
+)
+
+type fakeRepository struct {
+	users        []*database.User
+	games        []*database.Game
+	directories  map[string][]*database.Game
+	directoryErr error
+	deleted      []string
+	updates      map[string]*database.UserUpdate
+}
+
+func (r *fakeRepository) ListGameImportUsers(cohort database.DojoCohort, startKey string) ([]*database.User, string, error) {
+	var result []*database.User
+	for _, u := range r.users {
+		if u.DojoCohort == cohort {
+			result = append(result, u)
+		}
+	}
+	return result, "", nil
+}
+
+func (r *fakeRepository) UpdateUser(username string, update *database.UserUpdate) (*database.User, error) {
+	r.updates[username] = update
+	return nil, nil
+}
+
+func (r *fakeRepository) BatchPutGames(games []*database.Game) (int, error) {
+	r.games = append(r.games, games...)
+	return len(games), nil
+}
+
+func (r *fakeRepository) DeleteGame(username, cohort, id string) (*database.Game, error) {
+	r.deleted = append(r.deleted, cohort+"/"+id)
+	return nil, nil
+}
+
+func (r *fakeRepository) AddDirectoryGames(owner, id string, games []*database.Game) error {
+	if r.directoryErr != nil {
+		return r.directoryErr
+	}
+	r.directories[owner+"/"+id] = append(r.directories[owner+"/"+id], games...)
+	for _, g := range games {
+		g.Directories = append(g.Directories, owner+"/"+id)
+	}
+	return nil
+}
+
+func (r *fakeRepository) SetGameFingerprint(cohort database.DojoCohort, id, fingerprint string) error {
+	return nil
+}
+
+func (r *fakeRepository) GetGameKeyByFingerprint(owner, fingerprint string) (*database.GameKey, error) {
+	for _, g := range r.games {
+		if g.Owner == owner && g.Fingerprint == fingerprint {
+			return &database.GameKey{Cohort: g.Cohort, Id: g.Id}, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestConsumeScheduleEntry(t *testing.T) {
+	schedule := []database.GameScheduleEntry{
+		{Date: "2024-01-02T18:00:00.000Z", Count: 2},
+		{Date: "2024-01-05T18:00:00.000Z", Count: 1},
+	}
+
+	schedule, ok := consumeScheduleEntry(schedule, "2024.01.02")
+	if !ok || len(schedule) != 2 || schedule[0].Count != 1 {
+		t.Errorf("First consume got: %+v, %t; want count decremented", schedule, ok)
+	}
+
+	schedule, ok = consumeScheduleEntry(schedule, "2024.01.02")
+	if !ok || len(schedule) != 1 || schedule[0].Date != "2024-01-05T18:00:00.000Z" {
+		t.Errorf("Second consume got: %+v, %t; want entry removed", schedule, ok)
+	}
+
+	if _, ok = consumeScheduleEntry(schedule, "2024.01.03"); ok {
+		t.Errorf("Consume of unscheduled date got: true; want false")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	yesterday := time.Now().UTC().Truncate(24 * time.Hour).Add(-24 * time.Hour)
+	pgn := "[White \"alice_li\"]\n[Black \"Bob\"]\n[Date \"" + yesterday.Format("2006.01.02") + "\"]\n[Result \"0-1\"]\n\n1. f3 e5 2. g4 Qh4# 0-1"
+
+	repo := &fakeRepository{
+		users: []*database.User{
+			{
+				Username:    "alice",
+				DisplayName: "Alice",
+				DojoCohort:  "1500-1600",
+				Ratings: map[database.RatingSystem]*database.Rating{
+					database.Lichess:  {Username: "alice_li"},
+					database.Chesscom: {Username: ""},
+				},
+				GameSchedule: []database.GameScheduleEntry{{Date: yesterday.Format(time.RFC3339), Count: 1}},
+			},
+		},
+		directories: make(map[string][]*database.Game),
+		updates:     make(map[string]*database.UserUpdate),
+	}
+	repository = repo
+	fetchers = map[database.RatingSystem]importer.Fetcher{
+		database.Lichess: &importer.FakeFetcher{
+			Site: "lichess.org",
+			Games: map[string][]importer.ImportedGame{
+				"alice_li": {
+					{Url: "https://lichess.org/1", Pgn: pgn, PlayedAt: yesterday},
+					{Url: "https://lichess.org/2", Pgn: pgn, PlayedAt: yesterday.Add(time.Hour)},
+					{Url: "https://lichess.org/3", Pgn: "1. e5 *", PlayedAt: yesterday.Add(2 * time.Hour)},
+				},
+			},
+		},
+	}
+
+	if _, err := Handler(nil, Event{}); err != nil {
+		t.Fatalf("Handler got error: %v", err)
+	}
+
+	if len(repo.games) != 1 {
+		t.Fatalf("Handler saved %d games; want 1 (duplicate and invalid games skipped)", len(repo.games))
+	}
+	game := repo.games[0]
+	if game.Owner != "alice" || game.Cohort != "1500-1600" || game.Orientation != "white" || !game.Unlisted {
+		t.Errorf("Saved game got: %+v; want unlisted game owned by alice with white orientation", game)
+	}
+	if game.PublishedAt != "" || game.TimelineId != "" {
+		t.Errorf("Saved game got publishedAt %q, timelineId %q; want unpublished game", game.PublishedAt, game.TimelineId)
+	}
+	if game.Fingerprint == "" || len(game.Directories) != 1 || game.Directories[0] != "alice/mygames" {
+		t.Errorf("Saved game got fingerprint %q, directories %v; want fingerprint and alice/mygames", game.Fingerprint, game.Directories)
+	}
+	if len(repo.directories["alice/mygames"]) != 1 {
+		t.Errorf("My Games directory got %d games; want 1", len(repo.directories["alice/mygames"]))
+	}
+
+	update := repo.updates["alice"]
+	if update == nil || update.GameImportCursors == nil {
+		t.Fatalf("User update got: %+v; want import cursors", update)
+	}
+	if got := (*update.GameImportCursors)[database.Lichess]; got != yesterday.Add(2*time.Hour).Format(time.RFC3339) {
+		t.Errorf("Lichess cursor got: %s; want time of the last fetched game", got)
+	}
+	if update.GameSchedule == nil || len(*update.GameSchedule) != 0 {
+		t.Errorf("Game schedule update got: %v; want scheduled game consumed", update.GameSchedule)
+	}
+
+	// A second run imports nothing new and does not update the user
+	repo.updates = make(map[string]*database.UserUpdate)
+	repo.users[0].GameImportCursors = *update.GameImportCursors
+	if _, err := Handler(nil, Event{}); err != nil {
+		t.Fatalf("Handler (second run) got error: %v", err)
+	}
+	if len(repo.games) != 1 || len(repo.updates) != 0 {
+		t.Errorf("Second run got %d games and %d updates; want 1 and 0", len(repo.games), len(repo.updates))
+	}
+}
+
+func TestSaveImportDirectoryFailure(t *testing.T) {
+	repo := &fakeRepository{
+		directories:  make(map[string][]*database.Game),
+		updates:      make(map[string]*database.UserUpdate),
+		directoryErr: errors.New(500, "Temporary server error", ""),
+	}
+	repository = repo
+
+	user := &database.User{Username: "alice"}
+	game := &database.Game{Cohort: "1500-1600", Id: "2024.01.02_abc", Owner: "alice"}
+	result := &userImport{
+		games:          []*database.Game{game},
+		cursors:        map[database.RatingSystem]string{database.Lichess: "2024-01-02T00:00:00Z"},
+		cursorsChanged: true,
+	}
+
+	if err := saveImport(user, result); err == nil {
+		t.Fatalf("saveImport got nil error; want error")
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != "1500-1600/2024.01.02_abc" {
+		t.Errorf("Deleted games got: %v; want the game outside of My Games", repo.deleted)
+	}
+	if repo.updates["alice"] != nil {
+		t.Errorf("saveImport got update %+v; want import cursors unchanged", repo.updates["alice"])
+	}
+}
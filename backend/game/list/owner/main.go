@@ -43,7 +43,7 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 		}
 
 		return api.Success(&ListGamesResponse{
-			Games:            games,
+			Games:            database.FilterListedGames(games, info.Username),
 			LastEvaluatedKey: lastKey,
 		}), nil
 	}
@@ -59,7 +59,7 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	}
 
 	return api.Success(&ListGamesResponse{
-		Games:            games,
+		Games:            database.FilterListedGames(games, info.Username),
 		LastEvaluatedKey: lastKey,
 	}), nil
 }
@@ -26,6 +26,9 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
+	if err := repository.LoadEngineSummaries(games); err != nil {
+		return api.Failure(err), nil
+	}
 
 	response := struct {
 		Games            []database.Game `json:"games"`
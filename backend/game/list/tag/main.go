@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.GameLister = database.DynamoDB
+
+type ListGamesResponse struct {
+	Games            []*database.Game `json:"games"`
+	LastEvaluatedKey string           `json:"lastEvaluatedKey,omitempty"`
+}
+
+// Handler lists the games with a given tag, either within a cohort or owned by a user.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+
+	tag, err := database.NormalizeTag(event.QueryStringParameters["tag"])
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	owner := event.QueryStringParameters["owner"]
+	cohort := event.QueryStringParameters["cohort"]
+	startKey := event.QueryStringParameters["startKey"]
+
+	var games []*database.Game
+	var lastKey string
+	switch {
+	case owner != "":
+		games, lastKey, err = repository.ListGamesByOwnerTag(owner == info.Username, owner, tag, startKey)
+	case cohort != "":
+		games, lastKey, err = repository.ListGamesByTag(database.DojoCohort(cohort), tag, startKey)
+	default:
+		err = errors.New(400, "Invalid request: owner or cohort is required", "")
+	}
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	return api.Success(&ListGamesResponse{
+		Games:            database.FilterListedGames(games, info.Username),
+		LastEvaluatedKey: lastKey,
+	}), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
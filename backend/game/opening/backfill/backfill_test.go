@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type fakeRepository struct {
+	database.GameLister
+	games    []*database.Game
+	openings map[string]chess.Opening
+}
+
+func (r *fakeRepository) ScanCohort(cohort database.DojoCohort, startKey string) ([]*database.Game, string, error) {
+	var result []*database.Game
+	for _, g := range r.games {
+		if g.Cohort == cohort {
+			result = append(result, g)
+		}
+	}
+	return result, "", nil
+}
+
+func (r *fakeRepository) SetGameOpening(game *database.Game, opening chess.Opening) error {
+	r.openings[game.Id] = opening
+	return nil
+}
+
+func TestBackfillOpenings(t *testing.T) {
+	ruyLopez := chess.Opening{Eco: "C60", Name: "Ruy Lopez"}
+	repo := &fakeRepository{
+		games: []*database.Game{
+			{Cohort: "1500-1600", Id: "missing", Pgn: "1. e4 e5 2. Nf3 Nc6 3. Bb5 a5 *"},
+			{Cohort: "1500-1600", Id: "malformed", Headers: map[string]string{"ECO": "C", "Opening": "King's Pawn Game"}, Pgn: "1. e4 e5 2. Nf3 Nc6 3. Bb5 *"},
+			{Cohort: "1600-1700", Id: "current", Headers: map[string]string{"ECO": "C60", "Opening": "Ruy Lopez"}, Pgn: "1. e4 e5 2. Nf3 Nc6 3. Bb5 *"},
+			{Cohort: "1600-1700", Id: "unclassified", Pgn: "*"},
+			{Cohort: "1600-1700", Id: "corrupt", Pgn: "1. e5 *"},
+		},
+		openings: make(map[string]chess.Opening),
+	}
+	repository = repo
+
+	result, err := backfillOpenings(Event{}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("backfillOpenings got error: %v", err)
+	}
+
+	want := BackfillResult{GamesScanned: 5, GamesUpdated: 2}
+	if *result != want {
+		t.Errorf("backfillOpenings got: %+v; want: %+v", *result, want)
+	}
+	if len(repo.openings) != 2 || repo.openings["missing"] != ruyLopez || repo.openings["malformed"] != ruyLopez {
+		t.Errorf("SetGameOpening got: %+v; want missing and malformed set to %+v", repo.openings, ruyLopez)
+	}
+}
+
+func TestBackfillOpeningsDeadline(t *testing.T) {
+	repo := &fakeRepository{
+		games: []*database.Game{
+			{Cohort: "1600-1700", Id: "missing", Pgn: "1. e4 e5 2. Nf3 Nc6 3. Bb5 a5 *"},
+		},
+		openings: make(map[string]chess.Opening),
+	}
+	repository = repo
+
+	result, err := backfillOpenings(Event{Cohort: "1600-1700", StartKey: "key"}, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatalf("backfillOpenings got error: %v", err)
+	}
+
+	want := Event{Cohort: "1600-1700", StartKey: "key"}
+	if result.Next == nil || *result.Next != want || result.GamesScanned != 0 {
+		t.Errorf("backfillOpenings got: %+v; want no games scanned and next: %+v", *result, want)
+	}
+
+	result, err = backfillOpenings(*result.Next, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("backfillOpenings got error: %v", err)
+	}
+	if result.Next != nil || result.GamesUpdated != 1 {
+		t.Errorf("backfillOpenings got: %+v; want one game updated and no next", *result)
+	}
+
+	if _, err := backfillOpenings(Event{Cohort: "invalid"}, time.Now().Add(time.Hour)); err == nil {
+		t.Errorf("backfillOpenings with invalid cohort got nil error")
+	}
+}
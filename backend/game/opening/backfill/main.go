@@ -0,0 +1,143 @@
+// Implements a manually invoked job which sets the ECO and Opening headers of all
+// existing games whose headers are missing or inconsistent with the opening classifier.
+//
+// A single run stops shortly before the Lambda timeout. If it did not finish, its result
+// contains the event which resumes the backfill where it stopped.
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The time before the Lambda deadline at which a run stops scanning.
+const deadlineMargin = time.Minute
+
+// Event is the input of a backfill run. An empty event starts from the beginning.
+type Event struct {
+	// The cohort to resume scanning from.
+	Cohort database.DojoCohort `json:"cohort,omitempty"`
+
+	// The start key within Cohort to resume scanning from.
+	StartKey string `json:"startKey,omitempty"`
+}
+
+type BackfillRepository interface {
+	database.GameLister
+	database.GameOpeningSetter
+}
+
+var repository BackfillRepository = database.DynamoDB
+
+// BackfillResult contains the counts produced by a backfill run.
+type BackfillResult struct {
+	// The number of games scanned.
+	GamesScanned int `json:"gamesScanned"`
+
+	// The number of games whose opening headers were updated.
+	GamesUpdated int `json:"gamesUpdated"`
+
+	// The number of games which could not be updated.
+	GamesFailed int `json:"gamesFailed"`
+
+	// The event which resumes the backfill. Nil if the backfill finished.
+	Next *Event `json:"next,omitempty"`
+}
+
+// backfillOpenings scans the games in the database, starting from the given event, and
+// sets the opening headers of the games whose headers are missing or inconsistent with
+// their classified opening. Failures to update a single game are logged and counted, so
+// that one bad game does not stop the backfill. If the given deadline passes, scanning
+// stops and the result contains the event which resumes it.
+func backfillOpenings(start Event, deadline time.Time) (*BackfillResult, error) {
+	result := &BackfillResult{}
+
+	cohorts := database.Cohorts
+	if start.Cohort != "" {
+		i := slices.Index(cohorts, start.Cohort)
+		if i < 0 {
+			return nil, errors.New(400, fmt.Sprintf("Invalid request: cohort %q not found", start.Cohort), "")
+		}
+		cohorts = cohorts[i:]
+	}
+
+	for i, cohort := range cohorts {
+		var startKey string
+		if i == 0 {
+			startKey = start.StartKey
+		}
+
+		for ok := true; ok; ok = startKey != "" {
+			if time.Now().After(deadline) {
+				result.Next = &Event{Cohort: cohort, StartKey: startKey}
+				return result, nil
+			}
+
+			games, lastKey, err := repository.ScanCohort(cohort, startKey)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, g := range games {
+				result.GamesScanned++
+
+				parsed, err := g.ParsePgn()
+				if err != nil {
+					log.Warnf("Skipping corrupt game %s/%s: %v", g.Cohort, g.Id, err)
+					continue
+				}
+
+				opening, ok := database.OpeningUpdate(g, parsed)
+				if !ok {
+					continue
+				}
+				if err := repository.SetGameOpening(g, opening); err != nil {
+					log.Errorf("Failed to set opening of game %s/%s: %v", g.Cohort, g.Id, err)
+					result.GamesFailed++
+				} else {
+					result.GamesUpdated++
+				}
+			}
+
+			startKey = lastKey
+		}
+	}
+	return result, nil
+}
+
+func Handler(ctx context.Context, event Event) (*BackfillResult, error) {
+	log.Infof("Event: %#v", event)
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		log.SetRequestId(lc.AwsRequestID)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(15 * time.Minute)
+	}
+
+	result, err := backfillOpenings(event, deadline.Add(-deadlineMargin))
+	if err != nil {
+		log.Errorf("Failed to backfill openings: %v", err)
+		return nil, err
+	}
+
+	log.Infof("Scanned %d games, updated %d and failed to update %d",
+		result.GamesScanned, result.GamesUpdated, result.GamesFailed)
+	if result.Next != nil {
+		log.Infof("Stopped before the timeout; resume with %#v", *result.Next)
+	}
+	return result, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
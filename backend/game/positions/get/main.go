@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The maximum number of games aggregated into a single position summary. Positions in
+// more games are summarized over a sample of the most recent games, and the older games
+// can be summarized by requesting the next page.
+const maxPositionGames = 500
+
+var repository database.PositionLister = database.DynamoDB
+
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	fen := event.QueryStringParameters["fen"]
+	if fen == "" {
+		err := errors.New(400, "Invalid request: fen is required", "")
+		return api.Failure(err), nil
+	}
+	fen, err := chess.NormalizeFen(fen)
+	if err != nil {
+		err = errors.Wrap(400, "Invalid request: fen is invalid", "", err)
+		return api.Failure(err), nil
+	}
+
+	cohort := database.DojoCohort(event.QueryStringParameters["cohort"])
+	startDate := event.QueryStringParameters["startDate"]
+	endDate := event.QueryStringParameters["endDate"]
+
+	startKey := event.QueryStringParameters["startKey"]
+
+	entries, lastKey, err := repository.ListPositionGames(fen, cohort, startDate, endDate, startKey, maxPositionGames)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(database.SummarizePosition(fen, entries, lastKey)), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The max number of a user's most recent games included in the report.
+const maxReportGames = 200
+
+type RepertoireRepository interface {
+	database.GameLister
+	database.GameGetter
+	database.CourseGetter
+	database.CourseLister
+}
+
+var repository RepertoireRepository = database.DynamoDB
+
+type RepertoireResponse struct {
+	*database.RepertoireReport
+
+	// The number of games considered, including games outside the repertoire.
+	GamesConsidered int `json:"gamesConsidered"`
+}
+
+// Handler returns a report comparing the owner's games to the lines of the opening
+// modules they have progress on.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	owner := event.PathParameters["owner"]
+	if owner == "" {
+		return api.Failure(errors.New(400, "Invalid request: owner is required", "")), nil
+	}
+	startDate := event.QueryStringParameters["startDate"]
+	endDate := event.QueryStringParameters["endDate"]
+
+	user, err := repository.GetUser(owner)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	courses, err := listOpeningCourses()
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	white, black := database.BuildRepertoires(courses, user.OpeningProgress)
+
+	var keys []database.GameKey
+	var startKey string
+	for ok := true; ok && len(keys) < maxReportGames; ok = startKey != "" {
+		games, lastKey, err := repository.ListGamesByOwner(owner == info.Username, owner, startDate, endDate, startKey)
+		if err != nil {
+			return api.Failure(err), nil
+		}
+		for _, g := range games {
+			if len(keys) < maxReportGames {
+				keys = append(keys, database.GameKey{Cohort: g.Cohort, Id: g.Id})
+			}
+		}
+		startKey = lastKey
+	}
+
+	games, err := repository.BatchGetGames(keys)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	return api.Success(&RepertoireResponse{
+		RepertoireReport: database.BuildRepertoireReport(white, black, games),
+		GamesConsidered:  len(keys),
+	}), nil
+}
+
+// listOpeningCourses returns the full contents of every opening course. The course list
+// contains only summaries, so each course is fetched individually.
+func listOpeningCourses() ([]*database.Course, error) {
+	var courses []*database.Course
+	var startKey string
+	for ok := true; ok; ok = startKey != "" {
+		summaries, lastKey, err := repository.ListCourses(string(database.Opening), startKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range summaries {
+			course, err := repository.GetCourse(string(summary.Type), summary.Id)
+			if err != nil {
+				return nil, err
+			}
+			courses = append(courses, course)
+		}
+		startKey = lastKey
+	}
+	return courses, nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,81 @@
+// Implements a lambda handler which claims or releases a game in the review queue, so
+// that multiple reviewers do not review the same game. The caller must be an admin.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.GameReviewClaimer = database.DynamoDB
+
+type ClaimRequest struct {
+	// The cohort of the game.
+	Cohort string `json:"cohort"`
+
+	// The id of the game.
+	Id string `json:"id"`
+
+	// Whether to release the caller's claim instead of claiming the game.
+	Release bool `json:"release"`
+}
+
+func main() {
+	lambda.Start(Handler)
+}
+
+// Handler claims the requested game for the caller until database.GameReviewClaimDuration
+// passes, or releases the caller's claim. Claiming a game the caller already claimed
+// extends the claim.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	user, err := repository.GetUser(info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if !user.IsAdmin {
+		return api.Failure(errors.New(403, "Invalid request: you must be an admin to call this function", "")), nil
+	}
+
+	request := ClaimRequest{}
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
+	}
+	if request.Cohort == "" {
+		return api.Failure(errors.New(400, "Invalid request: cohort is required", "")), nil
+	}
+	if request.Id == "" {
+		return api.Failure(errors.New(400, "Invalid request: id is required", "")), nil
+	}
+
+	var game *database.Game
+	if request.Release {
+		game, err = repository.ReleaseGameReview(user.Username, request.Cohort, request.Id)
+	} else {
+		reviewer := &database.Reviewer{
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			Cohort:      user.DojoCohort,
+		}
+		now := time.Now()
+		game, err = repository.ClaimGameReview(reviewer, request.Cohort, request.Id, now, now.Add(database.GameReviewClaimDuration))
+	}
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(game), nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestBecameLate(t *testing.T) {
+	runAt := time.Date(2024, 1, 10, 14, 0, 0, 0, time.UTC)
+
+	table := []struct {
+		name     string
+		deadline string
+		want     bool
+	}{
+		{name: "SinceLastRun", deadline: "2024-01-10T09:00:00Z", want: true},
+		{name: "AtLastRun", deadline: "2024-01-09T14:00:00Z", want: false},
+		{name: "BeforeLastRun", deadline: "2024-01-08T20:00:00Z", want: false},
+		{name: "AfterThisRun", deadline: "2024-01-10T14:00:03Z", want: false},
+		{name: "InvalidDeadline", deadline: "", want: false},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			entry := &database.ReviewQueueEntry{Deadline: tc.deadline, Overdue: true}
+			if got := becameLate(entry, runAt); got != tc.want {
+				t.Errorf("becameLate(%q) got: %t; want: %t", tc.deadline, got, tc.want)
+			}
+		})
+	}
+}
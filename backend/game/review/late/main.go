@@ -0,0 +1,207 @@
+// Implements a scheduled lambda handler which detects game reviews that were not completed
+// by their deadline. Each late review paid through Stripe is partially refunded once, if
+// the lateGameReviewRefundPercent environment variable is positive, and its owner is
+// notified of the refund. The admins are sent a summary on Discord of the reviews which
+// became late since the previous run or were refunded in this run.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/discord"
+	payment "github.com/jackstenglein/chess-dojo-scheduler/backend/paymentService"
+)
+
+type Event events.CloudWatchEvent
+
+var repository database.GameReviewRefunder = database.DynamoDB
+
+var frontendHost = os.Getenv("frontendHost")
+var channelId = os.Getenv("discordGameReviewChannelId")
+
+// The max number of late reviews listed individually in the Discord message, which is
+// limited to 2000 characters.
+const maxListedReviews = 15
+
+// The time between runs of the handler, which must match its schedule. Reviews whose
+// deadline passed within one interval of the current run became late since the previous run.
+const runInterval = 24 * time.Hour
+
+// lateReview is a game review which was not completed by its deadline.
+type lateReview struct {
+	entry  database.ReviewQueueEntry
+	refund *database.GameReviewRefund
+
+	// Whether the refund was issued by this run.
+	refunded bool
+
+	err error
+}
+
+func main() {
+	lambda.Start(handler)
+}
+
+func handler(ctx context.Context, event Event) (Event, error) {
+	log.SetRequestId(event.ID)
+	log.Infof("Event: %#v", event)
+
+	percentage, err := strconv.ParseInt(os.Getenv("lateGameReviewRefundPercent"), 10, 64)
+	if err != nil || percentage < 0 || percentage > 100 {
+		log.Infof("Refunds are disabled (lateGameReviewRefundPercent=%q)", os.Getenv("lateGameReviewRefundPercent"))
+		percentage = 0
+	}
+
+	var games []database.Game
+	var startKey string
+	for {
+		page, lastKey, err := repository.ListGamesForReview(startKey)
+		if err != nil {
+			log.Errorf("Failed to list games for review: %v", err)
+			return event, err
+		}
+		games = append(games, page...)
+		if lastKey == "" {
+			break
+		}
+		startKey = lastKey
+	}
+
+	now := time.Now()
+	// The windows of consecutive runs start at their scheduled times, so they do not overlap
+	runAt := event.Time
+	if runAt.IsZero() {
+		runAt = now
+	}
+
+	var late []lateReview
+	for _, entry := range database.NewReviewQueue(games, now) {
+		if !entry.Overdue {
+			// Overdue games are always first in the queue
+			break
+		}
+
+		review := lateReview{entry: entry}
+		if percentage > 0 {
+			review.refund, review.refunded, review.err = refundReview(&entry, percentage, now)
+			if review.err != nil {
+				log.Errorf("Failed to refund late review of %s/%s: %v", entry.Game.Cohort, entry.Game.Id, review.err)
+			}
+		}
+		if review.refunded || becameLate(&entry, runAt) {
+			late = append(late, review)
+		}
+	}
+
+	log.Infof("Found %d new or newly refunded late reviews", len(late))
+	if len(late) == 0 {
+		return event, nil
+	}
+
+	message := getMessage(late)
+	if channelId == "" {
+		log.Infof("discordGameReviewChannelId is not set, skipping admin notification: %s", message)
+		return event, nil
+	}
+	if _, err := discord.SendMessageInChannel(message, channelId); err != nil {
+		log.Errorf("Failed to send late review notification: %v", err)
+		return event, err
+	}
+	return event, nil
+}
+
+// becameLate returns true if the deadline of the given overdue review passed between the
+// previous run of the handler and the run scheduled at runAt.
+func becameLate(entry *database.ReviewQueueEntry, runAt time.Time) bool {
+	deadline, err := time.Parse(time.RFC3339, entry.Deadline)
+	return err == nil && deadline.After(runAt.Add(-runInterval)) && !deadline.After(runAt)
+}
+
+// refundReview refunds the given percentage of the price of the given late review, records
+// the refund on the game and notifies the owner. If the review was already refunded, the
+// existing refund is returned and refunded is false. Nil is returned if the review was not
+// paid through Stripe.
+func refundReview(entry *database.ReviewQueueEntry, percentage int64, now time.Time) (refund *database.GameReviewRefund, refunded bool, err error) {
+	game, err := repository.GetGame(string(entry.Game.Cohort), entry.Game.Id)
+	if err != nil {
+		return nil, false, err
+	}
+	if game.ReviewRefund != nil {
+		return game.ReviewRefund, false, nil
+	}
+
+	result, err := payment.CreateGameReviewRefund(game, percentage)
+	if err != nil || result == nil {
+		return nil, false, err
+	}
+
+	refund = &database.GameReviewRefund{
+		StripeId:   result.ID,
+		Amount:     result.Amount,
+		Currency:   string(result.Currency),
+		Percentage: percentage,
+		Deadline:   entry.Deadline,
+		RefundedAt: now.Format(time.RFC3339),
+	}
+	if err := repository.RecordGameReviewRefund(game.Cohort, game.Id, refund); err != nil {
+		return nil, false, err
+	}
+	log.Infof("Refunded %d %s for late review of %s/%s", refund.Amount, refund.Currency, game.Cohort, game.Id)
+
+	if err := database.SendGameReviewRefundEvent(game, percentage); err != nil {
+		log.Errorf("Failed to send refund notification for %s/%s: %v", game.Cohort, game.Id, err)
+	}
+	return refund, true, nil
+}
+
+// getMessage returns the Discord message notifying admins of the given late reviews.
+func getMessage(late []lateReview) string {
+	paid := 0
+	for _, review := range late {
+		if review.entry.Game.Review != nil && review.entry.Game.Review.StripeId != "" {
+			paid++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s **%d game review", discord.MessageEmojiClock, len(late)))
+	if len(late) != 1 {
+		sb.WriteString("s")
+	}
+	sb.WriteString(fmt.Sprintf(" became late or were refunded since the last check (%d paid through Stripe)**\n", paid))
+
+	for i, review := range late {
+		if i == maxListedReviews {
+			sb.WriteString(fmt.Sprintf("...and %d more. See the [review queue](<%s/games/review-queue>).", len(late)-i, frontendHost))
+			break
+		}
+
+		game := &review.entry.Game
+		sb.WriteString(fmt.Sprintf("- [%s - %s](<%s/games/%s/%s>)", game.White, game.Black, frontendHost, game.Cohort, game.Id))
+		if game.Review != nil {
+			sb.WriteString(fmt.Sprintf(" (%s)", game.Review.Type))
+		}
+		if deadline, err := time.Parse(time.RFC3339, review.entry.Deadline); err == nil {
+			sb.WriteString(fmt.Sprintf(", due %s", deadline.Format(time.DateOnly)))
+		}
+		if review.entry.ClaimedBy != nil {
+			sb.WriteString(fmt.Sprintf(", claimed by %s", review.entry.ClaimedBy.DisplayName))
+		}
+		if review.refund != nil {
+			sb.WriteString(fmt.Sprintf(", refunded %d%%", review.refund.Percentage))
+		} else if review.err != nil {
+			sb.WriteString(", **refund failed**")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+// Implements a lambda handler which returns the review queue in priority order, along
+// with the workload of each reviewer. The caller must be an admin.
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.GameReviewQueuer = database.DynamoDB
+
+type QueueResponse struct {
+	// The games waiting for review, in the order they should be reviewed.
+	Games []database.ReviewQueueEntry `json:"games"`
+
+	// The number of games in the queue which are overdue.
+	Overdue int `json:"overdue"`
+
+	// The workload of each reviewer with claimed games.
+	Workloads []database.ReviewerWorkload `json:"workloads"`
+}
+
+func main() {
+	lambda.Start(Handler)
+}
+
+// Handler returns the full review queue. If the reviewer query parameter is set, only the
+// games claimed by that reviewer are returned. If the unclaimed query parameter is true,
+// only games which are not claimed are returned. Overdue and Workloads always cover the
+// full queue.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	user, err := repository.GetUser(info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if !user.IsAdmin {
+		return api.Failure(errors.New(403, "Invalid request: you must be an admin to call this function", "")), nil
+	}
+
+	var games []database.Game
+	var startKey string
+	for {
+		page, lastKey, err := repository.ListGamesForReview(startKey)
+		if err != nil {
+			return api.Failure(err), nil
+		}
+		games = append(games, page...)
+		if lastKey == "" {
+			break
+		}
+		startKey = lastKey
+	}
+	if err := repository.LoadEngineSummaries(games); err != nil {
+		return api.Failure(err), nil
+	}
+
+	queue := database.NewReviewQueue(games, time.Now())
+	response := QueueResponse{
+		Games:     filterQueue(queue, event.QueryStringParameters["reviewer"], event.QueryStringParameters["unclaimed"] == "true"),
+		Workloads: database.GetReviewerWorkloads(queue),
+	}
+	for _, entry := range queue {
+		if entry.Overdue {
+			response.Overdue++
+		}
+	}
+	return api.Success(response), nil
+}
+
+// filterQueue returns the entries of the queue claimed by the given reviewer, or all
+// entries if reviewer is empty. If unclaimed is true, only unclaimed entries are returned
+// instead.
+func filterQueue(queue []database.ReviewQueueEntry, reviewer string, unclaimed bool) []database.ReviewQueueEntry {
+	if reviewer == "" && !unclaimed {
+		return queue
+	}
+
+	result := make([]database.ReviewQueueEntry, 0, len(queue))
+	for _, entry := range queue {
+		if unclaimed {
+			if entry.ClaimedBy == nil {
+				result = append(result, entry)
+			}
+		} else if entry.ClaimedBy != nil && entry.ClaimedBy.Username == reviewer {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
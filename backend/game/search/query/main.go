@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The maximum number of index entries read for a single search term.
+const maxTermEntries = 2000
+
+// The number of results returned per page.
+const pageSize = 25
+
+var repository database.GameSearcher = database.DynamoDB
+
+type SearchGamesResponse struct {
+	// The page of games matching the query, in ranked order.
+	Games []database.GameSearchResult `json:"games"`
+
+	// The total number of games matching the query.
+	Total int `json:"total"`
+
+	// Whether a query term matched more games than could be searched, in which case some
+	// matching games may be missing from the results.
+	Truncated bool `json:"truncated"`
+
+	// The startKey of the next page, if one exists.
+	LastEvaluatedKey string `json:"lastEvaluatedKey,omitempty"`
+}
+
+// Handler returns the games containing every term of the query in their player names,
+// event, site, opening or comments, ranked by where and how often the terms occur.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	terms, ok := database.ParseSearchQuery(event.QueryStringParameters["query"])
+	if !ok {
+		err := errors.New(400, "Invalid request: query must contain at least one search term", "")
+		return api.Failure(err), nil
+	}
+
+	offset := 0
+	if startKey := event.QueryStringParameters["startKey"]; startKey != "" {
+		var err error
+		if offset, err = strconv.Atoi(startKey); err != nil || offset < 0 {
+			err = errors.Wrap(400, "Invalid request: startKey is not valid", "", err)
+			return api.Failure(err), nil
+		}
+	}
+
+	cohort := database.DojoCohort(event.QueryStringParameters["cohort"])
+	entriesByTerm := make(map[string][]database.GameSearchEntry, len(terms))
+	truncated := false
+	for _, term := range terms {
+		entries, termTruncated, err := repository.ListGameSearchEntries(term, cohort, maxTermEntries)
+		if err != nil {
+			return api.Failure(err), nil
+		}
+		if len(entries) == 0 {
+			// No game can contain every term
+			return api.Success(&SearchGamesResponse{Games: []database.GameSearchResult{}}), nil
+		}
+		entriesByTerm[term] = entries
+		truncated = truncated || termTruncated
+	}
+
+	results := database.RankSearchResults(terms, entriesByTerm)
+	response := &SearchGamesResponse{
+		Games:     []database.GameSearchResult{},
+		Total:     len(results),
+		Truncated: truncated,
+	}
+	if offset < len(results) {
+		end := min(offset+pageSize, len(results))
+		response.Games = results[offset:end]
+		if end < len(results) {
+			response.LastEvaluatedKey = strconv.Itoa(end)
+		}
+	}
+	return api.Success(response), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
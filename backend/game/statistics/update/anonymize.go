@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The PGN commands kept in the anonymized dataset. All other commands and all free-text
+// comments are removed.
+var anonymizedCommands = map[string]bool{
+	"clk":  true,
+	"emt":  true,
+	"eval": true,
+}
+
+// The size of the rating bands written to the anonymized dataset.
+const ratingBandSize = 100
+
+// fetchDatasetSalt fetches the salt used to generate pseudonyms for the current environment
+// from AWS SecretManager. The salt must never change, or the pseudonyms of players will
+// differ between exports.
+func fetchDatasetSalt() (string, error) {
+	svc := secretsmanager.New(session.Must(session.NewSession()))
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(fmt.Sprintf("chess-dojo-%s-datasetSalt", os.Getenv("stage"))),
+	}
+	result, err := svc.GetSecretValue(input)
+	if err != nil {
+		return "", err
+	}
+	return *result.SecretString, nil
+}
+
+// pseudonym returns a stable pseudonymous id for the given player name. The same name
+// always produces the same id for the same salt, but the name cannot be recovered without
+// the salt.
+func pseudonym(salt []byte, name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "?" {
+		return "?"
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(name))
+	return "P" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// ratingBand returns the rating band containing the given PGN Elo value, in the form
+// 1500-1600, or an empty string if the rating is not a positive number.
+func ratingBand(elo string) string {
+	rating, err := strconv.Atoi(strings.TrimSpace(elo))
+	if err != nil || rating <= 0 {
+		return ""
+	}
+	start := rating - rating%ratingBandSize
+	return fmt.Sprintf("%d-%d", start, start+ratingBandSize)
+}
+
+// anonymizeGame returns the PGN of the given game with the players replaced by pseudonyms,
+// the ratings replaced by rating bands and all other identifying headers and comments
+// removed.
+func anonymizeGame(game *database.Game, salt []byte) (string, error) {
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		return "", err
+	}
+
+	white := parsed.Header("White")
+	black := parsed.Header("Black")
+	whiteBand := ratingBand(parsed.Header("WhiteElo"))
+	blackBand := ratingBand(parsed.Header("BlackElo"))
+	setUp := parsed.Header("SetUp")
+	fen := parsed.Header("FEN")
+
+	for name := range parsed.Headers {
+		parsed.DeleteHeader(name)
+	}
+
+	parsed.SetHeader("White", pseudonym(salt, white))
+	parsed.SetHeader("Black", pseudonym(salt, black))
+	parsed.SetHeader("Result", parsed.Result)
+	parsed.SetHeader("Cohort", string(game.Cohort))
+	if whiteBand != "" {
+		parsed.SetHeader("WhiteRatingBand", whiteBand)
+	}
+	if blackBand != "" {
+		parsed.SetHeader("BlackRatingBand", blackBand)
+	}
+	if fen != "" {
+		parsed.SetHeader("SetUp", setUp)
+		parsed.SetHeader("FEN", fen)
+	}
+
+	stripComments(parsed.Root)
+	parsed.Walk(stripComments)
+	return parsed.String(), nil
+}
+
+// stripComments removes the free-text comments and non-allowlisted commands of the given node.
+func stripComments(n *chess.Node) {
+	n.Comment = ""
+	n.CommentBefore = ""
+	for name := range n.Commands {
+		if !anonymizedCommands[name] {
+			delete(n.Commands, name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestPseudonym(t *testing.T) {
+	salt := []byte("salt")
+
+	if got := pseudonym(salt, " Magnus "); got != pseudonym(salt, "magnus") {
+		t.Errorf("pseudonym is not stable across case and whitespace: %s", got)
+	}
+	if pseudonym(salt, "magnus") == pseudonym([]byte("other"), "magnus") {
+		t.Errorf("pseudonym is the same for different salts")
+	}
+	if pseudonym(salt, "magnus") == pseudonym(salt, "hikaru") {
+		t.Errorf("pseudonym is the same for different names")
+	}
+	if got := pseudonym(salt, ""); got != "?" {
+		t.Errorf("pseudonym of empty name got: %s; want: ?", got)
+	}
+}
+
+func TestRatingBand(t *testing.T) {
+	table := []struct {
+		elo  string
+		want string
+	}{
+		{elo: "1543", want: "1500-1600"},
+		{elo: "1500", want: "1500-1600"},
+		{elo: "99", want: "0-100"},
+		{elo: "0", want: ""},
+		{elo: "?", want: ""},
+	}
+
+	for _, tc := range table {
+		if got := ratingBand(tc.elo); got != tc.want {
+			t.Errorf("ratingBand(%s) got: %s; want: %s", tc.elo, got, tc.want)
+		}
+	}
+}
+
+func TestAnonymizeGame(t *testing.T) {
+	game := &database.Game{
+		Cohort: "1500-1600",
+		Pgn: `[Event "Club Championship"]
+[Site "Springfield"]
+[White "Alice Smith"]
+[Black "Bob Jones"]
+[WhiteElo "1543"]
+[BlackElo "1611"]
+[Result "1-0"]
+
+{ Played at home } 1. e4 { [%clk 0:05:00] [%cal Ge2e4] I always play this } e5 { [%eval 0.3] } 1-0`,
+	}
+
+	pgn, err := anonymizeGame(game, []byte("salt"))
+	if err != nil {
+		t.Fatalf("anonymizeGame got error: %v", err)
+	}
+
+	for _, banned := range []string{"Alice", "Bob", "Club", "Springfield", "1543", "home", "always", "%cal"} {
+		if strings.Contains(pgn, banned) {
+			t.Errorf("anonymizeGame output contains %q: %s", banned, pgn)
+		}
+	}
+	for _, want := range []string{"[WhiteRatingBand \"1500-1600\"]", "[BlackRatingBand \"1600-1700\"]", "[Cohort \"1500-1600\"]", "%clk 0:05:00", "%eval 0.3", "1-0"} {
+		if !strings.Contains(pgn, want) {
+			t.Errorf("anonymizeGame output missing %q: %s", want, pgn)
+		}
+	}
+}
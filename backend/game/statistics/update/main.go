@@ -2,108 +2,594 @@ package main
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var stage = os.Getenv("stage")
-
 type Event events.CloudWatchEvent
 
+// EventDetail contains optional parameters which can be passed when invoking the export manually.
+type EventDetail struct {
+	// If true, every partition is rebuilt, ignoring the checkpoint in the manifest.
+	Full bool `json:"full"`
+
+	// If true, the anonymized research dataset is exported instead of the game database.
+	// The dataset is always fully rebuilt.
+	Anonymized bool `json:"anonymized"`
+}
+
+type UserLister interface {
+	// BatchGetUsersProjection returns the users with the provided usernames and projection expression.
+	BatchGetUsersProjection(usernames []string, projectionExpression string) ([]*database.User, error)
+}
+
 var repository database.GameLister = database.DynamoDB
+var userRepository UserLister = database.DynamoDB
+var store database.ExportStore = database.S3
+var getDatasetSalt = fetchDatasetSalt
 
-func getZipFile() (*os.File, *zip.Writer, io.Writer, error) {
-	date := time.Now().Format(time.DateOnly)
-	archive, err := os.Create(fmt.Sprintf("/tmp/%s_dojo_database.zip", date))
-	if err != nil {
-		return nil, nil, nil, err
+// The partition month used for games whose id does not start with a date.
+const legacyMonth = "legacy"
+
+// exporter writes a partitioned export of the game database, along with its manifest and
+// a zip file containing every partition.
+type exporter struct {
+	// The S3 key prefix of the partitions and manifest.
+	prefix string
+
+	// The S3 key of the zip file containing every partition.
+	zipKey string
+
+	// The name of the PGN file inside the zip file, following the date.
+	zipName string
+
+	// If non-nil, games are anonymized using this salt and the games of owners who opted
+	// out of the research dataset are excluded.
+	salt []byte
+
+	// Whether each game owner has opted out of the research dataset, mapped by username.
+	optOut map[string]bool
+}
+
+// newDatabaseExporter returns an exporter for the public game database.
+func newDatabaseExporter() *exporter {
+	return &exporter{prefix: "database", zipKey: "dojo_database.zip", zipName: "dojo_database.pgn"}
+}
+
+// newDatasetExporter returns an exporter for the anonymized research dataset.
+func newDatasetExporter(salt []byte) *exporter {
+	return &exporter{
+		prefix:  "dataset",
+		zipKey:  "dataset/dojo_dataset.zip",
+		zipName: "dojo_dataset.pgn",
+		salt:    salt,
+		optOut:  make(map[string]bool),
 	}
+}
 
-	zipWriter := zip.NewWriter(archive)
-	w, err := zipWriter.Create(fmt.Sprintf("%s_dojo_database.pgn", date))
-	if err != nil {
-		defer archive.Close()
-		return nil, nil, nil, err
+// manifestKey returns the S3 key of the exporter's manifest.
+func (e *exporter) manifestKey() string {
+	return e.prefix + "/manifest.json"
+}
+
+// The ids of newer games start with the upload date in the form 2023.01.02.
+var idDateRegex = regexp.MustCompile(`^(\d{4})\.(\d{2})\.\d{2}_`)
+
+// Partition describes a single PGN file of the game database, containing the games of one
+// cohort uploaded in one month.
+type Partition struct {
+	// The cohort of the games in the partition.
+	Cohort database.DojoCohort `json:"cohort"`
+
+	// The upload month of the games in the partition, in the form 2023-01, or legacy
+	// for games whose id does not contain a date.
+	Month string `json:"month"`
+
+	// The S3 key of the PGN file.
+	Key string `json:"key"`
+
+	// The number of games in the PGN file.
+	Count int `json:"count"`
+
+	// The number of games left out of the PGN file because they could not be parsed.
+	Skipped int `json:"skipped,omitempty"`
+
+	// The hex-encoded SHA-256 checksum of the PGN file.
+	Sha256 string `json:"sha256"`
+
+	// The time the partition was last written, in time.RFC3339 format.
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// Manifest describes the full partitioned game database.
+type Manifest struct {
+	// The start time of the last successful export, in time.RFC3339 format. Games created
+	// or updated after this time are included in the next export.
+	Checkpoint string `json:"checkpoint"`
+
+	// The total number of games across all partitions.
+	TotalGames int `json:"totalGames"`
+
+	// The total number of games left out of the partitions because they could not be
+	// parsed. Games skipped in partitions without any other games are not counted.
+	TotalSkipped int `json:"totalSkipped,omitempty"`
+
+	// The partitions of the database, mapped by cohort/month.
+	Partitions map[string]*Partition `json:"partitions"`
+}
+
+// partitionMonth returns the partition month of the game with the given id.
+func partitionMonth(id string) string {
+	match := idDateRegex.FindStringSubmatch(id)
+	if match == nil {
+		return legacyMonth
+	}
+	return fmt.Sprintf("%s-%s", match[1], match[2])
+}
+
+// partitionName returns the manifest key of the given partition.
+func partitionName(cohort database.DojoCohort, month string) string {
+	return fmt.Sprintf("%s/%s", cohort, month)
+}
+
+// setPartition updates the manifest with the given partition. Empty partitions are removed.
+func (m *Manifest) setPartition(p *Partition) {
+	name := partitionName(p.Cohort, p.Month)
+	if old := m.Partitions[name]; old != nil {
+		m.TotalGames -= old.Count
+		m.TotalSkipped -= old.Skipped
+	}
+	if p.Count == 0 {
+		delete(m.Partitions, name)
+		return
 	}
+	m.Partitions[name] = p
+	m.TotalGames += p.Count
+	m.TotalSkipped += p.Skipped
+}
 
-	return archive, zipWriter, w, nil
+// sortedPartitions returns the partitions of the manifest, sorted by cohort and month.
+func (m *Manifest) sortedPartitions() []*Partition {
+	partitions := make([]*Partition, 0, len(m.Partitions))
+	for _, p := range m.Partitions {
+		partitions = append(partitions, p)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitionName(partitions[i].Cohort, partitions[i].Month) < partitionName(partitions[j].Cohort, partitions[j].Month)
+	})
+	return partitions
 }
 
-func processGames(w io.Writer, games []*database.Game) error {
-	for _, game := range games {
-		if game.Unlisted {
-			continue
+// loadOptOuts fetches the research dataset opt-out status of any owners of the given
+// games which have not already been fetched.
+func (e *exporter) loadOptOuts(games []*database.Game) error {
+	var usernames []string
+	for _, g := range games {
+		if _, ok := e.optOut[g.Owner]; !ok {
+			e.optOut[g.Owner] = false
+			usernames = append(usernames, g.Owner)
 		}
-		if _, err := w.Write([]byte(game.Pgn)); err != nil {
-			return err
+	}
+
+	for start := 0; start < len(usernames); start += 100 {
+		end := start + 100
+		if end > len(usernames) {
+			end = len(usernames)
 		}
-		if _, err := w.Write([]byte("\n\n")); err != nil {
+		users, err := userRepository.BatchGetUsersProjection(usernames[start:end], "username,researchOptOut")
+		if err != nil {
 			return err
 		}
+		for _, u := range users {
+			e.optOut[u.Username] = u.ResearchOptOut
+		}
 	}
 	return nil
 }
 
-func uploadFile(archive *os.File) error {
-	log.Info("Uploading file")
-	archive.Seek(0, 0)
-	uploader := s3manager.NewUploader(session.Must(session.NewSession()))
-	_, err := uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(fmt.Sprintf("chess-dojo-%s-game-database", stage)),
-		Key:    aws.String("dojo_database.zip"),
-		Body:   archive,
-	})
-	return err
+// getManifest returns the exporter's current manifest, or nil if it does not exist.
+func (e *exporter) getManifest() (*Manifest, error) {
+	reader, err := store.GetExport(e.manifestKey())
+	if err != nil {
+		var aerr *errors.Error
+		if errors.As(err, &aerr) && aerr.Code == 404 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal manifest", err)
+	}
+	if manifest.Partitions == nil {
+		manifest.Partitions = make(map[string]*Partition)
+	}
+	return &manifest, nil
 }
 
-func Handler(ctx context.Context, event Event) (Event, error) {
-	log.Infof("Event: %#v", event)
-	log.SetRequestId(event.ID)
+// gamePgn returns the PGN of the given game as it is written to a partition. False is
+// returned if the game is unlisted or excluded from the research dataset. The game
+// database contains the PGN as it was saved, but the research dataset must parse it to
+// anonymize it, so an error is returned if the game is corrupt.
+func (e *exporter) gamePgn(game *database.Game) (string, bool, error) {
+	if game.Unlisted || (e.salt != nil && e.optOut[game.Owner]) {
+		return "", false, nil
+	}
+
+	if e.salt != nil {
+		anonymized, err := anonymizeGame(game, e.salt)
+		if err != nil {
+			return "", false, err
+		}
+		return anonymized, true, nil
+	}
+	return game.Pgn, true, nil
+}
+
+// partitionWriter streams the games of a single partition to its PGN file. The upload is
+// started when the first game is written, so partitions without games are never uploaded.
+type partitionWriter struct {
+	// The partition being written.
+	partition *Partition
+
+	// The running checksum of the written PGN file.
+	checksum hash.Hash
+
+	// The writer end of the pipe being uploaded. Nil until the first game is written.
+	pipe *io.PipeWriter
+
+	// Receives the result of the upload.
+	done chan error
+}
+
+// newPartitionWriter returns a writer for the given partition of the exporter.
+func (e *exporter) newPartitionWriter(cohort database.DojoCohort, month string, now string) *partitionWriter {
+	return &partitionWriter{
+		partition: &Partition{
+			Cohort:    cohort,
+			Month:     month,
+			Key:       fmt.Sprintf("%s/%s/%s.pgn", e.prefix, cohort, month),
+			UpdatedAt: now,
+		},
+		checksum: sha256.New(),
+	}
+}
+
+// write appends the given PGN to the partition.
+func (w *partitionWriter) write(pgn string) error {
+	if w.pipe == nil {
+		reader, writer := io.Pipe()
+		w.pipe = writer
+		w.done = make(chan error, 1)
+		go func() {
+			err := store.UploadExport(w.partition.Key, reader)
+			reader.CloseWithError(io.ErrClosedPipe)
+			w.done <- err
+		}()
+	}
+
+	if _, err := io.WriteString(io.MultiWriter(w.pipe, w.checksum), strings.TrimSpace(pgn)+"\n\n"); err != nil {
+		return err
+	}
+	w.partition.Count++
+	return nil
+}
+
+// close finishes the upload of the partition and returns it.
+func (w *partitionWriter) close() (*Partition, error) {
+	if w.pipe != nil {
+		w.pipe.Close()
+		if err := <-w.done; err != nil {
+			return nil, err
+		}
+	}
+	w.partition.Sha256 = hex.EncodeToString(w.checksum.Sum(nil))
+	return w.partition, nil
+}
+
+// abort cancels the upload of the partition, leaving any existing PGN file unchanged.
+func (w *partitionWriter) abort(err error) {
+	if w.pipe != nil {
+		w.pipe.CloseWithError(err)
+		<-w.done
+	}
+}
+
+// pageLister returns a page of games, including the PGN text, starting at the given key.
+type pageLister func(startKey string) ([]*database.Game, string, error)
+
+// exportPartitions streams the games returned by list to their partitions of the given
+// cohort and records the written partitions in the manifest. Only games whose month is
+// accepted by include are written. The games must be sorted by id, so that each dated
+// partition is complete once a game of a later month is read and at most one dated
+// partition is uploaded at a time. The months of the written partitions are returned.
+func (e *exporter) exportPartitions(manifest *Manifest, cohort database.DojoCohort, now string, list pageLister, include func(month string) bool) (map[string]bool, error) {
+	written := make(map[string]bool)
+	writers := make(map[string]*partitionWriter)
+
+	closeWriter := func(month string) error {
+		partition, err := writers[month].close()
+		delete(writers, month)
+		if err != nil {
+			return err
+		}
+		log.Infof("Wrote %d games to partition %s/%s/%s, skipping %d corrupt games", partition.Count, e.prefix, cohort, month, partition.Skipped)
+		manifest.setPartition(partition)
+		return nil
+	}
+
+	err := func() error {
+		var startKey string
+		for ok := true; ok; ok = startKey != "" {
+			games, lastKey, err := list(startKey)
+			if err != nil {
+				return err
+			}
+			if e.salt != nil {
+				if err := e.loadOptOuts(games); err != nil {
+					return err
+				}
+			}
+
+			for _, g := range games {
+				month := partitionMonth(g.Id)
+				if !include(month) {
+					continue
+				}
+
+				w := writers[month]
+				if w == nil {
+					if written[month] {
+						return errors.New(500, "Temporary server error", fmt.Sprintf("Game %s/%s is not sorted by id", cohort, g.Id))
+					}
+					for m := range writers {
+						if m != legacyMonth {
+							if err := closeWriter(m); err != nil {
+								return err
+							}
+						}
+					}
+					w = e.newPartitionWriter(cohort, month, now)
+					writers[month] = w
+					written[month] = true
+				}
+
+				pgn, ok, err := e.gamePgn(g)
+				if err != nil {
+					log.Warnf("Skipping corrupt game %s/%s: %v", g.Cohort, g.Id, err)
+					w.partition.Skipped++
+				} else if ok {
+					if err := w.write(pgn); err != nil {
+						return err
+					}
+				}
+			}
+			startKey = lastKey
+		}
+
+		for month := range writers {
+			if err := closeWriter(month); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
 
-	archive, zipWriter, w, err := getZipFile()
 	if err != nil {
-		log.Errorf("Failed to create pgn file: %v", err)
-		return event, err
+		for _, w := range writers {
+			w.abort(err)
+		}
+		return nil, err
 	}
-	defer archive.Close()
+	return written, nil
+}
 
-	var games []*database.Game
-	var startKey string
+// fullExport rebuilds every partition of every cohort.
+func (e *exporter) fullExport(manifest *Manifest, now string) error {
+	for _, cohort := range database.Cohorts {
+		written, err := e.exportPartitions(manifest, cohort, now, func(startKey string) ([]*database.Game, string, error) {
+			return repository.ScanCohort(cohort, startKey)
+		}, func(string) bool { return true })
+		if err != nil {
+			return err
+		}
 
+		// Existing partitions with no games left are removed
+		for _, p := range manifest.Partitions {
+			if p.Cohort == cohort && !written[p.Month] {
+				manifest.setPartition(&Partition{Cohort: cohort, Month: p.Month})
+			}
+		}
+	}
+	return nil
+}
+
+// incrementalExport rebuilds only the partitions containing games created or updated
+// since the manifest's checkpoint. Deleted games are not detected and remain in their
+// partition until it is next rebuilt or a full export is run.
+func (e *exporter) incrementalExport(manifest *Manifest, now string) error {
 	for _, cohort := range database.Cohorts {
+		months := make(map[string]bool)
+		var startKey string
 		for ok := true; ok; ok = startKey != "" {
-			games, startKey, err = repository.ScanCohort(cohort, startKey)
+			games, lastKey, err := repository.ListChangedGames(cohort, manifest.Checkpoint, startKey)
 			if err != nil {
-				log.Errorf("Failed to scan games: %v", err)
-				return event, err
+				return err
 			}
+			for _, g := range games {
+				months[partitionMonth(g.Id)] = true
+			}
+			startKey = lastKey
+		}
 
-			log.Infof("Processing %d games", len(games))
-			if err := processGames(w, games); err != nil {
-				log.Errorf("Failed to process games: %v", err)
-				return event, err
+		for month := range months {
+			if err := e.exportPartition(manifest, cohort, month, now); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
+
+// exportPartition rebuilds the given partition.
+func (e *exporter) exportPartition(manifest *Manifest, cohort database.DojoCohort, month string, now string) error {
+	list := func(startKey string) ([]*database.Game, string, error) {
+		if month == legacyMonth {
+			return repository.ScanCohort(cohort, startKey)
+		}
+		return repository.ListGamesByIdPrefix(cohort, strings.ReplaceAll(month, "-", "."), startKey)
+	}
+
+	written, err := e.exportPartitions(manifest, cohort, now, list, func(m string) bool { return m == month })
+	if err != nil {
+		return err
+	}
+	if !written[month] {
+		manifest.setPartition(&Partition{Cohort: cohort, Month: month})
+	}
+	return nil
+}
+
+// uploadZip combines every partition of the manifest into a single zip file containing
+// one PGN file.
+func (e *exporter) uploadZip(manifest *Manifest) error {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		err := e.writeZip(writer, manifest)
+		writer.CloseWithError(err)
+		done <- err
+	}()
+
+	uploadErr := store.UploadExport(e.zipKey, reader)
+	reader.CloseWithError(io.ErrClosedPipe)
+	writeErr := <-done
+	if uploadErr != nil {
+		return uploadErr
+	}
+	return writeErr
+}
+
+// writeZip writes the zip file containing every partition of the manifest to w.
+func (e *exporter) writeZip(w io.Writer, manifest *Manifest) error {
+	zipWriter := zip.NewWriter(w)
+	f, err := zipWriter.Create(fmt.Sprintf("%s_%s", time.Now().Format(time.DateOnly), e.zipName))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range manifest.sortedPartitions() {
+		reader, err := store.GetExport(p.Key)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// putManifest saves the given manifest.
+func (e *exporter) putManifest(manifest *Manifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed to marshal manifest", err)
+	}
+	return store.UploadExport(e.manifestKey(), bytes.NewReader(b))
+}
 
-	zipWriter.Close()
+// run performs a full or incremental export, depending on the existing manifest.
+func (e *exporter) run(full bool) error {
+	// The checkpoint is taken before reading any games so that games updated during the
+	// export are picked up by the next run.
+	now := time.Now().Format(time.RFC3339)
+
+	manifest, err := e.getManifest()
+	if err != nil {
+		return err
+	}
+
+	if manifest == nil || full {
+		log.Infof("Running full export to %s", e.prefix)
+		if manifest == nil {
+			manifest = &Manifest{Partitions: make(map[string]*Partition)}
+		}
+		err = e.fullExport(manifest, now)
+	} else {
+		log.Infof("Running incremental export to %s since %s", e.prefix, manifest.Checkpoint)
+		err = e.incrementalExport(manifest, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := e.uploadZip(manifest); err != nil {
+		return err
+	}
+
+	manifest.Checkpoint = now
+	if err := e.putManifest(manifest); err != nil {
+		return err
+	}
+	log.Infof("Export complete with %d games in %d partitions, skipping %d corrupt games", manifest.TotalGames, len(manifest.Partitions), manifest.TotalSkipped)
+	return nil
+}
+
+func Handler(ctx context.Context, event Event) (Event, error) {
+	log.Infof("Event: %#v", event)
+	log.SetRequestId(event.ID)
+
+	var detail EventDetail
+	if len(event.Detail) > 0 {
+		if err := json.Unmarshal(event.Detail, &detail); err != nil {
+			log.Warnf("Failed to unmarshal event detail: %v", err)
+		}
+	}
+
+	e := newDatabaseExporter()
+	if detail.Anonymized {
+		salt, err := getDatasetSalt()
+		if err != nil {
+			log.Errorf("Failed to get dataset salt: %v", err)
+			return event, err
+		}
+		e = newDatasetExporter([]byte(salt))
+		detail.Full = true
+	}
+
+	if !detail.Full && os.Getenv("gamesUpdatedIndex") != "true" {
+		// Incremental exports query the updatedAt index, which is deployed separately
+		log.Infof("Running full export because the updatedAt index is not enabled")
+		detail.Full = true
+	}
 
-	if err := uploadFile(archive); err != nil {
-		log.Errorf("Failed to upload pgn file: %v", err)
+	if err := e.run(detail.Full); err != nil {
+		log.Errorf("Failed to export games: %v", err)
 		return event, err
 	}
-	log.Info("File uploaded")
 	return event, nil
 }
 
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type fakeRepository struct {
+	database.GameLister
+	games []*database.Game
+}
+
+func (r *fakeRepository) cohortGames(cohort database.DojoCohort) []*database.Game {
+	var result []*database.Game
+	for _, g := range r.games {
+		if g.Cohort == cohort {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+func (r *fakeRepository) ScanCohort(cohort database.DojoCohort, startKey string) ([]*database.Game, string, error) {
+	return r.cohortGames(cohort), "", nil
+}
+
+func (r *fakeRepository) ListChangedGames(cohort database.DojoCohort, since, startKey string) ([]*database.Game, string, error) {
+	var result []*database.Game
+	for _, g := range r.cohortGames(cohort) {
+		if g.UpdatedAt >= since {
+			result = append(result, &database.Game{Cohort: g.Cohort, Id: g.Id})
+		}
+	}
+	return result, "", nil
+}
+
+func (r *fakeRepository) ListGamesByIdPrefix(cohort database.DojoCohort, prefix, startKey string) ([]*database.Game, string, error) {
+	var result []*database.Game
+	for _, g := range r.cohortGames(cohort) {
+		if strings.HasPrefix(g.Id, prefix) {
+			result = append(result, g)
+		}
+	}
+	return result, "", nil
+}
+
+type fakeUserRepository struct {
+	optOut map[string]bool
+}
+
+func (r *fakeUserRepository) BatchGetUsersProjection(usernames []string, projectionExpression string) ([]*database.User, error) {
+	var users []*database.User
+	for _, u := range usernames {
+		users = append(users, &database.User{Username: u, ResearchOptOut: r.optOut[u]})
+	}
+	return users, nil
+}
+
+type fakeStore struct {
+	database.ExportStore
+	mu      sync.Mutex
+	files   map[string][]byte
+	uploads []string
+}
+
+func (s *fakeStore) UploadExport(key string, body io.Reader) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[key] = b
+	s.uploads = append(s.uploads, key)
+	return nil
+}
+
+func (s *fakeStore) GetExport(key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.files[key]
+	if !ok {
+		return nil, errors.New(404, "Invalid request: export not found", "")
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func newGame(cohort database.DojoCohort, id, createdAt string, unlisted bool) *database.Game {
+	return &database.Game{
+		Cohort:    cohort,
+		Id:        id,
+		Owner:     "owner-" + id,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Unlisted:  unlisted,
+		Pgn:       "[Event \"" + id + "\"]\n\n1. e4 e5 *",
+	}
+}
+
+func TestPartitionMonth(t *testing.T) {
+	table := []struct {
+		id   string
+		want string
+	}{
+		{id: "2024.01.02_5d3a", want: "2024-01"},
+		{id: "2023.12.31_abcd", want: "2023-12"},
+		{id: "5d3a-1234", want: legacyMonth},
+		{id: "2024-01-02_abcd", want: legacyMonth},
+	}
+
+	for _, tc := range table {
+		if got := partitionMonth(tc.id); got != tc.want {
+			t.Errorf("partitionMonth(%s) got: %s; want: %s", tc.id, got, tc.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	repo := &fakeRepository{
+		games: []*database.Game{
+			newGame("1500-1600", "2024.01.02_a", "2024-01-02T00:00:00Z", false),
+			newGame("1500-1600", "2024.01.05_b", "2024-01-05T00:00:00Z", true),
+			newGame("1500-1600", "2024.02.01_c", "2024-02-01T00:00:00Z", false),
+			newGame("0-300", "legacy-id", "2022-01-01T00:00:00Z", false),
+		},
+	}
+	fs := &fakeStore{files: make(map[string][]byte)}
+	repository = repo
+	store = fs
+	t.Setenv("gamesUpdatedIndex", "true")
+
+	if _, err := Handler(nil, Event{}); err != nil {
+		t.Fatalf("Handler (full) got error: %v", err)
+	}
+
+	e := newDatabaseExporter()
+	manifest, err := e.getManifest()
+	if err != nil || manifest == nil {
+		t.Fatalf("getManifest got: %v, %v; want manifest", manifest, err)
+	}
+	if manifest.TotalGames != 3 {
+		t.Errorf("Full export TotalGames got: %d; want: 3", manifest.TotalGames)
+	}
+	for _, name := range []string{"1500-1600/2024-01", "1500-1600/2024-02", "0-300/legacy"} {
+		if manifest.Partitions[name] == nil {
+			t.Errorf("Full export missing partition %s", name)
+		}
+	}
+	if jan := string(fs.files["database/1500-1600/2024-01.pgn"]); strings.Contains(jan, "2024.01.05_b") {
+		t.Errorf("Full export included unlisted game: %s", jan)
+	}
+	if _, ok := fs.files[e.zipKey]; !ok {
+		t.Errorf("Full export did not upload %s", e.zipKey)
+	}
+
+	// Only the February partition changes in the incremental run
+	repo.games = append(repo.games, newGame("1500-1600", "2024.02.10_d", time.Now().Add(time.Hour).Format(time.RFC3339), false))
+	fs.uploads = nil
+	if _, err := Handler(nil, Event{}); err != nil {
+		t.Fatalf("Handler (incremental) got error: %v", err)
+	}
+
+	want := []string{"database/1500-1600/2024-02.pgn", e.zipKey, e.manifestKey()}
+	if strings.Join(fs.uploads, ",") != strings.Join(want, ",") {
+		t.Errorf("Incremental export uploads got: %v; want: %v", fs.uploads, want)
+	}
+
+	manifest, _ = e.getManifest()
+	if manifest.TotalGames != 4 || manifest.Partitions["1500-1600/2024-02"].Count != 2 {
+		t.Errorf("Incremental export got TotalGames %d, February count %d; want 4, 2", manifest.TotalGames, manifest.Partitions["1500-1600/2024-02"].Count)
+	}
+
+	// Without the updatedAt index, every partition is rebuilt
+	t.Setenv("gamesUpdatedIndex", "false")
+	fs.uploads = nil
+	if _, err := Handler(nil, Event{}); err != nil {
+		t.Fatalf("Handler (no index) got error: %v", err)
+	}
+	if len(fs.uploads) != 5 {
+		t.Errorf("Export without index uploads got: %v; want all 3 partitions, zip and manifest", fs.uploads)
+	}
+}
+
+func TestHandlerAnonymized(t *testing.T) {
+	corrupt := newGame("1500-1600", "2024.01.04_c", "2024-01-04T00:00:00Z", false)
+	corrupt.Pgn = "[Event \"?\"]\n\n1. e4 e5 2. Ke3 *"
+	repository = &fakeRepository{
+		games: []*database.Game{
+			newGame("1500-1600", "2024.01.02_a", "2024-01-02T00:00:00Z", false),
+			newGame("1500-1600", "2024.01.03_b", "2024-01-03T00:00:00Z", false),
+			corrupt,
+		},
+	}
+	userRepository = &fakeUserRepository{optOut: map[string]bool{"owner-2024.01.03_b": true}}
+	fs := &fakeStore{files: make(map[string][]byte)}
+	store = fs
+	getDatasetSalt = func() (string, error) { return "salt", nil }
+
+	if _, err := Handler(nil, Event{Detail: []byte(`{"anonymized": true}`)}); err != nil {
+		t.Fatalf("Handler got error: %v", err)
+	}
+
+	e := newDatasetExporter(nil)
+	manifest, err := e.getManifest()
+	if err != nil || manifest == nil {
+		t.Fatalf("getManifest got: %v, %v; want manifest", manifest, err)
+	}
+	if manifest.TotalGames != 1 || manifest.TotalSkipped != 1 {
+		t.Errorf("TotalGames, TotalSkipped got: %d, %d; want: 1, 1", manifest.TotalGames, manifest.TotalSkipped)
+	}
+
+	jan := string(fs.files["dataset/1500-1600/2024-01.pgn"])
+	if strings.Contains(jan, "2024.01.02_a") || strings.Contains(jan, "2024.01.03_b") {
+		t.Errorf("Anonymized partition contains identifying headers or opted-out games: %s", jan)
+	}
+	if _, ok := fs.files["database/manifest.json"]; ok {
+		t.Errorf("Anonymized export wrote the public database manifest")
+	}
+	if _, ok := fs.files[e.zipKey]; !ok {
+		t.Errorf("Anonymized export did not upload %s", e.zipKey)
+	}
+}
+
+func TestGamePgnCorrupt(t *testing.T) {
+	game := newGame("1500-1600", "2024.01.04_c", "2024-01-04T00:00:00Z", false)
+	game.Pgn = "[Event \"?\"]\n\n1. e4 e5 2. Ke3 *"
+
+	pgn, ok, err := newDatabaseExporter().gamePgn(game)
+	if err != nil || !ok || pgn != game.Pgn {
+		t.Errorf("Database gamePgn got: (%q, %t, %v); want the saved PGN", pgn, ok, err)
+	}
+
+	if _, _, err := newDatasetExporter([]byte("salt")).gamePgn(game); err == nil {
+		t.Errorf("Dataset gamePgn got no error; want error")
+	}
+}
+
+func TestExportPartitions(t *testing.T) {
+	table := []struct {
+		name        string
+		ids         []string
+		wantCounts  map[string]int
+		wantUploads []string
+		wantErr     bool
+	}{
+		{
+			name:        "Sorted",
+			ids:         []string{"1abc", "2024.01.02_a", "2024.01.03_b", "2024.02.01_c", "zzzz"},
+			wantCounts:  map[string]int{"2024-01": 2, "2024-02": 1, legacyMonth: 2},
+			wantUploads: []string{"database/1500-1600/2024-01.pgn", "database/1500-1600/2024-02.pgn", "database/1500-1600/legacy.pgn"},
+		},
+		{
+			name:    "Unsorted",
+			ids:     []string{"2024.02.01_c", "2024.01.02_a", "2024.02.02_d"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			var games []*database.Game
+			for _, id := range tc.ids {
+				games = append(games, newGame("1500-1600", id, "2024-01-01T00:00:00Z", false))
+			}
+			fs := &fakeStore{files: make(map[string][]byte)}
+			store = fs
+
+			e := newDatabaseExporter()
+			manifest := &Manifest{Partitions: make(map[string]*Partition)}
+			list := func(startKey string) ([]*database.Game, string, error) {
+				return games, "", nil
+			}
+
+			_, err := e.exportPartitions(manifest, "1500-1600", "now", list, func(string) bool { return true })
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("exportPartitions got nil error; want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("exportPartitions got error: %v", err)
+			}
+
+			for month, count := range tc.wantCounts {
+				p := manifest.Partitions[partitionName("1500-1600", month)]
+				if p == nil || p.Count != count {
+					t.Errorf("Partition %s got: %+v; want count %d", month, p, count)
+				}
+			}
+			sort.Strings(fs.uploads)
+			if strings.Join(fs.uploads, ",") != strings.Join(tc.wantUploads, ",") {
+				t.Errorf("Uploads got: %v; want: %v", fs.uploads, tc.wantUploads)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package stream
+
+import (
+	"reflect"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// analyzeClocks updates the time profile of the changed game if it does not match the
+// game's PGN. Saving the profile triggers another record for the same game, which is then
+// skipped because the profile matches.
+func analyzeClocks(repo Repository, change *Change) error {
+	newGame := change.NewGame
+	if newGame == nil {
+		return nil
+	}
+	if change.OldGame != nil && newGame.TimeProfile != nil && change.OldGame.Pgn == newGame.Pgn {
+		log.Debugf("Skipping game %s/%s with unchanged PGN", newGame.Cohort, newGame.Id)
+		return nil
+	}
+
+	parsed, err := newGame.ParsePgn()
+	if err != nil {
+		log.Warnf("Skipping corrupt game %s/%s: %v", newGame.Cohort, newGame.Id, err)
+		return nil
+	}
+
+	profile := database.ExtractTimeProfile(parsed)
+	if reflect.DeepEqual(profile, newGame.TimeProfile) {
+		return nil
+	}
+
+	if err := repo.SetGameTimeProfile(newGame.Cohort, newGame.Id, profile); err != nil {
+		return err
+	}
+	log.Infof("Updated time profile of game %s/%s", newGame.Cohort, newGame.Id)
+	return nil
+}
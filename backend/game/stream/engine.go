@@ -0,0 +1,35 @@
+package stream
+
+import (
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// requestEngineAnalysis sends the changed game to the engine analysis queue if it is pending
+// Sensei review and does not already have an analysis of its current mainline, so that
+// reviewers see an engine summary in the review queue. The analysis itself can take several
+// minutes, so it is run by the engine/analyze function rather than in the stream handler.
+// Saving the analysis triggers another record for the same game, which is then skipped
+// because the analysis matches.
+func requestEngineAnalysis(repo Repository, change *Change) error {
+	game := change.NewGame
+	if game == nil || game.ReviewStatus != database.GameReviewStatus_Pending {
+		return nil
+	}
+
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		log.Warnf("Skipping corrupt game %s/%s: %v", game.Cohort, game.Id, err)
+		return nil
+	}
+	if game.EngineAnalysis != nil && game.EngineAnalysis.Matches(parsed) {
+		log.Debugf("Skipping game %s/%s with up-to-date analysis", game.Cohort, game.Id)
+		return nil
+	}
+
+	if err := repo.RequestEngineAnalysis(game.Cohort, game.Id); err != nil {
+		return err
+	}
+	log.Infof("Requested engine analysis of game %s/%s", game.Cohort, game.Id)
+	return nil
+}
@@ -0,0 +1,40 @@
+package stream
+
+import (
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// classifyOpening sets the ECO and Opening headers of the changed game if they are missing
+// or inconsistent with its mainline, as decided by database.OpeningUpdate. Saving the
+// headers triggers another record for the same game, which is then skipped because the
+// headers match.
+func classifyOpening(repo Repository, change *Change) error {
+	game := change.NewGame
+	if game == nil {
+		return nil
+	}
+
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		log.Warnf("Skipping corrupt game %s/%s: %v", game.Cohort, game.Id, err)
+		return nil
+	}
+
+	opening, ok := database.OpeningUpdate(game, parsed)
+	if !ok {
+		return nil
+	}
+	if err := repo.SetGameOpening(game, opening); err != nil {
+		var aerr *errors.Error
+		if errors.As(err, &aerr) && aerr.Code == 409 {
+			// The game changed, so its newer record is classified instead
+			log.Debugf("Skipping changed game %s/%s", game.Cohort, game.Id)
+			return nil
+		}
+		return err
+	}
+	log.Infof("Set opening of game %s/%s to %s %s", game.Cohort, game.Id, opening.Eco, opening.Name)
+	return nil
+}
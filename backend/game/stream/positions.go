@@ -0,0 +1,65 @@
+package stream
+
+import (
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// indexPositions updates the position index to match the new image of the changed game.
+// Entries for positions which no longer occur in the game are removed.
+func indexPositions(repo Repository, change *Change) error {
+	oldGame, newGame := change.OldGame, change.NewGame
+	if oldGame != nil && newGame != nil && !positionIndexChanged(oldGame, newGame) {
+		log.Debugf("Skipping game %s/%s with unchanged PGN", newGame.Cohort, newGame.Id)
+		return nil
+	}
+
+	oldEntries := extractPositionEntries(oldGame)
+	newEntries := extractPositionEntries(newGame)
+
+	current := make(map[string]bool, len(newEntries))
+	for _, entry := range newEntries {
+		current[entry.NormalizedFen] = true
+	}
+	var stale []database.PositionGame
+	for _, entry := range oldEntries {
+		if !current[entry.NormalizedFen] {
+			stale = append(stale, entry)
+		}
+	}
+
+	if err := repo.DeletePositionGames(stale); err != nil {
+		return err
+	}
+	written, err := repo.PutPositionGames(newEntries)
+	if err != nil {
+		return err
+	}
+	log.Infof("Wrote %d and deleted %d position entries", written, len(stale))
+	return nil
+}
+
+// positionIndexChanged returns true if the fields stored in the position index differ
+// between the given old and new versions of a game.
+func positionIndexChanged(oldGame, newGame *database.Game) bool {
+	return oldGame.Pgn != newGame.Pgn ||
+		oldGame.Unlisted != newGame.Unlisted ||
+		oldGame.White != newGame.White ||
+		oldGame.Black != newGame.Black ||
+		oldGame.Date != newGame.Date ||
+		oldGame.OwnerDisplayName != newGame.OwnerDisplayName
+}
+
+// extractPositionEntries returns the position index entries for the given game. Unlisted
+// and unparseable games produce no entries.
+func extractPositionEntries(game *database.Game) []database.PositionGame {
+	if game == nil || game.Unlisted {
+		return nil
+	}
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		log.Warnf("Skipping corrupt game %s/%s: %v", game.Cohort, game.Id, err)
+		return nil
+	}
+	return database.ExtractPositionGames(game, parsed)
+}
@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func (r *fakeRepository) PutPositionGames(entries []database.PositionGame) (int, error) {
+	r.positionPuts = append(r.positionPuts, entries...)
+	return len(entries), nil
+}
+
+func (r *fakeRepository) DeletePositionGames(entries []database.PositionGame) error {
+	r.positionDeletes = append(r.positionDeletes, entries...)
+	return nil
+}
+
+func TestIndexPositionsUnlisted(t *testing.T) {
+	listed := &database.Game{
+		Cohort: "1500-1600",
+		Id:     "2024.01.02_a",
+		Owner:  "owner",
+		Date:   "2024.01.02",
+		Pgn:    "1. e4 e5 *",
+	}
+	unlisted := *listed
+	unlisted.Unlisted = true
+
+	table := []struct {
+		name        string
+		oldGame     *database.Game
+		newGame     *database.Game
+		wantPuts    bool
+		wantDeletes bool
+	}{
+		{name: "InsertListed", newGame: listed, wantPuts: true},
+		{name: "InsertUnlisted", newGame: &unlisted},
+		{name: "Unlist", oldGame: listed, newGame: &unlisted, wantDeletes: true},
+		{name: "Relist", oldGame: &unlisted, newGame: listed, wantPuts: true},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			if err := indexPositions(repo, &Change{OldGame: tc.oldGame, NewGame: tc.newGame}); err != nil {
+				t.Fatalf("indexPositions got error: %v", err)
+			}
+			if got := len(repo.positionPuts) > 0; got != tc.wantPuts {
+				t.Errorf("indexPositions wrote entries: %t; want: %t", got, tc.wantPuts)
+			}
+			if got := len(repo.positionDeletes) > 0; got != tc.wantDeletes {
+				t.Errorf("indexPositions deleted entries: %t; want: %t", got, tc.wantDeletes)
+			}
+		})
+	}
+}
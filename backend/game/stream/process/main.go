@@ -0,0 +1,38 @@
+// Implements the DynamoDB stream handler of the games table, which runs every processor
+// in the stream package over each record.
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/stream"
+)
+
+var repository stream.Repository = database.DynamoDB
+
+func main() {
+	lambda.Start(handler)
+}
+
+func handler(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	log.Debugf("Event: %#v", event)
+
+	failures := make([]events.DynamoDBBatchItemFailure, 0, len(event.Records))
+
+	for _, record := range event.Records {
+		if err := stream.Process(repository, record); err != nil {
+			log.Errorf("Failed to process record %s: %v", record.Change.SequenceNumber, err)
+			failures = append(failures, events.DynamoDBBatchItemFailure{
+				ItemIdentifier: record.Change.SequenceNumber,
+			})
+		}
+	}
+
+	return events.DynamoDBEventResponse{
+		BatchItemFailures: failures,
+	}, nil
+}
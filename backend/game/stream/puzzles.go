@@ -0,0 +1,53 @@
+package stream
+
+import (
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// extractPersonalPuzzles saves personal puzzles generated from the losing mistakes in the
+// changed game if its engine analysis was added or replaced. The owner's color is found by
+// matching their display name and rating usernames against the White and Black headers,
+// and the game is skipped if it cannot be determined.
+func extractPersonalPuzzles(repo Repository, change *Change) error {
+	newGame := change.NewGame
+	if newGame == nil || newGame.EngineAnalysis == nil {
+		return nil
+	}
+	if oldGame := change.OldGame; oldGame != nil && oldGame.EngineAnalysis != nil &&
+		oldGame.EngineAnalysis.AnalyzedAt == newGame.EngineAnalysis.AnalyzedAt {
+		return nil
+	}
+
+	parsed, err := newGame.ParsePgn()
+	if err != nil {
+		log.Warnf("Skipping corrupt game %s/%s: %v", newGame.Cohort, newGame.Id, err)
+		return nil
+	}
+
+	owner, err := repo.GetUser(newGame.Owner)
+	if err != nil {
+		var aerr *errors.Error
+		if errors.As(err, &aerr) && aerr.Code == 404 {
+			log.Warnf("Skipping game %s/%s with missing owner %s", newGame.Cohort, newGame.Id, newGame.Owner)
+			return nil
+		}
+		return err
+	}
+	color, ok := newGame.PlayerColor(owner.PlayerNames())
+	if !ok {
+		log.Infof("Skipping game %s/%s where %s is not a player", newGame.Cohort, newGame.Id, newGame.Owner)
+		return nil
+	}
+
+	puzzles := database.ExtractPersonalPuzzles(newGame, parsed, color)
+	if len(puzzles) == 0 {
+		return nil
+	}
+	if err := repo.PutPersonalPuzzles(puzzles); err != nil {
+		return err
+	}
+	log.Infof("Saved %d personal puzzles for %s from game %s/%s", len(puzzles), newGame.Owner, newGame.Cohort, newGame.Id)
+	return nil
+}
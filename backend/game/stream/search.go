@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"maps"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// indexSearchTerms updates the search index to match the new image of the changed game.
+// Entries for terms which no longer occur in the game are removed.
+func indexSearchTerms(repo Repository, change *Change) error {
+	oldGame, newGame := change.OldGame, change.NewGame
+	if oldGame != nil && newGame != nil && !searchIndexChanged(oldGame, newGame) {
+		log.Debugf("Skipping game %s/%s with unchanged search fields", newGame.Cohort, newGame.Id)
+		return nil
+	}
+
+	oldEntries := extractSearchEntries(oldGame)
+	newEntries := extractSearchEntries(newGame)
+
+	current := make(map[string]bool, len(newEntries))
+	for _, entry := range newEntries {
+		current[entry.Term] = true
+	}
+	var stale []database.GameSearchEntry
+	for _, entry := range oldEntries {
+		if !current[entry.Term] {
+			stale = append(stale, entry)
+		}
+	}
+
+	if err := repo.DeleteGameSearchEntries(stale); err != nil {
+		return err
+	}
+	written, err := repo.PutGameSearchEntries(newEntries)
+	if err != nil {
+		return err
+	}
+	log.Infof("Wrote %d and deleted %d search entries", written, len(stale))
+	return nil
+}
+
+// searchIndexChanged returns true if the fields stored in the search index differ between
+// the given old and new versions of a game.
+func searchIndexChanged(oldGame, newGame *database.Game) bool {
+	return oldGame.Pgn != newGame.Pgn ||
+		oldGame.Unlisted != newGame.Unlisted ||
+		oldGame.White != newGame.White ||
+		oldGame.Black != newGame.Black ||
+		oldGame.Date != newGame.Date ||
+		oldGame.OwnerDisplayName != newGame.OwnerDisplayName ||
+		!maps.Equal(oldGame.Headers, newGame.Headers)
+}
+
+// extractSearchEntries returns the search index entries for the given game. Unlisted and
+// unparseable games produce no entries.
+func extractSearchEntries(game *database.Game) []database.GameSearchEntry {
+	if game == nil || game.Unlisted {
+		return nil
+	}
+	parsed, err := game.ParsePgn()
+	if err != nil {
+		log.Warnf("Skipping corrupt game %s/%s: %v", game.Cohort, game.Id, err)
+		return nil
+	}
+	return database.ExtractSearchEntries(game, parsed)
+}
@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func (r *fakeRepository) PutGameSearchEntries(entries []database.GameSearchEntry) (int, error) {
+	r.searchPuts = append(r.searchPuts, entries...)
+	return len(entries), nil
+}
+
+func (r *fakeRepository) DeleteGameSearchEntries(entries []database.GameSearchEntry) error {
+	r.searchDeletes = append(r.searchDeletes, entries...)
+	return nil
+}
+
+func TestIndexSearchTermsUnlisted(t *testing.T) {
+	listed := &database.Game{
+		Cohort:  "1500-1600",
+		Id:      "2024.01.02_a",
+		Owner:   "owner",
+		White:   "carlsen",
+		Black:   "nakamura",
+		Date:    "2024.01.02",
+		Headers: map[string]string{"White": "Carlsen", "Black": "Nakamura"},
+		Pgn:     "[White \"Carlsen\"]\n[Black \"Nakamura\"]\n\n1. e4 e5 *",
+	}
+	unlisted := *listed
+	unlisted.Unlisted = true
+
+	table := []struct {
+		name        string
+		oldGame     *database.Game
+		newGame     *database.Game
+		wantPuts    bool
+		wantDeletes bool
+	}{
+		{name: "InsertListed", newGame: listed, wantPuts: true},
+		{name: "InsertUnlisted", newGame: &unlisted},
+		{name: "Unlist", oldGame: listed, newGame: &unlisted, wantDeletes: true},
+		{name: "Relist", oldGame: &unlisted, newGame: listed, wantPuts: true},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			if err := indexSearchTerms(repo, &Change{OldGame: tc.oldGame, NewGame: tc.newGame}); err != nil {
+				t.Fatalf("indexSearchTerms got error: %v", err)
+			}
+			if got := len(repo.searchPuts) > 0; got != tc.wantPuts {
+				t.Errorf("indexSearchTerms wrote entries: %t; want: %t", got, tc.wantPuts)
+			}
+			if got := len(repo.searchDeletes) > 0; got != tc.wantDeletes {
+				t.Errorf("indexSearchTerms deleted entries: %t; want: %t", got, tc.wantDeletes)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package stream contains the processors run over the games table stream. DynamoDB
+// Streams only supports a couple of concurrent readers per shard, so rather than each
+// processor reading the stream in its own Lambda, a single handler decodes each record
+// once and passes it to every processor.
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// Repository is the union of the repositories used by the processors.
+type Repository interface {
+	database.GameTagIndexer
+	database.PositionIndexer
+	database.GameSearchIndexer
+	database.TimeProfileSetter
+	database.EngineAnalysisRequester
+	database.PersonalPuzzlePutter
+	database.UserGetter
+	database.GameOpeningSetter
+	database.GameVersionPutter
+}
+
+// Change is a games table stream record with its images decoded.
+type Change struct {
+	// The stream record.
+	Record events.DynamoDBEventRecord
+
+	// The game before the change. Nil if the game was inserted.
+	OldGame *database.Game
+
+	// The game after the change. Nil if the game was removed.
+	NewGame *database.Game
+}
+
+// processor updates the data derived from a game after a single change to it.
+// Processors must be idempotent, as a record is retried for every processor if any
+// of them fails.
+type processor struct {
+	// The name of the processor, used in logs.
+	name string
+
+	// The function which processes the change.
+	process func(repo Repository, change *Change) error
+}
+
+// processors is the list of processors run over each record, in order.
+var processors = []processor{
+	{name: "indexGameTags", process: indexGameTags},
+	{name: "indexPositions", process: indexPositions},
+	{name: "indexSearchTerms", process: indexSearchTerms},
+	{name: "analyzeClocks", process: analyzeClocks},
+	{name: "requestEngineAnalysis", process: requestEngineAnalysis},
+	{name: "extractPersonalPuzzles", process: extractPersonalPuzzles},
+	{name: "classifyOpening", process: classifyOpening},
+	{name: "snapshotGameVersion", process: snapshotGameVersion},
+}
+
+// Process decodes the given record and runs every processor over it. A failing
+// processor does not prevent the others from running; the returned error joins the
+// errors of all failed processors.
+func Process(repo Repository, record events.DynamoDBEventRecord) error {
+	change, err := NewChange(record)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range processors {
+		if err := p.process(repo, change); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewChange decodes the old and new images of the given record.
+func NewChange(record events.DynamoDBEventRecord) (*Change, error) {
+	change := &Change{Record: record}
+	if len(record.Change.OldImage) > 0 {
+		change.OldGame = &database.Game{}
+		if err := unmarshalStreamImage(record.Change.OldImage, change.OldGame); err != nil {
+			return nil, err
+		}
+	}
+	if len(record.Change.NewImage) > 0 {
+		change.NewGame = &database.Game{}
+		if err := unmarshalStreamImage(record.Change.NewImage, change.NewGame); err != nil {
+			return nil, err
+		}
+	}
+	return change, nil
+}
+
+// unmarshalStreamImage converts events.DynamoDBAttributeValue to struct
+// TODO: replace this with dynamodbstreams/attributevalue after updating to go aws sdk v2.
+func unmarshalStreamImage(attribute map[string]events.DynamoDBAttributeValue, out interface{}) error {
+	dbAttrMap := make(map[string]*dynamodb.AttributeValue)
+
+	for k, v := range attribute {
+		var dbAttr dynamodb.AttributeValue
+		bytes, marshalErr := v.MarshalJSON()
+		if marshalErr != nil {
+			return marshalErr
+		}
+
+		json.Unmarshal(bytes, &dbAttr)
+		dbAttrMap[k] = &dbAttr
+	}
+
+	return dynamodbattribute.UnmarshalMap(dbAttrMap, out)
+}
@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// indexGameTags updates the system tags of the changed game if they do not match its PGN,
+// and updates the tag index to match the new image of the game. Saving the system tags
+// triggers another record for the same game, which indexes the new system tags.
+func indexGameTags(repo Repository, change *Change) error {
+	oldGame, newGame := change.OldGame, change.NewGame
+
+	if newGame != nil && (oldGame == nil || systemTagsChanged(oldGame, newGame)) {
+		parsed, err := newGame.ParsePgn()
+		if err != nil {
+			log.Warnf("Skipping system tags of corrupt game %s/%s: %v", newGame.Cohort, newGame.Id, err)
+		} else if tags := database.GuessSystemTags(newGame, parsed); !slices.Equal(tags, newGame.SystemTags) {
+			if err := repo.SetGameSystemTags(newGame.Cohort, newGame.Id, tags); err != nil {
+				return err
+			}
+			log.Infof("Updated system tags of game %s/%s to %v", newGame.Cohort, newGame.Id, tags)
+		}
+	}
+
+	if oldGame != nil && newGame != nil && !tagIndexChanged(oldGame, newGame) {
+		log.Debugf("Skipping game %s/%s with unchanged tags", newGame.Cohort, newGame.Id)
+		return nil
+	}
+
+	var oldEntries, newEntries []database.GameTag
+	if oldGame != nil {
+		oldEntries = database.ExtractGameTags(oldGame)
+	}
+	if newGame != nil {
+		newEntries = database.ExtractGameTags(newGame)
+	}
+
+	current := make(map[string]bool, len(newEntries))
+	for _, entry := range newEntries {
+		current[entry.Tag] = true
+	}
+	var stale []database.GameTag
+	for _, entry := range oldEntries {
+		if !current[entry.Tag] {
+			stale = append(stale, entry)
+		}
+	}
+
+	if err := repo.DeleteGameTags(stale); err != nil {
+		return err
+	}
+	written, err := repo.PutGameTags(newEntries)
+	if err != nil {
+		return err
+	}
+	log.Infof("Wrote %d and deleted %d tag entries", written, len(stale))
+	return nil
+}
+
+// systemTagsChanged returns true if the fields used by database.GuessSystemTags differ
+// between the given old and new versions of a game.
+func systemTagsChanged(oldGame, newGame *database.Game) bool {
+	return oldGame.Pgn != newGame.Pgn ||
+		oldGame.Headers["Event"] != newGame.Headers["Event"] ||
+		!slices.Equal(oldGame.SystemTags, newGame.SystemTags)
+}
+
+// tagIndexChanged returns true if the fields stored in the tag index differ between the
+// given old and new versions of a game.
+func tagIndexChanged(oldGame, newGame *database.Game) bool {
+	return !slices.Equal(oldGame.Tags, newGame.Tags) ||
+		!slices.Equal(oldGame.SystemTags, newGame.SystemTags) ||
+		oldGame.Unlisted != newGame.Unlisted ||
+		oldGame.White != newGame.White ||
+		oldGame.Black != newGame.Black ||
+		oldGame.Date != newGame.Date ||
+		oldGame.OwnerDisplayName != newGame.OwnerDisplayName ||
+		!maps.Equal(oldGame.Headers, newGame.Headers)
+}
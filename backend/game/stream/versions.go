@@ -0,0 +1,59 @@
+package stream
+
+import (
+	"maps"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/chess"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The headers set by classifyOpening. Changes to only these headers are not versioned, so
+// that classifying a new game does not save a version of it.
+var classifiedHeaders = []string{"ECO", "Opening"}
+
+// snapshotGameVersion saves the old image of the changed game as a GameVersion if the
+// game's PGN or headers changed, so that owners can restore it later. Changes to other
+// fields, such as comments, are not versioned. The version id includes the record's
+// sequence number, so retried records save the same version.
+func snapshotGameVersion(repo Repository, change *Change) error {
+	oldGame, newGame := change.OldGame, change.NewGame
+	if oldGame == nil || newGame == nil {
+		return nil
+	}
+	if oldGame.Pgn == "" || (!versionedPgnChanged(oldGame, newGame) && !versionedHeadersChanged(oldGame, newGame)) {
+		return nil
+	}
+
+	record := change.Record
+	version := database.NewGameVersion(oldGame, record.Change.ApproximateCreationDateTime.Time, record.Change.SequenceNumber)
+	if err := repo.PutGameVersion(version); err != nil {
+		return err
+	}
+	log.Infof("Saved version %s of game %s/%s", version.Version, oldGame.Cohort, oldGame.Id)
+	return nil
+}
+
+// versionedPgnChanged returns true if the PGNs of the given old and new versions of a game
+// differ other than in the tag pairs of classifiedHeaders.
+func versionedPgnChanged(oldGame, newGame *database.Game) bool {
+	oldPgn, newPgn := oldGame.Pgn, newGame.Pgn
+	for _, header := range classifiedHeaders {
+		if value, ok := newGame.Headers[header]; ok {
+			oldPgn = chess.SetPgnHeader(oldPgn, header, value)
+			newPgn = chess.SetPgnHeader(newPgn, header, value)
+		}
+	}
+	return oldPgn != newPgn
+}
+
+// versionedHeadersChanged returns true if the headers of the given old and new versions of
+// a game differ, ignoring classifiedHeaders.
+func versionedHeadersChanged(oldGame, newGame *database.Game) bool {
+	oldHeaders, newHeaders := maps.Clone(oldGame.Headers), maps.Clone(newGame.Headers)
+	for _, header := range classifiedHeaders {
+		delete(oldHeaders, header)
+		delete(newHeaders, header)
+	}
+	return !maps.Equal(oldHeaders, newHeaders)
+}
@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type fakeRepository struct {
+	Repository
+	versions        []*database.GameVersion
+	searchPuts      []database.GameSearchEntry
+	searchDeletes   []database.GameSearchEntry
+	positionPuts    []database.PositionGame
+	positionDeletes []database.PositionGame
+}
+
+func (r *fakeRepository) PutGameVersion(version *database.GameVersion) error {
+	r.versions = append(r.versions, version)
+	return nil
+}
+
+func TestSnapshotGameVersion(t *testing.T) {
+	headers := map[string]string{"White": "bob", "Black": "alice"}
+
+	table := []struct {
+		name    string
+		oldGame *database.Game
+		newGame *database.Game
+		want    bool
+	}{
+		{
+			name:    "PgnChanged",
+			oldGame: &database.Game{Pgn: "1. e4 *", Headers: headers},
+			newGame: &database.Game{Pgn: "1. d4 *", Headers: headers},
+			want:    true,
+		},
+		{
+			name:    "HeadersChanged",
+			oldGame: &database.Game{Pgn: "1. e4 *", Headers: headers},
+			newGame: &database.Game{Pgn: "1. e4 *", Headers: map[string]string{"White": "bob", "Black": "carol"}},
+			want:    true,
+		},
+		{
+			name:    "OpeningClassified",
+			oldGame: &database.Game{Pgn: "1. e4 *", Headers: headers},
+			newGame: &database.Game{Pgn: "1. e4 *", Headers: map[string]string{"White": "bob", "Black": "alice", "ECO": "B00", "Opening": "King's Pawn Opening"}},
+		},
+		{
+			name:    "OpeningTagsSet",
+			oldGame: &database.Game{Pgn: "[White \"bob\"]\n\n1. e4 *", Headers: headers},
+			newGame: &database.Game{Pgn: "[White \"bob\"]\n[ECO \"B00\"]\n[Opening \"King's Pawn Opening\"]\n\n1. e4 *", Headers: map[string]string{"White": "bob", "Black": "alice", "ECO": "B00", "Opening": "King's Pawn Opening"}},
+		},
+		{
+			name:    "Unchanged",
+			oldGame: &database.Game{Pgn: "1. e4 *", Headers: headers, Tags: []string{"old"}},
+			newGame: &database.Game{Pgn: "1. e4 *", Headers: headers, Tags: []string{"new"}},
+		},
+		{
+			name:    "Inserted",
+			newGame: &database.Game{Pgn: "1. e4 *", Headers: headers},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			change := &Change{
+				Record:  events.DynamoDBEventRecord{Change: events.DynamoDBStreamRecord{SequenceNumber: "1"}},
+				OldGame: tc.oldGame,
+				NewGame: tc.newGame,
+			}
+
+			if err := snapshotGameVersion(repo, change); err != nil {
+				t.Fatalf("snapshotGameVersion got error: %v", err)
+			}
+			if got := len(repo.versions) > 0; got != tc.want {
+				t.Errorf("snapshotGameVersion saved version: %t; want: %t", got, tc.want)
+			}
+		})
+	}
+}
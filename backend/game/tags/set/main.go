@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.GameTagSetter = database.DynamoDB
+
+type SetTagsRequest struct {
+	// The cohort of the game to tag.
+	Cohort database.DojoCohort `json:"cohort"`
+
+	// The id of the game to tag.
+	Id string `json:"id"`
+
+	// The new tags of the game, which replace any existing owner-defined tags.
+	Tags []string `json:"tags"`
+}
+
+// Handler sets the owner-defined tags of a game. The tag index is updated by the
+// games table stream handler.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		err := errors.New(400, "Invalid request: username is required", "")
+		return api.Failure(err), nil
+	}
+
+	var request SetTagsRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		err = errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)
+		return api.Failure(err), nil
+	}
+	if request.Cohort == "" || request.Id == "" {
+		err := errors.New(400, "Invalid request: cohort and id are required", "")
+		return api.Failure(err), nil
+	}
+
+	tags, err := database.NormalizeTags(request.Tags)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	game, err := repository.SetGameTags(info.Username, request.Cohort, request.Id, tags)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(game), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/versions"
+)
+
+var repository database.GameVersionRepository = database.DynamoDB
+
+type DiffVersionsResponse struct {
+	// The older version being compared.
+	From string `json:"from"`
+
+	// The newer version being compared.
+	To string `json:"to"`
+
+	database.GameDiff
+}
+
+// Handler returns the difference between the from and to versions of a game. The to
+// version defaults to the game's current state.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	cohort := database.DojoCohort(event.QueryStringParameters["cohort"])
+	id := event.QueryStringParameters["id"]
+
+	game, err := versions.GetOwnedGame(repository, info.Username, cohort, id)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	from, err := versions.GetVersion(repository, game, event.QueryStringParameters["from"])
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	to := event.QueryStringParameters["to"]
+	if to == "" {
+		to = database.CurrentGameVersion
+	}
+	toVersion, err := versions.GetVersion(repository, game, to)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	diff, err := database.DiffGameVersions(from, toVersion)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(DiffVersionsResponse{
+		From:     from.Version,
+		To:       toVersion.Version,
+		GameDiff: *diff,
+	}), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/versions"
+)
+
+var repository database.GameVersionRepository = database.DynamoDB
+
+type ListVersionsResponse struct {
+	Versions         []database.GameVersion `json:"versions"`
+	LastEvaluatedKey string                 `json:"lastEvaluatedKey,omitempty"`
+}
+
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	cohort := database.DojoCohort(event.QueryStringParameters["cohort"])
+	id := event.QueryStringParameters["id"]
+
+	game, err := versions.GetOwnedGame(repository, info.Username, cohort, id)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	result, lastKey, err := repository.ListGameVersions(game.Cohort, game.Id, event.QueryStringParameters["startKey"])
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	return api.Success(ListVersionsResponse{
+		Versions:         result,
+		LastEvaluatedKey: lastKey,
+	}), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/game/versions"
+)
+
+var repository database.GameVersionRepository = database.DynamoDB
+
+type RestoreVersionRequest struct {
+	// The cohort of the game to restore.
+	Cohort database.DojoCohort `json:"cohort"`
+
+	// The id of the game to restore.
+	Id string `json:"id"`
+
+	// The version to restore.
+	Version string `json:"version"`
+}
+
+// Handler overwrites the PGN and headers of a game with those of an older version. The
+// state being overwritten is itself saved as a version by the games table stream handler,
+// so a restore can be undone.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+
+	var request RestoreVersionRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		err = errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)
+		return api.Failure(err), nil
+	}
+	if request.Version == database.CurrentGameVersion {
+		err := errors.New(400, "Invalid request: the current version cannot be restored", "")
+		return api.Failure(err), nil
+	}
+
+	game, err := versions.GetOwnedGame(repository, info.Username, request.Cohort, request.Id)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	version, err := versions.GetVersion(repository, game, request.Version)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	game, err = repository.RestoreGameVersion(info.Username, version)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(game), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
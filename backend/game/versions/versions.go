@@ -0,0 +1,43 @@
+// Package versions contains the helpers shared by the game version handlers.
+package versions
+
+import (
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// GetOwnedGame returns the game with the given cohort and id. A 403 error is returned if
+// the game is not owned by the given user, as only owners may view or restore the
+// versions of a game.
+func GetOwnedGame(repo database.GameGetter, username string, cohort database.DojoCohort, id string) (*database.Game, error) {
+	if username == "" {
+		return nil, errors.New(400, "Invalid request: username is required", "")
+	}
+	if !database.IsValidCohort(cohort) {
+		return nil, errors.New(400, "Invalid request: cohort is invalid", "")
+	}
+	if id == "" {
+		return nil, errors.New(400, "Invalid request: id is required", "")
+	}
+
+	game, err := repo.GetGame(string(cohort), id)
+	if err != nil {
+		return nil, err
+	}
+	if game.Owner != username {
+		return nil, errors.New(403, "Invalid request: only the owner of a game can access its versions", "")
+	}
+	return game, nil
+}
+
+// GetVersion returns the given version of the given game. CurrentGameVersion returns the
+// game's current state.
+func GetVersion(repo database.GameVersionRepository, game *database.Game, version string) (*database.GameVersion, error) {
+	if version == "" {
+		return nil, errors.New(400, "Invalid request: version is required", "")
+	}
+	if version == database.CurrentGameVersion {
+		return game.CurrentVersion(), nil
+	}
+	return repo.GetGameVersion(game.Cohort, game.Id, version)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.CommentModerator = database.DynamoDB
+
+type ListReportsResponse struct {
+	Reports          []database.CommentReport `json:"reports"`
+	LastEvaluatedKey string                   `json:"lastEvaluatedKey,omitempty"`
+}
+
+// Handler returns the moderation queue of comment reports, oldest first. The status query
+// parameter selects open or resolved reports and defaults to open. Only admins can call
+// this API.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	user, err := repository.GetUser(info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if !user.IsAdmin {
+		return api.Failure(errors.New(403, "You do not have permission to perform this action", "")), nil
+	}
+
+	status := database.CommentReportStatus(event.QueryStringParameters["status"])
+	if status == "" {
+		status = database.CommentReportStatus_Open
+	}
+	if status != database.CommentReportStatus_Open && status != database.CommentReportStatus_Resolved {
+		return api.Failure(errors.New(400, "Invalid request: status must be OPEN or RESOLVED", "")), nil
+	}
+
+	reports, lastKey, err := repository.ListCommentReports(status, event.QueryStringParameters["startKey"])
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(ListReportsResponse{
+		Reports:          reports,
+		LastEvaluatedKey: lastKey,
+	}), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
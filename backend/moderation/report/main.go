@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.CommentModerator = database.DynamoDB
+
+type ReportCommentRequest struct {
+	// The reported comment.
+	Target database.CommentTarget `json:"target"`
+
+	// The reason for the report.
+	Reason database.CommentReportReason `json:"reason"`
+
+	// Optional details on the reason for the report.
+	Details string `json:"details"`
+}
+
+// Handler reports a comment on a game, timeline entry or event to the admins. The
+// content of the comment is saved with the report, so that admins see what was reported
+// even if the comment is later edited.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	var request ReportCommentRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return api.Failure(errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)), nil
+	}
+	if err := request.Target.Validate(); err != nil {
+		return api.Failure(err), nil
+	}
+
+	reporter, err := repository.GetUser(info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	comment, err := repository.GetReportedComment(&request.Target)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	report, err := database.NewCommentReport(&request.Target, comment, reporter, request.Reason, request.Details)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if err := repository.CreateCommentReport(report); err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(report), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
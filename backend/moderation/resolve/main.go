@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.CommentModerator = database.DynamoDB
+
+type ResolveReportRequest struct {
+	// The id of the report to resolve.
+	Id string `json:"id"`
+
+	// The action to take on the report.
+	Action database.CommentReportAction `json:"action"`
+}
+
+// Handler resolves a comment report by either removing the reported comment or
+// dismissing the report. Removed comments are soft deleted, so that their replies are
+// kept. Only admins can call this API.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	user, err := repository.GetUser(info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if !user.IsAdmin {
+		return api.Failure(errors.New(403, "You do not have permission to perform this action", "")), nil
+	}
+
+	var request ResolveReportRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return api.Failure(errors.Wrap(400, "Invalid request: unable to unmarshal body", "", err)), nil
+	}
+	if request.Id == "" {
+		return api.Failure(errors.New(400, "Invalid request: id is required", "")), nil
+	}
+	if request.Action != database.CommentReportAction_Removed && request.Action != database.CommentReportAction_Dismissed {
+		return api.Failure(errors.New(400, "Invalid request: action must be REMOVED or DISMISSED", "")), nil
+	}
+
+	report, err := repository.GetCommentReport(request.Id)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if report.Status != database.CommentReportStatus_Open {
+		return api.Failure(errors.New(400, "Invalid request: report is already resolved", "")), nil
+	}
+
+	if request.Action == database.CommentReportAction_Removed {
+		comment, err := repository.GetReportedComment(&report.Target)
+		if err != nil {
+			return api.Failure(err), nil
+		}
+		// The comment may have been removed through another report
+		if !comment.Deleted {
+			if err := repository.RemoveComment(&report.Target, user.Username); err != nil {
+				return api.Failure(err), nil
+			}
+		}
+	}
+
+	report, err = repository.ResolveCommentReport(request.Id, user.Username, request.Action)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(report), nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
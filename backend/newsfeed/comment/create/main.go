@@ -14,7 +14,12 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.TimelineCommenter = database.DynamoDB
+type CommentRepository interface {
+	database.TimelineCommenter
+	database.MentionResolver
+}
+
+var repository CommentRepository = database.DynamoDB
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	log.SetRequestId(event.RequestContext.RequestID)
@@ -64,6 +69,18 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 		log.Error("Failed to create notification event: ", err)
 	}
 
+	target := &database.CommentTarget{
+		Type:      database.CommentTargetType_Timeline,
+		Owner:     entry.Owner,
+		Id:        entry.Id,
+		CommentId: comment.Id,
+	}
+	if mentions, err := database.ResolveMentions(repository, comment.Content, comment.Owner); err != nil {
+		log.Error("Failed to resolve mentions:", err)
+	} else if err := database.SendMentionEvent(target, comment.Owner, comment.OwnerDisplayName, mentions); err != nil {
+		log.Error("Failed to create mention notification event: ", err)
+	}
+
 	return api.Success(entry), nil
 }
 
@@ -86,7 +86,7 @@ export async function handleCalendarInvite(event: CalendarInviteEvent) {
  * Returns the calendar event with the given id.
  * @param id The id of the event to fetch.
  */
-async function getEvent(id: string): Promise<Event | undefined> {
+export async function getEvent(id: string): Promise<Event | undefined> {
     const getEventOutput = await dynamo.send(
         new GetItemCommand({
             Key: { id: { S: id } },
@@ -5,7 +5,9 @@ import { unmarshall } from '@aws-sdk/util-dynamodb';
 import { Game, PositionComment } from '@jackstenglein/chess-dojo-common/src/database/game';
 import {
     GameCommentEvent,
+    GameCommentReactionEvent,
     GameReviewEvent,
+    GameReviewRefundEvent,
     NotificationTypes,
 } from '@jackstenglein/chess-dojo-common/src/database/notification';
 import { ApiError } from '../directoryService/api';
@@ -146,6 +148,63 @@ async function putNewCommentNotification(game: GameProjection) {
     console.log(`Successfully created game comment notification for user ${game.owner}: `, result);
 }
 
+/**
+ * Creates notifications for GameCommentReactionEvents.
+ * @param event The event to create notifications for.
+ */
+export async function handleGameCommentReaction(event: GameCommentReactionEvent) {
+    const user = await getNotificationSettings(event.owner);
+    if (!user) {
+        console.error(
+            `Unable to add comment reaction notification for user ${event.owner}: not found`,
+        );
+        return;
+    }
+    if (user.notificationSettings?.siteNotificationSettings?.disableGameCommentReaction) {
+        console.log(`Skipping user ${event.owner} as gameCommentReaction is disabled`);
+        return;
+    }
+
+    const getGameOutput = await dynamo.send(
+        new GetItemCommand({
+            Key: {
+                cohort: { S: event.game.cohort },
+                id: { S: event.game.id },
+            },
+            ProjectionExpression: `cohort, #id, headers`,
+            ExpressionAttributeNames: {
+                '#id': 'id',
+            },
+            TableName: gameTable,
+        }),
+    );
+    if (!getGameOutput.Item) {
+        throw new ApiError({
+            statusCode: 404,
+            publicMessage: `Invalid request: game ${event.game.cohort}/${event.game.id} not found`,
+        });
+    }
+
+    const game = unmarshall(getGameOutput.Item) as Pick<Game, 'cohort' | 'id' | 'headers'>;
+    const input = new UpdateItemBuilder()
+        .key('username', event.owner)
+        .key('id', `${NotificationTypes.GAME_COMMENT_REACTION}|${game.cohort}|${game.id}`)
+        .set('type', NotificationTypes.GAME_COMMENT_REACTION)
+        .set('updatedAt', new Date().toISOString())
+        .set('gameCommentMetadata', {
+            cohort: game.cohort,
+            id: game.id,
+            headers: game.headers,
+        })
+        .add('count', 1)
+        .table(notificationTable)
+        .build();
+    await dynamo.send(input);
+    console.log(
+        `Successfully created ${NotificationTypes.GAME_COMMENT_REACTION} notification for ${event.owner}`,
+    );
+}
+
 /**
  * Creates notifications for a completed game review.
  * @param event The event to create notifications for.
@@ -200,3 +259,58 @@ export async function handleGameReview(event: GameReviewEvent) {
         `Successfully created ${NotificationTypes.GAME_REVIEW_COMPLETE} notification for ${game.owner}`,
     );
 }
+
+/**
+ * Creates notifications for a partially refunded late game review.
+ * @param event The event to create notifications for.
+ */
+export async function handleGameReviewRefund(event: GameReviewRefundEvent) {
+    const getGameOutput = await dynamo.send(
+        new GetItemCommand({
+            Key: {
+                cohort: { S: event.game.cohort },
+                id: { S: event.game.id },
+            },
+            ProjectionExpression: `cohort, #id, headers, #owner`,
+            ExpressionAttributeNames: {
+                '#owner': 'owner',
+                '#id': 'id',
+            },
+            TableName: gameTable,
+        }),
+    );
+    if (!getGameOutput.Item) {
+        throw new ApiError({
+            statusCode: 404,
+            publicMessage: `Invalid request: game ${event.game.cohort}/${event.game.id} not found`,
+        });
+    }
+
+    const game = unmarshall(getGameOutput.Item) as Pick<
+        Game,
+        'cohort' | 'id' | 'headers' | 'owner'
+    >;
+    const user = await getNotificationSettings(game.owner);
+    if (!user) {
+        return;
+    }
+
+    const input = new UpdateItemBuilder()
+        .key('username', user.username)
+        .key('id', `${NotificationTypes.GAME_REVIEW_REFUND}|${game.cohort}|${game.id}`)
+        .set('type', NotificationTypes.GAME_REVIEW_REFUND)
+        .set('updatedAt', new Date().toISOString())
+        .set('gameReviewRefundMetadata', {
+            cohort: game.cohort,
+            id: game.id,
+            headers: game.headers,
+            percentage: event.percentage,
+        })
+        .add('count', 1)
+        .table(notificationTable)
+        .build();
+    await dynamo.send(input);
+    console.log(
+        `Successfully created ${NotificationTypes.GAME_REVIEW_REFUND} notification for ${game.owner}`,
+    );
+}
@@ -14,7 +14,13 @@ import { ApiError } from '../directoryService/api';
 import { dynamo, UpdateItemBuilder } from '../directoryService/database';
 import { handleClubJoinRequest, handleClubJoinRequestApproved } from './club';
 import { handleCalendarInvite, handleEventBooked } from './events';
-import { handleGameComment, handleGameReview } from './game';
+import {
+    handleGameComment,
+    handleGameCommentReaction,
+    handleGameReview,
+    handleGameReviewRefund,
+} from './game';
+import { handleMention } from './mention';
 import { handleRoundRobinStart } from './roundRobin';
 import { handleSubscriptionCreated } from './subscription';
 import { handleTimelineComment, handleTimelineReaction } from './timeline';
@@ -44,8 +50,12 @@ function handleEvent(event: NotificationEvent) {
     switch (event.type) {
         case NotificationEventTypes.GAME_COMMENT:
             return handleGameComment(event);
+        case NotificationEventTypes.GAME_COMMENT_REACTION:
+            return handleGameCommentReaction(event);
         case NotificationEventTypes.GAME_REVIEW_COMPLETE:
             return handleGameReview(event);
+        case NotificationEventTypes.GAME_REVIEW_REFUND:
+            return handleGameReviewRefund(event);
         case NotificationEventTypes.NEW_FOLLOWER:
             return handleNewFollower(event);
         case NotificationEventTypes.TIMELINE_COMMENT:
@@ -64,6 +74,8 @@ function handleEvent(event: NotificationEvent) {
             return handleRoundRobinStart(event);
         case NotificationEventTypes.SUBSCRIPTION_CREATED:
             return handleSubscriptionCreated(event);
+        case NotificationEventTypes.MENTION:
+            return handleMention(event);
         default:
             throw new ApiError({
                 statusCode: 400,
@@ -0,0 +1,155 @@
+import { GetItemCommand } from '@aws-sdk/client-dynamodb';
+import { unmarshall } from '@aws-sdk/util-dynamodb';
+import { Game } from '@jackstenglein/chess-dojo-common/src/database/game';
+import {
+    MentionEvent,
+    Notification,
+    NotificationTypes,
+} from '@jackstenglein/chess-dojo-common/src/database/notification';
+import { TimelineEntry } from '@jackstenglein/chess-dojo-common/src/database/timeline';
+import { ApiError } from '../directoryService/api';
+import { dynamo, UpdateItemBuilder } from '../directoryService/database';
+import { getEvent } from './events';
+import { getNotificationSettings } from './user';
+
+const gameTable = `${process.env.stage}-games`;
+const timelineTable = `${process.env.stage}-timeline`;
+const notificationTable = `${process.env.stage}-notifications`;
+
+type MentionMetadata = NonNullable<Notification['mentionMetadata']>;
+
+/**
+ * Creates notifications for MentionEvents.
+ * @param event The event to create notifications for.
+ */
+export async function handleMention(event: MentionEvent) {
+    const metadata = await getMentionMetadata(event);
+
+    for (const username of event.usernames) {
+        if (username === event.author) {
+            continue;
+        }
+
+        const user = await getNotificationSettings(username);
+        if (!user) {
+            console.log(`Skipping mention of ${username}: user not found`);
+            continue;
+        }
+        if (user.notificationSettings?.siteNotificationSettings?.disableMention) {
+            console.log(`Skipping user ${username} as mention is disabled`);
+            continue;
+        }
+
+        const input = new UpdateItemBuilder()
+            .key('username', username)
+            .key('id', `${NotificationTypes.MENTION}|${getMentionKey(metadata)}`)
+            .set('type', NotificationTypes.MENTION)
+            .set('updatedAt', new Date().toISOString())
+            .set('mentionMetadata', metadata)
+            .add('count', 1)
+            .table(notificationTable)
+            .build();
+        await dynamo.send(input);
+        console.log(
+            `Successfully created ${NotificationTypes.MENTION} notification for ${username}`,
+        );
+    }
+}
+
+/**
+ * Returns the notification metadata for the given mention event.
+ * @param event The event to get the metadata for.
+ */
+async function getMentionMetadata(event: MentionEvent): Promise<MentionMetadata> {
+    const { target } = event;
+
+    switch (target.type) {
+        case 'GAME': {
+            const getGameOutput = await dynamo.send(
+                new GetItemCommand({
+                    Key: {
+                        cohort: { S: target.cohort ?? '' },
+                        id: { S: target.gameId ?? '' },
+                    },
+                    ProjectionExpression: `cohort, #id, headers`,
+                    ExpressionAttributeNames: {
+                        '#id': 'id',
+                    },
+                    TableName: gameTable,
+                }),
+            );
+            if (!getGameOutput.Item) {
+                throw new ApiError({
+                    statusCode: 404,
+                    publicMessage: `Invalid request: game ${target.cohort}/${target.gameId} not found`,
+                });
+            }
+            const game = unmarshall(getGameOutput.Item) as Pick<Game, 'cohort' | 'id' | 'headers'>;
+            return {
+                type: target.type,
+                cohort: game.cohort,
+                id: game.id,
+                title: `${game.headers.White} - ${game.headers.Black}`,
+                mentionedBy: event.displayName,
+            };
+        }
+
+        case 'TIMELINE': {
+            const getTimelineEntry = await dynamo.send(
+                new GetItemCommand({
+                    Key: {
+                        owner: { S: target.owner ?? '' },
+                        id: { S: target.id ?? '' },
+                    },
+                    TableName: timelineTable,
+                }),
+            );
+            if (!getTimelineEntry.Item) {
+                throw new ApiError({
+                    statusCode: 404,
+                    publicMessage: `timeline entry ${target.owner}/${target.id} not found`,
+                });
+            }
+            const entry = unmarshall(getTimelineEntry.Item) as TimelineEntry;
+            return {
+                type: target.type,
+                owner: entry.owner,
+                id: entry.id,
+                title: entry.requirementName,
+                mentionedBy: event.displayName,
+            };
+        }
+
+        case 'EVENT': {
+            const calendarEvent = await getEvent(target.id ?? '');
+            if (!calendarEvent) {
+                throw new ApiError({
+                    statusCode: 404,
+                    publicMessage: `Invalid request: event ${target.id} not found`,
+                });
+            }
+            return {
+                type: target.type,
+                id: calendarEvent.id,
+                title: calendarEvent.title,
+                mentionedBy: event.displayName,
+            };
+        }
+    }
+}
+
+/**
+ * Returns the part of the notification id identifying the object containing the mention.
+ * Mentions in the same game, timeline entry or event are grouped into one notification.
+ * @param metadata The metadata of the mention.
+ */
+function getMentionKey(metadata: MentionMetadata): string {
+    switch (metadata.type) {
+        case 'GAME':
+            return `${metadata.type}|${metadata.cohort}|${metadata.id}`;
+        case 'TIMELINE':
+            return `${metadata.type}|${metadata.owner}|${metadata.id}`;
+        case 'EVENT':
+            return `${metadata.type}|${metadata.id}`;
+    }
+}
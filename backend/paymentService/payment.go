@@ -480,3 +480,74 @@ func CreateEventRefund(event *database.Event, participant *database.Participant,
 	}
 	return result, errors.Wrap(500, "Failed to create Stripe refund", "", err)
 }
+
+// CreateGameReviewRefund refunds the given percentage of the price paid for the given game's
+// review. Nil is returned if percentage is not positive, the review was not paid through
+// Stripe or the payment was already refunded. At most one refund is created per review,
+// even if this function is called multiple times: if the payment already has a refund for
+// the game's review, that refund is returned instead of creating a new one.
+func CreateGameReviewRefund(game *database.Game, percentage int64) (*stripe.Refund, error) {
+	if percentage <= 0 {
+		return nil, nil
+	}
+	if game.Review == nil || game.Review.StripeId == "" {
+		return nil, nil
+	}
+
+	checkoutSession, err := GetCheckoutSession(game.Review.StripeId)
+	if err != nil {
+		return nil, err
+	}
+	if checkoutSession.PaymentIntent == nil || checkoutSession.AmountTotal <= 0 {
+		return nil, nil
+	}
+
+	existing, err := findGameReviewRefund(checkoutSession.PaymentIntent.ID, game)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	amount := checkoutSession.AmountTotal * percentage / 100
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(checkoutSession.PaymentIntent.ID),
+		Amount:        stripe.Int64(amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+		Metadata: map[string]string{
+			"type":       string(CheckoutSessionType_GameReview),
+			"reviewType": string(game.Review.Type),
+			"cohort":     string(game.Cohort),
+			"id":         game.Id,
+			"username":   game.Owner,
+		},
+	}
+	params.SetIdempotencyKey(fmt.Sprintf("game-review-refund-%s", game.Review.StripeId))
+
+	result, err := refund.New(params)
+	if serr, ok := err.(*stripe.Error); ok {
+		if serr.Code == stripe.ErrorCodeChargeAlreadyRefunded {
+			return nil, nil
+		}
+	}
+	return result, errors.Wrap(500, "Failed to create Stripe refund", "", err)
+}
+
+// findGameReviewRefund returns the refund of the given PaymentIntent which was created by
+// CreateGameReviewRefund for the given game's review. Failed and canceled refunds are
+// ignored. Nil is returned if there is no such refund.
+func findGameReviewRefund(paymentIntentId string, game *database.Game) (*stripe.Refund, error) {
+	iter := refund.List(&stripe.RefundListParams{PaymentIntent: stripe.String(paymentIntentId)})
+	for iter.Next() {
+		r := iter.Refund()
+		if r.Status == stripe.RefundStatusFailed || r.Status == stripe.RefundStatusCanceled {
+			continue
+		}
+		if r.Metadata["type"] == string(CheckoutSessionType_GameReview) &&
+			r.Metadata["cohort"] == string(game.Cohort) && r.Metadata["id"] == game.Id {
+			return r, nil
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(500, "Failed to list Stripe refunds", "", err)
+	}
+	return nil, nil
+}
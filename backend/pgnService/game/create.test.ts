@@ -1,5 +1,7 @@
 'use strict';
 
+import { readFileSync } from 'fs';
+import { join } from 'path';
 import { assert, test } from 'vitest';
 import { getGame, isFairyChess } from './create';
 
@@ -80,3 +82,35 @@ test('getGame handles incomplete pgn', () => {
     const pgn = game.pgn.trim();
     assert.equal(pgn[pgn.length - 1], '*');
 });
+
+test('getGame fingerprint matches the shared test vectors', () => {
+    // These vectors are shared with GameFingerprint in the Go database package
+    const vectors = JSON.parse(
+        readFileSync(join(__dirname, '../../database/testdata/fingerprints.json'), 'utf-8'),
+    ) as { name: string; pgn: string; fingerprint: string }[];
+
+    for (const vector of vectors) {
+        const game = getGame(undefined, vector.pgn);
+        assert.equal(game.fingerprint ?? '', vector.fingerprint, vector.name);
+    }
+});
+
+test('getGame fingerprint changes with the moves', () => {
+    const pgnText = `[White "Alice"]
+[Black "Bob"]
+[Date "2024.01.02"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0`;
+    const different = `[White "Alice"]
+[Black "Bob"]
+[Date "2024.01.02"]
+[Result "*"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 g6 *`;
+
+    assert.notEqual(
+        getGame(undefined, different).fingerprint,
+        getGame(undefined, pgnText).fingerprint,
+    );
+});
@@ -5,6 +5,7 @@ import {
     DynamoDBClient,
     GetItemCommand,
     PutItemCommand,
+    QueryCommand,
 } from '@aws-sdk/client-dynamodb';
 import { marshall, unmarshall } from '@aws-sdk/util-dynamodb';
 import { Chess } from '@jackstenglein/chess';
@@ -17,6 +18,7 @@ import {
     CreateGameRequest,
     CreateGameSchema,
     GameImportTypes,
+    GameKey,
     GameOrientation,
     GameOrientations,
 } from '@jackstenglein/chess-dojo-common/src/database/game';
@@ -27,6 +29,7 @@ import {
     APIGatewayProxyHandlerV2,
     APIGatewayProxyResultV2,
 } from 'aws-lambda';
+import { createHash } from 'crypto';
 import { v4 as uuidv4 } from 'uuid';
 import { checkAccess } from '../../directoryService/access';
 import { addDirectoryItems } from '../../directoryService/addItems';
@@ -98,22 +101,35 @@ export const handler: APIGatewayProxyHandlerV2 = async (event) => {
             });
         }
 
-        const updated = await batchPutGames(games);
+        // Clones are intentional copies, so they are never treated as duplicates
+        const { newGames, existingGames } =
+            request.type === GameImportTypes.clone
+                ? { newGames: games, existingGames: [] }
+                : await removeDuplicates(games);
+
+        const updated = newGames.length > 0 ? await batchPutGames(newGames) : 0;
 
         if (request.directory) {
-            await addGamesToDirectory(request.directory.owner, request.directory.id, games);
+            await addGamesToDirectory(request.directory.owner, request.directory.id, [
+                ...newGames,
+                ...existingGames,
+            ]);
         }
 
         if (request.publish) {
-            for (const game of games) {
+            for (const game of newGames) {
                 await createTimelineEntry(game);
             }
         }
 
         if (games.length === 1) {
-            return success(games[0]);
+            if (existingGames.length > 0) {
+                const { cohort, id } = existingGames[0];
+                return success({ ...existingGames[0], duplicateOf: { cohort, id } });
+            }
+            return success(newGames[0]);
         }
-        return success({ count: updated });
+        return success({ count: updated, duplicates: games.length - newGames.length });
     } catch (err) {
         return errToApiGatewayProxyResultV2(err);
     }
@@ -314,6 +330,7 @@ export function getGame(
         const uploadDate = now.toISOString().slice(0, '2024-01-01'.length);
 
         const game: Game = {
+            fingerprint: getFingerprint(chess),
             cohort: user?.dojoCohort || '',
             id: `${uploadDate.replaceAll('-', '.')}_${uuidv4()}`,
             white: chess.header().tags.White?.toLowerCase() || '?',
@@ -349,6 +366,140 @@ export function getGame(
     }
 }
 
+/**
+ * Returns a hash of the players, date, starting position and mainline moves of the given
+ * chess instance, or undefined if the game has no moves. Two uploads of the same game have
+ * the same fingerprint regardless of their annotations, variations or other headers.
+ *
+ * This must match GameFingerprint in the Go database package, which is used to backfill
+ * the fingerprints of existing games.
+ * @param chess The chess instance to get the fingerprint for.
+ * @returns The fingerprint of the game.
+ */
+export function getFingerprint(chess: Chess): string | undefined {
+    const moves = chess.history();
+    if (moves.length === 0) {
+        return undefined;
+    }
+
+    const tags = chess.header().valueMap();
+    const data = [
+        (tags.White ?? '').trim().toLowerCase(),
+        (tags.Black ?? '').trim().toLowerCase(),
+        (tags.Date ?? '').trim(),
+        (tags.FEN ?? '').trim(),
+        moves.map((move) => move.san.replace(/[+#]+$/, '')).join(' '),
+    ].join('\n');
+
+    return createHash('sha256').update(data).digest('hex');
+}
+
+/**
+ * Removes games which duplicate an existing game of the same owner or an earlier game in
+ * the list. Games are duplicates if they have the same fingerprint.
+ * @param games The games to check. Must all have the same owner.
+ * @returns The games which are not duplicates and the existing games which were duplicated.
+ */
+async function removeDuplicates(
+    games: Game[],
+): Promise<{ newGames: Game[]; existingGames: Game[] }> {
+    const newGames: Game[] = [];
+    const existingGames: Game[] = [];
+    const seen = new Set<string>();
+
+    for (const game of games) {
+        if (!game.fingerprint) {
+            newGames.push(game);
+            continue;
+        }
+        if (seen.has(game.fingerprint)) {
+            continue;
+        }
+        seen.add(game.fingerprint);
+
+        const existing = await getGameByFingerprint(game.owner, game.fingerprint);
+        if (existing) {
+            console.log(
+                'Game %s duplicates existing game %s/%s',
+                game.id,
+                existing.cohort,
+                existing.id,
+            );
+            existingGames.push(existing);
+        } else {
+            newGames.push(game);
+        }
+    }
+
+    return { newGames, existingGames };
+}
+
+/**
+ * Returns the key of a game with the given owner and fingerprint, if one exists.
+ * @param owner The owner of the game.
+ * @param fingerprint The fingerprint of the game.
+ * @param exclude The key of a game which is not returned, if any.
+ * @returns The key of the existing game, or undefined if there is none.
+ */
+export async function getDuplicateKey(
+    owner: string,
+    fingerprint: string,
+    exclude?: GameKey,
+): Promise<GameKey | undefined> {
+    const queryOutput = await dynamo.send(
+        new QueryCommand({
+            KeyConditionExpression: '#fingerprint = :fingerprint AND #owner = :owner',
+            ExpressionAttributeNames: {
+                '#fingerprint': 'fingerprint',
+                '#owner': 'owner',
+            },
+            ExpressionAttributeValues: {
+                ':fingerprint': { S: fingerprint },
+                ':owner': { S: owner },
+            },
+            IndexName: 'FingerprintIdx',
+            TableName: gamesTable,
+        }),
+    );
+
+    return queryOutput.Items?.map((item) => unmarshall(item) as GameKey).find(
+        (key) => key.cohort !== exclude?.cohort || key.id !== exclude?.id,
+    );
+}
+
+/**
+ * Returns the game with the given owner and fingerprint, if one exists.
+ * @param owner The owner of the game.
+ * @param fingerprint The fingerprint of the game.
+ * @returns The existing game, or undefined if there is none.
+ */
+async function getGameByFingerprint(
+    owner: string,
+    fingerprint: string,
+): Promise<Game | undefined> {
+    const key = await getDuplicateKey(owner, fingerprint);
+    if (!key) {
+        return undefined;
+    }
+
+    const getItemOutput = await dynamo.send(
+        new GetItemCommand({
+            Key: {
+                cohort: { S: key.cohort },
+                id: { S: key.id },
+            },
+            TableName: gamesTable,
+        }),
+    );
+    if (!getItemOutput.Item) {
+        return undefined;
+    }
+
+    const game = unmarshall(getItemOutput.Item) as Game;
+    game.directories = game.directories ? [...game.directories] : undefined;
+    return game;
+}
+
 /**
  * Gets the default orientation for the given chess instance and user. If any of
  * the user's usernames match the White/Black header in the chess instance, that
@@ -64,6 +64,12 @@ export interface Game {
 
     /** A set of directories containing this game, in the form `owner/id`. */
     directories?: string[];
+
+    /**
+     * A hash of the players, date and mainline moves of the game, used to detect duplicate
+     * uploads. Omitted for games without moves.
+     */
+    fingerprint?: string;
 }
 
 export interface GameUpdate {
@@ -85,6 +91,12 @@ export interface GameUpdate {
     /** The PGN of the game. */
     pgn?: string;
 
+    /**
+     * The fingerprint of the game. Only included if the PGN changed. Null if the new PGN has
+     * no moves, in which case the existing fingerprint is removed.
+     */
+    fingerprint?: string | null;
+
     /** The orientation of the game. */
     orientation?: GameOrientation;
 
@@ -96,6 +108,9 @@ export interface GameUpdate {
 
     /** The ID of the timeline entry associated with this game's publishing. */
     timelineId?: string;
+
+    /** The username of the user who changed the PGN. Only included if the PGN changed. */
+    lastEditedBy?: string;
 }
 
 export interface GameImportHeaders {
@@ -26,6 +26,7 @@ import {
     createTimelineEntry,
     dynamo,
     gamesTable,
+    getDuplicateKey,
     getGame,
     getPgnTexts,
     getUserInfo,
@@ -53,9 +54,15 @@ async function updateGame(event: APIGatewayProxyEventV2): Promise<APIGatewayProx
     }
 
     const request = parseEvent(event, UpdateGameSchema);
-    const update = await getGameUpdate(request);
+    const update = await getGameUpdate(userInfo.username, request);
 
-    const result = await applyUpdate(userInfo.username, request.cohort, request.id, update);
+    const result = await applyUpdate(
+        userInfo.username,
+        request.cohort,
+        request.id,
+        update,
+        request.updatedAt,
+    );
     if (update.timelineId) {
         await createTimelineEntry(result.new);
     } else if (update.unlisted && request.timelineId) {
@@ -64,15 +71,19 @@ async function updateGame(event: APIGatewayProxyEventV2): Promise<APIGatewayProx
 
     await updateDirectories(result.new, result.old);
 
-    return success(result.new);
+    const duplicateOf = update.fingerprint
+        ? await getDuplicateKey(result.new.owner, update.fingerprint, result.new)
+        : undefined;
+    return success({ ...result.new, duplicateOf });
 }
 
 /**
  * Returns a GameUpdate based on the given request.
+ * @param username The username of the user making the request.
  * @param request The UpdateGameRequest to process.
  * @returns A GameUpdate based on the given request.
  */
-async function getGameUpdate(request: UpdateGameRequest): Promise<GameUpdate> {
+async function getGameUpdate(username: string, request: UpdateGameRequest): Promise<GameUpdate> {
     const update: GameUpdate = {
         updatedAt: new Date().toISOString(),
     };
@@ -102,6 +113,8 @@ async function getGameUpdate(request: UpdateGameRequest): Promise<GameUpdate> {
         update.date = game.date;
         update.pgn = game.pgn;
         update.headers = game.headers;
+        update.lastEditedBy = username;
+        update.fingerprint = game.fingerprint ?? null;
 
         const result = game.headers['Result'];
         const missingDataErr = isMissingData({ ...update, result });
@@ -123,6 +136,8 @@ async function getGameUpdate(request: UpdateGameRequest): Promise<GameUpdate> {
  * @param cohort The cohort the Game is in.
  * @param id The id of the Game.
  * @param update The update to apply.
+ * @param expectedUpdatedAt If specified, the update fails with a 409 error if the Game's
+ * updatedAt field no longer matches this value.
  * @returns The updated Game.
  */
 async function applyUpdate(
@@ -130,13 +145,21 @@ async function applyUpdate(
     cohort: string,
     id: string,
     update: GameUpdate,
+    expectedUpdatedAt?: string,
 ): Promise<{ old: Game; new: Game }> {
     const updateParams = getUpdateParams(update);
     updateParams.ExpressionAttributeNames['#owner'] = 'owner';
     updateParams.ExpressionAttributeValues[':owner'] = { S: owner };
 
+    let conditionExpression = 'attribute_exists(id) AND #owner = :owner';
+    if (expectedUpdatedAt !== undefined) {
+        conditionExpression += ' AND #updatedAt = :expectedUpdatedAt';
+        updateParams.ExpressionAttributeNames['#updatedAt'] = 'updatedAt';
+        updateParams.ExpressionAttributeValues[':expectedUpdatedAt'] = { S: expectedUpdatedAt };
+    }
+
     const input = new UpdateItemCommand({
-        ConditionExpression: 'attribute_exists(id) AND #owner = :owner',
+        ConditionExpression: conditionExpression,
         Key: {
             cohort: { S: cohort },
             id: { S: id },
@@ -144,6 +167,7 @@ async function applyUpdate(
         TableName: gamesTable,
         ...updateParams,
         ReturnValues: 'ALL_OLD',
+        ReturnValuesOnConditionCheckFailure: 'ALL_OLD',
     });
 
     try {
@@ -151,7 +175,10 @@ async function applyUpdate(
         if (response.Attributes) {
             const oldGame = unmarshall(response.Attributes) as Game;
             oldGame.directories = [...(oldGame.directories ?? [])];
-            const newGame = { ...oldGame, ...update };
+            const newGame = { ...oldGame, ...update } as Game;
+            if (update.fingerprint === null) {
+                delete newGame.fingerprint;
+            }
             return { old: oldGame, new: newGame };
         } else {
             throw new ApiError({
@@ -162,6 +189,16 @@ async function applyUpdate(
         }
     } catch (err) {
         if (err instanceof ConditionalCheckFailedException) {
+            const current = err.Item ? (unmarshall(err.Item) as Game) : undefined;
+            if (current?.owner === owner && expectedUpdatedAt !== undefined) {
+                throw new ApiError({
+                    statusCode: 409,
+                    publicMessage:
+                        'Conflict: the game was changed by someone else. Reload the page and try again.',
+                    privateMessage: `DDB conditional check failed on updatedAt ${expectedUpdatedAt}`,
+                    cause: err,
+                });
+            }
             throw new ApiError({
                 statusCode: 400,
                 publicMessage:
@@ -179,21 +216,29 @@ async function applyUpdate(
     }
 }
 
+/**
+ * Returns the DynamoDB update parameters for the given params. Attributes with a null value
+ * are removed from the item.
+ * @param params The attributes to update.
+ * @returns The UpdateExpression, ExpressionAttributeNames and ExpressionAttributeValues.
+ */
 function getUpdateParams(params: { [key: string]: any }) {
+    const setEntries = Object.entries(params).filter(([, value]) => value !== null);
+    const removeKeys = Object.keys(params).filter((key) => params[key] === null);
+
+    let updateExpression = `set ${setEntries.map(([key]) => `#${key} = :${key}`).join(', ')}`;
+    if (removeKeys.length > 0) {
+        updateExpression += ` remove ${removeKeys.map((key) => `#${key}`).join(', ')}`;
+    }
+
     return {
-        UpdateExpression: `set ${Object.entries(params)
-            .map(([key]) => `#${key} = :${key}, `)
-            .reduce((acc, str) => acc + str)
-            .slice(0, -2)}`,
+        UpdateExpression: updateExpression,
         ExpressionAttributeNames: Object.keys(params).reduce(
             (acc, key) => ({ ...acc, [`#${key}`]: key }),
             {} as Record<string, string>,
         ),
         ExpressionAttributeValues: marshall(
-            Object.entries(params).reduce(
-                (acc, [key, value]) => ({ ...acc, [`:${key}`]: value }),
-                {},
-            ),
+            setEntries.reduce((acc, [key, value]) => ({ ...acc, [`:${key}`]: value }), {}),
             { removeUndefinedValues: true },
         ),
     };
@@ -1,4 +1,10 @@
-import { GetItemCommand, PutItemCommand } from '@aws-sdk/client-dynamodb';
+import {
+    AttributeValue,
+    GetItemCommand,
+    PutItemCommand,
+    QueryCommand,
+    QueryCommandOutput,
+} from '@aws-sdk/client-dynamodb';
 import { marshall, unmarshall } from '@aws-sdk/util-dynamodb';
 import {
     getPuzzleOverview,
@@ -8,6 +14,7 @@ import {
 import {
     NextPuzzleRequest,
     nextPuzzleRequestSchema,
+    PersonalPuzzle,
     Puzzle,
     PuzzleHistory,
 } from '@jackstenglein/chess-dojo-common/src/puzzles/api';
@@ -25,6 +32,7 @@ import { dynamo, UpdateItemBuilder } from '../directoryService/database';
 
 const userTable = `${process.env.stage}-users`;
 const puzzleResultsTable = `${process.env.stage}-puzzle-results`;
+const personalPuzzlesTable = `${process.env.stage}-personal-puzzles`;
 
 const mongoClient = new MongoClient(process.env.MONGODB_URI ?? '', {
     auth: {
@@ -51,10 +59,22 @@ export const handler: APIGatewayProxyHandlerV2 = async (event) => {
         const userInfo = requireUserInfo(event);
         let user = await fetchUser(userInfo.username);
 
-        if (request.previousPuzzle) {
+        if (request.previousPuzzle?.personal) {
+            user = await handlePreviousPersonalPuzzle(request.previousPuzzle, user);
+        } else if (request.previousPuzzle) {
             user = await handlePreviousPuzzle(request.previousPuzzle, user);
         }
 
+        if (request.personal) {
+            const puzzle = await fetchNextPersonalPuzzle(
+                user.username,
+                request.themes,
+                request.nextId,
+                request.previousPuzzle?.id,
+            );
+            return success({ puzzle, user });
+        }
+
         if (request.nextId) {
             const document = await mongoClient
                 .db('puzzles')
@@ -125,6 +145,66 @@ const SCORE_PER_RESULT = {
     loss: 0,
 };
 
+type PuzzleUser = Pick<User, 'username' | 'puzzles' | 'ratingSystem' | 'ratings'>;
+
+type RatedPuzzle = Pick<
+    Puzzle,
+    'themes' | 'rating' | 'ratingDeviation' | 'volatility' | 'plays' | 'successfulPlays'
+>;
+
+/**
+ * Calculates the new ratings of the user and the puzzle after the user played the puzzle.
+ * @param user The user who played the puzzle.
+ * @param puzzle The puzzle the user played.
+ * @param result The result of the puzzle, from the user's perspective.
+ * @param date The time the puzzle was played, in ISO 8601.
+ * @returns The updates to the user's puzzle themes and the update to the puzzle.
+ */
+function ratePuzzle(
+    user: PuzzleUser,
+    puzzle: RatedPuzzle,
+    result: keyof typeof SCORE_PER_RESULT,
+    date: string,
+) {
+    const updatesByTheme: Record<string, PuzzleThemeOverview> = {};
+    let puzzleUpdate: Omit<RatedPuzzle, 'themes'> | undefined;
+
+    for (const theme of ['OVERALL'].concat(puzzle.themes)) {
+        const ranking = new Glicko2({ rating: 1000 });
+        const themeOverview = getPuzzleOverview(user, theme);
+        const userRanking = ranking.makePlayer(
+            themeOverview.rating,
+            themeOverview.ratingDeviation,
+            themeOverview.volatility,
+        );
+        const puzzleRanking = ranking.makePlayer(
+            puzzle.rating,
+            puzzle.ratingDeviation,
+            puzzle.volatility,
+        );
+        ranking.updateRatings([[userRanking, puzzleRanking, SCORE_PER_RESULT[result]]]);
+
+        updatesByTheme[theme] = {
+            rating: Math.round(userRanking.getRating()),
+            ratingDeviation: userRanking.getRd(),
+            volatility: userRanking.getVol(),
+            plays: (user.puzzles?.[theme]?.plays ?? 0) + 1,
+            lastPlayed: date,
+        };
+        if (theme === 'OVERALL') {
+            puzzleUpdate = {
+                rating: Math.round(puzzleRanking.getRating()),
+                ratingDeviation: puzzleRanking.getRd(),
+                volatility: puzzleRanking.getVol(),
+                plays: (puzzle.plays ?? 0) + 1,
+                successfulPlays: (puzzle.successfulPlays ?? 0) + (result === 'win' ? 1 : 0),
+            };
+        }
+    }
+
+    return { updatesByTheme, puzzleUpdate: puzzleUpdate as Omit<RatedPuzzle, 'themes'> };
+}
+
 /**
  * Updates the ratings of the previous puzzle and the user. Also saves an entry for the user/puzzle
  * in the puzzle results table.
@@ -134,7 +214,7 @@ const SCORE_PER_RESULT = {
  */
 async function handlePreviousPuzzle(
     previousPuzzle: Required<NextPuzzleRequest>['previousPuzzle'],
-    user: Pick<User, 'username' | 'puzzles' | 'ratingSystem' | 'ratings'>,
+    user: PuzzleUser,
 ) {
     const updatesByTheme: Record<string, PuzzleThemeOverview> = {};
     let attempts = 0;
@@ -156,52 +236,17 @@ async function handlePreviousPuzzle(
                     return puzzle;
                 }
 
-                for (const theme of ['OVERALL'].concat(puzzle.themes)) {
-                    const ranking = new Glicko2({ rating: 1000 });
-                    const themeOverview = getPuzzleOverview(user, theme);
-                    const userRanking = ranking.makePlayer(
-                        themeOverview.rating,
-                        themeOverview.ratingDeviation,
-                        themeOverview.volatility,
-                    );
-                    const puzzleRanking = ranking.makePlayer(
-                        puzzle.rating,
-                        puzzle.ratingDeviation,
-                        puzzle.volatility,
-                    );
-                    ranking.updateRatings([
-                        [userRanking, puzzleRanking, SCORE_PER_RESULT[previousPuzzle.result]],
-                    ]);
-
-                    updatesByTheme[theme] = {
-                        rating: Math.round(userRanking.getRating()),
-                        ratingDeviation: userRanking.getRd(),
-                        volatility: userRanking.getVol(),
-                        plays: (user.puzzles?.[theme]?.plays ?? 0) + 1,
-                        lastPlayed: date,
-                    };
-                    if (theme === 'OVERALL') {
-                        const update = {
-                            rating: Math.round(puzzleRanking.getRating()),
-                            ratingDeviation: puzzleRanking.getRd(),
-                            volatility: puzzleRanking.getVol(),
-                            plays: (puzzle.plays ?? 0) + 1,
-                            successfulPlays:
-                                (puzzle.successfulPlays ?? 0) +
-                                (previousPuzzle.result === 'win' ? 1 : 0),
-                        };
-                        console.log(
-                            `Updating original puzzle: ${JSON.stringify(puzzle, undefined, 2)} with update: ${JSON.stringify(update, undefined, 2)}`,
-                        );
-
-                        await collection.updateOne(
-                            { _id: previousPuzzle.id },
-                            {
-                                $set: update,
-                            },
-                        );
-                    }
-                }
+                const rating = ratePuzzle(user, puzzle, previousPuzzle.result, date);
+                Object.assign(updatesByTheme, rating.updatesByTheme);
+                console.log(
+                    `Updating original puzzle: ${JSON.stringify(puzzle, undefined, 2)} with update: ${JSON.stringify(rating.puzzleUpdate, undefined, 2)}`,
+                );
+                await collection.updateOne(
+                    { _id: previousPuzzle.id },
+                    {
+                        $set: rating.puzzleUpdate,
+                    },
+                );
                 return puzzle;
             });
             break;
@@ -219,6 +264,73 @@ async function handlePreviousPuzzle(
         });
     }
 
+    return saveResult(previousPuzzle, user, puzzle, updatesByTheme, date);
+}
+
+/**
+ * Updates the ratings of the previous personal puzzle and the user. Also saves an entry for
+ * the user/puzzle in the puzzle results table.
+ * @param previousPuzzle The previous personal puzzle the user took.
+ * @param user The user who took the puzzle.
+ * @return The updated user object.
+ */
+async function handlePreviousPersonalPuzzle(
+    previousPuzzle: Required<NextPuzzleRequest>['previousPuzzle'],
+    user: PuzzleUser,
+) {
+    const date = new Date().toISOString();
+    const output = await dynamo.send(
+        new GetItemCommand({
+            Key: {
+                username: { S: user.username },
+                id: { S: previousPuzzle.id },
+            },
+            TableName: personalPuzzlesTable,
+        }),
+    );
+    if (!output.Item) {
+        throw new ApiError({ statusCode: 404, publicMessage: `Personal puzzle not found` });
+    }
+    const puzzle = unmarshall(output.Item) as PersonalPuzzle;
+
+    let updatesByTheme: Record<string, PuzzleThemeOverview> = {};
+    if (previousPuzzle.rated) {
+        const rating = ratePuzzle(user, puzzle, previousPuzzle.result, date);
+        updatesByTheme = rating.updatesByTheme;
+
+        const input = new UpdateItemBuilder()
+            .key('username', user.username)
+            .key('id', puzzle.id)
+            .set('rating', rating.puzzleUpdate.rating)
+            .set('ratingDeviation', rating.puzzleUpdate.ratingDeviation)
+            .set('volatility', rating.puzzleUpdate.volatility)
+            .set('plays', rating.puzzleUpdate.plays)
+            .set('successfulPlays', rating.puzzleUpdate.successfulPlays)
+            .table(personalPuzzlesTable)
+            .build();
+        await dynamo.send(input);
+    }
+
+    return saveResult(previousPuzzle, user, puzzle, updatesByTheme, date);
+}
+
+/**
+ * Saves the user's updated puzzle ratings and an entry for the user/puzzle in the puzzle
+ * results table.
+ * @param previousPuzzle The previous puzzle the user took.
+ * @param user The user who took the puzzle.
+ * @param puzzle The puzzle the user took, if it could be fetched.
+ * @param updatesByTheme The updates to the user's puzzle themes.
+ * @param date The time the puzzle was played, in ISO 8601.
+ * @returns The updated user object.
+ */
+async function saveResult(
+    previousPuzzle: Required<NextPuzzleRequest>['previousPuzzle'],
+    user: PuzzleUser,
+    puzzle: Pick<Puzzle, 'fen' | 'rating'> | null,
+    updatesByTheme: Record<string, PuzzleThemeOverview>,
+    date: string,
+) {
     console.log(
         `Updating original user puzzles: ${JSON.stringify(user.puzzles, undefined, 2)} with update: ${JSON.stringify(updatesByTheme, undefined, 2)}`,
     );
@@ -252,3 +364,61 @@ async function handlePreviousPuzzle(
 
     return { ...user, puzzles: { ...user.puzzles, ...updatesByTheme } };
 }
+
+/**
+ * Fetches the next personal puzzle for the given user. Puzzles with the fewest plays are
+ * returned first, and the oldest puzzle is returned among those.
+ * @param username The username to fetch the puzzle for.
+ * @param themes The themes the puzzle must match any of. If not provided, all themes are allowed.
+ * @param nextId The id of the puzzle to fetch. If provided, the themes are ignored.
+ * @param previousId The id of the previous puzzle, which is skipped if possible.
+ * @returns The next personal puzzle.
+ */
+async function fetchNextPersonalPuzzle(
+    username: string,
+    themes?: string[],
+    nextId?: string,
+    previousId?: string,
+): Promise<Puzzle> {
+    if (nextId) {
+        const output = await dynamo.send(
+            new GetItemCommand({
+                Key: { username: { S: username }, id: { S: nextId } },
+                TableName: personalPuzzlesTable,
+            }),
+        );
+        if (!output.Item) {
+            throw new ApiError({ statusCode: 404, publicMessage: `Personal puzzle not found` });
+        }
+        const puzzle = unmarshall(output.Item) as PersonalPuzzle;
+        return { ...puzzle, _id: puzzle.id };
+    }
+
+    const puzzles: PersonalPuzzle[] = [];
+    let startKey: Record<string, AttributeValue> | undefined = undefined;
+    do {
+        const output: QueryCommandOutput = await dynamo.send(
+            new QueryCommand({
+                KeyConditionExpression: `#username = :username`,
+                ExpressionAttributeNames: { '#username': 'username' },
+                ExpressionAttributeValues: { ':username': { S: username } },
+                ExclusiveStartKey: startKey,
+                TableName: personalPuzzlesTable,
+            }),
+        );
+        puzzles.push(...(output.Items?.map((item) => unmarshall(item) as PersonalPuzzle) ?? []));
+        startKey = output.LastEvaluatedKey;
+    } while (startKey);
+
+    const candidates = puzzles
+        .filter((p) => !themes || p.themes.some((t) => themes.includes(t)))
+        .sort((lhs, rhs) => lhs.plays - rhs.plays || lhs.createdAt.localeCompare(rhs.createdAt));
+    const puzzle = candidates.find((p) => p.id !== previousId) ?? candidates[0];
+    if (!puzzle) {
+        throw new ApiError({
+            statusCode: 404,
+            publicMessage: `No personal puzzles found. Personal puzzles are generated from your mistakes in games with engine analysis.`,
+        });
+    }
+    return { ...puzzle, _id: puzzle.id };
+}
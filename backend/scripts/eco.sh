@@ -0,0 +1,18 @@
+#!/usr/bin/env bash
+
+# Writes the opening table of the chess package, chess/eco.tsv, from the lichess
+# chess-openings dataset, which has a TSV file per ECO volume in the form eco<TAB>name<TAB>pgn.
+
+set -euo pipefail
+
+url=https://raw.githubusercontent.com/lichess-org/chess-openings/master
+out=${1:-$(dirname "$0")/../chess/eco.tsv}
+tmp=$(mktemp)
+trap 'rm -f "$tmp"' EXIT
+
+printf 'eco\tname\tpgn\n' > "$tmp"
+for volume in a b c d e; do
+    curl -sSfL "$url/$volume.tsv" | tail -n +2 >> "$tmp"
+done
+mv "$tmp" "$out"
+trap - EXIT
@@ -0,0 +1,135 @@
+// Implements a Lambda handler that generates Swiss pairings for the next
+// round of a section of the open classical. By default, the pairings are
+// only returned for preview, along with a hash of them. If commit is set, the
+// pairings are saved as a new round, but only if they still match the hash of
+// the previewed pairings. Otherwise, a 409 is returned and the admin must
+// preview the pairings again.
+//
+// The caller must be an admin or tournament admin.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+const MAX_ROUND = 7
+
+type GeneratePairingsRequest struct {
+	// The region to generate pairings for.
+	Region string `json:"region"`
+
+	// The section to generate pairings for.
+	Section string `json:"section"`
+
+	// The round to generate pairings for. Must be the next round of the section.
+	Round int `json:"round"`
+
+	// Whether to save the pairings as a new round. If false, the pairings are only
+	// returned for preview.
+	Commit bool `json:"commit"`
+
+	// The hash of the previewed pairings. Required if commit is set.
+	PairingsHash string `json:"pairingsHash"`
+}
+
+type GeneratePairingsResponse struct {
+	// The round the pairings were generated for.
+	Round int `json:"round"`
+
+	// The generated pairings.
+	Pairings []database.OpenClassicalPairing `json:"pairings"`
+
+	// The hash of the generated pairings, which must be passed back to commit them.
+	PairingsHash string `json:"pairingsHash"`
+
+	// The updated open classical. Only present if the pairings were committed.
+	OpenClassical *database.OpenClassical `json:"openClassical,omitempty"`
+}
+
+var repository = database.DynamoDB
+
+func main() {
+	lambda.Start(handler)
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	user, err := repository.GetUser(info.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if !user.IsAdmin && !user.IsTournamentAdmin {
+		return api.Failure(errors.New(403, "Invalid request: you are not a tournament admin", "")), nil
+	}
+
+	request := GeneratePairingsRequest{}
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
+	}
+	if request.Region == "" {
+		return api.Failure(errors.New(400, "Invalid request: region is required", "")), nil
+	}
+	if request.Section == "" {
+		return api.Failure(errors.New(400, "Invalid request: section is required", "")), nil
+	}
+	if request.Round < 1 || request.Round > MAX_ROUND {
+		return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: round must be between 1 and %d", MAX_ROUND), "")), nil
+	}
+	if request.Commit && request.PairingsHash == "" {
+		return api.Failure(errors.New(400, "Invalid request: pairingsHash is required to commit pairings", "")), nil
+	}
+
+	openClassical, err := repository.GetOpenClassical(database.CurrentLeaderboard)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if openClassical.AcceptingRegistrations {
+		return api.Failure(errors.New(400, "Invalid request: registrations must be closed before generating pairings", "")), nil
+	}
+
+	sectionName := fmt.Sprintf("%s_%s", request.Region, request.Section)
+	section, ok := openClassical.Sections[sectionName]
+	if !ok {
+		return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: section %q not found", sectionName), "")), nil
+	}
+	if request.Round != len(section.Rounds)+1 {
+		return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: the next round of section %q is %d", sectionName, len(section.Rounds)+1), "")), nil
+	}
+
+	pairings, err := database.GenerateOpenClassicalPairings(&section, request.Round)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	hash, err := database.OpenClassicalPairingsHash(pairings)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	response := GeneratePairingsResponse{Round: request.Round, Pairings: pairings, PairingsHash: hash}
+	if request.Commit {
+		if request.PairingsHash != hash {
+			return api.Failure(errors.New(409, "Conflict: the pairings changed since they were previewed. Preview them again before committing.", "")), nil
+		}
+		response.OpenClassical, err = repository.OpenClassicalAddRound(request.Region, request.Section, request.Round, pairings)
+		if err != nil {
+			return api.Failure(err), nil
+		}
+	}
+	return api.Success(response), nil
+}
@@ -99,7 +99,7 @@ func handlePairings(request SetPairingsRequest) api.Response {
 	sectionName := fmt.Sprintf("%s_%s", request.Region, request.Section)
 	section := openClassical.Sections[sectionName]
 	if request.Round-1 >= len(section.Rounds) {
-		openClassical, err = repository.OpenClassicalAddRound(request.Region, request.Section, pairings)
+		openClassical, err = repository.OpenClassicalAddRound(request.Region, request.Section, len(section.Rounds)+1, pairings)
 	} else {
 		openClassical, err = repository.OpenClassicalSetRound(request.Region, request.Section, request.Round-1, pairings)
 	}
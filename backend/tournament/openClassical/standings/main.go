@@ -0,0 +1,85 @@
+// Implements a Lambda handler that returns the standings of a section of
+// the open classical after a given round, including tiebreaks.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type GetStandingsResponse struct {
+	// The region of the section.
+	Region string `json:"region"`
+
+	// The rating range of the section.
+	Section string `json:"section"`
+
+	// The round the standings were computed after.
+	Round int `json:"round"`
+
+	// The standings of the section, in rank order.
+	Standings []database.OpenClassicalStanding `json:"standings"`
+}
+
+var repository = database.DynamoDB
+var stage = os.Getenv("stage")
+
+func main() {
+	if stage == "prod" {
+		log.SetLevel(log.InfoLevel)
+	}
+	lambda.Start(handler)
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	log.SetRequestId(event.RequestContext.RequestID)
+	log.Infof("Event: %#v", event)
+
+	region := event.QueryStringParameters["region"]
+	if region == "" {
+		return api.Failure(errors.New(400, "Invalid request: region is required", "")), nil
+	}
+	section := event.QueryStringParameters["section"]
+	if section == "" {
+		return api.Failure(errors.New(400, "Invalid request: section is required", "")), nil
+	}
+
+	startsAt := event.QueryStringParameters["startsAt"]
+	if startsAt == "" {
+		startsAt = database.CurrentLeaderboard
+	}
+
+	openClassical, err := repository.GetOpenClassical(startsAt)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	sectionName := fmt.Sprintf("%s_%s", region, section)
+	s, ok := openClassical.Sections[sectionName]
+	if !ok {
+		return api.Failure(errors.New(404, fmt.Sprintf("Invalid request: section %q not found", sectionName), "")), nil
+	}
+
+	round := len(s.Rounds)
+	if r := event.QueryStringParameters["round"]; r != "" {
+		round, err = strconv.Atoi(r)
+		if err != nil || round < 1 || round > len(s.Rounds) {
+			return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: round must be between 1 and %d", len(s.Rounds)), "")), nil
+		}
+	}
+
+	return api.Success(GetStandingsResponse{
+		Region:    region,
+		Section:   section,
+		Round:     round,
+		Standings: database.GetOpenClassicalStandings(&s, round),
+	}), nil
+}
@@ -257,6 +257,14 @@ export const DirectorySchema = z.object({
 
     /** A map from username to the user's access role in the directory. */
     access: z.record(z.string(), z.nativeEnum(DirectoryAccessRole)).optional(),
+
+    /**
+     * A map from club id to the access role of the club's members in the directory.
+     * Roles given to a user directly take precedence over the roles of their clubs.
+     */
+    clubAccess: z
+        .record(z.string(), z.enum([DirectoryAccessRole.Viewer, DirectoryAccessRole.Editor]))
+        .optional(),
 });
 
 /** A directory owned by a user. */
@@ -406,11 +414,22 @@ export const ShareDirectorySchema = DirectorySchema.pick({
     owner: true,
     id: true,
     access: true,
-}).required();
+})
+    .required()
+    .extend({
+        /** The new club access of the directory. If omitted, the club access is unchanged. */
+        clubAccess: DirectorySchema.shape.clubAccess,
+    });
 
 /** A request to share a directory. */
 export type ShareDirectoryRequest = z.infer<typeof ShareDirectorySchema>;
 
+/**
+ * A request to replace the user and club access of a directory, sent to
+ * PUT /directory/{owner}/{id}/access.
+ */
+export type ShareDirectoryAccessRequest = Required<Pick<Directory, 'access' | 'clubAccess'>>;
+
 /** Verifies the type of a request to list the breadcrumbs for a directory. */
 export const ListBreadcrumbsSchema = DirectorySchema.pick({
     /** The owner of the directory. */
@@ -451,6 +470,25 @@ export const ExportDirectorySchema = z
             .optional(),
         /** Whether to recursively export subdirectories of the given directories. */
         recursive: z.boolean().optional(),
+        /** Whether to export every game owned by the caller. */
+        ownerGames: z.boolean().optional(),
+        /** Filters applied to each exported game. */
+        filters: z
+            .object({
+                /** The first played date to include, in the form 2023.01.02. */
+                startDate: z.string().optional(),
+                /** The last played date to include, in the form 2023.01.02. */
+                endDate: z.string().optional(),
+                /**
+                 * The color played by the owner of the game. The owner's color is found by
+                 * matching their display name and rating usernames against the game's
+                 * White and Black headers.
+                 */
+                color: z.enum(['white', 'black', 'either']).optional(),
+                /** The PGN results to include. */
+                results: z.enum(['1-0', '0-1', '1/2-1/2', '*']).array().optional(),
+            })
+            .optional(),
         /** Options when exporting the PGNs. */
         options: PdfExportSchema.pick({
             skipHeader: true,
@@ -466,8 +504,8 @@ export const ExportDirectorySchema = z
             })
             .optional(),
     })
-    .refine((val) => val.games?.length || val.directories?.length, {
-        message: 'At least one game or directory is required',
+    .refine((val) => val.games?.length || val.directories?.length || val.ownerGames, {
+        message: 'At least one game or directory, or ownerGames, is required',
     });
 
 /** A request to export a directory. */
@@ -1,4 +1,5 @@
 import { z } from 'zod';
+import { Reaction } from './timeline';
 
 const gameOrientation = z.enum(['white', 'black']);
 
@@ -199,6 +200,12 @@ const updateGame = z.object({
 
     /** The import headers of the game. */
     headers: gameHeaderSchema.optional(),
+
+    /**
+     * The updatedAt field of the game when the client loaded it. If specified, the update
+     * fails if the game has been updated since.
+     */
+    updatedAt: z.string().optional(),
 });
 
 /** Verifies a request to update a game. */
@@ -311,8 +318,35 @@ export interface GameInfo extends GameKey {
      */
     review?: GameReview;
 
+    /** The refund issued because the review was not completed by its deadline. */
+    reviewRefund?: GameReviewRefund;
+
+    /**
+     * A summary of the engine analysis of the game. Set only on games
+     * submitted for review.
+     */
+    engineSummary?: EngineSummary;
+
     /** The time class of the game. Currently set only on master games. */
     timeClass?: string;
+
+    /** The tags set by the owner of the game. */
+    tags?: string[];
+
+    /**
+     * The tags assigned to the game automatically based on its content,
+     * such as endgame, time trouble or tournament.
+     */
+    systemTags?: string[];
+
+    /** The usernames of the users other than the owner who can edit the game's PGN. */
+    editors?: string[];
+
+    /**
+     * The username of the owner or editor who last changed the game's PGN.
+     * Omitted if the owner last changed it before editors were supported.
+     */
+    lastEditedBy?: string;
 }
 
 export interface CommentOwner {
@@ -362,6 +396,39 @@ export interface PositionComment {
 
     /** A PGN which suggests a variation starting from this position. */
     suggestedVariation?: string;
+
+    /** Reactions left by users on the comment, mapped by their usernames. */
+    reactions?: Record<string, Reaction>;
+
+    /** The number of users who left each type of reaction on the comment. */
+    reactionCounts?: Record<string, number>;
+
+    /** The time the comment was removed by its owner or a moderator, if it was removed. */
+    deletedAt?: string;
+}
+
+/** A compact summary of the clock times recorded in a game's mainline. */
+export interface TimeProfile {
+    /** The base time of the game in seconds. */
+    initialSeconds: number;
+
+    /** The increment of the game in seconds. */
+    incrementSeconds?: number;
+
+    /** The number of plies played before the first mainline move. */
+    startPly?: number;
+
+    /** The seconds spent on each mainline move, or -1 if unknown. */
+    moveSeconds: number[];
+
+    /** The move number on which white first entered time trouble. */
+    whiteTimeTrouble?: number;
+
+    /** The move number on which black first entered time trouble. */
+    blackTimeTrouble?: number;
+
+    /** The indices in moveSeconds of the long thinks in the game. */
+    longThinks?: number[];
 }
 
 export type Game = GameInfo & {
@@ -373,6 +440,8 @@ export type Game = GameInfo & {
      * to the comment.
      */
     positionComments: Record<string, Record<string, PositionComment>>;
+    /** A summary of the clock times in the game. Omitted for games without clock times. */
+    timeProfile?: TimeProfile;
 };
 
 /** The status of a game review. */
@@ -407,4 +476,301 @@ export interface GameReview {
         /** The cohort of the reviewer. */
         cohort: string;
     };
+
+    /**
+     * The reviewer who claimed the game from the review queue. The claim
+     * is only active until claimExpiresAt.
+     */
+    claimedBy?: GameReviewer;
+
+    /** The date the game was claimed in ISO format. */
+    claimedAt?: string;
+
+    /** The date the claim on the game expires in ISO format. */
+    claimExpiresAt?: string;
+}
+
+/** A partial refund of a game review which was not completed by its deadline. */
+export interface GameReviewRefund {
+    /** The amount refunded, in the smallest unit of the currency. */
+    amount: number;
+
+    /** The three-letter ISO code of the currency of the refund. */
+    currency: string;
+
+    /** The percentage of the review price that was refunded. */
+    percentage: number;
+
+    /** The deadline the review missed in ISO format. */
+    deadline: string;
+
+    /** The date the refund was issued in ISO format. */
+    refundedAt: string;
+}
+
+export interface GameReviewer {
+    /** The username of the reviewer. */
+    username: string;
+
+    /** The display name of the reviewer. */
+    displayName: string;
+
+    /** The cohort of the reviewer. */
+    cohort: string;
+}
+
+/** A game in the review queue, as returned by the review queue API. */
+export interface ReviewQueueEntry {
+    /** The game waiting for review. */
+    game: GameInfo;
+
+    /** The date the review should be completed by in ISO format. */
+    deadline?: string;
+
+    /** Whether the deadline has passed. */
+    overdue: boolean;
+
+    /** The reviewer who currently has the game claimed, if the claim is active. */
+    claimedBy?: GameReviewer;
+}
+
+/** The games a reviewer currently has claimed. */
+export interface ReviewerWorkload {
+    /** The reviewer. */
+    reviewer: GameReviewer;
+
+    /** The number of games the reviewer has claimed. */
+    claimed: number;
+
+    /** The number of claimed games which are overdue. */
+    overdue: number;
+}
+
+/** The response from the review queue API. */
+export interface GetReviewQueueResponse {
+    /** The games waiting for review, in the order they should be reviewed. */
+    games: ReviewQueueEntry[];
+
+    /** The number of games in the queue which are overdue. */
+    overdue: number;
+
+    /** The workload of each reviewer with claimed games. */
+    workloads: ReviewerWorkload[];
+}
+
+/** A request to claim or release a game in the review queue. */
+export interface ClaimGameReviewRequest {
+    /** The cohort of the game. */
+    cohort: string;
+
+    /** The id of the game. */
+    id: string;
+
+    /** Whether to release the caller's claim instead of claiming the game. */
+    release?: boolean;
+}
+
+export interface EnginePlayerSummary {
+    /** The number of inaccuracies played. */
+    inaccuracies: number;
+
+    /** The number of mistakes played. */
+    mistakes: number;
+
+    /** The number of blunders played. */
+    blunders: number;
+
+    /** The average centipawn loss per move. */
+    averageCentipawnLoss: number;
+}
+
+export interface EngineSummary {
+    /** The name of the engine which produced the analysis. */
+    engine: string;
+
+    /** The depth searched in each position. */
+    depth: number;
+
+    /** The time the analysis was produced, in ISO format. */
+    analyzedAt: string;
+
+    /** The summary of White's moves. */
+    white: EnginePlayerSummary;
+
+    /** The summary of Black's moves. */
+    black: EnginePlayerSummary;
+}
+
+/** A snapshot of the PGN and headers of a game, saved before the game was overwritten. */
+export interface GameVersion {
+    /** The key of the game, in the form cohort/id. */
+    gameId: string;
+
+    /** The id of the version, or `current` for the game's current state. */
+    version: string;
+
+    /** The cohort of the game. */
+    cohort: string;
+
+    /** The id of the game. */
+    id: string;
+
+    /** The owner of the game. */
+    owner: string;
+
+    /** The time the game was last updated before this version was replaced, in ISO format. */
+    updatedAt: string;
+
+    /** The time this version was replaced, in ISO format. */
+    replacedAt: string;
+
+    /** The username of the owner or editor who saved this version. */
+    editedBy?: string;
+
+    /** The PGN headers of the version. */
+    headers: Record<string, string>;
+
+    /** The PGN text of the version. Omitted when listing versions. */
+    pgn?: string;
+}
+
+/** A PGN header which differs between two versions of a game. */
+export interface HeaderDiff {
+    /** The name of the header. */
+    name: string;
+
+    /** The value of the header in the older version. Empty if the header was added. */
+    before: string;
+
+    /** The value of the header in the newer version. Empty if the header was removed. */
+    after: string;
+}
+
+/** The annotations of a single move. */
+export interface MoveAnnotation {
+    /** The comment after the move, without commands. */
+    comment?: string;
+
+    /** The commands embedded in the comment, such as clk or cal. */
+    commands?: Record<string, string>;
+
+    /** The Numeric Annotation Glyphs of the move. */
+    nags?: number[];
+}
+
+/** A move which differs between two versions of a game. */
+export interface MoveDiff {
+    /** Whether the move was added, removed or had its annotations changed. */
+    type: 'ADDED' | 'REMOVED' | 'CHANGED';
+
+    /** The ply of the move. */
+    ply: number;
+
+    /** The SAN of the moves from the start of the game up to and including this move. */
+    line: string[];
+
+    /** The annotations of the move in the older version. Unset if the move was added. */
+    before?: MoveAnnotation;
+
+    /** The annotations of the move in the newer version. Unset if the move was removed. */
+    after?: MoveAnnotation;
+}
+
+/** The difference between two versions of a game. */
+export interface GameDiff {
+    /** The older version being compared. */
+    from: string;
+
+    /** The newer version being compared. */
+    to: string;
+
+    /** The headers which were added, removed or changed. */
+    headers: HeaderDiff[];
+
+    /** The moves, including variations, which were added, removed or changed. */
+    moves: MoveDiff[];
+}
+
+/** A part of a game indexed for search. */
+export type SearchField = 'player' | 'event' | 'site' | 'opening' | 'comment';
+
+/** A game matching a search query. */
+export interface GameSearchResult {
+    cohort: string;
+    id: string;
+    owner: string;
+    ownerDisplayName: string;
+    white: string;
+    black: string;
+    date: string;
+    headers: PgnHeaders;
+
+    /** The total score of the query terms in the game. Higher scores rank first. */
+    score: number;
+
+    /** The fields of the game matching any query term. */
+    matchedFields: SearchField[];
+}
+
+/** The response to a game search. */
+export interface SearchGamesResponse {
+    /** The page of games matching the query, in ranked order. */
+    games: GameSearchResult[];
+
+    /** The total number of games matching the query. */
+    total: number;
+
+    /**
+     * Whether a query term matched more games than could be searched, in which
+     * case some matching games may be missing from the results.
+     */
+    truncated: boolean;
+
+    /** The startKey of the next page, if one exists. */
+    lastEvaluatedKey?: string;
+}
+
+/** A request to save the PGN of a game as its owner or one of its editors. */
+export interface AnnotateGameRequest {
+    /** The cohort of the game. */
+    cohort: string;
+
+    /** The id of the game. */
+    id: string;
+
+    /** The new PGN of the game. */
+    pgn: string;
+
+    /** The updatedAt field of the game when the editor loaded it. */
+    updatedAt: string;
+}
+
+/** A request to toggle a reaction on a position comment. */
+export interface PositionCommentReactionRequest {
+    /** The cohort of the game containing the comment. */
+    cohort: string;
+
+    /** The id of the game containing the comment. */
+    gameId: string;
+
+    /** The normalized FEN of the comment. */
+    fen: string;
+
+    /** A comma-separated list of the parent comment ids. Empty for a top-level comment. */
+    parentIds?: string;
+
+    /** The id of the comment. */
+    id: string;
+
+    /** The type of reaction to toggle. */
+    type: string;
+}
+
+/** The response to an AnnotateGameRequest. */
+export interface AnnotateGameResponse {
+    /** The updated game. */
+    game: GameInfo;
+
+    /** Whether the PGN was merged with concurrent changes by other editors. */
+    merged: boolean;
 }
@@ -0,0 +1,96 @@
+/** The type of object a reported comment was left on. */
+export enum CommentTargetType {
+    Game = 'GAME',
+    Timeline = 'TIMELINE',
+    Event = 'EVENT',
+}
+
+/** Identifies a comment on a game position, timeline entry or event. */
+export interface CommentTarget {
+    /** The type of object the comment was left on. */
+    type: CommentTargetType;
+    /** The cohort of the game. Only set for game comments. */
+    cohort?: string;
+    /** The id of the game. Only set for game comments. */
+    gameId?: string;
+    /** The normalized FEN of the comment. Only set for game comments. */
+    fen?: string;
+    /** A comma-separated list of the parent comment ids. Only set for game replies. */
+    parentIds?: string;
+    /** The owner of the timeline entry. Only set for timeline comments. */
+    owner?: string;
+    /** The id of the timeline entry or event. */
+    id?: string;
+    /** The id of the comment. */
+    commentId: string;
+}
+
+/** The reason a user reported a comment. */
+export enum CommentReportReason {
+    Spam = 'SPAM',
+    Harassment = 'HARASSMENT',
+    Inappropriate = 'INAPPROPRIATE',
+    Other = 'OTHER',
+}
+
+/** The status of a comment report. */
+export enum CommentReportStatus {
+    Open = 'OPEN',
+    Resolved = 'RESOLVED',
+}
+
+/** The action an admin took when resolving a comment report. */
+export enum CommentReportAction {
+    /** The comment was removed. */
+    Removed = 'REMOVED',
+    /** The comment was kept. */
+    Dismissed = 'DISMISSED',
+}
+
+/** A user's report of a comment. */
+export interface CommentReport {
+    /** The id of the report, which is unique per comment and reporter. */
+    id: string;
+    /** The reported comment. */
+    target: CommentTarget;
+    /** The username of the user who reported the comment. */
+    reporter: string;
+    /** The display name of the user who reported the comment. */
+    reporterDisplayName: string;
+    /** The reason the comment was reported. */
+    reason: CommentReportReason;
+    /** Optional details provided by the reporter. */
+    details?: string;
+    /** The username of the owner of the comment. */
+    commentOwner: string;
+    /** The content of the comment when it was reported. */
+    commentContent: string;
+    /** The status of the report. */
+    status: CommentReportStatus;
+    /** The time the report was created, in ISO 8601. */
+    createdAt: string;
+    /** The username of the admin who resolved the report. */
+    resolvedBy?: string;
+    /** The time the report was resolved, in ISO 8601. */
+    resolvedAt?: string;
+    /** The action taken when the report was resolved. */
+    action?: CommentReportAction;
+}
+
+/** A request to report a comment. */
+export interface ReportCommentRequest {
+    /** The comment to report. */
+    target: CommentTarget;
+    /** The reason the comment is being reported. */
+    reason: CommentReportReason;
+    /** Optional details about the report. */
+    details?: string;
+}
+
+/** A request to resolve a comment report. */
+export interface ResolveCommentReportRequest {
+    /** The id of the report. */
+    id: string;
+    /** The action to take. */
+    action: CommentReportAction;
+}
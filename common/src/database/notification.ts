@@ -5,8 +5,12 @@ import { RoundRobinSchema } from '../roundRobin/api';
 const NotificationEventTypeSchema = z.enum([
     /** A comment is left on a game */
     'GAME_COMMENT',
+    /** An emoji reaction is left on a game comment */
+    'GAME_COMMENT_REACTION',
     /** A sensei game review is completed */
     'GAME_REVIEW_COMPLETE',
+    /** A late game review is partially refunded */
+    'GAME_REVIEW_REFUND',
     /** A user gets a new follower */
     'NEW_FOLLOWER',
     /** A comment is left on a timeline entry */
@@ -25,6 +29,8 @@ const NotificationEventTypeSchema = z.enum([
     'ROUND_ROBIN_START',
     /** A user has created their subscription */
     'SUBSCRIPTION_CREATED',
+    /** Users are mentioned in a comment */
+    'MENTION',
 ]);
 
 /** The types of a notification event. */
@@ -53,6 +59,24 @@ const GameCommentEventSchema = z.object({
 /** The type of a notification event when a comment is left on a game. */
 export type GameCommentEvent = z.infer<typeof GameCommentEventSchema>;
 
+/** The type of a notification event when a reaction is left on a game comment. */
+const GameCommentReactionEventSchema = z.object({
+    /** The type of the event. */
+    type: z.literal(NotificationEventTypes.GAME_COMMENT_REACTION),
+    /** The game containing the comment. */
+    game: z.object({
+        /** The cohort of the game. */
+        cohort: z.string(),
+        /** The id of the game. */
+        id: z.string(),
+    }),
+    /** The username of the owner of the comment that was reacted on. */
+    owner: z.string(),
+});
+
+/** The type of a notification event when a reaction is left on a game comment. */
+export type GameCommentReactionEvent = z.infer<typeof GameCommentReactionEventSchema>;
+
 /** The type of a notification event when a game review is completed. */
 const GameReviewEventSchema = z.object({
     /** The type of the event. */
@@ -69,6 +93,24 @@ const GameReviewEventSchema = z.object({
 /** The type of a notification event when a game review is completed. */
 export type GameReviewEvent = z.infer<typeof GameReviewEventSchema>;
 
+/** The type of a notification event when a late game review is partially refunded. */
+const GameReviewRefundEventSchema = z.object({
+    /** The type of the event. */
+    type: z.literal(NotificationEventTypes.GAME_REVIEW_REFUND),
+    /** The game whose review was refunded. */
+    game: z.object({
+        /** The cohort of the game. */
+        cohort: z.string(),
+        /** The id of the game. */
+        id: z.string(),
+    }),
+    /** The percentage of the review's price that was refunded. */
+    percentage: z.number(),
+});
+
+/** The type of a notification event when a late game review is partially refunded. */
+export type GameReviewRefundEvent = z.infer<typeof GameReviewRefundEventSchema>;
+
 /** The type of a notification event when a user gets a new follower. */
 const NewFollowerEventSchema = z.object({
     /** The type of the event. */
@@ -195,11 +237,43 @@ const SubscriptionCreatedEventSchema = z.object({
 /** The type of a notification event when a user has created their subscription. */
 export type SubscriptionCreatedEvent = z.infer<typeof SubscriptionCreatedEventSchema>;
 
+/** The type of a notification event when users are mentioned in a comment. */
+const MentionEventSchema = z.object({
+    /** The type of the event. */
+    type: z.literal(NotificationEventTypes.MENTION),
+    /** The comment containing the mentions. */
+    target: z.object({
+        /** The type of object the comment was left on. */
+        type: z.enum(['GAME', 'TIMELINE', 'EVENT']),
+        /** The cohort of the game. Only set for game comments. */
+        cohort: z.string().optional(),
+        /** The id of the game. Only set for game comments. */
+        gameId: z.string().optional(),
+        /** The owner of the timeline entry. Only set for timeline comments. */
+        owner: z.string().optional(),
+        /** The id of the timeline entry or event. */
+        id: z.string().optional(),
+        /** The id of the comment. */
+        commentId: z.string(),
+    }),
+    /** The username of the author of the comment. */
+    author: z.string(),
+    /** The display name of the author of the comment. */
+    displayName: z.string(),
+    /** The usernames mentioned in the comment. */
+    usernames: z.array(z.string()),
+});
+
+/** The type of a notification event when users are mentioned in a comment. */
+export type MentionEvent = z.infer<typeof MentionEventSchema>;
+
 /** The schema of an event that generates notifications. */
 export const NotificationEventSchema = z.discriminatedUnion('type', [
     NewFollowerEventSchema,
     GameCommentEventSchema,
+    GameCommentReactionEventSchema,
     GameReviewEventSchema,
+    GameReviewRefundEventSchema,
     TimelineCommentEventSchema,
     TimelineReactionEventSchema,
     ClubJoinRequesetEventSchema,
@@ -208,6 +282,7 @@ export const NotificationEventSchema = z.discriminatedUnion('type', [
     CalendarInviteEventSchema,
     RoundRobinStartEventSchema,
     SubscriptionCreatedEventSchema,
+    MentionEventSchema,
 ]);
 
 /** An event that generates notifications. */
@@ -221,6 +296,9 @@ const NotificationTypeSchema = z.enum([
     /** A reply is left on a game comment */
     'GAME_COMMENT_REPLY',
 
+    /** An emoji reaction is left on a game comment */
+    'GAME_COMMENT_REACTION',
+
     /** A game was added with a position the user follows */
     'EXPLORER_GAME',
 
@@ -242,11 +320,17 @@ const NotificationTypeSchema = z.enum([
     /** A sensei game review is completed */
     'GAME_REVIEW_COMPLETE',
 
+    /** A late game review is partially refunded */
+    'GAME_REVIEW_REFUND',
+
     /** Invited to an event on the calendar */
     'CALENDAR_INVITE',
 
     /** A round robin tournament has started */
     'ROUND_ROBIN_START',
+
+    /** The user is mentioned in a comment */
+    'MENTION',
 ]);
 
 /** The types of notifications. */
@@ -307,6 +391,21 @@ export interface Notification {
         };
     };
 
+    /** Metadata for a game review refund Notification. */
+    gameReviewRefundMetadata?: {
+        /** The cohort of the Game. */
+        cohort: string;
+
+        /** The id of the Game. */
+        id: string;
+
+        /** The headers of the Game. */
+        headers: Record<string, string>;
+
+        /** The percentage of the review's price that was refunded. */
+        percentage: number;
+    };
+
     /** Metadata for a new follower notification. */
     newFollowerMetadata?: {
         /** The username of the new follower. */
@@ -377,4 +476,20 @@ export interface Notification {
         /** The name of the tournament. */
         name: string;
     };
+
+    /** Metadata for a mention in a comment. */
+    mentionMetadata?: {
+        /** The type of object containing the comment with the mention. */
+        type: 'GAME' | 'TIMELINE' | 'EVENT';
+        /** The cohort of the game. Only set for game comments. */
+        cohort?: string;
+        /** The owner of the timeline entry. Only set for timeline comments. */
+        owner?: string;
+        /** The id of the game, timeline entry or event. */
+        id: string;
+        /** The title of the game, timeline entry or event. */
+        title: string;
+        /** The display name of the user who last mentioned the notified user. */
+        mentionedBy: string;
+    };
 }
@@ -83,6 +83,8 @@ export interface Comment {
     updatedAt: string;
     /** The text content of the comment. */
     content: string;
+    /** The time the comment was removed by a moderator, in ISO 8601, if it was removed. */
+    deletedAt?: string;
 }
 
 /** Metadata for a graduation timeline entry. */
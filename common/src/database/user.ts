@@ -51,6 +51,12 @@ export interface User {
     enableLightMode: boolean;
     /** Whether to enable zen mode. */
     enableZenMode: boolean;
+    /** Whether to exclude the user's games from the anonymized research dataset. */
+    researchOptOut?: boolean;
+    /** Whether to import the user's classical games from their linked Lichess and Chess.com accounts each night. */
+    autoImportGames?: boolean;
+    /** The time of the most recent automatically imported game, mapped by rating system. */
+    gameImportCursors?: Partial<Record<RatingSystem, string>>;
     timezoneOverride: string;
     timeFormat: TimeFormat;
 
@@ -264,6 +270,8 @@ export interface EmailNotificationSettings {
 export interface SiteNotificationSettings {
     disableGameComment: boolean;
     disableGameCommentReplies: boolean;
+    disableGameCommentReaction?: boolean;
+    disableMention?: boolean;
     disableNewFollower: boolean;
     disableNewsfeedComment: boolean;
     disableNewsfeedReaction: boolean;
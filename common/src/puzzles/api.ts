@@ -19,6 +19,8 @@ export const nextPuzzleRequestSchema = z.object({
             pgn: z.string().optional(),
             /** Whether the puzzle was rated or not. */
             rated: z.boolean(),
+            /** Whether the puzzle was one of the user's personal puzzles. */
+            personal: z.boolean().optional(),
         })
         .optional(),
     /** The themes that the next puzzle must be in. The puzzle must match any of the themes. */
@@ -30,6 +32,11 @@ export const nextPuzzleRequestSchema = z.object({
      * parameters will be ignored.
      */
     nextId: z.string().optional(),
+    /**
+     * If true, the next puzzle is taken from the user's personal puzzles, which are generated
+     * from the mistakes in their own games. The relativeRating parameter is ignored.
+     */
+    personal: z.boolean().optional(),
 });
 
 /** A request to get the next puzzle. */
@@ -58,6 +65,20 @@ export interface Puzzle {
     themes: string[];
 }
 
+/** A puzzle generated from a position where the user made a losing mistake in their own game. */
+export type PersonalPuzzle = Omit<Puzzle, '_id'> & {
+    /** The username of the user who made the mistake. */
+    username: string;
+    /** The cohort of the game the puzzle was taken from. */
+    cohort: string;
+    /** The id of the game the puzzle was taken from. */
+    gameId: string;
+    /** The index of the user's mistake in the game's mainline. */
+    ply: number;
+    /** The time the puzzle was created, in ISO 8601. */
+    createdAt: string;
+};
+
 /** The response to get the next puzzle. */
 export interface NextPuzzleResponse {
     /** The next puzzle to play. */
@@ -163,6 +163,7 @@ import {
 } from './roundRobinApi';
 import { ScoreboardApiContextType, getScoreboard } from './scoreboardApi';
 import {
+    OpenClassicalGeneratePairingsRequest,
     OpenClassicalPutPairingsRequest,
     OpenClassicalRegistrationRequest,
     OpenClassicalSubmitResultsRequest,
@@ -177,8 +178,10 @@ import {
     adminUnbanPlayer,
     adminVerifyResult,
     adminWithdrawPlayer,
+    generateOpenClassicalPairings,
     getLeaderboard,
     getOpenClassical,
+    getOpenClassicalStandings,
     listPreviousOpenClassicals,
     putOpenClassicalPairings,
     registerForOpenClassical,
@@ -290,7 +293,7 @@ export function ApiProvider({ children }: { children: ReactNode }) {
                 createMessage(idToken, auth.user, id, content),
 
             createGame: (req: CreateGameRequest) => createGame(idToken, req),
-            getGame: (cohort: string, id: string) => getGame(cohort, id),
+            getGame: (cohort: string, id: string) => getGame(cohort, id, Boolean(idToken)),
             featureGame: (cohort: string, id: string, featured: string) =>
                 featureGame(idToken, cohort, id, featured),
             updateGame: (cohort: string, id: string, req: Partial<UpdateGameRequest>) =>
@@ -365,12 +368,20 @@ export function ApiProvider({ children }: { children: ReactNode }) {
             ) => getLeaderboard(site, timePeriod, tournamentType, timeControl, date),
 
             getOpenClassical: (startsAt?: string) => getOpenClassical(startsAt),
+            getOpenClassicalStandings: (
+                region: string,
+                section: string,
+                round?: number,
+                startsAt?: string,
+            ) => getOpenClassicalStandings(region, section, round, startsAt),
             registerForOpenClassical: (req: OpenClassicalRegistrationRequest) =>
                 registerForOpenClassical(idToken, req),
             submitResultsForOpenClassical: (req: OpenClassicalSubmitResultsRequest) =>
                 submitResultsForOpenClassical(idToken, req),
             putOpenClassicalPairings: (req: OpenClassicalPutPairingsRequest) =>
                 putOpenClassicalPairings(idToken, req),
+            generateOpenClassicalPairings: (req: OpenClassicalGeneratePairingsRequest) =>
+                generateOpenClassicalPairings(idToken, req),
             listPreviousOpenClassicals: (startKey?: string) => listPreviousOpenClassicals(startKey),
             adminGetRegistrations: (region: string, section: string) =>
                 adminGetRegistrations(idToken, region, section),
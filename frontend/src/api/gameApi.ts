@@ -9,7 +9,14 @@ import {
 import { PgnMergeRequest } from '@jackstenglein/chess-dojo-common/src/pgn/merge';
 import { AxiosResponse } from 'axios';
 import { DateTime } from 'luxon';
-import { Game, GameInfo, GameReviewType, PositionComment, isGameResult } from '../database/game';
+import {
+    Game,
+    GameInfo,
+    GameKey,
+    GameReviewType,
+    PositionComment,
+    isGameResult,
+} from '../database/game';
 import { axiosService } from './axiosService';
 
 export interface GameApiContextType {
@@ -18,7 +25,9 @@ export interface GameApiContextType {
      * @param req The CreateGameRequest.
      * @returns The newly created Game.
      */
-    createGame: (req: CreateGameRequest) => Promise<AxiosResponse<Game | EditGameResponse>>;
+    createGame: (
+        req: CreateGameRequest,
+    ) => Promise<AxiosResponse<SaveGameResponse | EditGameResponse>>;
 
     /**
      * getGame returns the requested game.
@@ -49,7 +58,7 @@ export interface GameApiContextType {
         cohort: string,
         id: string,
         req: Partial<UpdateGameRequest>,
-    ) => Promise<AxiosResponse<Game>>;
+    ) => Promise<AxiosResponse<SaveGameResponse>>;
 
     /**
      * Deletes the specified games from the database. The caller
@@ -200,9 +209,21 @@ export interface GameApiContextType {
 export interface EditGameResponse {
     headers: GameHeader[];
     count: number;
+
+    /** The number of games which were skipped because they duplicate an existing game. */
+    duplicates?: number;
 }
 
-export function isGame(obj: Game | EditGameResponse): obj is Game {
+/** The response to creating or updating a single game. */
+export type SaveGameResponse = Game & {
+    /**
+     * The key of another game of the owner with the same players, date and moves, if one
+     * exists. When creating a game, the existing game is returned instead of a new game.
+     */
+    duplicateOf?: GameKey;
+};
+
+export function isGame<T extends Game>(obj: T | EditGameResponse): obj is T {
     return !('count' in obj);
 }
 
@@ -213,7 +234,7 @@ export function isGame(obj: Game | EditGameResponse): obj is Game {
  * @returns The newly created Game.
  */
 export function createGame(idToken: string, req: CreateGameRequest) {
-    return axiosService.post<Game | EditGameResponse>('/game2', req, {
+    return axiosService.post<SaveGameResponse | EditGameResponse>('/game2', req, {
         headers: {
             Authorization: 'Bearer ' + idToken,
         },
@@ -225,13 +246,16 @@ export function createGame(idToken: string, req: CreateGameRequest) {
  * getGame returns the requested game.
  * @param cohort The cohort the game is in.
  * @param id The id of the game.
+ * @param authenticated Whether to send the request as the signed-in user. Unlisted games
+ * in private directories can only be fetched by authenticated users with access.
  * @returns An AxiosResponse containing the requested game.
  */
-export function getGame(cohort: string, id: string) {
+export function getGame(cohort: string, id: string, authenticated = false) {
     cohort = encodeURIComponent(cohort);
     id = btoa(id); // Base64 encode id because API Gateway can't handle ? in the id
 
-    return axiosService.get<Game>(`/public/game/${cohort}/${id}`, {
+    const path = authenticated ? `/game/v2/${cohort}/${id}` : `/public/game/${cohort}/${id}`;
+    return axiosService.get<Game>(path, {
         functionName: 'getGame',
     });
 }
@@ -279,7 +303,7 @@ export function updateGame(
     // Base64 encode id because API Gateway can't handle ? in the id, even if it is URI encoded
     id = btoa(id);
 
-    return axiosService.put<Game>(`/game2/${cohort}/${id}`, req, {
+    return axiosService.put<SaveGameResponse>(`/game2/${cohort}/${id}`, req, {
         headers: { Authorization: 'Bearer ' + idToken },
         functionName: 'updateGame',
     });
@@ -3,6 +3,8 @@ import {
     Leaderboard,
     LeaderboardSite,
     OpenClassical,
+    OpenClassicalPairing,
+    OpenClassicalStanding,
     TournamentType,
 } from '../database/tournament';
 import { axiosService } from './axiosService';
@@ -36,6 +38,22 @@ export interface TournamentApiContextType {
      */
     getOpenClassical: (startsAt?: string) => Promise<AxiosResponse<OpenClassical>>;
 
+    /**
+     * Fetches the standings of a section of the requested Open Classical.
+     * @param region The region of the section.
+     * @param section The rating range of the section.
+     * @param round The round to get the standings after. If not provided, the latest round is used.
+     * @param startsAt The time period the open classical starts at. If not provided, the
+     * current tournament will be used.
+     * @returns An AxiosResponse containing the standings.
+     */
+    getOpenClassicalStandings: (
+        region: string,
+        section: string,
+        round?: number,
+        startsAt?: string,
+    ) => Promise<AxiosResponse<OpenClassicalStandingsResponse>>;
+
     /**
      * Submits a request to register for the Open Classical.
      * @param req The Open Classical registration request.
@@ -64,6 +82,16 @@ export interface TournamentApiContextType {
         req: OpenClassicalPutPairingsRequest,
     ) => Promise<AxiosResponse<OpenClassical>>;
 
+    /**
+     * Generates Swiss pairings for the next round of a section using the given request.
+     * Only admins and tournament admins can call this function.
+     * @param req The Open Classical generate pairings request.
+     * @returns An AxiosResponse containing the generated pairings.
+     */
+    generateOpenClassicalPairings: (
+        req: OpenClassicalGeneratePairingsRequest,
+    ) => Promise<AxiosResponse<OpenClassicalGeneratePairingsResponse>>;
+
     /**
      * Returns a list of previous open classicals.
      * @param startKey The optional start key to use when listing the open classicals.
@@ -149,6 +177,21 @@ export interface OpenClassicalRegistrationRequest {
     byeRequests: boolean[];
 }
 
+/** The standings of a section of the Open Classical. */
+export interface OpenClassicalStandingsResponse {
+    /** The region of the section. */
+    region: string;
+
+    /** The rating range of the section. */
+    section: string;
+
+    /** The round the standings were computed after. */
+    round: number;
+
+    /** The standings of the section, in rank order. */
+    standings: OpenClassicalStanding[];
+}
+
 /** A request to submit results for the Open Classical. */
 export interface OpenClassicalSubmitResultsRequest {
     region: string;
@@ -178,6 +221,37 @@ export interface OpenClassicalPutPairingsRequest {
     csvData?: string;
 }
 
+export interface OpenClassicalGeneratePairingsRequest {
+    /** The region to generate pairings for. */
+    region: string;
+
+    /** The section to generate pairings for. */
+    section: string;
+
+    /** The round to generate pairings for. Must be the next round of the section. */
+    round: number;
+
+    /** Whether to save the pairings as a new round. If false, the pairings are only previewed. */
+    commit: boolean;
+
+    /** The hash of the previewed pairings. Required if commit is true. */
+    pairingsHash?: string;
+}
+
+export interface OpenClassicalGeneratePairingsResponse {
+    /** The round the pairings were generated for. */
+    round: number;
+
+    /** The generated pairings. */
+    pairings: OpenClassicalPairing[];
+
+    /** The hash of the generated pairings, which must be passed back to commit them. */
+    pairingsHash: string;
+
+    /** The updated open classical. Only present if the pairings were committed. */
+    openClassical?: OpenClassical;
+}
+
 export interface OpenClassicalVerifyResultRequest {
     /** The region of the pairing to update. */
     region: string;
@@ -239,6 +313,30 @@ export function getOpenClassical(startsAt?: string) {
     });
 }
 
+/**
+ * Fetches the standings of a section of the requested Open Classical.
+ * @param region The region of the section.
+ * @param section The rating range of the section.
+ * @param round The round to get the standings after. If not provided, the latest round is used.
+ * @param startsAt The time period the open classical starts at. If not provided, the
+ * current tournament will be used.
+ * @returns An AxiosResponse containing the standings.
+ */
+export function getOpenClassicalStandings(
+    region: string,
+    section: string,
+    round?: number,
+    startsAt?: string,
+) {
+    return axiosService.get<OpenClassicalStandingsResponse>(
+        `/public/tournaments/open-classical/standings`,
+        {
+            params: { region, section, round, startsAt },
+            functionName: 'getOpenClassicalStandings',
+        },
+    );
+}
+
 /**
  * Submits a request to register for the Open Classical.
  * @param idToken The id token of the signed-in user.
@@ -282,6 +380,27 @@ export function putOpenClassicalPairings(idToken: string, req: OpenClassicalPutP
     });
 }
 
+/**
+ * Generates Swiss pairings for the next round of the open classical using the given request.
+ * Only admins and tournament admins can call this function.
+ * @param idToken The id token of the current signed-in user.
+ * @param req The request to use when generating pairings.
+ * @returns An AxiosResponse containing the generated pairings.
+ */
+export function generateOpenClassicalPairings(
+    idToken: string,
+    req: OpenClassicalGeneratePairingsRequest,
+) {
+    return axiosService.post<OpenClassicalGeneratePairingsResponse>(
+        `/tournaments/open-classical/admin/pairings/generate`,
+        req,
+        {
+            headers: { Authorization: 'Bearer ' + idToken },
+            functionName: 'generateOpenClassicalPairings',
+        },
+    );
+}
+
 interface ListPreviousOpenClassicalsResponse {
     openClassicals: OpenClassical[];
     lastEvaluatedKey: string;
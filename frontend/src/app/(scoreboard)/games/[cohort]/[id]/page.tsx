@@ -19,8 +19,14 @@ export async function generateMetadata({
     params: Promise<{ cohort: string; id: string }>;
 }): Promise<Metadata> {
     const { cohort, id } = await params;
-    const response = await getGame(cohort, id);
-    const game = response.data;
+    let game: Game;
+    try {
+        const response = await getGame(cohort, id);
+        game = response.data;
+    } catch {
+        // Games in private directories cannot be fetched without signing in
+        return defaultMetadata;
+    }
 
     const chess = new Chess({ pgn: game.pgn });
     const move = chess.lastMove();
@@ -151,6 +151,7 @@ export function ProfileEditorPage({ user }: { user: User }) {
     const [ratingSystem, setRatingSystem] = useState(user.ratingSystem);
     const [ratingEditors, setRatingEditors] = useState(getRatingEditors(user.ratings));
     const [enableZenMode, setEnableZenMode] = useState(user.enableZenMode);
+    const [autoImportGames, setAutoImportGames] = useState(user.autoImportGames);
 
     const [notificationSettings, setNotificationSettings] = useState(user.notificationSettings);
 
@@ -171,6 +172,7 @@ export function ProfileEditorPage({ user }: { user: User }) {
             ratingSystem,
             ratings: getRatingsFromEditors(ratingEditors),
             enableZenMode,
+            autoImportGames,
             notificationSettings,
         },
         profilePictureData,
@@ -415,6 +417,8 @@ export function ProfileEditorPage({ user }: { user: User }) {
                             setRatingEditors={setRatingEditors}
                             enableZenMode={enableZenMode}
                             setEnableZenMode={setEnableZenMode}
+                            autoImportGames={!!autoImportGames}
+                            setAutoImportGames={setAutoImportGames}
                             errors={errors}
                         />
 
@@ -47,20 +47,26 @@ const StatusIcon: React.FC<StatusIconProps> = ({ game }) => {
     const api = useApi();
     const request = useRequest<Date>();
     const [initialPgn, setInitialPgn] = useState(chess?.renderPgn() || '');
+    const [updatedAt, setUpdatedAt] = useState(game.updatedAt);
     const [hasChanges, setHasChanges] = useState(false);
     const [undoLog, setUndoLog] = useState<UndoLog[]>([]);
     const [anchorEl, setAnchorEl] = useState<HTMLElement | null>(null);
     const { user } = useAuth();
     const reconcile = useReconcile();
 
+    useEffect(() => {
+        setUpdatedAt(game.updatedAt);
+    }, [game.updatedAt]);
+
     const onSave = (cohort: string, id: string, pgnText: string, isUndo?: boolean) => {
         if (pgnText !== initialPgn) {
             request.onStart();
             api.updateGame(cohort, id, {
                 type: GameImportTypes.editor,
                 pgnText,
+                updatedAt,
             })
-                .then(() => {
+                .then((resp) => {
                     trackEvent(EventType.UpdateGame, {
                         method: 'autosave',
                         dojo_cohort: cohort,
@@ -78,6 +84,7 @@ const StatusIcon: React.FC<StatusIconProps> = ({ game }) => {
 
                     const date = new Date();
                     request.onSuccess(date);
+                    setUpdatedAt(resp.data.updatedAt);
                     setInitialPgn(pgnText);
                     setHasChanges(false);
                 })
@@ -147,9 +147,12 @@ function getLink(notification: Notification): string {
     switch (notification.type) {
         case NotificationTypes.GAME_COMMENT:
         case NotificationTypes.GAME_COMMENT_REPLY:
+        case NotificationTypes.GAME_COMMENT_REACTION:
             return `/games/${notification.gameCommentMetadata?.cohort}/${notification.gameCommentMetadata?.id}`;
         case NotificationTypes.GAME_REVIEW_COMPLETE:
             return `/games/${notification.gameReviewMetadata?.cohort}/${notification.gameReviewMetadata?.id}`;
+        case NotificationTypes.GAME_REVIEW_REFUND:
+            return `/games/${notification.gameReviewRefundMetadata?.cohort}/${notification.gameReviewRefundMetadata?.id}`;
 
         case NotificationTypes.NEW_FOLLOWER:
             return `/profile/${notification.newFollowerMetadata?.username}`;
@@ -174,5 +177,15 @@ function getLink(notification: Notification): string {
 
         case NotificationTypes.ROUND_ROBIN_START:
             return `/tournaments/round-robin?cohort=${notification.roundRobinStartMetadata?.cohort}`;
+
+        case NotificationTypes.MENTION:
+            switch (notification.mentionMetadata?.type) {
+                case 'GAME':
+                    return `/games/${notification.mentionMetadata.cohort}/${notification.mentionMetadata.id}`;
+                case 'TIMELINE':
+                    return `/newsfeed/${notification.mentionMetadata.owner}/${notification.mentionMetadata.id}`;
+                default:
+                    return `/meeting/${notification.mentionMetadata?.id}`;
+            }
     }
 }
@@ -66,6 +66,14 @@ const sections: NotificationSettingsSection[] = [
                 label: 'Notify me when a reply is added to a game comment thread I participated in',
                 path: 'siteNotificationSettings.disableGameCommentReplies',
             },
+            {
+                label: 'Notify me when a reaction is added to my game comments',
+                path: 'siteNotificationSettings.disableGameCommentReaction',
+            },
+            {
+                label: 'Notify me when someone mentions me in a comment',
+                path: 'siteNotificationSettings.disableMention',
+            },
             {
                 label: 'Notify me when I have a new follower',
                 path: 'siteNotificationSettings.disableNewFollower',
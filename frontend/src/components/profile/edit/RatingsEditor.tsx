@@ -37,6 +37,10 @@ interface RatingsEditorProps {
     enableZenMode: boolean;
     /** A callback to set whether zen mode is enabled. */
     setEnableZenMode: (enabled: boolean) => void;
+    /** Whether the nightly import of games from Lichess and Chess.com is enabled in the profile editor. */
+    autoImportGames: boolean;
+    /** A callback to set whether the nightly game import is enabled. */
+    setAutoImportGames: (enabled: boolean) => void;
     /** The errors in the profile editor. */
     errors: Record<string, string>;
 }
@@ -50,6 +54,8 @@ export function RatingsEditor({
     setRatingEditors,
     enableZenMode,
     setEnableZenMode,
+    autoImportGames,
+    setAutoImportGames,
     errors,
 }: RatingsEditorProps) {
     const setUsername = (ratingSystem: RatingSystem, username: string) => {
@@ -275,6 +281,16 @@ export function RatingsEditor({
                     />
                 }
             />
+
+            <FormControlLabel
+                label='Import my classical games from Lichess and Chess.com each night (imported games stay unlisted until you publish them)'
+                control={
+                    <Checkbox
+                        checked={autoImportGames}
+                        onChange={(e) => setAutoImportGames(e.target.checked)}
+                    />
+                }
+            />
         </Stack>
     );
 }
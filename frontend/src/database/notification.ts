@@ -15,9 +15,12 @@ export function getTitle(notification: Notification): string {
     switch (notification.type) {
         case NotificationTypes.GAME_COMMENT:
         case NotificationTypes.GAME_COMMENT_REPLY:
+        case NotificationTypes.GAME_COMMENT_REACTION:
             return `${notification.gameCommentMetadata?.headers.White} - ${notification.gameCommentMetadata?.headers.Black}`;
         case NotificationTypes.GAME_REVIEW_COMPLETE:
             return `${notification.gameReviewMetadata?.headers.White} - ${notification.gameReviewMetadata?.headers.Black}`;
+        case NotificationTypes.GAME_REVIEW_REFUND:
+            return `${notification.gameReviewRefundMetadata?.headers.White} - ${notification.gameReviewRefundMetadata?.headers.Black}`;
         case NotificationTypes.NEW_FOLLOWER:
             return 'You have a new follower';
         case NotificationTypes.TIMELINE_COMMENT:
@@ -36,6 +39,8 @@ export function getTitle(notification: Notification): string {
             return `You've been invited to an event on the calendar`;
         case NotificationTypes.ROUND_ROBIN_START:
             return `Round robin ${notification.roundRobinStartMetadata?.cohort} ${notification.roundRobinStartMetadata?.name} has started`;
+        case NotificationTypes.MENTION:
+            return notification.mentionMetadata?.title || 'You were mentioned in a comment';
     }
 }
 
@@ -49,8 +54,14 @@ export function getDescription(notification: Notification): string {
             return count === 1
                 ? `There is a new reply to a comment thread you participated in.`
                 : `There are ${count} new replies in comment threads you participated in.`;
+        case NotificationTypes.GAME_COMMENT_REACTION:
+            return `There ${count !== 1 ? `are ${count}` : 'is a'} new reaction${
+                count !== 1 ? 's' : ''
+            } on your comments.`;
         case NotificationTypes.GAME_REVIEW_COMPLETE:
             return `${notification.gameReviewMetadata?.reviewer.displayName} reviewed your game. Check the game settings for more info.`;
+        case NotificationTypes.GAME_REVIEW_REFUND:
+            return `Your game review is past its deadline, so ${notification.gameReviewRefundMetadata?.percentage}% of its price was refunded. The review is still in the queue.`;
         case NotificationTypes.NEW_FOLLOWER:
             return `${notification.newFollowerMetadata?.displayName}`;
         case NotificationTypes.TIMELINE_COMMENT:
@@ -78,5 +89,9 @@ export function getDescription(notification: Notification): string {
         }
         case NotificationTypes.ROUND_ROBIN_START:
             return ``;
+        case NotificationTypes.MENTION:
+            return count === 1
+                ? `${notification.mentionMetadata?.mentionedBy} mentioned you in a comment.`
+                : `You were mentioned in ${count} comments, most recently by ${notification.mentionMetadata?.mentionedBy}.`;
     }
 }
@@ -149,6 +149,35 @@ export interface OpenClassicalSection {
     rounds: OpenClassicalRound[];
 }
 
+export interface OpenClassicalStanding {
+    /** The player's rank in the section. Players tied on points and every tiebreak share a rank. */
+    rank: number;
+
+    /** The player. */
+    player: OpenClassicalPlayer;
+
+    /** The player's status in the open classical. */
+    status: OpenClassicalPlayerStatus;
+
+    /** The player's total points. */
+    points: number;
+
+    /** The sum of the player's opponents' points. */
+    buchholz: number;
+
+    /** The player's Buchholz score, excluding the lowest contribution. */
+    buchholzCut1: number;
+
+    /** The sum of the points of the opponents the player beat, plus half of those they drew. */
+    sonnebornBerger: number;
+
+    /** The rating the player performed at in played games. Zero if no games were played. */
+    performanceRating: number;
+
+    /** The number of games the player actually played, excluding byes and forfeits. */
+    gamesPlayed: number;
+}
+
 /**
  * Returns a sorted list of the rating ranges in the given open classical.
  * @param openClassical The open classical to get the rating ranges for.
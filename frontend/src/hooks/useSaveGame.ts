@@ -41,7 +41,15 @@ export default function useSaveGame(): UseSaveGameFields {
             onCreateGame(createReq, response.data, router);
 
             if (isGame(response.data)) {
-                request.onSuccess();
+                request.onSuccess(
+                    response.data.duplicateOf
+                        ? 'This game was already uploaded, opening the existing game'
+                        : undefined,
+                );
+            } else if (response.data.duplicates) {
+                request.onSuccess(
+                    `Created ${response.data.count} games, skipped ${response.data.duplicates} duplicates`,
+                );
             } else {
                 request.onSuccess(`Created ${response.data.count} games`);
             }
@@ -58,8 +66,12 @@ export default function useSaveGame(): UseSaveGameFields {
 
         request.onStart();
         try {
-            await api.updateGame(game.cohort, game.id, updateReq);
-            request.onSuccess();
+            const response = await api.updateGame(game.cohort, game.id, updateReq);
+            request.onSuccess(
+                response.data.duplicateOf
+                    ? 'Game saved, but it has the same players, date and moves as another of your games'
+                    : undefined,
+            );
         } catch (err) {
             request.onFailure(err);
         }